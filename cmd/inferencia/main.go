@@ -3,21 +3,39 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
+	"google.golang.org/grpc"
+
+	"github.com/menezmethod/inferencia/internal/async"
+	"github.com/menezmethod/inferencia/internal/audio"
 	"github.com/menezmethod/inferencia/internal/auth"
 	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/batch"
+	"github.com/menezmethod/inferencia/internal/breaker"
+	"github.com/menezmethod/inferencia/internal/cache"
 	"github.com/menezmethod/inferencia/internal/config"
+	"github.com/menezmethod/inferencia/internal/embedbatch"
+	"github.com/menezmethod/inferencia/internal/grpcapi"
+	"github.com/menezmethod/inferencia/internal/handler"
 	"github.com/menezmethod/inferencia/internal/logging"
 	"github.com/menezmethod/inferencia/internal/middleware"
 	"github.com/menezmethod/inferencia/internal/observability"
+	"github.com/menezmethod/inferencia/internal/priority"
 	"github.com/menezmethod/inferencia/internal/router"
+	"github.com/menezmethod/inferencia/internal/semcache"
 	"github.com/menezmethod/inferencia/internal/server"
+	"github.com/menezmethod/inferencia/internal/speech"
+	"github.com/menezmethod/inferencia/internal/streams"
+	"github.com/menezmethod/inferencia/internal/voices"
 	"github.com/menezmethod/inferencia/internal/watchdog"
 )
 
@@ -50,25 +68,49 @@ func main() {
 		if healthTimeout <= 0 {
 			healthTimeout = 5 * time.Second
 		}
+		reg.Balancer().SetWeight(b.Name, b.Weight)
+		reg.Balancer().SetLimit(b.Name, b.MaxConcurrency)
+		var be backend.Backend
 		switch b.Type {
 		case "mlx":
-			reg.Register(backend.NewMLX(b.Name, b.URL, healthTimeout, b.Timeout))
-			logger.Info("backend registered", "name", b.Name, "type", b.Type, "url", b.URL)
+			be = backend.NewMLX(b.Name, b.URL, healthTimeout, b.Timeout)
 		case "ollama":
-			reg.Register(backend.NewOllama(b.Name, b.URL, healthTimeout, b.Timeout))
-			logger.Info("backend registered", "name", b.Name, "type", b.Type, "url", b.URL)
+			be = backend.NewOllama(b.Name, b.URL, healthTimeout, b.Timeout)
 		default:
 			logger.Error("unknown backend type", "name", b.Name, "type", b.Type)
 			os.Exit(1)
 		}
+		if bc, ok := embedBatchConfig(cfg.EmbedBatching, b); ok {
+			be = embedbatch.New(be, bc)
+		}
+		reg.Register(be)
+		logger.Info("backend registered", "name", b.Name, "type", b.Type, "url", b.URL)
 	}
 
-	ttsRouter := router.NewRegistry()
+	// TTS, rerank and STT backends share one capability-aware router registry.
+	rtr := router.NewRegistry()
+	var encoder audio.Encoder
+	if cfg.TTS.Encoder == "ffmpeg" {
+		encoder = audio.FFmpeg{Path: cfg.TTS.FFmpegPath}
+	}
 	for _, t := range cfg.TTSBackends {
-		ttsBackend := backend.NewTTSHTTP(t.Name, t.URL, t.Timeout)
-		ttsRouter.Register(router.BackendInfo{
+		var ttsBackend backend.TTSBackend = backend.NewTTSHTTP(t.Name, t.URL, t.Timeout)
+		if t.Streaming {
+			ttsBackend = backend.NewTTSStreamHTTP(t.Name, t.URL, t.Timeout)
+		}
+		if len(t.Formats) > 0 || t.IgnoresSpeed {
+			ttsBackend = backend.NewTranscodingTTS(ttsBackend, backend.TranscodeConfig{
+				Formats:      t.Formats,
+				IgnoresSpeed: t.IgnoresSpeed,
+				Encoder:      encoder,
+			})
+		}
+		rtr.Balancer().SetWeight(t.Name, t.Weight)
+		rtr.Balancer().SetLimit(t.Name, t.MaxConcurrency)
+		rtr.Register(router.BackendInfo{
 			Name:       t.Name,
 			TTSBackend: ttsBackend,
+			DefaultVoice: t.DefaultVoice,
 			Capabilities: []router.Capability{router.CapTTS},
 			Models: []router.ModelInfo{
 				{ID: t.Name, Kind: router.CapTTS},
@@ -76,32 +118,284 @@ func main() {
 		})
 		logger.Info("tts backend registered", "name", t.Name, "url", t.URL)
 	}
+	for _, rb := range cfg.RerankBackends {
+		models := make([]router.ModelInfo, 0, len(rb.Models))
+		for _, m := range rb.Models {
+			models = append(models, router.ModelInfo{ID: m, Kind: router.CapRerank})
+		}
+		rtr.Balancer().SetWeight(rb.Name, rb.Weight)
+		rtr.Balancer().SetLimit(rb.Name, rb.MaxConcurrency)
+		rtr.Register(router.BackendInfo{
+			Name:          rb.Name,
+			RerankBackend: backend.NewRerankHTTP(rb.Name, rb.URL, rb.API, rb.Timeout),
+			Capabilities:  []router.Capability{router.CapRerank},
+			Models:        models,
+		})
+		logger.Info("rerank backend registered", "name", rb.Name, "url", rb.URL, "models", rb.Models)
+	}
+	for _, sb := range cfg.STTBackends {
+		models := make([]router.ModelInfo, 0, len(sb.Models))
+		for _, m := range sb.Models {
+			models = append(models, router.ModelInfo{ID: m, Kind: router.CapSTT})
+		}
+		rtr.Balancer().SetWeight(sb.Name, sb.Weight)
+		rtr.Balancer().SetLimit(sb.Name, sb.MaxConcurrency)
+		rtr.Register(router.BackendInfo{
+			Name:         sb.Name,
+			STTBackend:   backend.NewSTTHTTP(sb.Name, sb.URL, sb.Timeout),
+			Capabilities: []router.Capability{router.CapSTT},
+			Models:       models,
+		})
+		logger.Info("stt backend registered", "name", sb.Name, "url", sb.URL, "models", sb.Models)
+	}
+
+	if err := configureLoadBalancing(cfg.LoadBalancing, reg, rtr); err != nil {
+		logger.Error("invalid load balancing config", "err", err)
+		os.Exit(1)
+	}
+	logger.Info("load balancing configured",
+		"chat", reg.Balancer().Strategy(backend.KindChat),
+		"embed", reg.Balancer().Strategy(backend.KindEmbed),
+		"tts", rtr.Balancer().Strategy(router.CapTTS.String()),
+		"rerank", rtr.Balancer().Strategy(router.CapRerank.String()),
+		"stt", rtr.Balancer().Strategy(router.CapSTT.String()),
+	)
+
+	// Wait queue in front of backends with max_concurrency.
+	for _, lb := range []*backend.LoadBalancer{reg.Balancer(), rtr.Balancer()} {
+		lb.SetQueue(cfg.Queue.MaxSize, cfg.Queue.Timeout, cfg.Queue.RetryAfter)
+		lb.OnQueue(recordQueueEvent)
+		for name, n := range cfg.Priority.Reserved {
+			class, _ := priority.Parse(name) // validated by config.Load
+			lb.SetReserved(class, n)
+		}
+	}
+
+	// Drain state: log and export transitions, then apply the config flags.
+	reportDrain := func(name string, state backend.DrainState) {
+		middleware.BackendDrainState.WithLabelValues(name).Set(drainStateValue(state))
+		switch state {
+		case backend.DrainDraining:
+			logger.Info("backend draining", "backend", name)
+		case backend.DrainDrained:
+			logger.Info("backend drained", "backend", name)
+		case backend.DrainActive:
+			logger.Info("backend resumed", "backend", name)
+		}
+	}
+	reg.Balancer().OnDrainChange(reportDrain)
+	rtr.Balancer().OnDrainChange(reportDrain)
+	var drains configDrains
+	drains.apply(cfg, reg, rtr)
 
 	wd := watchdog.New(watchdog.Config{
 		Interval:       cfg.Watchdog.Interval,
 		FailThreshold:  cfg.Watchdog.FailThreshold,
 		RequestTimeout: cfg.Watchdog.RequestTimeout,
-	}, reg, ttsRouter, logger)
+	}, reg, rtr, logger)
+
+	// Handlers consult the watchdog's probes and, when enabled, the circuit
+	// breakers fed by live request outcomes.
+	var hc backend.HealthChecker = wd
+	if cfg.CircuitBreaker.Enabled {
+		cb := breaker.New(breaker.Config{
+			ConsecutiveFailures: cfg.CircuitBreaker.ConsecutiveFailures,
+			ErrorRate:           cfg.CircuitBreaker.ErrorRate,
+			Window:              cfg.CircuitBreaker.Window,
+			MinRequests:         cfg.CircuitBreaker.MinRequests,
+			Cooldown:            cfg.CircuitBreaker.Cooldown,
+			HalfOpenRequests:    cfg.CircuitBreaker.HalfOpenRequests,
+		}, logger)
+		hc = backend.CombineHealth(wd, cb)
+		logger.Info("circuit breaker enabled",
+			"consecutive_failures", cfg.CircuitBreaker.ConsecutiveFailures,
+			"error_rate", cfg.CircuitBreaker.ErrorRate,
+			"cooldown", cfg.CircuitBreaker.Cooldown,
+		)
+	}
+
+	handlerOpts := []handler.Option{
+		handler.WithStreamLimits(cfg.Streaming.HeartbeatInterval, cfg.Streaming.FirstChunkTimeout, cfg.Streaming.IdleTimeout, cfg.Streaming.MaxDuration),
+	}
+	if eb := cfg.EmbedBatching; eb.Enabled {
+		handlerOpts = append(handlerOpts, handler.WithEmbedBatching(embedbatch.Config{Window: eb.Window, MaxBatchSize: eb.MaxBatchSize}))
+	}
+	if cfg.Cache.Enabled {
+		respCache, errCache := cache.New(cache.Config{
+			MaxEntries: cfg.Cache.MaxEntries,
+			MaxBytes:   int64(cfg.Cache.MaxSizeMB) << 20,
+			TTL:        cfg.Cache.TTL,
+			Dir:        cfg.Cache.Dir,
+		})
+		if errCache != nil {
+			logger.Error("failed to open response cache", "err", errCache)
+			os.Exit(1)
+		}
+		handlerOpts = append(handlerOpts, handler.WithCache(respCache))
+		logger.Info("response cache enabled",
+			"entries", respCache.Len(),
+			"ttl", cfg.Cache.TTL,
+			"dir", cfg.Cache.Dir,
+		)
+	}
 
-	srv := server.New(cfg, reg, ks, wd, logger)
+	var semCache *semcache.Cache
+	if cfg.SemanticCache.Enabled {
+		semCache = semcache.New(semcache.Config{
+			Threshold:  cfg.SemanticCache.Threshold,
+			MaxEntries: cfg.SemanticCache.MaxEntries,
+			TTL:        cfg.SemanticCache.TTL,
+		})
+		handlerOpts = append(handlerOpts, handler.WithSemanticCache(semCache, cfg.SemanticCache.EmbeddingModel, cfg.SemanticCache.EmbedTimeout))
+		logger.Info("semantic cache enabled",
+			"threshold", cfg.SemanticCache.Threshold,
+			"embedding_model", cfg.SemanticCache.EmbeddingModel,
+		)
+	}
 
-	if ttsRouter.Len() > 0 {
-		rl := middleware.NewRateLimiter(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
-		protected := func(h http.Handler) http.Handler {
-			return middleware.Chain(h,
-				middleware.RequestID(),
-				middleware.Recover(logger),
-				middleware.Metrics(),
-				middleware.Logging(logger),
-				middleware.Auth(ks),
-				middleware.RateLimit(rl),
-			)
+	if cfg.Streams.Enabled {
+		handlerOpts = append(handlerOpts, handler.WithResumableStreams(streams.New(streams.Config{
+			Grace:      cfg.Streams.Grace,
+			MaxStreams: cfg.Streams.MaxStreams,
+			MaxBytes:   cfg.Streams.MaxBufferKB * 1024,
+		})))
+		logger.Info("resumable streams enabled", "grace", cfg.Streams.Grace)
+	}
+
+	// Async mode: long chat requests become background jobs.
+	var asyncJobs *async.Manager
+	if cfg.Async.Enabled {
+		asyncJobs = async.New(async.Config{
+			TTL:             cfg.Async.TTL,
+			Timeout:         cfg.Async.Timeout,
+			MaxPending:      cfg.Async.MaxPending,
+			WebhookSecret:   cfg.Async.Webhook.Secret,
+			WebhookHosts:    cfg.Async.Webhook.AllowedHosts,
+			WebhookTimeout:  cfg.Async.Webhook.Timeout,
+			WebhookAttempts: cfg.Async.Webhook.MaxAttempts,
+		}, logger)
+		handlerOpts = append(handlerOpts, handler.WithAsync(asyncJobs))
+		logger.Info("async mode enabled",
+			"ttl", cfg.Async.TTL,
+			"webhooks", cfg.Async.Webhook.Secret != "",
+		)
+	}
+
+	// The voice catalog and lexicons serve the TTS endpoints and chat
+	// requests that ask for the answer to be spoken.
+	var catalog *voices.Catalog
+	var speechOpts []handler.Option
+	if len(cfg.TTSBackends) > 0 {
+		catalog = voices.New(rtr, hc, cfg.TTS.VoiceCatalogTTL, logger)
+		lexicons := make(map[string]*speech.Lexicon, len(cfg.TTS.Lexicons))
+		for name, entries := range cfg.TTS.Lexicons {
+			lexicons[name] = speech.NewLexicon(cfg.TTS.Lexicon, entries)
 		}
-		server.RegisterTTSRoutes(srv, ttsRouter, wd, logger, protected)
+		speechOpts = []handler.Option{
+			handler.WithVoices(catalog),
+			handler.WithLexicons(speech.NewLexicon(cfg.TTS.Lexicon), lexicons, ks),
+		}
+		handlerOpts = append(handlerOpts, handler.WithChatAudio(rtr))
+		handlerOpts = append(handlerOpts, speechOpts...)
+	}
+
+	// One limiter for every route and the gRPC API, so a key's budget is
+	// shared across them.
+	rl := middleware.NewRateLimiter(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
+	srv := server.New(cfg, reg, ks, hc, rl, logger, handlerOpts...)
+
+	protected := func(h http.Handler) http.Handler {
+		return middleware.Chain(h,
+			middleware.RequestID(),
+			middleware.Recover(logger),
+			middleware.Metrics(),
+			middleware.Logging(logger),
+			middleware.Auth(ks),
+			middleware.Priority(ks),
+			middleware.RateLimit(rl),
+		)
+	}
+	var speechHandler http.Handler
+	if rtr.Len() > 0 {
+		if len(cfg.TTSBackends) > 0 {
+			ttsOpts := slices.Clone(speechOpts)
+			if lf := cfg.TTS.LongForm; lf.Enabled {
+				ttsOpts = append(ttsOpts, handler.WithLongSpeech(lf.MaxInputLength, lf.SegmentLength, lf.Parallelism, lf.Crossfade))
+			}
+			if tc := cfg.TTS.Cache; tc.Enabled {
+				speechCache, errCache := cache.New(cache.Config{
+					MaxBytes: int64(tc.MaxSizeMB) << 20,
+					TTL:      tc.TTL,
+					Dir:      tc.Dir,
+				})
+				if errCache != nil {
+					logger.Error("failed to open tts cache", "err", errCache)
+					os.Exit(1)
+				}
+				middleware.TTSCacheSizeBytes.Set(float64(speechCache.Bytes()))
+				ttsOpts = append(ttsOpts, handler.WithSpeechCache(speechCache))
+				logger.Info("tts cache enabled", "entries", speechCache.Len(), "dir", tc.Dir)
+			}
+			server.RegisterTTSRoutes(srv, rtr, hc, logger, protected, ttsOpts...)
+			speechHandler = handler.Audio(rtr, hc, logger, ttsOpts...)
+			server.RegisterVoiceRoutes(srv, catalog, protected)
+			if rt := cfg.Realtime; rt.Enabled {
+				rtOpts := append(ttsOpts, handler.WithRealtime(rt.MaxBuffer, rt.MaxDuration, rt.VADThreshold, rt.VADSilence), handler.WithRealtimeRateLimit(rl))
+				server.RegisterRealtimeRoutes(srv, reg, rtr, hc, logger, protected, rtOpts...)
+				logger.Info("realtime voice enabled", "stt_backends", len(cfg.STTBackends), "vad_silence", rt.VADSilence)
+			}
+		}
+		if len(cfg.RerankBackends) > 0 {
+			server.RegisterRerankRoutes(srv, rtr, hc, logger, protected)
+		}
+	}
+
+	server.RegisterAsyncRoutes(srv, asyncJobs, reg, hc, logger, protected, handlerOpts...)
+
+	// Batch API: lines run in-process through the chat and embeddings
+	// handlers at batch priority.
+	var batches *batch.Manager
+	if cfg.Batch.Enabled {
+		store, errStore := batch.OpenStore(cfg.Batch.Dir)
+		if errStore != nil {
+			logger.Error("failed to open batch store", "err", errStore)
+			os.Exit(1)
+		}
+		batches = batch.NewManager(store, server.BatchHandlers(reg, hc, logger, handlerOpts...), batch.Config{
+			Workers:     cfg.Batch.Workers,
+			MaxRequests: cfg.Batch.MaxRequests,
+		}, logger)
+		batches.Start()
+		server.RegisterBatchRoutes(srv, batches, store, int64(cfg.Batch.MaxFileSizeMB)<<20, logger, protected)
+		logger.Info("batch api enabled", "dir", cfg.Batch.Dir, "workers", cfg.Batch.Workers)
+	}
+
+	// gRPC API: calls run in-process through the same handlers as the HTTP
+	// routes, on a port of their own.
+	var grpcSrv *grpc.Server
+	if g := cfg.GRPC; g.Enabled {
+		grpcSrv = grpcapi.New(grpcapi.Handlers{
+			Chat:       handler.ChatCompletions(reg, hc, logger, handlerOpts...),
+			Embeddings: handler.Embeddings(reg, hc, logger, handlerOpts...),
+			Models:     handler.Models(reg, hc, logger),
+			Speech:     speechHandler,
+		}, ks, rl, g.Reflection, logger)
 	}
 
 	// Register consolidated health status endpoint.
-	server.RegisterHealthStatusRoute(srv, reg, ttsRouter)
+	server.RegisterHealthStatusRoute(srv, reg, rtr)
+
+	// Operator API, only when admin keys are configured.
+	if len(cfg.Admin.Keys) > 0 {
+		adminKS, errAdmin := auth.NewKeyStoreFromKeys(cfg.Admin.Keys)
+		if errAdmin != nil {
+			logger.Error("failed to load admin keys", "err", errAdmin)
+			os.Exit(1)
+		}
+		server.RegisterAdminRoutes(srv, reg, rtr, adminKS, logger)
+		server.RegisterSemanticCacheRoutes(srv, semCache, adminKS, logger)
+		logger.Info("admin api enabled", "keys", adminKS.Count())
+	}
 
 	wd.Start()
 
@@ -118,10 +412,42 @@ func main() {
 		logger.Info("opentelemetry tracing enabled", "endpoint", cfg.Observability.OTelEndpoint)
 	}
 
+	// SIGUSR1 re-reads the config file and applies the backends' drain flags,
+	// so a backend can be drained by editing the config without a restart.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGUSR1)
+	go func() {
+		for range reload {
+			newCfg, errLoad := config.Load(*configPath)
+			if errLoad != nil {
+				logger.Error("config reload failed, drain flags unchanged", "err", errLoad)
+				continue
+			}
+			logger.Info("config reloaded, applying drain flags")
+			drains.apply(newCfg, reg, rtr)
+		}
+	}()
+
 	// Graceful shutdown on SIGINT/SIGTERM.
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 
+	if grpcSrv != nil {
+		grpcAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.GRPC.Port)
+		lis, errListen := net.Listen("tcp", grpcAddr)
+		if errListen != nil {
+			logger.Error("grpc listen failed", "addr", grpcAddr, "err", errListen)
+			os.Exit(1)
+		}
+		go func() {
+			logger.Info("grpc server starting", "addr", grpcAddr, "reflection", cfg.GRPC.Reflection)
+			if err := grpcSrv.Serve(lis); err != nil {
+				logger.Error("grpc server error", "err", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
 	go func() {
 		logger.Info("server starting", "addr", cfg.Server.Addr())
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -141,9 +467,137 @@ func main() {
 		_ = tp.Shutdown(ctx)
 	}
 	server.Shutdown(ctx, srv, logger)
+	if grpcSrv != nil {
+		grpcapi.Shutdown(ctx, grpcSrv, logger)
+	}
+	if batches != nil {
+		batches.Stop()
+	}
+	if asyncJobs != nil {
+		asyncJobs.Stop()
+	}
 	logger.Info("server stopped")
 }
 
+// configDrains remembers which backends the config file drained, so that a
+// reload resumes only those and leaves drains started through the admin API
+// alone.
+type configDrains struct {
+	chat   map[string]bool
+	routed map[string]bool // TTS, rerank and STT backends
+}
+
+// apply drains the backends marked drain: true and resumes those an earlier
+// apply drained that no longer are. Backends that are not registered (e.g.
+// added to the file after startup) are ignored.
+func (d *configDrains) apply(cfg config.Config, reg *backend.Registry, rtr *router.Registry) {
+	chat := make(map[string]bool)
+	for _, b := range cfg.Backends {
+		if b.Drain {
+			chat[b.Name] = true
+		}
+	}
+	routed := make(map[string]bool)
+	for _, t := range cfg.TTSBackends {
+		if t.Drain {
+			routed[t.Name] = true
+		}
+	}
+	for _, rb := range cfg.RerankBackends {
+		if rb.Drain {
+			routed[rb.Name] = true
+		}
+	}
+	for _, sb := range cfg.STTBackends {
+		if sb.Drain {
+			routed[sb.Name] = true
+		}
+	}
+
+	for name := range chat {
+		_ = reg.Drain(name)
+	}
+	for name := range d.chat {
+		if !chat[name] {
+			_ = reg.Resume(name)
+		}
+	}
+	for name := range routed {
+		_ = rtr.Drain(name)
+	}
+	for name := range d.routed {
+		if !routed[name] {
+			_ = rtr.Resume(name)
+		}
+	}
+	d.chat, d.routed = chat, routed
+}
+
+// recordQueueEvent exports wait-queue activity as Prometheus metrics.
+func recordQueueEvent(e backend.QueueEvent) {
+	class := e.Class.String()
+	middleware.QueueDepth.WithLabelValues(e.Kind, class).Set(float64(e.Depth))
+	switch e.Outcome {
+	case backend.QueueAdmitted, backend.QueueCanceled:
+		middleware.QueueWaitDuration.WithLabelValues(e.Kind, class, e.Outcome).Observe(e.Wait.Seconds())
+	case backend.QueueTimedOut:
+		middleware.QueueWaitDuration.WithLabelValues(e.Kind, class, e.Outcome).Observe(e.Wait.Seconds())
+		middleware.QueueRejectionsTotal.WithLabelValues(e.Kind, "timeout").Inc()
+	case backend.QueueRejected:
+		middleware.QueueRejectionsTotal.WithLabelValues(e.Kind, "full").Inc()
+	}
+}
+
+// drainStateValue maps a drain state to the inferencia_backend_drain_state
+// gauge value.
+func drainStateValue(s backend.DrainState) float64 {
+	switch s {
+	case backend.DrainDraining:
+		return 1
+	case backend.DrainDrained:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// configureLoadBalancing applies the per-capability strategies and EWMA decay
+// to both registries' load balancers.
+func configureLoadBalancing(cfg config.LoadBalancing, reg *backend.Registry, rtr *router.Registry) error {
+	for _, c := range []struct {
+		lb       *backend.LoadBalancer
+		kind     string
+		strategy string
+	}{
+		{reg.Balancer(), backend.KindChat, cfg.Chat},
+		{reg.Balancer(), backend.KindEmbed, cfg.Embed},
+		{rtr.Balancer(), router.CapTTS.String(), cfg.TTS},
+		{rtr.Balancer(), router.CapRerank.String(), cfg.Rerank},
+		{rtr.Balancer(), router.CapSTT.String(), cfg.STT},
+	} {
+		s, err := backend.ParseStrategy(c.strategy)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.kind, err)
+		}
+		c.lb.SetStrategy(c.kind, s)
+	}
+	reg.Balancer().SetDecay(cfg.Decay)
+	rtr.Balancer().SetDecay(cfg.Decay)
+	return nil
+}
+
+// embedBatchConfig returns the splitting settings for backend b, and false
+// when its embedding calls need no splitting. Coalescing happens in the
+// embeddings handler, before a backend is chosen; a coalesced call larger
+// than b's max_embed_batch is split here.
+func embedBatchConfig(cfg config.EmbedBatching, b config.Backend) (embedbatch.Config, bool) {
+	bc := embedbatch.Config{MaxBatchSize: b.MaxEmbedBatch}
+	if cfg.Enabled && bc.MaxBatchSize == 0 {
+		bc.MaxBatchSize = cfg.MaxBatchSize
+	}
+	return bc, bc.MaxBatchSize > 0
+}
+
 func newLogger(cfg config.Log) *slog.Logger {
 	var level slog.Level
 	switch cfg.Level {
@@ -28,6 +28,70 @@ func BackendOverloaded(backend string) *Error {
 	}
 }
 
+// QueueFull returns 503 when every backend is at its concurrency limit and the
+// wait queue has no room.
+func QueueFull(backend string) *Error {
+	return &Error{
+		Status:  503,
+		Message: "Backend " + backend + " is at capacity and the wait queue is full. Please retry shortly.",
+		Type:    TypeBackendDown,
+		Code:    "queue_full",
+	}
+}
+
+// QueueTimeout returns 503 when a request waited too long for a free backend.
+func QueueTimeout(backend string) *Error {
+	return &Error{
+		Status:  503,
+		Message: "Timed out waiting for capacity on backend " + backend + ". Please retry shortly.",
+		Type:    TypeBackendDown,
+		Code:    "queue_timeout",
+	}
+}
+
+// TooManyJobs returns 429 when the server is already running as many async
+// jobs as it allows.
+func TooManyJobs() *Error {
+	return &Error{
+		Status:  429,
+		Message: "Too many async jobs are running. Please retry shortly.",
+		Type:    TypeRateLimit,
+		Code:    "too_many_jobs",
+	}
+}
+
+// ModelNotFound returns 404 when no backend of the endpoint's kind lists the
+// requested model.
+func ModelNotFound(model string) *Error {
+	return &Error{
+		Status:  404,
+		Message: "The model " + strconv.Quote(model) + " does not exist or is not served by any backend.",
+		Type:    TypeInvalidRequest,
+		Code:    "model_not_found",
+		Param:   "model",
+	}
+}
+
+// UnknownVoice returns 400 when no TTS backend (serving model, if named)
+// offers the requested voice. The message suggests the closest voices that
+// do exist.
+func UnknownVoice(voice, model string, suggestions []string) *Error {
+	msg := "Voice " + strconv.Quote(voice) + " is not offered by any TTS backend."
+	if model != "" {
+		msg = "Voice " + strconv.Quote(voice) + " is not offered by model " + strconv.Quote(model) + "."
+	}
+	if len(suggestions) > 0 {
+		msg += " Did you mean " + strings.Join(suggestions, ", ") + "?"
+	}
+	return &Error{
+		Status:  400,
+		Message: msg,
+		Type:    TypeInvalidRequest,
+		Code:    "voice_not_found",
+		Param:   "voice",
+	}
+}
+
 // FromBackendError maps a backend transport or upstream error to an OpenAI-compatible API error.
 func FromBackendError(backend string, err error) *Error {
 	if err == nil {
@@ -42,7 +106,7 @@ func FromBackendError(backend string, err error) *Error {
 		return BackendUnavailable(backend)
 	}
 
-	if status, ok := upstreamHTTPStatus(err); ok {
+	if status, ok := UpstreamStatus(err); ok {
 		switch status {
 		case 429, 502, 503:
 			return BackendOverloaded(backend)
@@ -85,7 +149,9 @@ func isConnectionUnavailable(err error) bool {
 		strings.Contains(msg, "dial tcp")
 }
 
-func upstreamHTTPStatus(err error) (int, bool) {
+// UpstreamStatus extracts the HTTP status code from a backend error of the form
+// "...: status 503: ...", as produced by the backend adapters.
+func UpstreamStatus(err error) (int, bool) {
 	msg := err.Error()
 	idx := strings.Index(msg, "status ")
 	if idx < 0 {
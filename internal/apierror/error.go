@@ -108,3 +108,24 @@ func Internal(msg string) *Error {
 		Type:    TypeServer,
 	}
 }
+
+// NotFound returns a 404 error for unknown resources.
+func NotFound(msg string) *Error {
+	return &Error{
+		Status:  http.StatusNotFound,
+		Message: msg,
+		Type:    TypeInvalidRequest,
+		Code:    "not_found",
+	}
+}
+
+// Forbidden returns a 403 error when the API key is valid but not allowed to
+// perform the request.
+func Forbidden(msg string) *Error {
+	return &Error{
+		Status:  http.StatusForbidden,
+		Message: msg,
+		Type:    TypeInvalidRequest,
+		Code:    "permission_denied",
+	}
+}
@@ -0,0 +1,416 @@
+// Package async runs API requests in the background for clients whose
+// generations outlast their own or a proxy's timeouts. A job is answered with
+// 202 and an ID, served in-process by the endpoint's handler, and its result
+// is kept for a TTL to be polled or pushed to a signed webhook.
+package async
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/auth"
+	"github.com/menezmethod/inferencia/internal/middleware"
+)
+
+// Status is a job's place in its lifecycle.
+type Status string
+
+// Job statuses. A job is failed when the endpoint answered with an error; its
+// response then holds the error body.
+const (
+	StatusInProgress Status = "in_progress"
+	StatusCompleted  Status = "completed"
+	StatusFailed     Status = "failed"
+)
+
+// SignatureHeader carries the webhook signature: "t=<unix time>,v1=<hex
+// HMAC-SHA256 of "<t>.<body>" keyed with the webhook secret>".
+const SignatureHeader = "X-Inferencia-Signature"
+
+// ErrNotFound is returned for jobs that do not exist, have expired or belong
+// to another API key.
+var ErrNotFound = errors.New("not found")
+
+// ErrTooManyJobs is returned when MaxPending jobs are already running.
+var ErrTooManyJobs = errors.New("too many async jobs")
+
+// Job is an async request and, once finished, its response.
+type Job struct {
+	ID          string    `json:"id"`
+	Object      string    `json:"object"`
+	Endpoint    string    `json:"endpoint"`
+	Status      Status    `json:"status"`
+	CreatedAt   int64     `json:"created_at"`
+	CompletedAt int64     `json:"completed_at,omitempty"`
+	ExpiresAt   int64     `json:"expires_at,omitempty"`
+	Response    *Response `json:"response,omitempty"`
+}
+
+// Response is the endpoint's answer to a job.
+type Response struct {
+	StatusCode int             `json:"status_code"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// Config controls async jobs.
+type Config struct {
+	TTL        time.Duration // how long finished jobs are kept
+	Timeout    time.Duration // per job; 0 = none
+	MaxPending int           // jobs running at once; 0 = unlimited
+
+	WebhookSecret   string   // signs callbacks; webhooks are refused without it
+	WebhookHosts    []string // hosts callbacks may go to; empty = any host with only public addresses
+	WebhookTimeout  time.Duration
+	WebhookAttempts int
+}
+
+// Manager runs and keeps async jobs in memory. It is safe for concurrent use.
+type Manager struct {
+	cfg    Config
+	client *http.Client
+	logger *slog.Logger
+
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	pending int
+}
+
+type job struct {
+	Job
+	owner string // auth.KeyID of the submitting key; only it can read the job
+}
+
+// New creates a Manager.
+func New(cfg Config, logger *slog.Logger) *Manager {
+	if cfg.WebhookAttempts < 1 {
+		cfg.WebhookAttempts = 1
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	client := &http.Client{Timeout: cfg.WebhookTimeout}
+	if len(cfg.WebhookHosts) == 0 {
+		// Without an allowlist, check every address actually dialed, so a
+		// name that resolves differently at delivery time cannot reach an
+		// internal service either. Proxies are not used, as they would hide
+		// the address.
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.Proxy = nil
+		t.DialContext = (&net.Dialer{Timeout: 30 * time.Second, Control: dialPublic}).DialContext
+		client.Transport = t
+	}
+	return &Manager{
+		cfg:    cfg,
+		client: client,
+		logger: logger,
+		ctx:    ctx,
+		stop:   stop,
+		jobs:   make(map[string]*job),
+	}
+}
+
+// Stop cancels running jobs and pending webhook deliveries and waits for
+// them to return.
+func (m *Manager) Stop() {
+	m.stop()
+	m.wg.Wait()
+}
+
+// Preferred reports whether h asks for asynchronous processing with
+// "Prefer: respond-async" (RFC 7240).
+func Preferred(h http.Header) bool {
+	for _, v := range h.Values("Prefer") {
+		for pref := range strings.SplitSeq(v, ",") {
+			name, _, _ := strings.Cut(pref, "=")
+			if strings.EqualFold(strings.TrimSpace(name), "respond-async") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckWebhook reports why raw cannot be used as a callback URL, if it
+// cannot. Without WebhookHosts, the host must resolve only to public
+// addresses, so API keys cannot make the server call internal services.
+func (m *Manager) CheckWebhook(raw string) error {
+	if m.cfg.WebhookSecret == "" {
+		return errors.New("webhooks are not enabled on this server")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("webhook must be an absolute http or https URL")
+	}
+	if len(m.cfg.WebhookHosts) > 0 {
+		if !slices.Contains(m.cfg.WebhookHosts, u.Hostname()) {
+			return fmt.Errorf("webhook host %s is not allowed", u.Hostname())
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookLookupTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("webhook host %s cannot be resolved", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !isPublic(addr) {
+			return fmt.Errorf("webhook host %s has a private, loopback or link-local address", u.Hostname())
+		}
+	}
+	return nil
+}
+
+// webhookLookupTimeout bounds resolving a webhook host in CheckWebhook.
+const webhookLookupTimeout = 5 * time.Second
+
+// errPrivateAddress is returned when a webhook would be delivered to an
+// address that is not public.
+var errPrivateAddress = errors.New("webhook address is not public")
+
+// isPublic reports whether addr may receive webhooks when no hosts are
+// allowlisted: not loopback, private, link-local (which includes cloud
+// metadata services), multicast or unspecified.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified()
+}
+
+// dialPublic is a net.Dialer Control function that refuses connections to
+// addresses that are not public.
+func dialPublic(_, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil || !isPublic(ap.Addr()) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// Submit starts a job that serves r, with body as its body, through h as if
+// it had been sent to endpoint. The job keeps r's context values (API key,
+// priority, request ID) but not its cancellation. When webhook is set, the
+// finished job is POSTed there.
+func (m *Manager) Submit(r *http.Request, endpoint string, body []byte, h http.Handler, webhook string) (Job, error) {
+	j := &job{
+		Job: Job{
+			ID:        newID(),
+			Object:    "async.job",
+			Endpoint:  endpoint,
+			Status:    StatusInProgress,
+			CreatedAt: time.Now().Unix(),
+		},
+		owner: auth.KeyID(middleware.APIKeyFromContext(r.Context())),
+	}
+
+	m.mu.Lock()
+	if m.cfg.MaxPending > 0 && m.pending >= m.cfg.MaxPending {
+		m.mu.Unlock()
+		return Job{}, ErrTooManyJobs
+	}
+	m.pending++
+	m.jobs[j.ID] = j
+	snapshot := j.Job
+	m.mu.Unlock()
+	middleware.AsyncJobsPending.Inc()
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	stopCancel := context.AfterFunc(m.ctx, cancel)
+	header := r.Header.Clone()
+	header.Del("Prefer")
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer stopCancel()
+		defer cancel()
+		if m.cfg.Timeout > 0 {
+			var cancelTimeout context.CancelFunc
+			ctx, cancelTimeout = context.WithTimeout(ctx, m.cfg.Timeout)
+			defer cancelTimeout()
+		}
+
+		rec := newRecorder()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			m.logger.Error("failed to build async request", "err", err)
+			apierror.Write(rec, apierror.Internal("Failed to start the request."))
+		} else {
+			req.Header = header
+			h.ServeHTTP(rec, req)
+		}
+		done := m.finish(j.ID, rec.status(), rec.body.Bytes())
+		m.logger.Info("async job finished", "job", done.ID, "endpoint", endpoint, "status", done.Status, "status_code", rec.status())
+		if webhook != "" {
+			m.deliver(webhook, done)
+		}
+	}()
+	return snapshot, nil
+}
+
+// Job returns job id if it belongs to the owner of apiKey.
+func (m *Manager) Job(apiKey, id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok || j.owner != auth.KeyID(apiKey) {
+		return Job{}, ErrNotFound
+	}
+	return j.Job, nil
+}
+
+// finish records a job's response and schedules its removal after the TTL.
+func (m *Manager) finish(id string, code int, body []byte) Job {
+	body = bytes.TrimSpace(body)
+	if !json.Valid(body) {
+		body, _ = json.Marshal(string(body))
+	}
+	status := StatusCompleted
+	if code >= 300 {
+		status = StatusFailed
+	}
+	now := time.Now()
+
+	m.mu.Lock()
+	j := m.jobs[id]
+	j.Status = status
+	j.CompletedAt = now.Unix()
+	j.ExpiresAt = now.Add(m.cfg.TTL).Unix()
+	j.Response = &Response{StatusCode: code, Body: body}
+	m.pending--
+	done := j.Job
+	m.mu.Unlock()
+
+	time.AfterFunc(m.cfg.TTL, func() {
+		m.mu.Lock()
+		delete(m.jobs, id)
+		m.mu.Unlock()
+	})
+	middleware.AsyncJobsPending.Dec()
+	middleware.AsyncJobsTotal.WithLabelValues(done.Endpoint, string(status)).Inc()
+	return done
+}
+
+// deliver POSTs the finished job to webhook, retrying with backoff on
+// transport errors and non-2xx answers.
+func (m *Manager) deliver(webhook string, j Job) {
+	payload, err := json.Marshal(j)
+	if err != nil {
+		m.logger.Error("failed to encode async job", "job", j.ID, "err", err)
+		return
+	}
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		err := m.post(webhook, payload)
+		if err == nil {
+			middleware.AsyncWebhookDeliveriesTotal.WithLabelValues("success").Inc()
+			return
+		}
+		if attempt >= m.cfg.WebhookAttempts {
+			m.logger.Warn("async webhook delivery failed", "job", j.ID, "attempts", attempt, "err", err)
+			middleware.AsyncWebhookDeliveriesTotal.WithLabelValues("failure").Inc()
+			return
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-m.ctx.Done():
+			middleware.AsyncWebhookDeliveriesTotal.WithLabelValues("failure").Inc()
+			return
+		}
+	}
+}
+
+func (m *Manager) post(webhook string, payload []byte) error {
+	req, err := http.NewRequestWithContext(m.ctx, http.MethodPost, webhook, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, "t="+strconv.FormatInt(ts, 10)+",v1="+Sign(m.cfg.WebhookSecret, ts, payload))
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "<ts>.<body>" keyed with secret, as
+// sent in SignatureHeader.
+func Sign(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newID() string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return "async_" + hex.EncodeToString(b[:])
+}
+
+// recorder captures the response of a handler served in-process.
+type recorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func newRecorder() *recorder {
+	return &recorder{header: make(http.Header)}
+}
+
+func (r *recorder) Header() http.Header { return r.header }
+
+func (r *recorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(p)
+}
+
+func (r *recorder) status() int {
+	if r.code == 0 {
+		return http.StatusOK
+	}
+	return r.code
+}
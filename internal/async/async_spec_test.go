@@ -0,0 +1,215 @@
+package async_test
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/async"
+	"github.com/menezmethod/inferencia/internal/middleware"
+)
+
+// echo answers with the request body, or 400 when it asks to fail.
+var echo = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if strings.Contains(string(body), "fail") {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	_, _ = w.Write(body)
+})
+
+func newRequest(key string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	r.Header.Set("Prefer", "respond-async")
+	return r.WithContext(middleware.WithAPIKey(r.Context(), key))
+}
+
+var _ = Describe("Manager", func() {
+	var (
+		m      *async.Manager
+		cfg    async.Config
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	)
+
+	BeforeEach(func() {
+		cfg = async.Config{TTL: time.Minute, WebhookSecret: "s3cret", WebhookAttempts: 3}
+	})
+
+	JustBeforeEach(func() {
+		m = async.New(cfg, logger)
+		DeferCleanup(m.Stop)
+	})
+
+	waitFor := func(key, id string) async.Job {
+		var j async.Job
+		Eventually(func() async.Status {
+			j, _ = m.Job(key, id)
+			return j.Status
+		}, 5*time.Second, 10*time.Millisecond).ShouldNot(Equal(async.StatusInProgress))
+		return j
+	}
+
+	It("runs the request in the background and keeps the response", func() {
+		var sawPrefer atomic.Bool
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sawPrefer.Store(r.Header.Get("Prefer") != "")
+			echo(w, r)
+		})
+		j, err := m.Submit(newRequest("sk-a"), "/v1/chat/completions", []byte(`{"model":"m"}`), h, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(j.Status).To(Equal(async.StatusInProgress))
+
+		j = waitFor("sk-a", j.ID)
+		Expect(j.Status).To(Equal(async.StatusCompleted))
+		Expect(j.Response.StatusCode).To(Equal(http.StatusOK))
+		Expect(j.Response.Body).To(MatchJSON(`{"model":"m"}`))
+		Expect(j.ExpiresAt).To(BeNumerically(">", j.CreatedAt))
+		Expect(sawPrefer.Load()).To(BeFalse())
+	})
+
+	It("marks jobs whose endpoint answered with an error as failed", func() {
+		j, err := m.Submit(newRequest("sk-a"), "/v1/chat/completions", []byte(`{"fail":true}`), echo, "")
+		Expect(err).NotTo(HaveOccurred())
+		j = waitFor("sk-a", j.ID)
+		Expect(j.Status).To(Equal(async.StatusFailed))
+		Expect(j.Response.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("only shows jobs to the key that submitted them", func() {
+		j, err := m.Submit(newRequest("sk-a"), "/v1/chat/completions", []byte(`{}`), echo, "")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = m.Job("sk-b", j.ID)
+		Expect(err).To(MatchError(async.ErrNotFound))
+	})
+
+	When("finished jobs expire", func() {
+		BeforeEach(func() { cfg.TTL = 50 * time.Millisecond })
+
+		It("forgets them after the TTL", func() {
+			j, err := m.Submit(newRequest("sk-a"), "/v1/chat/completions", []byte(`{}`), echo, "")
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(func() error {
+				_, err := m.Job("sk-a", j.ID)
+				return err
+			}, 5*time.Second, 10*time.Millisecond).Should(MatchError(async.ErrNotFound))
+		})
+	})
+
+	When("MaxPending jobs are running", func() {
+		BeforeEach(func() { cfg.MaxPending = 1 })
+
+		It("refuses more", func() {
+			release := make(chan struct{})
+			defer close(release)
+			block := http.HandlerFunc(func(http.ResponseWriter, *http.Request) { <-release })
+			_, err := m.Submit(newRequest("sk-a"), "/v1/chat/completions", []byte(`{}`), block, "")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = m.Submit(newRequest("sk-a"), "/v1/chat/completions", []byte(`{}`), block, "")
+			Expect(err).To(MatchError(async.ErrTooManyJobs))
+		})
+	})
+
+	Describe("posting to the webhook", func() {
+		BeforeEach(func() { cfg.WebhookHosts = []string{"127.0.0.1"} })
+
+		It("posts the finished job with a valid signature", func() {
+			type delivery struct {
+				sig  string
+				body []byte
+			}
+			deliveries := make(chan delivery, 1)
+			var calls atomic.Int32
+			hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				body, _ := io.ReadAll(r.Body)
+				deliveries <- delivery{sig: r.Header.Get(async.SignatureHeader), body: body}
+			}))
+			defer hook.Close()
+
+			j, err := m.Submit(newRequest("sk-a"), "/v1/chat/completions", []byte(`{"model":"m"}`), echo, hook.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			var d delivery
+			Eventually(deliveries, 5*time.Second).Should(Receive(&d))
+			var got async.Job
+			Expect(json.Unmarshal(d.body, &got)).To(Succeed())
+			Expect(got.ID).To(Equal(j.ID))
+			Expect(got.Status).To(Equal(async.StatusCompleted))
+
+			ts, mac, ok := strings.Cut(strings.TrimPrefix(d.sig, "t="), ",v1=")
+			Expect(ok).To(BeTrue())
+			t, err := strconv.ParseInt(ts, 10, 64)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mac).To(Equal(async.Sign("s3cret", t, d.body)))
+		})
+	})
+
+	Describe("CheckWebhook", func() {
+		BeforeEach(func() { cfg.WebhookHosts = []string{"hooks.example.com"} })
+
+		It("accepts http URLs on allowed hosts only", func() {
+			Expect(m.CheckWebhook("https://hooks.example.com/done")).To(Succeed())
+			Expect(m.CheckWebhook("https://evil.example.com/done")).NotTo(Succeed())
+			Expect(m.CheckWebhook("file:///etc/passwd")).NotTo(Succeed())
+		})
+
+		When("no hosts are allowlisted", func() {
+			BeforeEach(func() { cfg.WebhookHosts = nil })
+
+			It("refuses loopback, private and link-local addresses", func() {
+				for _, hook := range []string{
+					"http://127.0.0.1:8080/done",
+					"http://localhost/done",
+					"http://10.0.0.5/done",
+					"http://192.168.1.1/done",
+					"http://169.254.169.254/latest/meta-data/",
+					"http://[::1]/done",
+					"http://[fe80::1]/done",
+					"http://0.0.0.0/done",
+				} {
+					Expect(m.CheckWebhook(hook)).To(MatchError(ContainSubstring("private, loopback or link-local")), hook)
+				}
+				Expect(m.CheckWebhook("https://93.184.216.34/done")).To(Succeed())
+			})
+
+			It("does not deliver to a private address", func() {
+				var calls atomic.Int32
+				hook := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { calls.Add(1) }))
+				defer hook.Close()
+
+				_, err := m.Submit(newRequest("sk-a"), "/v1/chat/completions", []byte(`{}`), echo, hook.URL)
+				Expect(err).NotTo(HaveOccurred())
+				Consistently(calls.Load, 200*time.Millisecond).Should(BeZero())
+			})
+		})
+
+		When("no webhook secret is configured", func() {
+			BeforeEach(func() { cfg.WebhookSecret = "" })
+
+			It("refuses webhooks", func() {
+				Expect(m.CheckWebhook("https://hooks.example.com/done")).NotTo(Succeed())
+			})
+		})
+	})
+})
+
+var _ = Describe("Preferred", func() {
+	It("recognizes respond-async among other preferences", func() {
+		h := http.Header{}
+		Expect(async.Preferred(h)).To(BeFalse())
+		h.Set("Prefer", "return=minimal, Respond-Async, wait=10")
+		Expect(async.Preferred(h)).To(BeTrue())
+	})
+})
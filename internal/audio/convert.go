@@ -0,0 +1,106 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// PCMFormat is the layout of OpenAI's "pcm" response format: 24 kHz, 16-bit,
+// mono.
+var PCMFormat = Format{SampleRate: 24000, Channels: 1, BitsPerSample: 16}
+
+// ErrUnsupportedSamples is returned when samples are not 16-bit PCM, the
+// only width converted in Go.
+var ErrUnsupportedSamples = errors.New("audio: only 16-bit samples can be converted")
+
+// ToPCM converts samples in f to PCMFormat, mixing channels down to mono and
+// resampling to 24 kHz.
+func ToPCM(f Format, pcm []byte) ([]byte, error) {
+	if f.BitsPerSample != 16 {
+		return nil, ErrUnsupportedSamples
+	}
+	if f.Channels > 1 {
+		pcm = downmix(f, pcm)
+		f.Channels = 1
+	}
+	if f.SampleRate != PCMFormat.SampleRate {
+		pcm = stretch(f, pcm, float64(PCMFormat.SampleRate)/float64(f.SampleRate))
+	}
+	return pcm, nil
+}
+
+// ChangeSpeed plays samples in f speed times faster by resampling, so pitch
+// rises with speed as on a tape played fast.
+func ChangeSpeed(f Format, pcm []byte, speed float64) ([]byte, error) {
+	if f.BitsPerSample != 16 {
+		return nil, ErrUnsupportedSamples
+	}
+	if speed <= 0 || speed == 1 {
+		return pcm, nil
+	}
+	return stretch(f, pcm, 1/speed), nil
+}
+
+// Silence returns d of silence in f.
+func Silence(f Format, d time.Duration) []byte {
+	frames := int(d.Seconds() * float64(f.SampleRate))
+	return make([]byte, frames*f.BlockAlign())
+}
+
+// Level returns the RMS level of 16-bit samples, from 0 for silence to 1 at
+// full scale.
+func Level(pcm []byte) float64 {
+	n := len(pcm) / 2
+	if n == 0 {
+		return 0
+	}
+	var sum float64
+	for i := range n {
+		v := float64(int16(binary.LittleEndian.Uint16(pcm[2*i:]))) / math.MaxInt16
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(n))
+}
+
+// downmix averages the channels of each 16-bit frame.
+func downmix(f Format, pcm []byte) []byte {
+	frames := len(pcm) / f.BlockAlign()
+	out := make([]byte, 2*frames)
+	for i := range frames {
+		sum := 0
+		for c := range f.Channels {
+			off := i*f.BlockAlign() + 2*c
+			sum += int(int16(binary.LittleEndian.Uint16(pcm[off:])))
+		}
+		binary.LittleEndian.PutUint16(out[2*i:], uint16(int16(sum/f.Channels)))
+	}
+	return out
+}
+
+// stretch resamples 16-bit frames to factor times as many by linear
+// interpolation.
+func stretch(f Format, pcm []byte, factor float64) []byte {
+	align := f.BlockAlign()
+	in := len(pcm) / align
+	if in == 0 {
+		return nil
+	}
+	n := int(math.Round(float64(in) * factor))
+	out := make([]byte, n*align)
+	sample := func(frame, c int) float64 {
+		return float64(int16(binary.LittleEndian.Uint16(pcm[frame*align+2*c:])))
+	}
+	for i := range n {
+		pos := float64(i) / factor
+		j := min(int(pos), in-1)
+		k := min(j+1, in-1)
+		t := pos - float64(j)
+		for c := range f.Channels {
+			v := sample(j, c)*(1-t) + sample(k, c)*t
+			binary.LittleEndian.PutUint16(out[i*align+2*c:], uint16(int16(math.Round(v))))
+		}
+	}
+	return out
+}
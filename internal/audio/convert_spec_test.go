@@ -0,0 +1,78 @@
+package audio_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/audio"
+)
+
+var _ = Describe("ToPCM", func() {
+	It("mixes stereo down to mono", func() {
+		stereo := audio.Format{SampleRate: 24000, Channels: 2, BitsPerSample: 16}
+		Expect(audio.ToPCM(stereo, samples(100, 300, -50, -150))).To(Equal(samples(200, -100)))
+	})
+
+	It("resamples to 24 kHz", func() {
+		f := audio.Format{SampleRate: 12000, Channels: 1, BitsPerSample: 16}
+		Expect(audio.ToPCM(f, samples(0, 100, 200))).To(Equal(samples(0, 50, 100, 150, 200, 200)))
+	})
+
+	It("refuses samples that are not 16-bit", func() {
+		f := audio.Format{SampleRate: 24000, Channels: 1, BitsPerSample: 8}
+		_, err := audio.ToPCM(f, []byte{1, 2})
+		Expect(err).To(MatchError(audio.ErrUnsupportedSamples))
+	})
+})
+
+var _ = Describe("ChangeSpeed", func() {
+	It("halves the length at double speed", func() {
+		out, err := audio.ChangeSpeed(audio.PCMFormat, samples(0, 10, 20, 30, 40, 50), 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal(samples(0, 20, 40)))
+	})
+
+	It("leaves audio at normal speed alone", func() {
+		in := samples(1, 2, 3)
+		Expect(audio.ChangeSpeed(audio.PCMFormat, in, 1)).To(Equal(in))
+	})
+})
+
+var _ = Describe("Level", func() {
+	It("is the RMS of the samples relative to full scale", func() {
+		Expect(audio.Level(samples(0, 0))).To(BeZero())
+		Expect(audio.Level(samples(32767, -32767))).To(BeNumerically("~", 1, 1e-9))
+		Expect(audio.Level(samples(16384, -16384, 16384, -16384))).To(BeNumerically("~", 0.5, 1e-3))
+	})
+})
+
+var _ = Describe("FFmpeg", func() {
+	It("pipes audio through the command with the format's output options", func() {
+		dir := GinkgoT().TempDir()
+		path := filepath.Join(dir, "ffmpeg")
+		script := "#!/bin/sh\necho \"$@\"\ncat\n"
+		Expect(os.WriteFile(path, []byte(script), 0o755)).To(Succeed())
+
+		out, err := audio.FFmpeg{Path: path}.Transcode(context.Background(), []byte("audio"), "mp3")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(out)).To(Equal("-hide_banner -loglevel error -i pipe:0 -f mp3 -c:a libmp3lame pipe:1\naudio"))
+	})
+
+	It("rejects formats it has no output options for", func() {
+		_, err := audio.FFmpeg{}.Transcode(context.Background(), nil, "ogg")
+		Expect(err).To(MatchError(ContainSubstring(`unsupported output format "ogg"`)))
+	})
+
+	It("reports the command's errors", func() {
+		dir := GinkgoT().TempDir()
+		path := filepath.Join(dir, "ffmpeg")
+		Expect(os.WriteFile(path, []byte("#!/bin/sh\necho bad input >&2\nexit 1\n"), 0o755)).To(Succeed())
+
+		_, err := audio.FFmpeg{Path: path}.Transcode(context.Background(), nil, "wav")
+		Expect(err).To(MatchError(ContainSubstring("bad input")))
+	})
+})
@@ -0,0 +1,57 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Encoder converts audio between compressed formats, for the conversions Go
+// does not do natively.
+type Encoder interface {
+	// Transcode converts src, in any format the encoder can read, to format
+	// ("wav", "pcm", "mp3", "opus", "flac" or "aac").
+	Transcode(ctx context.Context, src []byte, format string) ([]byte, error)
+}
+
+// FFmpeg is an Encoder that pipes audio through the ffmpeg command.
+type FFmpeg struct {
+	// Path is the ffmpeg executable; "ffmpeg" is looked up on PATH.
+	Path string
+}
+
+// ffmpegOutputs are the ffmpeg output options for each response format.
+// Opus is wrapped in Ogg and PCM is headerless 24 kHz mono, as OpenAI
+// returns them.
+var ffmpegOutputs = map[string][]string{
+	"wav":  {"-f", "wav", "-c:a", "pcm_s16le"},
+	"pcm":  {"-f", "s16le", "-c:a", "pcm_s16le", "-ar", "24000", "-ac", "1"},
+	"mp3":  {"-f", "mp3", "-c:a", "libmp3lame"},
+	"opus": {"-f", "ogg", "-c:a", "libopus"},
+	"flac": {"-f", "flac"},
+	"aac":  {"-f", "adts", "-c:a", "aac"},
+}
+
+// Transcode runs ffmpeg with src on stdin and returns its stdout.
+func (e FFmpeg) Transcode(ctx context.Context, src []byte, format string) ([]byte, error) {
+	out, ok := ffmpegOutputs[format]
+	if !ok {
+		return nil, fmt.Errorf("ffmpeg: unsupported output format %q", format)
+	}
+	path := e.Path
+	if path == "" {
+		path = "ffmpeg"
+	}
+	args := append([]string{"-hide_banner", "-loglevel", "error", "-i", "pipe:0"}, out...)
+	cmd := exec.CommandContext(ctx, path, append(args, "pipe:1")...)
+	cmd.Stdin = bytes.NewReader(src)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,68 @@
+package audio
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// Joiner concatenates PCM segments of one format into a continuous stream,
+// optionally crossfading across each boundary. It works incrementally: Add
+// returns the samples that are final so they can be sent straight away, and
+// Flush returns the tail held back for the next crossfade.
+type Joiner struct {
+	f    Format
+	fade int // crossfade length in bytes, a whole number of frames
+	tail []byte
+}
+
+// NewJoiner returns a Joiner for segments in f. Crossfading needs 16-bit
+// samples; for other formats segments are joined end to end.
+func NewJoiner(f Format, crossfade time.Duration) *Joiner {
+	j := &Joiner{f: f}
+	if f.BitsPerSample == 16 && crossfade > 0 {
+		frames := int(crossfade.Seconds() * float64(f.SampleRate))
+		j.fade = frames * f.BlockAlign()
+	}
+	return j
+}
+
+// Format returns the format of the joined stream.
+func (j *Joiner) Format() Format { return j.f }
+
+// Add appends a segment and returns the samples now ready to be written.
+func (j *Joiner) Add(pcm []byte) []byte {
+	if j.fade == 0 {
+		return pcm
+	}
+	align := j.f.BlockAlign()
+	n := min(len(j.tail), len(pcm)) / align * align
+	out := make([]byte, 0, len(j.tail)+len(pcm))
+	out = append(out, j.tail[:len(j.tail)-n]...)
+	out = append(out, mix(j.tail[len(j.tail)-n:], pcm[:n], align)...)
+
+	rest := pcm[n:]
+	keep := min(j.fade, len(rest)/align*align)
+	out = append(out, rest[:len(rest)-keep]...)
+	j.tail = append(j.tail[:0], rest[len(rest)-keep:]...)
+	return out
+}
+
+// Flush returns the samples held back for crossfading.
+func (j *Joiner) Flush() []byte {
+	tail := j.tail
+	j.tail = nil
+	return tail
+}
+
+// mix fades out a while fading in b, linearly over their common length.
+func mix(a, b []byte, align int) []byte {
+	out := make([]byte, len(a))
+	frames := len(a) / align
+	for i := 0; i+1 < len(a); i += 2 {
+		t := (float64(i/align) + 0.5) / float64(frames)
+		x := float64(int16(binary.LittleEndian.Uint16(a[i:])))
+		y := float64(int16(binary.LittleEndian.Uint16(b[i:])))
+		binary.LittleEndian.PutUint16(out[i:], uint16(int16(x*(1-t)+y*t)))
+	}
+	return out
+}
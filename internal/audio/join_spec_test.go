@@ -0,0 +1,50 @@
+package audio_test
+
+import (
+	"encoding/binary"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/audio"
+)
+
+// samples encodes 16-bit mono samples.
+func samples(v ...int16) []byte {
+	b := make([]byte, 2*len(v))
+	for i, s := range v {
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(s))
+	}
+	return b
+}
+
+var _ = Describe("Joiner", func() {
+	mono := audio.Format{SampleRate: 1000, Channels: 1, BitsPerSample: 16}
+
+	It("joins segments end to end without a crossfade", func() {
+		j := audio.NewJoiner(mono, 0)
+		out := append(j.Add(samples(1, 2)), j.Add(samples(3))...)
+		out = append(out, j.Flush()...)
+		Expect(out).To(Equal(samples(1, 2, 3)))
+	})
+
+	It("crossfades the boundary between segments", func() {
+		// 2ms at 1kHz is two frames.
+		j := audio.NewJoiner(mono, 2*time.Millisecond)
+		first := j.Add(samples(100, 100, 100, 100))
+		Expect(first).To(Equal(samples(100, 100)))
+
+		second := j.Add(samples(-100, -100, -100, -100))
+		Expect(second).To(Equal(samples(50, -50)))
+		Expect(j.Flush()).To(Equal(samples(-100, -100)))
+	})
+
+	It("crossfades only as much as a short segment allows", func() {
+		j := audio.NewJoiner(mono, 10*time.Millisecond)
+		out := j.Add(samples(100, 100, 100))
+		out = append(out, j.Add(samples(0))...)
+		out = append(out, j.Flush()...)
+		Expect(out).To(HaveLen(6))
+	})
+})
@@ -0,0 +1,99 @@
+// Package audio reads and writes the uncompressed audio containers returned
+// by TTS backends, so synthesized segments can be joined into one stream.
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrNotWAV is returned for data that is not a PCM WAV file.
+var ErrNotWAV = errors.New("audio: not a PCM WAV file")
+
+// Format describes interleaved little-endian PCM samples.
+type Format struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+}
+
+// BlockAlign returns the size in bytes of one sample frame.
+func (f Format) BlockAlign() int { return f.Channels * f.BitsPerSample / 8 }
+
+// wavHeaderSize is the size of the canonical header written by WAVHeader.
+const wavHeaderSize = 44
+
+// ParseWAV returns the format and sample data of a PCM WAV file. A data
+// chunk whose declared size runs past the end of b (as written by servers
+// that stream WAV) is taken to end at the end of b.
+func ParseWAV(b []byte) (Format, []byte, error) {
+	if len(b) < 12 || string(b[0:4]) != "RIFF" || string(b[8:12]) != "WAVE" {
+		return Format{}, nil, ErrNotWAV
+	}
+	var (
+		f      Format
+		gotFmt bool
+	)
+	for off := 12; off+8 <= len(b); {
+		id := string(b[off : off+4])
+		size := int(binary.LittleEndian.Uint32(b[off+4 : off+8]))
+		body := off + 8
+		switch id {
+		case "fmt ":
+			if size < 16 || body+16 > len(b) {
+				return Format{}, nil, ErrNotWAV
+			}
+			// 1 is integer PCM; 0xFFFE (extensible) carries it in a sub-format.
+			if tag := binary.LittleEndian.Uint16(b[body:]); tag != 1 && tag != 0xFFFE {
+				return Format{}, nil, ErrNotWAV
+			}
+			f.Channels = int(binary.LittleEndian.Uint16(b[body+2:]))
+			f.SampleRate = int(binary.LittleEndian.Uint32(b[body+4:]))
+			f.BitsPerSample = int(binary.LittleEndian.Uint16(b[body+14:]))
+			gotFmt = true
+		case "data":
+			if !gotFmt || f.Channels == 0 || f.BitsPerSample == 0 {
+				return Format{}, nil, ErrNotWAV
+			}
+			end := body + size
+			if end > len(b) {
+				end = len(b)
+			}
+			return f, b[body:end], nil
+		}
+		// Chunks are padded to an even size.
+		off = body + size + size&1
+	}
+	return Format{}, nil, ErrNotWAV
+}
+
+// WAVHeader returns a canonical 44-byte header for dataLen bytes of samples
+// in f. A negative dataLen writes the maximum sizes, the usual convention for
+// a WAV stream whose length is not known when the header is sent.
+func WAVHeader(f Format, dataLen int) []byte {
+	riffLen, dataSize := uint32(0xFFFFFFFF), uint32(0xFFFFFFFF)
+	if dataLen >= 0 {
+		dataSize = uint32(dataLen)
+		riffLen = uint32(wavHeaderSize - 8 + dataLen)
+	}
+	h := make([]byte, wavHeaderSize)
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], riffLen)
+	copy(h[8:], "WAVE")
+	copy(h[12:], "fmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16)
+	binary.LittleEndian.PutUint16(h[20:], 1)
+	binary.LittleEndian.PutUint16(h[22:], uint16(f.Channels))
+	binary.LittleEndian.PutUint32(h[24:], uint32(f.SampleRate))
+	binary.LittleEndian.PutUint32(h[28:], uint32(f.SampleRate*f.BlockAlign()))
+	binary.LittleEndian.PutUint16(h[32:], uint16(f.BlockAlign()))
+	binary.LittleEndian.PutUint16(h[34:], uint16(f.BitsPerSample))
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], dataSize)
+	return h
+}
+
+// EncodeWAV wraps samples in f in a WAV container.
+func EncodeWAV(f Format, pcm []byte) []byte {
+	return append(WAVHeader(f, len(pcm)), pcm...)
+}
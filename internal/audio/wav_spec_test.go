@@ -0,0 +1,51 @@
+package audio_test
+
+import (
+	"encoding/binary"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/audio"
+)
+
+var _ = Describe("WAV", func() {
+	format := audio.Format{SampleRate: 24000, Channels: 1, BitsPerSample: 16}
+
+	It("round-trips samples through EncodeWAV and ParseWAV", func() {
+		pcm := []byte{1, 2, 3, 4, 5, 6}
+		f, got, err := audio.ParseWAV(audio.EncodeWAV(format, pcm))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f).To(Equal(format))
+		Expect(got).To(Equal(pcm))
+	})
+
+	It("writes the maximum sizes for a stream of unknown length", func() {
+		h := audio.WAVHeader(format, -1)
+		Expect(h).To(HaveLen(44))
+		Expect(binary.LittleEndian.Uint32(h[4:])).To(Equal(uint32(0xFFFFFFFF)))
+		Expect(binary.LittleEndian.Uint32(h[40:])).To(Equal(uint32(0xFFFFFFFF)))
+		Expect(binary.LittleEndian.Uint32(h[28:])).To(Equal(uint32(48000)))
+	})
+
+	It("reads a streamed data chunk up to the end of the input", func() {
+		wav := append(audio.WAVHeader(format, -1), 9, 9)
+		_, pcm, err := audio.ParseWAV(wav)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pcm).To(Equal([]byte{9, 9}))
+	})
+
+	It("skips chunks it does not know", func() {
+		wav := audio.EncodeWAV(format, []byte{7, 7})
+		list := append([]byte("LIST\x03\x00\x00\x00abc\x00"), wav[36:]...)
+		wav = append(wav[:36:36], list...)
+		_, pcm, err := audio.ParseWAV(wav)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pcm).To(Equal([]byte{7, 7}))
+	})
+
+	It("rejects data that is not WAV", func() {
+		_, _, err := audio.ParseWAV([]byte("ID3 mp3 data"))
+		Expect(err).To(MatchError(audio.ErrNotWAV))
+	})
+})
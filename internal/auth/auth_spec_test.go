@@ -6,6 +6,8 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/priority"
 )
 
 var _ = Describe("NewKeyStore", func() {
@@ -107,3 +109,60 @@ var _ = Describe("Validate", func() {
 		})
 	})
 })
+
+var _ = Describe("NewKeyStoreFromKeys", func() {
+	It("ignores INFERENCIA_API_KEYS and blank entries", func() {
+		_ = os.Setenv("INFERENCIA_API_KEYS", "sk-env")
+		defer func() { _ = os.Unsetenv("INFERENCIA_API_KEYS") }()
+
+		ks, err := NewKeyStoreFromKeys([]string{"sk-admin", " ", ""})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ks.Count()).To(Equal(1))
+		Expect(ks.Validate("sk-admin")).NotTo(HaveOccurred())
+		Expect(ks.Validate("sk-env")).To(MatchError(ErrInvalidKey))
+	})
+
+	It("returns an error when no keys are given", func() {
+		_, err := NewKeyStoreFromKeys(nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Policy", func() {
+	It("parses key attributes", func() {
+		content := `sk-ide name=ide priority=interactive lexicon=dev
+sk-batch priority=batch allow_priority=batch
+sk-plain
+`
+		path := filepath.Join(GinkgoT().TempDir(), "keys.txt")
+		Expect(os.WriteFile(path, []byte(content), 0644)).NotTo(HaveOccurred())
+
+		ks, err := NewKeyStore(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ks.Validate("sk-ide")).To(Succeed())
+
+		ide := ks.Policy("sk-ide")
+		Expect(ide.Name).To(Equal("ide"))
+		Expect(ide.Priority).To(Equal(priority.Interactive))
+		Expect(ide.Allows(priority.Batch)).To(BeTrue())
+		Expect(ide.Lexicon).To(Equal("dev"))
+
+		batch := ks.Policy("sk-batch")
+		Expect(batch.Allows(priority.Batch)).To(BeTrue())
+		Expect(batch.Allows(priority.Normal)).To(BeFalse())
+
+		Expect(ks.Policy("sk-plain").Priority).To(Equal(priority.Normal))
+		Expect(ks.Policy("sk-plain").Allows(priority.Interactive)).To(BeFalse())
+
+		Expect(ks.PolicyByID(KeyID("sk-ide"))).To(Equal(ide))
+		Expect(ks.PolicyByID(KeyID("sk-unknown")).Priority).To(Equal(priority.Normal))
+	})
+
+	It("reports the line of an invalid attribute", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "keys.txt")
+		Expect(os.WriteFile(path, []byte("sk-ok\nsk-bad priority=urgent\n"), 0644)).NotTo(HaveOccurred())
+
+		_, err := NewKeyStore(path)
+		Expect(err).To(MatchError(ContainSubstring("line 2")))
+	})
+})
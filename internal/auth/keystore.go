@@ -3,37 +3,72 @@
 // Keys are loaded from a text file (one key per line) or from
 // a comma-separated environment variable. Lines starting with #
 // are treated as comments. Empty lines are ignored.
+//
+// A key may be followed by space-separated policy attributes:
+//
+//	sk-ide-plugin name=ide priority=interactive
+//	sk-nightly    name=summarizer priority=batch
+//	sk-ops        allow_priority=batch,normal,interactive
+//	sk-support    lexicon=support
+//
+// priority sets the key's default class; allow_priority lists the classes it
+// may request with the X-Inferencia-Priority header (by default, its own
+// class and anything lower). lexicon names the pronunciation lexicon from
+// tts.lexicons applied to the key's speech requests.
 package auth
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"sync"
+
+	"github.com/menezmethod/inferencia/internal/priority"
 )
 
 // ErrInvalidKey is returned when an API key is not recognized.
 var ErrInvalidKey = errors.New("invalid api key")
 
+// KeyPolicy holds the attributes configured for a key.
+type KeyPolicy struct {
+	Name          string           // label for logs and metrics; empty if unset
+	Priority      priority.Class   // default class for the key's requests
+	AllowPriority []priority.Class // classes the key may request; nil means Priority and below
+	Lexicon       string           // pronunciation lexicon for speech; empty for the global one
+}
+
+// Allows reports whether the key may request class c.
+func (p KeyPolicy) Allows(c priority.Class) bool {
+	if p.AllowPriority == nil {
+		return c <= p.Priority
+	}
+	return slices.Contains(p.AllowPriority, c)
+}
+
 // KeyStore validates API keys against a set of known keys.
 type KeyStore struct {
 	mu   sync.RWMutex
-	keys map[string]struct{}
+	keys map[string]KeyPolicy
 }
 
 // NewKeyStore creates a KeyStore and loads keys from the given file path.
 // If the INFERENCIA_API_KEYS environment variable is set, those keys take
 // precedence over the file.
 func NewKeyStore(path string) (*KeyStore, error) {
-	ks := &KeyStore{keys: make(map[string]struct{})}
+	ks := &KeyStore{keys: make(map[string]KeyPolicy)}
 
 	// Environment variable takes precedence.
 	if env := os.Getenv("INFERENCIA_API_KEYS"); env != "" {
 		for _, k := range strings.Split(env, ",") {
-			if key := strings.TrimSpace(k); key != "" {
-				ks.keys[key] = struct{}{}
+			if entry := strings.TrimSpace(k); entry != "" {
+				if err := ks.add(entry); err != nil {
+					return nil, fmt.Errorf("INFERENCIA_API_KEYS: %w", err)
+				}
 			}
 		}
 		if len(ks.keys) == 0 {
@@ -58,6 +93,22 @@ func NewKeyStore(path string) (*KeyStore, error) {
 	return ks, nil
 }
 
+// NewKeyStoreFromKeys creates a KeyStore holding exactly the given keys.
+// Blank entries are ignored. It is used for key sets defined in configuration,
+// such as admin keys, where INFERENCIA_API_KEYS must not take precedence.
+func NewKeyStoreFromKeys(keys []string) (*KeyStore, error) {
+	ks := &KeyStore{keys: make(map[string]KeyPolicy)}
+	for _, k := range keys {
+		if key := strings.TrimSpace(k); key != "" {
+			ks.keys[key] = KeyPolicy{Priority: priority.Normal}
+		}
+	}
+	if len(ks.keys) == 0 {
+		return nil, errors.New("no valid keys provided")
+	}
+	return ks, nil
+}
+
 // Validate checks whether the given key is authorized.
 func (ks *KeyStore) Validate(key string) error {
 	ks.mu.RLock()
@@ -69,6 +120,40 @@ func (ks *KeyStore) Validate(key string) error {
 	return nil
 }
 
+// Policy returns the policy attached to key. Unknown keys get the default
+// policy (normal priority).
+func (ks *KeyStore) Policy(key string) KeyPolicy {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if p, ok := ks.keys[key]; ok {
+		return p
+	}
+	return KeyPolicy{Priority: priority.Normal}
+}
+
+// PolicyByID returns the policy attached to the key whose KeyID is id, for
+// requests served on a key's behalf when only its ID was kept. Unknown IDs get
+// the default policy.
+func (ks *KeyStore) PolicyByID(id string) KeyPolicy {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	for key, p := range ks.keys {
+		if KeyID(key) == id {
+			return p
+		}
+	}
+	return KeyPolicy{Priority: priority.Normal}
+}
+
+// KeyID returns a short, stable fingerprint of an API key, stored in its
+// place wherever a key must be remembered.
+func KeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
 // Count returns the number of loaded keys.
 func (ks *KeyStore) Count() int {
 	ks.mu.RLock()
@@ -85,12 +170,56 @@ func (ks *KeyStore) loadFile(path string) error {
 	defer func() { _ = f.Close() }()
 
 	scanner := bufio.NewScanner(f)
+	lineNo := 0
 	for scanner.Scan() {
+		lineNo++
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
-		ks.keys[line] = struct{}{}
+		if err := ks.add(line); err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
 	}
 	return scanner.Err()
 }
+
+// add parses "key [attr=value ...]" and stores the key with its policy.
+func (ks *KeyStore) add(entry string) error {
+	fields := strings.Fields(entry)
+	policy := KeyPolicy{Priority: priority.Normal}
+	for _, attr := range fields[1:] {
+		name, value, ok := strings.Cut(attr, "=")
+		if !ok {
+			return fmt.Errorf("malformed key attribute %q (want name=value)", attr)
+		}
+		switch name {
+		case "name":
+			policy.Name = value
+		case "priority":
+			c, err := priority.Parse(value)
+			if err != nil {
+				return err
+			}
+			policy.Priority = c
+		case "lexicon":
+			policy.Lexicon = value
+		case "allow_priority":
+			policy.AllowPriority = []priority.Class{}
+			for _, v := range strings.Split(value, ",") {
+				c, err := priority.Parse(v)
+				if err != nil {
+					return err
+				}
+				policy.AllowPriority = append(policy.AllowPriority, c)
+			}
+		default:
+			return fmt.Errorf("unknown key attribute %q", name)
+		}
+	}
+	if policy.AllowPriority != nil && !policy.Allows(policy.Priority) {
+		policy.AllowPriority = append(policy.AllowPriority, policy.Priority)
+	}
+	ks.keys[fields[0]] = policy
+	return nil
+}
@@ -11,12 +11,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 )
 
 // ErrBackendNotFound is returned when a requested backend doesn't exist.
 var ErrBackendNotFound = errors.New("backend not found")
 
+// ErrNotSent is reported as the outcome of a request that was admitted to a
+// backend but answered without it, for example from a cache. It counts as
+// neither a success nor a failure.
+var ErrNotSent = errors.New("request answered without the backend")
+
 // ErrNoHealthyBackend is returned when every registered backend is degraded.
 var ErrNoHealthyBackend = errors.New("no healthy backend available")
 
@@ -68,6 +78,16 @@ type TTSBackend interface {
 	Voices(ctx context.Context) ([]Voice, error)
 }
 
+// StreamingTTSBackend is a TTSBackend whose server can send audio while it is
+// still being synthesized.
+type StreamingTTSBackend interface {
+	TTSBackend
+
+	// SynthesizeStream starts synthesis and returns once the audio begins to
+	// arrive. The caller must close the returned stream's Audio.
+	SynthesizeStream(ctx context.Context, req TTSRequest) (*TTSStream, error)
+}
+
 // Backend is the legacy composite interface for backward compatibility.
 // It combines ChatBackend and EmbedBackend.
 type Backend interface {
@@ -142,9 +162,16 @@ func (r *Registry) PrimaryName() string {
 	return r.primary
 }
 
-// PrimaryHealthy returns a healthy backend using least-connections load balancing
-// across all healthy backends. When hc is nil, all backends are considered healthy.
+// PrimaryHealthy returns a healthy backend for chat traffic using the
+// configured load-balancing strategy across all healthy backends. When hc is
+// nil, all backends are considered healthy.
 func (r *Registry) PrimaryHealthy(hc HealthChecker) (Backend, error) {
+	return r.PrimaryHealthyFor(KindChat, hc)
+}
+
+// PrimaryHealthyFor is like PrimaryHealthy but balances with the strategy
+// configured for the given capability kind (KindChat or KindEmbed).
+func (r *Registry) PrimaryHealthyFor(kind string, hc HealthChecker) (Backend, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -157,9 +184,53 @@ func (r *Registry) PrimaryHealthy(hc HealthChecker) (Backend, error) {
 		return nil, ErrNoHealthyBackend
 	}
 
-	name := r.lb.Select(healthy)
-	r.lb.Acquire(name)
-	return r.backends[name], nil
+	for len(healthy) > 0 {
+		name := r.lb.SelectFor(kind, healthy)
+		if name == "" {
+			break
+		}
+		r.lb.Acquire(name)
+		if NotifyDispatch(hc, name) {
+			return r.backends[name], nil
+		}
+		r.lb.Release(name)
+		healthy = slices.DeleteFunc(healthy, func(n string) bool { return n == name })
+	}
+	return nil, ErrNoHealthyBackend
+}
+
+// AdmitHealthy is like PrimaryHealthyFor but honours per-backend concurrency
+// limits: backends at their limit are skipped, and when all healthy backends
+// are full the call waits for a free slot. It returns ErrQueueFull or
+// ErrQueueTimeout when the request cannot be admitted, or ctx's error if the
+// caller gives up first.
+func (r *Registry) AdmitHealthy(ctx context.Context, kind string, hc HealthChecker) (Backend, error) {
+	r.mu.RLock()
+	if len(r.backends) == 0 {
+		r.mu.RUnlock()
+		return nil, ErrBackendNotFound
+	}
+	healthy := r.healthyOrderLocked(hc)
+	r.mu.RUnlock()
+
+	if len(healthy) == 0 {
+		return nil, ErrNoHealthyBackend
+	}
+
+	for len(healthy) > 0 {
+		name, err := r.lb.Admit(ctx, kind, healthy)
+		if err != nil {
+			return nil, err
+		}
+		if NotifyDispatch(hc, name) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.backends[name], nil
+		}
+		r.lb.Release(name)
+		healthy = slices.DeleteFunc(healthy, func(n string) bool { return n == name })
+	}
+	return nil, ErrNoHealthyBackend
 }
 
 // ReleaseBackend decrements the in-flight counter for a backend after a request completes.
@@ -167,20 +238,74 @@ func (r *Registry) ReleaseBackend(name string) {
 	r.lb.Release(name)
 }
 
+// ObserveLatency feeds a latency sample for a backend into the load balancer.
+// For chat, d should be the time to first token.
+func (r *Registry) ObserveLatency(kind, name string, d time.Duration) {
+	r.lb.Observe(kind, name, d)
+}
+
+// Balancer returns the registry's load balancer so callers can configure
+// strategies and weights.
+func (r *Registry) Balancer() *LoadBalancer {
+	return r.lb
+}
+
+// Scores returns the current load-balancing inputs for a backend, keyed by
+// capability kind.
+func (r *Registry) Scores(name string) map[string]Score {
+	return map[string]Score{
+		KindChat:  r.lb.Score(KindChat, name),
+		KindEmbed: r.lb.Score(KindEmbed, name),
+	}
+}
+
+// Drain stops name from receiving new requests while its in-flight requests
+// finish. It returns ErrBackendNotFound for unknown backends.
+func (r *Registry) Drain(name string) error {
+	if _, err := r.Get(name); err != nil {
+		return err
+	}
+	r.lb.Drain(name)
+	return nil
+}
+
+// Resume puts a drained backend back into rotation. It returns
+// ErrBackendNotFound for unknown backends.
+func (r *Registry) Resume(name string) error {
+	if _, err := r.Get(name); err != nil {
+		return err
+	}
+	r.lb.Resume(name)
+	return nil
+}
+
+// DrainState reports whether name is active, draining, or drained.
+func (r *Registry) DrainState(name string) DrainState {
+	return r.lb.DrainState(name)
+}
+
+// healthyOrderLocked lists healthy, non-draining backends with the primary
+// first and the rest sorted by name, so selection does not depend on map
+// iteration order.
 func (r *Registry) healthyOrderLocked(hc HealthChecker) []string {
+	eligible := func(name string) bool {
+		return !r.lb.Draining(name) && (hc == nil || hc.IsHealthy(name))
+	}
 	var order []string
-	if r.primary != "" && (hc == nil || hc.IsHealthy(r.primary)) {
+	if r.primary != "" && eligible(r.primary) {
 		order = append(order, r.primary)
 	}
+	var rest []string
 	for name := range r.backends {
 		if name == r.primary {
 			continue
 		}
-		if hc == nil || hc.IsHealthy(name) {
-			order = append(order, name)
+		if eligible(name) {
+			rest = append(rest, name)
 		}
 	}
-	return order
+	sort.Strings(rest)
+	return append(order, rest...)
 }
 
 // All returns all registered backends.
@@ -222,6 +347,29 @@ type ChatRequest struct {
 
 	// Response format (structured outputs).
 	ResponseFormat json.RawMessage `json:"response_format,omitempty"`
+
+	// Audio output. The gateway speaks the answer itself, so these are
+	// cleared before the request reaches a backend.
+	Modalities []string   `json:"modalities,omitempty"`
+	Audio      *ChatAudio `json:"audio,omitempty"`
+}
+
+// ChatAudio asks for the answer to be spoken as well as written, as in
+// OpenAI's audio output modality. Model selects the TTS model; it is an
+// inferencia extension.
+type ChatAudio struct {
+	Voice  string `json:"voice"`
+	Format string `json:"format"` // wav, mp3, opus or pcm16
+	Model  string `json:"model,omitempty"`
+}
+
+// MessageAudio is the spoken form of an assistant message. Data is base64
+// audio; in a streamed chunk it is the audio of one sentence, and Transcript
+// that sentence.
+type MessageAudio struct {
+	ID         string `json:"id"`
+	Data       string `json:"data,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
 }
 
 // Message represents a single message in a chat conversation.
@@ -231,6 +379,7 @@ type Message struct {
 	Name       string          `json:"name,omitempty"`
 	ToolCalls  []ToolCall      `json:"tool_calls,omitempty"`
 	ToolCallID string          `json:"tool_call_id,omitempty"`
+	Audio      *MessageAudio   `json:"audio,omitempty"`
 }
 
 // Tool represents a tool definition in the OpenAI format.
@@ -284,6 +433,39 @@ type Usage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
+// SplitPromptTokens attributes a batch's prompt tokens to its inputs in
+// proportion to their length. Backends only report a total per request, so
+// this is exact for a single input and an estimate otherwise; the shares
+// always add up to the total.
+func SplitPromptTokens(usage *Usage, inputs []string) []int {
+	shares := make([]int, len(inputs))
+	if usage == nil || len(inputs) == 0 {
+		return shares
+	}
+	total := usage.PromptTokens
+	if total == 0 {
+		total = usage.TotalTokens
+	}
+
+	length := 0
+	for _, in := range inputs {
+		length += len(in)
+	}
+	if length == 0 {
+		shares[0] = total
+		return shares
+	}
+
+	cum, prev := 0, 0
+	for i, in := range inputs {
+		cum += len(in)
+		upto := int(math.Round(float64(total) * float64(cum) / float64(length)))
+		shares[i] = upto - prev
+		prev = upto
+	}
+	return shares
+}
+
 // ModelsResponse represents the OpenAI models list response.
 type ModelsResponse struct {
 	Object string  `json:"object"`
@@ -301,8 +483,23 @@ type Model struct {
 // EmbedRequest represents an OpenAI embeddings request.
 type EmbedRequest struct {
 	Model          string          `json:"model"`
-	Input          json.RawMessage `json:"input"` // string or []string
-	EncodingFormat string          `json:"encoding_format,omitempty"`
+	Input          json.RawMessage `json:"input"`                     // string or []string
+	EncodingFormat string          `json:"encoding_format,omitempty"` // float (default) or base64
+	Dimensions     *int            `json:"dimensions,omitempty"`
+}
+
+// Texts decodes Input when it is a string or a non-empty array of strings.
+// Token-array inputs report false.
+func (r EmbedRequest) Texts() ([]string, bool) {
+	var one string
+	if err := json.Unmarshal(r.Input, &one); err == nil {
+		return []string{one}, true
+	}
+	var many []string
+	if err := json.Unmarshal(r.Input, &many); err == nil && len(many) > 0 {
+		return many, true
+	}
+	return nil, false
 }
 
 // EmbedResponse represents an OpenAI embeddings response.
@@ -315,9 +512,9 @@ type EmbedResponse struct {
 
 // Embedding represents a single embedding vector.
 type Embedding struct {
-	Object    string    `json:"object"`
-	Index     int       `json:"index"`
-	Embedding []float64 `json:"embedding"`
+	Object    string `json:"object"`
+	Index     int    `json:"index"`
+	Embedding Vector `json:"embedding"`
 }
 
 // --- TTS types ---
@@ -329,6 +526,7 @@ type TTSRequest struct {
 	Voice          string  `json:"voice"`
 	ResponseFormat string  `json:"response_format,omitempty"` // wav, mp3, opus, flac
 	Speed          float64 `json:"speed,omitempty"`
+	Stream         bool    `json:"stream,omitempty"` // send audio as it is synthesized
 }
 
 // TTSResponse represents the result of a text-to-speech synthesis.
@@ -338,6 +536,12 @@ type TTSResponse struct {
 	DurationMs int
 }
 
+// TTSStream is audio being read from a TTS backend as it is synthesized.
+type TTSStream struct {
+	Audio  io.ReadCloser
+	Format string // Content-Type
+}
+
 // Voice represents a TTS voice option.
 type Voice struct {
 	ID       string `json:"id"`
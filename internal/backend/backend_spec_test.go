@@ -3,6 +3,10 @@ package backend
 import (
 	"context"
 	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -54,6 +58,159 @@ var _ = Describe("ChatRequest", func() {
 	})
 })
 
+var _ = Describe("Vector", func() {
+	It("decodes an array of floats", func() {
+		var e Embedding
+		Expect(json.Unmarshal([]byte(`{"embedding":[0.5,-1]}`), &e)).To(Succeed())
+		Expect(e.Embedding).To(Equal(Vector{0.5, -1}))
+	})
+
+	It("decodes base64-packed float32 values and round-trips them", func() {
+		v := Vector{0.5, -1, 2.25}
+		var e Embedding
+		Expect(json.Unmarshal([]byte(`{"embedding":"`+v.Base64()+`"}`), &e)).To(Succeed())
+		Expect(e.Embedding).To(Equal(v))
+	})
+
+	It("rejects base64 that is not a whole number of float32 values", func() {
+		var e Embedding
+		Expect(json.Unmarshal([]byte(`{"embedding":"AAA="}`), &e)).NotTo(Succeed())
+	})
+
+	It("truncates to unit length", func() {
+		v := Vector{3, 4, 12}.Truncate(2)
+		Expect(v).To(HaveLen(2))
+		Expect(v[0]).To(BeNumerically("~", 0.6, 1e-9))
+		Expect(v[1]).To(BeNumerically("~", 0.8, 1e-9))
+		Expect(Vector{1, 2}.Truncate(3)).To(Equal(Vector{1, 2}))
+	})
+})
+
+var _ = Describe("RerankHTTP", func() {
+	It("translates requests and results for Text Embeddings Inference", func() {
+		var got map[string]any
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/rerank"))
+			Expect(json.NewDecoder(r.Body).Decode(&got)).To(Succeed())
+			_, _ = w.Write([]byte(`[{"index":1,"score":0.8,"text":"b"},{"index":0,"score":0.2,"text":"a"}]`))
+		}))
+		defer srv.Close()
+
+		b := NewRerankHTTP("tei", srv.URL, "tei", time.Second)
+		resp, err := b.Rerank(context.Background(), RerankRequest{
+			Model:           "bge",
+			Query:           "q",
+			Documents:       []RerankDocument{{Text: "a"}, {Text: "b"}},
+			ReturnDocuments: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got["texts"]).To(Equal([]any{"a", "b"}))
+		Expect(got["return_text"]).To(BeTrue())
+		Expect(resp.Results).To(Equal([]RerankResult{
+			{Index: 1, RelevanceScore: 0.8, Document: &RerankDocument{Text: "b"}},
+			{Index: 0, RelevanceScore: 0.2, Document: &RerankDocument{Text: "a"}},
+		}))
+	})
+})
+
+var _ = Describe("STTHTTP", func() {
+	It("uploads the audio as multipart form data", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/v1/audio/transcriptions"))
+			file, header, err := r.FormFile("file")
+			Expect(err).NotTo(HaveOccurred())
+			data, _ := io.ReadAll(file)
+			Expect(data).To(Equal([]byte("RIFF")))
+			Expect(header.Filename).To(Equal("audio.wav"))
+			Expect(r.FormValue("model")).To(Equal("whisper-1"))
+			Expect(r.FormValue("language")).To(Equal("en"))
+			Expect(r.Form).NotTo(HaveKey("prompt"))
+			_, _ = w.Write([]byte(`{"text":"hello there"}`))
+		}))
+		defer srv.Close()
+
+		resp, err := NewSTTHTTP("whisper", srv.URL, time.Second).Transcribe(context.Background(), TranscriptionRequest{
+			Audio:    []byte("RIFF"),
+			Model:    "whisper-1",
+			Language: "en",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Text).To(Equal("hello there"))
+	})
+
+	It("reports the server's errors", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "no model", http.StatusBadRequest)
+		}))
+		defer srv.Close()
+
+		_, err := NewSTTHTTP("whisper", srv.URL, time.Second).Transcribe(context.Background(), TranscriptionRequest{Audio: []byte("RIFF")})
+		Expect(err).To(MatchError(ContainSubstring("status 400")))
+	})
+})
+
+var _ = Describe("TTSHTTP voices", func() {
+	It("lists voices from /v1/audio/voices and describes Kokoro voice IDs", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/v1/audio/voices"))
+			_, _ = w.Write([]byte(`{"voices":["bm_george",{"id":"custom","name":"Custom","language":"de"}]}`))
+		}))
+		defer srv.Close()
+
+		voices, err := NewTTSHTTP("kokoro", srv.URL, time.Second).Voices(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(voices).To(Equal([]Voice{
+			{ID: "bm_george", Name: "bm_george", Gender: "male", Language: "en-GB"},
+			{ID: "custom", Name: "Custom", Language: "de"},
+		}))
+	})
+
+	It("falls back to /v1/models when the server has no voice list", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/models" {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"chatterbox-default"}]}`))
+		}))
+		defer srv.Close()
+
+		voices, err := NewTTSHTTP("chatterbox", srv.URL, time.Second).Voices(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(voices).To(Equal([]Voice{{ID: "chatterbox-default", Name: "chatterbox-default"}}))
+	})
+})
+
+var _ = Describe("TTSStreamHTTP", func() {
+	It("asks the server to stream and hands back the body unread", func() {
+		var got map[string]any
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(json.NewDecoder(r.Body).Decode(&got)).To(Succeed())
+			w.Header().Set("Content-Type", "audio/L16")
+			_, _ = w.Write([]byte("pcm"))
+		}))
+		defer srv.Close()
+
+		b := NewTTSStreamHTTP("kokoro", srv.URL, time.Second)
+		stream, err := b.SynthesizeStream(context.Background(), TTSRequest{Input: "hi", ResponseFormat: "pcm"})
+		Expect(err).NotTo(HaveOccurred())
+		defer func() { _ = stream.Audio.Close() }()
+		Expect(got["stream"]).To(BeTrue())
+		Expect(stream.Format).To(Equal("audio/L16"))
+		Expect(io.ReadAll(stream.Audio)).To(Equal([]byte("pcm")))
+	})
+
+	It("returns the server's error before any audio", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "no voice", http.StatusBadRequest)
+		}))
+		defer srv.Close()
+
+		_, err := NewTTSStreamHTTP("kokoro", srv.URL, time.Second).SynthesizeStream(context.Background(), TTSRequest{Input: "hi"})
+		Expect(err).To(MatchError(ContainSubstring("status 400")))
+	})
+})
+
 var _ = Describe("Registry", func() {
 	Describe("NewRegistry", func() {
 		It("returns an empty registry", func() {
@@ -5,3 +5,81 @@ package backend
 type HealthChecker interface {
 	IsHealthy(name string) bool
 }
+
+// PassiveHealth is implemented by health checkers that learn from live traffic
+// rather than probes, such as circuit breakers. The registries call OnDispatch
+// once a backend has been picked for a request, picking another when it
+// refuses, and handlers report how the request went through ReportOutcome.
+type PassiveHealth interface {
+	HealthChecker
+
+	// OnDispatch is called after name was selected for a request. It
+	// reports false when name may not take the request after all, as when
+	// another request got the last trial slot since IsHealthy was asked.
+	OnDispatch(name string) bool
+
+	// RecordOutcome is called when a request dispatched to name completes.
+	// err is nil on success.
+	RecordOutcome(name string, err error)
+}
+
+// CombineHealth returns a HealthChecker that reports a backend healthy only when
+// every checker does. Nil checkers are skipped. Dispatches and outcomes are
+// forwarded to every checker that implements PassiveHealth.
+func CombineHealth(checkers ...HealthChecker) HealthChecker {
+	var c combinedHealth
+	for _, hc := range checkers {
+		if hc != nil {
+			c = append(c, hc)
+		}
+	}
+	return c
+}
+
+type combinedHealth []HealthChecker
+
+func (c combinedHealth) IsHealthy(name string) bool {
+	for _, hc := range c {
+		if !hc.IsHealthy(name) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c combinedHealth) OnDispatch(name string) bool {
+	for i, hc := range c {
+		if !NotifyDispatch(hc, name) {
+			// Hand back what the checkers before it reserved.
+			for _, prev := range c[:i] {
+				ReportOutcome(prev, name, ErrNotSent)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+func (c combinedHealth) RecordOutcome(name string, err error) {
+	for _, hc := range c {
+		ReportOutcome(hc, name, err)
+	}
+}
+
+// NotifyDispatch tells hc that name was selected, if hc tracks live traffic,
+// and reports whether name may take the request. It is safe to call with a
+// nil hc.
+func NotifyDispatch(hc HealthChecker, name string) bool {
+	if p, ok := hc.(PassiveHealth); ok {
+		return p.OnDispatch(name)
+	}
+	return true
+}
+
+// ReportOutcome forwards the result of a request to hc, if hc tracks live
+// traffic. It is safe to call with a nil hc.
+func ReportOutcome(hc HealthChecker, name string, err error) {
+	if p, ok := hc.(PassiveHealth); ok {
+		p.RecordOutcome(name, err)
+	}
+}
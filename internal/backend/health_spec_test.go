@@ -1,6 +1,8 @@
 package backend
 
 import (
+	"context"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -16,6 +18,27 @@ func (s stubHealthChecker) IsHealthy(name string) bool {
 	return s.healthy[name]
 }
 
+// recordingHealth is a PassiveHealth that records dispatches and outcomes,
+// refusing dispatches to the backends in refuse.
+type recordingHealth struct {
+	stubHealthChecker
+	refuse     map[string]bool
+	dispatched []string
+	outcomes   map[string]error
+}
+
+func (r *recordingHealth) OnDispatch(name string) bool {
+	r.dispatched = append(r.dispatched, name)
+	return !r.refuse[name]
+}
+
+func (r *recordingHealth) RecordOutcome(name string, err error) {
+	if r.outcomes == nil {
+		r.outcomes = make(map[string]error)
+	}
+	r.outcomes[name] = err
+}
+
 var _ = Describe("PrimaryHealthy", func() {
 	It("returns primary when healthy", func() {
 		reg := NewRegistry()
@@ -66,3 +89,105 @@ var _ = Describe("PrimaryHealthy", func() {
 		reg.ReleaseBackend(b2.Name())
 	})
 })
+
+var _ = Describe("CombineHealth", func() {
+	It("reports healthy only when every checker agrees", func() {
+		hc := CombineHealth(
+			stubHealthChecker{healthy: map[string]bool{"ollama": true, "mlx": true}},
+			nil,
+			stubHealthChecker{healthy: map[string]bool{"ollama": true, "mlx": false}},
+		)
+		Expect(hc.IsHealthy("ollama")).To(BeTrue())
+		Expect(hc.IsHealthy("mlx")).To(BeFalse())
+	})
+
+	It("forwards dispatches and outcomes to passive checkers", func() {
+		passive := &recordingHealth{}
+		hc := CombineHealth(stubHealthChecker{}, passive)
+
+		reg := NewRegistry()
+		reg.Register(&minimalBackend{name: "ollama"})
+		b, err := reg.PrimaryHealthy(hc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(passive.dispatched).To(Equal([]string{"ollama"}))
+
+		ReportOutcome(hc, b.Name(), ErrNoHealthyBackend)
+		Expect(passive.outcomes).To(HaveKeyWithValue("ollama", ErrNoHealthyBackend))
+	})
+
+	It("hands back earlier reservations when a later checker refuses", func() {
+		first := &recordingHealth{}
+		hc := CombineHealth(first, &recordingHealth{refuse: map[string]bool{"ollama": true}})
+
+		Expect(NotifyDispatch(hc, "ollama")).To(BeFalse())
+		Expect(first.outcomes).To(HaveKeyWithValue("ollama", ErrNotSent))
+	})
+
+	It("picks another backend when a dispatch is refused", func() {
+		reg := NewRegistry()
+		reg.Register(&minimalBackend{name: "ollama"})
+		reg.Register(&minimalBackend{name: "mlx"})
+		hc := &recordingHealth{refuse: map[string]bool{"ollama": true}}
+
+		for range 2 {
+			b, err := reg.AdmitHealthy(context.Background(), KindChat, hc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(b.Name()).To(Equal("mlx"))
+			reg.ReleaseBackend(b.Name())
+		}
+		Expect(reg.Balancer().InFlight("ollama")).To(BeZero())
+
+		hc.refuse["mlx"] = true
+		_, err := reg.PrimaryHealthyFor(KindChat, hc)
+		Expect(err).To(MatchError(ErrNoHealthyBackend))
+	})
+
+	It("tolerates a nil checker in NotifyDispatch and ReportOutcome", func() {
+		Expect(func() {
+			NotifyDispatch(nil, "ollama")
+			ReportOutcome(nil, "ollama", nil)
+		}).NotTo(Panic())
+	})
+})
+
+var _ = Describe("Registry drain", func() {
+	It("skips draining backends and keeps in-flight requests counted", func() {
+		reg := NewRegistry()
+		reg.Register(&minimalBackend{name: "ollama"})
+		reg.Register(&minimalBackend{name: "mlx"})
+
+		b, err := reg.PrimaryHealthy(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b.Name()).To(Equal("ollama"))
+
+		Expect(reg.Drain("ollama")).To(Succeed())
+		Expect(reg.DrainState("ollama")).To(Equal(DrainDraining))
+
+		for range 3 {
+			next, err := reg.PrimaryHealthy(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(next.Name()).To(Equal("mlx"))
+			reg.ReleaseBackend(next.Name())
+		}
+
+		reg.ReleaseBackend("ollama")
+		Expect(reg.DrainState("ollama")).To(Equal(DrainDrained))
+	})
+
+	It("returns ErrNoHealthyBackend when every backend is drained", func() {
+		reg := NewRegistry()
+		reg.Register(&minimalBackend{name: "ollama"})
+		Expect(reg.Drain("ollama")).To(Succeed())
+
+		_, err := reg.PrimaryHealthy(nil)
+		Expect(err).To(MatchError(ErrNoHealthyBackend))
+
+		Expect(reg.Resume("ollama")).To(Succeed())
+		_, err = reg.PrimaryHealthy(nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("rejects unknown backends", func() {
+		Expect(NewRegistry().Drain("missing")).To(MatchError(ErrBackendNotFound))
+	})
+})
@@ -1,18 +1,219 @@
 package backend
 
-import "sync"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
 
-// LoadBalancer tracks in-flight requests per backend and selects the least-loaded
-// candidate. When multiple backends have the same load, selection round-robins.
+	"github.com/menezmethod/inferencia/internal/priority"
+)
+
+// Capability kinds used to key per-capability load-balancing state. They match
+// router.Capability.String() so both registries share the same vocabulary.
+const (
+	KindChat  = "chat"
+	KindEmbed = "embed"
+)
+
+// Strategy names a backend selection algorithm.
+type Strategy string
+
+const (
+	// StrategyLeastConnections picks the backend with the fewest in-flight
+	// requests, round-robining between ties. This is the default.
+	StrategyLeastConnections Strategy = "least_connections"
+	// StrategyEWMA picks the backend with the lowest exponentially weighted
+	// moving average latency (time-to-first-token for chat), scaled by load.
+	StrategyEWMA Strategy = "ewma"
+	// StrategyPeakEWMA is like StrategyEWMA but jumps to latency spikes
+	// immediately and decays them over time, so a backend that just got slow
+	// is avoided right away and re-probed once the spike has faded.
+	StrategyPeakEWMA Strategy = "peak_ewma"
+	// StrategyP2C samples two random candidates and picks the less loaded one.
+	StrategyP2C Strategy = "p2c"
+	// StrategyWeighted distributes requests in proportion to static weights
+	// using smooth weighted round-robin.
+	StrategyWeighted Strategy = "weighted"
+)
+
+// DefaultDecay is the EWMA time constant used when none is configured.
+const DefaultDecay = 10 * time.Second
+
+// Queue defaults used when none are configured.
+const (
+	DefaultQueueSize       = 100
+	DefaultQueueTimeout    = 30 * time.Second
+	DefaultQueueRetryAfter = 5 * time.Second
+)
+
+// ErrQueueFull is returned by Admit when every candidate is at its concurrency
+// limit and the wait queue has no room.
+var ErrQueueFull = errors.New("backend wait queue is full")
+
+// ErrQueueTimeout is returned by Admit when no candidate freed up within the
+// queue timeout.
+var ErrQueueTimeout = errors.New("timed out waiting for backend capacity")
+
+// Queue event outcomes reported to the OnQueue callback.
+const (
+	QueueEnqueued = "enqueued" // every candidate was full; the request waits
+	QueueAdmitted = "admitted" // a waiting request got a backend
+	QueueTimedOut = "timeout"  // the queue timeout elapsed
+	QueueCanceled = "canceled" // the client went away while waiting
+	QueueRejected = "rejected" // the queue was full
+)
+
+// QueueEvent describes a change in the wait queue for one capability kind and
+// priority class.
+type QueueEvent struct {
+	Kind    string
+	Class   priority.Class
+	Backend string        // set when Outcome is QueueAdmitted
+	Outcome string        // one of the Queue* outcomes
+	Depth   int           // requests of this kind and class still waiting after the event
+	Wait    time.Duration // time spent in the queue; zero for enqueued and rejected
+}
+
+// ParseStrategy validates a strategy name. An empty name selects
+// StrategyLeastConnections.
+func ParseStrategy(s string) (Strategy, error) {
+	switch Strategy(s) {
+	case "":
+		return StrategyLeastConnections, nil
+	case StrategyLeastConnections, StrategyEWMA, StrategyPeakEWMA, StrategyP2C, StrategyWeighted:
+		return Strategy(s), nil
+	default:
+		return "", fmt.Errorf("unknown load-balancing strategy %q", s)
+	}
+}
+
+// Score is a point-in-time view of the inputs the load balancer uses for one
+// backend and capability. It is exposed in the health output.
+type Score struct {
+	Strategy       Strategy `json:"strategy"`
+	InFlight       int      `json:"in_flight"`
+	LatencyMs      float64  `json:"latency_ms"`
+	Samples        int      `json:"samples"`
+	Weight         float64  `json:"weight"`
+	Cost           float64  `json:"cost"`
+	MaxConcurrency int      `json:"max_concurrency,omitempty"`
+}
+
+// DrainState describes whether a backend accepts new requests.
+type DrainState string
+
+const (
+	// DrainActive is the normal state: the backend receives new requests.
+	DrainActive DrainState = "active"
+	// DrainDraining means the backend receives no new requests but still has
+	// requests in flight.
+	DrainDraining DrainState = "draining"
+	// DrainDrained means a draining backend has no requests left in flight and
+	// can be stopped or removed safely.
+	DrainDrained DrainState = "drained"
+)
+
+// latencyStats holds the moving averages for one backend and capability.
+type latencyStats struct {
+	ewma    float64 // seconds
+	peak    float64 // seconds
+	samples int
+	updated time.Time
+}
+
+// LoadBalancer tracks in-flight requests and observed latencies per backend and
+// selects a candidate using the strategy configured for the request's
+// capability. In-flight counts are shared across capabilities because they
+// describe the load on the machine, while latencies are tracked per capability
+// (time-to-first-token for chat is not comparable to an embedding round trip).
 type LoadBalancer struct {
-	mu     sync.Mutex
-	active map[string]int
-	rr     uint64
+	mu         sync.Mutex
+	active     map[string]int
+	rr         uint64
+	strategies map[string]Strategy
+	weights    map[string]float64
+	swrr       map[string]float64 // smooth weighted round-robin state, keyed by kind/name
+	latency    map[string]*latencyStats
+	decay      time.Duration
+	now        func() time.Time
+	draining   map[string]bool
+	onDrain    func(name string, state DrainState)
+
+	limits       map[string]int         // max in-flight per backend; absent means unlimited
+	reserved     map[priority.Class]int // slots per limited backend held back for a class and above
+	queue        []*waiter              // waiting requests, highest class first, FIFO within a class
+	queueMax     int
+	queueTimeout time.Duration
+	retryAfter   time.Duration
+	onQueue      func(QueueEvent)
 }
 
-// NewLoadBalancer creates a LoadBalancer.
+// waiter is a request parked until one of its candidates has capacity.
+type waiter struct {
+	kind     string
+	class    priority.Class
+	names    []string
+	enqueued time.Time
+	ready    chan string // receives the admitted backend; buffered
+}
+
+// NewLoadBalancer creates a LoadBalancer that uses least-connections for every
+// capability until SetStrategy is called.
 func NewLoadBalancer() *LoadBalancer {
-	return &LoadBalancer{active: make(map[string]int)}
+	return &LoadBalancer{
+		active:     make(map[string]int),
+		strategies: make(map[string]Strategy),
+		weights:    make(map[string]float64),
+		swrr:       make(map[string]float64),
+		latency:    make(map[string]*latencyStats),
+		decay:      DefaultDecay,
+		now:        time.Now,
+		draining:   make(map[string]bool),
+
+		limits:       make(map[string]int),
+		reserved:     make(map[priority.Class]int),
+		queueMax:     DefaultQueueSize,
+		queueTimeout: DefaultQueueTimeout,
+		retryAfter:   DefaultQueueRetryAfter,
+	}
+}
+
+// SetStrategy selects the strategy used for a capability kind.
+func (lb *LoadBalancer) SetStrategy(kind string, s Strategy) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.strategies[kind] = s
+}
+
+// Strategy returns the strategy configured for a capability kind.
+func (lb *LoadBalancer) Strategy(kind string) Strategy {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return lb.strategyLocked(kind)
+}
+
+// SetWeight sets the static weight of a backend. Weights only affect the
+// weighted strategy; a backend without a weight counts as 1.
+func (lb *LoadBalancer) SetWeight(name string, w float64) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if w > 0 {
+		lb.weights[name] = w
+	}
+}
+
+// SetDecay sets the EWMA time constant. Larger values smooth more.
+func (lb *LoadBalancer) SetDecay(d time.Duration) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if d > 0 {
+		lb.decay = d
+	}
 }
 
 // Acquire increments the in-flight count for name.
@@ -22,39 +223,506 @@ func (lb *LoadBalancer) Acquire(name string) {
 	lb.active[name]++
 }
 
-// Release decrements the in-flight count for name.
+// Release decrements the in-flight count for name and hands the freed slot to
+// the longest-waiting request that can use it. When a draining backend's last
+// request completes, the drain callback is told it is drained, once.
 func (lb *LoadBalancer) Release(name string) {
 	lb.mu.Lock()
-	defer lb.mu.Unlock()
+	drained := false
 	if lb.active[name] > 0 {
 		lb.active[name]--
+		drained = lb.draining[name] && lb.active[name] == 0
+	}
+	events := lb.dispatchLocked()
+	fn := lb.onDrain
+	lb.mu.Unlock()
+
+	lb.emit(events)
+	if drained && fn != nil {
+		fn(name, DrainDrained)
 	}
 }
 
-// Select picks the backend with the fewest in-flight requests from names.
-// Ties are broken with round-robin. The caller must call Acquire for the
-// returned name before dispatching the request.
+// SetLimit caps the number of in-flight requests for name. Zero or negative
+// removes the cap. A backend at its cap is not eligible for new requests;
+// Admit queues them until a slot frees up.
+func (lb *LoadBalancer) SetLimit(name string, max int) {
+	lb.mu.Lock()
+	if max > 0 {
+		lb.limits[name] = max
+	} else {
+		delete(lb.limits, name)
+	}
+	events := lb.dispatchLocked()
+	lb.mu.Unlock()
+	lb.emit(events)
+}
+
+// SetReserved holds back n slots on every backend with a concurrency limit for
+// requests of class c or higher. With a limit of 4 and one slot reserved for
+// interactive, batch and normal requests can use at most 3.
+func (lb *LoadBalancer) SetReserved(c priority.Class, n int) {
+	lb.mu.Lock()
+	if n > 0 {
+		lb.reserved[c] = n
+	} else {
+		delete(lb.reserved, c)
+	}
+	events := lb.dispatchLocked()
+	lb.mu.Unlock()
+	lb.emit(events)
+}
+
+// Limit returns the in-flight cap for name, or 0 when it is unlimited.
+func (lb *LoadBalancer) Limit(name string) int {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return lb.limits[name]
+}
+
+// SetQueue configures the wait queue used by Admit: at most maxSize waiting
+// requests, each waiting at most timeout. retryAfter is the back-off suggested
+// to clients that were turned away. Non-positive values keep the current
+// setting.
+func (lb *LoadBalancer) SetQueue(maxSize int, timeout, retryAfter time.Duration) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if maxSize > 0 {
+		lb.queueMax = maxSize
+	}
+	if timeout > 0 {
+		lb.queueTimeout = timeout
+	}
+	if retryAfter > 0 {
+		lb.retryAfter = retryAfter
+	}
+}
+
+// RetryAfter returns the back-off to suggest when Admit fails with
+// ErrQueueFull or ErrQueueTimeout.
+func (lb *LoadBalancer) RetryAfter() time.Duration {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return lb.retryAfter
+}
+
+// QueueDepth returns the number of requests waiting for capacity.
+func (lb *LoadBalancer) QueueDepth() int {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return len(lb.queue)
+}
+
+// OnQueue registers fn to be called, outside the balancer's lock, whenever a
+// request enters, leaves, or is turned away from the wait queue.
+func (lb *LoadBalancer) OnQueue(fn func(QueueEvent)) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.onQueue = fn
+}
+
+// Admit picks a backend from names with capacity to spare, using the strategy
+// configured for kind, and counts the request as in flight. When every
+// candidate is at its concurrency limit (taking slots reserved for higher
+// priority classes into account) the request waits until one frees up, ctx is
+// done, or the queue timeout elapses. Waiting requests are served highest
+// priority class first (see priority.FromContext), FIFO within a class. The
+// caller must call Release for the returned name when the request completes.
+func (lb *LoadBalancer) Admit(ctx context.Context, kind string, names []string) (string, error) {
+	if len(names) == 0 {
+		return "", ErrNoHealthyBackend
+	}
+	class := priority.FromContext(ctx)
+
+	lb.mu.Lock()
+	if open := lb.openLocked(names, class); len(open) > 0 {
+		name := lb.selectLocked(kind, open)
+		lb.active[name]++
+		lb.mu.Unlock()
+		return name, nil
+	}
+	if len(lb.queue) >= lb.queueMax {
+		ev := QueueEvent{Kind: kind, Class: class, Outcome: QueueRejected, Depth: lb.depthLocked(kind, class)}
+		lb.mu.Unlock()
+		lb.emit([]QueueEvent{ev})
+		return "", ErrQueueFull
+	}
+	w := &waiter{kind: kind, class: class, names: names, enqueued: lb.now(), ready: make(chan string, 1)}
+	lb.enqueueLocked(w)
+	timeout := lb.queueTimeout
+	ev := QueueEvent{Kind: kind, Class: class, Outcome: QueueEnqueued, Depth: lb.depthLocked(kind, class)}
+	lb.mu.Unlock()
+	lb.emit([]QueueEvent{ev})
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var err error
+	outcome := QueueTimedOut
+	select {
+	case name := <-w.ready:
+		return name, nil
+	case <-ctx.Done():
+		err = ctx.Err()
+		outcome = QueueCanceled
+	case <-timer.C:
+		err = ErrQueueTimeout
+	}
+
+	lb.mu.Lock()
+	if !lb.removeWaiterLocked(w) {
+		// A slot was handed over just as we gave up; take it so it is
+		// released through the normal path.
+		lb.mu.Unlock()
+		return <-w.ready, nil
+	}
+	ev = QueueEvent{Kind: kind, Class: class, Outcome: outcome, Depth: lb.depthLocked(kind, class), Wait: lb.now().Sub(w.enqueued)}
+	lb.mu.Unlock()
+	lb.emit([]QueueEvent{ev})
+	return "", err
+}
+
+// Drain stops name from being selected for new requests. Requests already in
+// flight are unaffected; once they finish the backend is drained. Draining an
+// idle backend drains it immediately.
+func (lb *LoadBalancer) Drain(name string) {
+	lb.mu.Lock()
+	if lb.draining[name] {
+		lb.mu.Unlock()
+		return
+	}
+	lb.draining[name] = true
+	idle := lb.active[name] == 0
+	fn := lb.onDrain
+	lb.mu.Unlock()
+
+	if fn != nil {
+		fn(name, DrainDraining)
+		if idle {
+			fn(name, DrainDrained)
+		}
+	}
+}
+
+// Resume puts a draining or drained backend back into rotation.
+func (lb *LoadBalancer) Resume(name string) {
+	lb.mu.Lock()
+	if !lb.draining[name] {
+		lb.mu.Unlock()
+		return
+	}
+	delete(lb.draining, name)
+	events := lb.dispatchLocked()
+	fn := lb.onDrain
+	lb.mu.Unlock()
+
+	if fn != nil {
+		fn(name, DrainActive)
+	}
+	lb.emit(events)
+}
+
+// DrainState reports whether name is active, draining, or drained.
+func (lb *LoadBalancer) DrainState(name string) DrainState {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return lb.drainStateLocked(name)
+}
+
+// Draining reports whether name is excluded from selection.
+func (lb *LoadBalancer) Draining(name string) bool {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return lb.draining[name]
+}
+
+// OnDrainChange registers fn to be called, outside the balancer's lock, whenever
+// a backend starts draining, finishes draining, or resumes.
+func (lb *LoadBalancer) OnDrainChange(fn func(name string, state DrainState)) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.onDrain = fn
+}
+
+// Observe records a latency sample for name under the given capability kind.
+// For chat this should be the time to first token; for other kinds, the full
+// round trip.
+func (lb *LoadBalancer) Observe(kind, name string, d time.Duration) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	sample := d.Seconds()
+	now := lb.now()
+	key := kind + "/" + name
+	st, ok := lb.latency[key]
+	if !ok {
+		lb.latency[key] = &latencyStats{ewma: sample, peak: sample, samples: 1, updated: now}
+		return
+	}
+
+	w := math.Min(lb.decayFactor(st.updated, now), maxKeep)
+	st.ewma = st.ewma*w + sample*(1-w)
+	if sample > st.peak {
+		st.peak = sample
+	} else {
+		st.peak = st.peak*w + sample*(1-w)
+	}
+	st.samples++
+	st.updated = now
+}
+
+// Select picks a backend from names using the default (least-connections)
+// strategy. The caller must call Acquire for the returned name before
+// dispatching the request.
 func (lb *LoadBalancer) Select(names []string) string {
+	return lb.SelectFor("", names)
+}
+
+// SelectFor picks a backend from names using the strategy configured for kind.
+// Backends at their concurrency limit are only considered when every candidate
+// is; SelectFor never waits (see Admit). Draining backends are never picked,
+// and SelectFor returns "" when every candidate is draining. The caller must
+// call Acquire for the returned name before dispatching the request.
+func (lb *LoadBalancer) SelectFor(kind string, names []string) string {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if open := lb.openLocked(names, priority.Normal); len(open) > 0 {
+		names = open
+	} else {
+		names = slices.DeleteFunc(slices.Clone(names), func(name string) bool { return lb.draining[name] })
+	}
 	if len(names) == 0 {
 		return ""
 	}
+	return lb.selectLocked(kind, names)
+}
+
+// selectLocked applies kind's strategy to a non-empty candidate list.
+func (lb *LoadBalancer) selectLocked(kind string, names []string) string {
 	if len(names) == 1 {
 		return names[0]
 	}
 
+	switch lb.strategyLocked(kind) {
+	case StrategyEWMA, StrategyPeakEWMA:
+		return lb.pickMinLocked(names, func(name string) float64 {
+			return lb.costLocked(kind, name)
+		})
+	case StrategyP2C:
+		return lb.pickP2CLocked(kind, names)
+	case StrategyWeighted:
+		return lb.pickWeightedLocked(kind, names)
+	default:
+		return lb.pickMinLocked(names, func(name string) float64 {
+			return float64(lb.active[name])
+		})
+	}
+}
+
+// Score reports the load-balancing inputs for name under kind.
+func (lb *LoadBalancer) Score(kind, name string) Score {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	s := Score{
+		Strategy:       lb.strategyLocked(kind),
+		InFlight:       lb.active[name],
+		Weight:         lb.weightLocked(name),
+		MaxConcurrency: lb.limits[name],
+	}
+	if st, ok := lb.latency[kind+"/"+name]; ok {
+		s.Samples = st.samples
+		s.LatencyMs = lb.latencyLocked(s.Strategy, st) * 1000
+	}
+	switch s.Strategy {
+	case StrategyEWMA, StrategyPeakEWMA:
+		s.Cost = lb.costLocked(kind, name)
+	case StrategyWeighted:
+		s.Cost = float64(s.InFlight) / s.Weight
+	default:
+		s.Cost = float64(s.InFlight)
+	}
+	return s
+}
+
+// InFlight returns the current in-flight count for name.
+func (lb *LoadBalancer) InFlight(name string) int {
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
+	return lb.active[name]
+}
+
+// openLocked returns the names that are neither draining nor at their
+// concurrency limit for class, i.e. the limit minus the slots reserved for
+// higher classes.
+func (lb *LoadBalancer) openLocked(names []string, class priority.Class) []string {
+	held := 0
+	for c, n := range lb.reserved {
+		if c > class {
+			held += n
+		}
+	}
+	var open []string
+	for _, name := range names {
+		if lb.draining[name] {
+			continue
+		}
+		if limit, ok := lb.limits[name]; ok && lb.active[name] >= limit-held {
+			continue
+		}
+		open = append(open, name)
+	}
+	return open
+}
+
+// enqueueLocked inserts w behind every waiter of the same or a higher class.
+func (lb *LoadBalancer) enqueueLocked(w *waiter) {
+	i := len(lb.queue)
+	for i > 0 && lb.queue[i-1].class < w.class {
+		i--
+	}
+	lb.queue = slices.Insert(lb.queue, i, w)
+}
 
-	minLoad := lb.active[names[0]]
-	for _, name := range names[1:] {
-		if load := lb.active[name]; load < minLoad {
-			minLoad = load
+// dispatchLocked hands free capacity to waiting requests in queue order. A
+// waiter whose candidates are all still full keeps its place without blocking
+// the ones behind it.
+func (lb *LoadBalancer) dispatchLocked() []QueueEvent {
+	var events []QueueEvent
+	kept := lb.queue[:0]
+	for _, w := range lb.queue {
+		open := lb.openLocked(w.names, w.class)
+		if len(open) == 0 {
+			kept = append(kept, w)
+			continue
+		}
+		name := lb.selectLocked(w.kind, open)
+		lb.active[name]++
+		w.ready <- name
+		events = append(events, QueueEvent{Kind: w.kind, Class: w.class, Backend: name, Outcome: QueueAdmitted, Wait: lb.now().Sub(w.enqueued)})
+	}
+	clear(lb.queue[len(kept):])
+	lb.queue = kept
+	for i := range events {
+		events[i].Depth = lb.depthLocked(events[i].Kind, events[i].Class)
+	}
+	return events
+}
+
+// removeWaiterLocked drops w from the queue, reporting false if it was already
+// admitted.
+func (lb *LoadBalancer) removeWaiterLocked(w *waiter) bool {
+	for i, q := range lb.queue {
+		if q == w {
+			lb.queue = append(lb.queue[:i], lb.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// depthLocked counts waiting requests of the given kind and class.
+func (lb *LoadBalancer) depthLocked(kind string, class priority.Class) int {
+	n := 0
+	for _, w := range lb.queue {
+		if w.kind == kind && w.class == class {
+			n++
+		}
+	}
+	return n
+}
+
+// emit reports queue events to the OnQueue callback. It must be called without
+// holding lb.mu.
+func (lb *LoadBalancer) emit(events []QueueEvent) {
+	if len(events) == 0 {
+		return
+	}
+	lb.mu.Lock()
+	fn := lb.onQueue
+	lb.mu.Unlock()
+	if fn == nil {
+		return
+	}
+	for _, e := range events {
+		fn(e)
+	}
+}
+
+func (lb *LoadBalancer) drainStateLocked(name string) DrainState {
+	switch {
+	case !lb.draining[name]:
+		return DrainActive
+	case lb.active[name] > 0:
+		return DrainDraining
+	default:
+		return DrainDrained
+	}
+}
+
+func (lb *LoadBalancer) strategyLocked(kind string) Strategy {
+	if s, ok := lb.strategies[kind]; ok {
+		return s
+	}
+	return StrategyLeastConnections
+}
+
+func (lb *LoadBalancer) weightLocked(name string) float64 {
+	if w, ok := lb.weights[name]; ok {
+		return w
+	}
+	return 1
+}
+
+// maxKeep caps how much of the previous average survives an update, so that
+// bursts of samples arriving at the same instant still move the average.
+const maxKeep = 0.9
+
+// decayFactor returns the share of a previous value that survives after the
+// elapsed time, following the time-decayed EWMA used by Finagle and Linkerd.
+func (lb *LoadBalancer) decayFactor(then, now time.Time) float64 {
+	elapsed := now.Sub(then).Seconds()
+	if elapsed <= 0 {
+		return 1
+	}
+	return math.Exp(-elapsed / lb.decay.Seconds())
+}
+
+// latencyLocked returns the latency estimate the strategy works with. For peak
+// EWMA the excess of the last spike over the average fades with time since the
+// last sample, so a backend is not punished forever for one slow request.
+func (lb *LoadBalancer) latencyLocked(s Strategy, st *latencyStats) float64 {
+	if s != StrategyPeakEWMA {
+		return st.ewma
+	}
+	return st.ewma + (st.peak-st.ewma)*lb.decayFactor(st.updated, lb.now())
+}
+
+// costLocked is latency × (in-flight + 1). Backends without samples cost zero so
+// they are explored before the balancer settles.
+func (lb *LoadBalancer) costLocked(kind, name string) float64 {
+	st, ok := lb.latency[kind+"/"+name]
+	if !ok {
+		return 0
+	}
+	return lb.latencyLocked(lb.strategyLocked(kind), st) * float64(lb.active[name]+1)
+}
+
+// pickMinLocked returns the name with the lowest cost, round-robining ties.
+func (lb *LoadBalancer) pickMinLocked(names []string, cost func(string) float64) string {
+	costs := make([]float64, len(names))
+	minCost := math.Inf(1)
+	for i, name := range names {
+		costs[i] = cost(name)
+		if costs[i] < minCost {
+			minCost = costs[i]
 		}
 	}
 
 	var tied []string
-	for _, name := range names {
-		if lb.active[name] == minLoad {
+	for i, name := range names {
+		if costs[i] == minCost {
 			tied = append(tied, name)
 		}
 	}
@@ -64,9 +732,41 @@ func (lb *LoadBalancer) Select(names []string) string {
 	return pick
 }
 
-// InFlight returns the current in-flight count for name (for tests).
-func (lb *LoadBalancer) InFlight(name string) int {
-	lb.mu.Lock()
-	defer lb.mu.Unlock()
-	return lb.active[name]
+// pickP2CLocked samples two distinct candidates and keeps the one with fewer
+// in-flight requests, falling back to latency when loads are equal.
+func (lb *LoadBalancer) pickP2CLocked(kind string, names []string) string {
+	i := rand.IntN(len(names))
+	j := rand.IntN(len(names) - 1)
+	if j >= i {
+		j++
+	}
+	a, b := names[i], names[j]
+	if lb.active[a] != lb.active[b] {
+		if lb.active[a] < lb.active[b] {
+			return a
+		}
+		return b
+	}
+	if lb.costLocked(kind, b) < lb.costLocked(kind, a) {
+		return b
+	}
+	return a
+}
+
+// pickWeightedLocked implements smooth weighted round-robin (as in nginx):
+// every candidate gains its weight, the largest wins and pays back the total.
+func (lb *LoadBalancer) pickWeightedLocked(kind string, names []string) string {
+	var total float64
+	best := ""
+	for _, name := range names {
+		w := lb.weightLocked(name)
+		total += w
+		key := kind + "/" + name
+		lb.swrr[key] += w
+		if best == "" || lb.swrr[key] > lb.swrr[kind+"/"+best] {
+			best = name
+		}
+	}
+	lb.swrr[kind+"/"+best] -= total
+	return best
 }
@@ -1,8 +1,13 @@
 package backend
 
 import (
+	"context"
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/priority"
 )
 
 var _ = Describe("LoadBalancer", func() {
@@ -44,3 +49,336 @@ var _ = Describe("LoadBalancer", func() {
 		Expect(lb.InFlight("missing")).To(Equal(0))
 	})
 })
+
+var _ = Describe("LoadBalancer strategies", func() {
+	var (
+		lb  *LoadBalancer
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		lb = NewLoadBalancer()
+		now = time.Unix(1_700_000_000, 0)
+		lb.now = func() time.Time { return now }
+	})
+
+	Describe("ParseStrategy", func() {
+		It("defaults an empty name to least connections", func() {
+			s, err := ParseStrategy("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s).To(Equal(StrategyLeastConnections))
+		})
+
+		It("rejects unknown names", func() {
+			_, err := ParseStrategy("random")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ewma", func() {
+		BeforeEach(func() {
+			lb.SetStrategy(KindChat, StrategyEWMA)
+		})
+
+		It("prefers the backend with the lower observed latency", func() {
+			lb.Observe(KindChat, "m3-ultra", 100*time.Millisecond)
+			lb.Observe(KindChat, "mini-pc", 500*time.Millisecond)
+
+			Expect(lb.SelectFor(KindChat, []string{"mini-pc", "m3-ultra"})).To(Equal("m3-ultra"))
+		})
+
+		It("spills over to the slower backend once the fast one is loaded", func() {
+			lb.Observe(KindChat, "m3-ultra", 100*time.Millisecond)
+			lb.Observe(KindChat, "mini-pc", 500*time.Millisecond)
+			for range 5 {
+				lb.Acquire("m3-ultra")
+			}
+
+			Expect(lb.SelectFor(KindChat, []string{"mini-pc", "m3-ultra"})).To(Equal("mini-pc"))
+		})
+
+		It("explores backends without samples first", func() {
+			lb.Observe(KindChat, "m3-ultra", 100*time.Millisecond)
+
+			Expect(lb.SelectFor(KindChat, []string{"m3-ultra", "new"})).To(Equal("new"))
+		})
+
+		It("keeps latencies separate per capability", func() {
+			lb.Observe(KindEmbed, "m3-ultra", 5*time.Second)
+			lb.Observe(KindChat, "m3-ultra", 100*time.Millisecond)
+			lb.Observe(KindChat, "mini-pc", 500*time.Millisecond)
+
+			Expect(lb.SelectFor(KindChat, []string{"mini-pc", "m3-ultra"})).To(Equal("m3-ultra"))
+		})
+	})
+
+	Describe("peak_ewma", func() {
+		It("avoids a backend right after a spike and returns once it decays", func() {
+			lb.SetStrategy(KindChat, StrategyPeakEWMA)
+			lb.Observe(KindChat, "a", 100*time.Millisecond)
+			lb.Observe(KindChat, "a", 2*time.Second)
+			lb.Observe(KindChat, "b", 400*time.Millisecond)
+
+			Expect(lb.SelectFor(KindChat, []string{"a", "b"})).To(Equal("b"))
+
+			now = now.Add(time.Minute)
+			Expect(lb.SelectFor(KindChat, []string{"a", "b"})).To(Equal("a"))
+		})
+	})
+
+	Describe("p2c", func() {
+		It("picks the less loaded of the two sampled backends", func() {
+			lb.SetStrategy(KindChat, StrategyP2C)
+			lb.Acquire("a")
+			lb.Acquire("a")
+
+			for range 10 {
+				Expect(lb.SelectFor(KindChat, []string{"a", "b"})).To(Equal("b"))
+			}
+		})
+	})
+
+	Describe("weighted", func() {
+		It("distributes selections in proportion to the weights", func() {
+			lb.SetStrategy(KindChat, StrategyWeighted)
+			lb.SetWeight("m3-ultra", 3)
+
+			counts := map[string]int{}
+			for range 8 {
+				counts[lb.SelectFor(KindChat, []string{"m3-ultra", "mini-pc"})]++
+			}
+			Expect(counts).To(Equal(map[string]int{"m3-ultra": 6, "mini-pc": 2}))
+		})
+	})
+
+	Describe("Score", func() {
+		It("reports the strategy, load, and latency estimate", func() {
+			lb.SetStrategy(KindChat, StrategyEWMA)
+			lb.SetWeight("a", 2)
+			lb.Observe(KindChat, "a", 200*time.Millisecond)
+			lb.Acquire("a")
+
+			s := lb.Score(KindChat, "a")
+			Expect(s.Strategy).To(Equal(StrategyEWMA))
+			Expect(s.InFlight).To(Equal(1))
+			Expect(s.Samples).To(Equal(1))
+			Expect(s.Weight).To(Equal(2.0))
+			Expect(s.LatencyMs).To(BeNumerically("~", 200, 0.001))
+			Expect(s.Cost).To(BeNumerically("~", 0.4, 0.001))
+		})
+	})
+})
+
+var _ = Describe("LoadBalancer drain", func() {
+	var (
+		lb     *LoadBalancer
+		events []string
+	)
+
+	BeforeEach(func() {
+		lb = NewLoadBalancer()
+		events = nil
+		lb.OnDrainChange(func(name string, state DrainState) {
+			events = append(events, name+":"+string(state))
+		})
+	})
+
+	It("drains immediately when nothing is in flight", func() {
+		lb.Drain("a")
+		Expect(lb.DrainState("a")).To(Equal(DrainDrained))
+		Expect(events).To(Equal([]string{"a:draining", "a:drained"}))
+	})
+
+	It("reports drained once the last in-flight request is released", func() {
+		lb.Acquire("a")
+		lb.Acquire("a")
+		lb.Drain("a")
+		Expect(lb.DrainState("a")).To(Equal(DrainDraining))
+		Expect(lb.Draining("a")).To(BeTrue())
+
+		lb.Release("a")
+		Expect(lb.DrainState("a")).To(Equal(DrainDraining))
+		lb.Release("a")
+		Expect(lb.DrainState("a")).To(Equal(DrainDrained))
+		Expect(events).To(Equal([]string{"a:draining", "a:drained"}))
+
+		lb.Release("a")
+		Expect(events).To(Equal([]string{"a:draining", "a:drained"}))
+	})
+
+	It("resumes and ignores repeated drains", func() {
+		lb.Drain("a")
+		lb.Drain("a")
+		lb.Resume("a")
+		lb.Resume("a")
+		Expect(lb.DrainState("a")).To(Equal(DrainActive))
+		Expect(events).To(Equal([]string{"a:draining", "a:drained", "a:active"}))
+	})
+
+	It("never selects a draining backend, even when the rest are full", func() {
+		lb.SetLimit("b", 1)
+		lb.Acquire("b")
+		lb.Drain("a")
+		Expect(lb.SelectFor(KindChat, []string{"a", "b"})).To(Equal("b"))
+		Expect(lb.SelectFor(KindChat, []string{"a"})).To(BeEmpty())
+	})
+})
+
+var _ = Describe("LoadBalancer concurrency limits", func() {
+	var (
+		lb     *LoadBalancer
+		events chan QueueEvent
+	)
+
+	BeforeEach(func() {
+		lb = NewLoadBalancer()
+		lb.SetLimit("a", 1)
+		lb.SetQueue(2, time.Second, 3*time.Second)
+		events = make(chan QueueEvent, 16)
+		lb.OnQueue(func(e QueueEvent) { events <- e })
+	})
+
+	It("treats a full backend as ineligible", func() {
+		lb.Acquire("a")
+		for range 3 {
+			Expect(lb.SelectFor(KindChat, []string{"a", "b"})).To(Equal("b"))
+		}
+
+		name, err := lb.Admit(context.Background(), KindChat, []string{"a", "b"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("b"))
+		Expect(lb.Score(KindChat, "a").MaxConcurrency).To(Equal(1))
+	})
+
+	It("queues when every candidate is full and admits in FIFO order", func() {
+		lb.Acquire("a")
+
+		got := make(chan string, 2)
+		for i := range 2 {
+			go func() {
+				defer GinkgoRecover()
+				name, err := lb.Admit(context.Background(), KindChat, []string{"a"})
+				Expect(err).NotTo(HaveOccurred())
+				got <- name + string(rune('0'+i))
+			}()
+			Eventually(lb.QueueDepth).Should(Equal(i + 1))
+		}
+		Expect(lb.InFlight("a")).To(Equal(1))
+
+		lb.Release("a")
+		Eventually(got).Should(Receive(Equal("a0")))
+		Expect(lb.InFlight("a")).To(Equal(1))
+		Consistently(got, 50*time.Millisecond).ShouldNot(Receive())
+
+		lb.Release("a")
+		Eventually(got).Should(Receive(Equal("a1")))
+		Expect(lb.QueueDepth()).To(BeZero())
+
+		var admitted []QueueEvent
+		for len(events) > 0 {
+			if e := <-events; e.Outcome == QueueAdmitted {
+				admitted = append(admitted, e)
+			}
+		}
+		Expect(admitted).To(HaveLen(2))
+		Expect(admitted[0].Backend).To(Equal("a"))
+	})
+
+	It("times out when no slot frees up", func() {
+		lb.SetQueue(2, 20*time.Millisecond, 0)
+		lb.Acquire("a")
+
+		_, err := lb.Admit(context.Background(), KindChat, []string{"a"})
+		Expect(err).To(MatchError(ErrQueueTimeout))
+		Expect(lb.QueueDepth()).To(BeZero())
+		Expect(lb.RetryAfter()).To(Equal(3 * time.Second))
+	})
+
+	It("rejects when the queue is full", func() {
+		lb.SetQueue(1, time.Second, 0)
+		lb.Acquire("a")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			_, err := lb.Admit(ctx, KindChat, []string{"a"})
+			done <- err
+		}()
+		Eventually(lb.QueueDepth).Should(Equal(1))
+
+		_, err := lb.Admit(context.Background(), KindChat, []string{"a"})
+		Expect(err).To(MatchError(ErrQueueFull))
+
+		cancel()
+		Eventually(done).Should(Receive(MatchError(context.Canceled)))
+		Expect(lb.QueueDepth()).To(BeZero())
+	})
+
+	It("admits waiters when a limit is raised", func() {
+		lb.Acquire("a")
+		got := make(chan string, 1)
+		go func() {
+			name, _ := lb.Admit(context.Background(), KindChat, []string{"a"})
+			got <- name
+		}()
+		Eventually(lb.QueueDepth).Should(Equal(1))
+
+		lb.SetLimit("a", 2)
+		Eventually(got).Should(Receive(Equal("a")))
+		Expect(lb.InFlight("a")).To(Equal(2))
+	})
+})
+
+var _ = Describe("LoadBalancer priority classes", func() {
+	var lb *LoadBalancer
+
+	BeforeEach(func() {
+		lb = NewLoadBalancer()
+		lb.SetQueue(10, time.Second, 0)
+	})
+
+	admitAsync := func(class priority.Class, got chan<- priority.Class) {
+		go func() {
+			defer GinkgoRecover()
+			ctx := priority.WithClass(context.Background(), class)
+			_, err := lb.Admit(ctx, KindChat, []string{"a"})
+			Expect(err).NotTo(HaveOccurred())
+			got <- class
+		}()
+	}
+
+	It("serves higher classes first, FIFO within a class", func() {
+		lb.SetLimit("a", 1)
+		lb.Acquire("a")
+
+		got := make(chan priority.Class, 3)
+		for i, class := range []priority.Class{priority.Batch, priority.Normal, priority.Interactive} {
+			admitAsync(class, got)
+			Eventually(lb.QueueDepth).Should(Equal(i + 1))
+		}
+
+		for _, want := range []priority.Class{priority.Interactive, priority.Normal, priority.Batch} {
+			lb.Release("a")
+			Eventually(got).Should(Receive(Equal(want)))
+		}
+	})
+
+	It("holds reserved slots back for higher classes", func() {
+		lb.SetLimit("a", 2)
+		lb.SetReserved(priority.Interactive, 1)
+		lb.Acquire("a")
+
+		got := make(chan priority.Class, 2)
+		admitAsync(priority.Batch, got)
+		Eventually(lb.QueueDepth).Should(Equal(1))
+		Consistently(got, 50*time.Millisecond).ShouldNot(Receive())
+
+		admitAsync(priority.Interactive, got)
+		Eventually(got).Should(Receive(Equal(priority.Interactive)))
+		Expect(lb.QueueDepth()).To(Equal(1))
+
+		lb.Release("a")
+		lb.Release("a")
+		Eventually(got).Should(Receive(Equal(priority.Batch)))
+	})
+})
@@ -0,0 +1,188 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RerankBackend scores documents by their relevance to a query.
+type RerankBackend interface {
+	Probe
+
+	// Rerank returns a relevance score for each document in req.
+	Rerank(ctx context.Context, req RerankRequest) (*RerankResponse, error)
+}
+
+// RerankRequest is a Cohere/Jina-compatible rerank request.
+type RerankRequest struct {
+	Model           string           `json:"model,omitempty"`
+	Query           string           `json:"query"`
+	Documents       []RerankDocument `json:"documents"`
+	TopN            *int             `json:"top_n,omitempty"`
+	ReturnDocuments bool             `json:"return_documents,omitempty"`
+}
+
+// RerankDocument is a document to rank. It decodes from a plain string or
+// from an object with a "text" field, and encodes as the object.
+type RerankDocument struct {
+	Text string `json:"text"`
+}
+
+// UnmarshalJSON accepts both document shapes.
+func (d *RerankDocument) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &d.Text)
+	}
+	var obj struct {
+		Text *string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	if obj.Text == nil {
+		return errors.New(`document must be a string or an object with a "text" field`)
+	}
+	d.Text = *obj.Text
+	return nil
+}
+
+// RerankResponse lists the documents' scores, most relevant first.
+type RerankResponse struct {
+	ID      string         `json:"id,omitempty"`
+	Model   string         `json:"model,omitempty"`
+	Results []RerankResult `json:"results"`
+	Usage   *Usage         `json:"usage,omitempty"`
+}
+
+// RerankResult is the score of the document at Index in the request.
+type RerankResult struct {
+	Index          int             `json:"index"`
+	RelevanceScore float64         `json:"relevance_score"`
+	Document       *RerankDocument `json:"document,omitempty"`
+}
+
+// RerankHTTP implements RerankBackend for servers with a Cohere-style
+// POST /v1/rerank endpoint (llama.cpp, Infinity, vLLM) or, with API "tei",
+// Hugging Face Text Embeddings Inference's POST /rerank.
+type RerankHTTP struct {
+	name    string
+	baseURL string
+	api     string
+	client  *http.Client
+}
+
+// NewRerankHTTP creates a rerank backend adapter. api is "tei" for Text
+// Embeddings Inference and "" or "cohere" for everything else.
+func NewRerankHTTP(name, baseURL, api string, timeout time.Duration) *RerankHTTP {
+	return &RerankHTTP{
+		name:    name,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		api:     api,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Name returns the backend identifier.
+func (b *RerankHTTP) Name() string { return b.name }
+
+// Health checks whether the rerank server is reachable.
+func (b *RerankHTTP) Health(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+"/health", nil)
+	if err != nil {
+		return fmt.Errorf("create rerank health request: %w", err)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("rerank health check: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("rerank health check: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Rerank sends req to the rerank server.
+func (b *RerankHTTP) Rerank(ctx context.Context, req RerankRequest) (*RerankResponse, error) {
+	if b.api == "tei" {
+		return b.rerankTEI(ctx, req)
+	}
+
+	var result RerankResponse
+	if err := b.post(ctx, "/v1/rerank", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// rerankTEI translates req to Text Embeddings Inference's request shape,
+// which takes "texts" and returns a bare array of results.
+func (b *RerankHTTP) rerankTEI(ctx context.Context, req RerankRequest) (*RerankResponse, error) {
+	texts := make([]string, len(req.Documents))
+	for i, d := range req.Documents {
+		texts[i] = d.Text
+	}
+	body := struct {
+		Query      string   `json:"query"`
+		Texts      []string `json:"texts"`
+		ReturnText bool     `json:"return_text,omitempty"`
+	}{req.Query, texts, req.ReturnDocuments}
+
+	var results []struct {
+		Index int     `json:"index"`
+		Score float64 `json:"score"`
+		Text  *string `json:"text,omitempty"`
+	}
+	if err := b.post(ctx, "/rerank", body, &results); err != nil {
+		return nil, err
+	}
+
+	out := &RerankResponse{Model: req.Model, Results: make([]RerankResult, len(results))}
+	for i, r := range results {
+		out.Results[i] = RerankResult{Index: r.Index, RelevanceScore: r.Score}
+		if r.Text != nil {
+			out.Results[i].Document = &RerankDocument{Text: *r.Text}
+		}
+	}
+	return out, nil
+}
+
+func (b *RerankHTTP) post(ctx context.Context, path string, body, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal rerank request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, b.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("create rerank request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("rerank: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("rerank: status %d: %s", resp.StatusCode, string(respBody))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("decode rerank response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// STTBackend transcribes speech to text.
+type STTBackend interface {
+	Probe
+
+	// Transcribe returns the text spoken in req.Audio.
+	Transcribe(ctx context.Context, req TranscriptionRequest) (*TranscriptionResponse, error)
+}
+
+// TranscriptionRequest is an OpenAI-compatible transcription request. Audio
+// is a complete file in any format the server reads, such as WAV.
+type TranscriptionRequest struct {
+	Audio    []byte
+	Filename string
+	Model    string
+	Language string // ISO-639-1 code; empty to detect
+	Prompt   string // text the speech is likely to continue
+}
+
+// TranscriptionResponse is the text of a transcription.
+type TranscriptionResponse struct {
+	Text string `json:"text"`
+}
+
+// STTHTTP implements STTBackend for servers with an OpenAI-compatible
+// POST /v1/audio/transcriptions endpoint (faster-whisper-server, Speaches,
+// whisper.cpp's server, vLLM).
+type STTHTTP struct {
+	name    string
+	baseURL string
+	client  *http.Client
+}
+
+// NewSTTHTTP creates a speech-to-text backend adapter.
+func NewSTTHTTP(name, baseURL string, timeout time.Duration) *STTHTTP {
+	return &STTHTTP{
+		name:    name,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Name returns the backend identifier.
+func (b *STTHTTP) Name() string { return b.name }
+
+// Health checks whether the STT server is reachable.
+func (b *STTHTTP) Health(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+"/health", nil)
+	if err != nil {
+		return fmt.Errorf("create stt health request: %w", err)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("stt health check: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("stt health check: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Transcribe uploads req.Audio as multipart form data.
+func (b *STTHTTP) Transcribe(ctx context.Context, req TranscriptionRequest) (*TranscriptionResponse, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	filename := req.Filename
+	if filename == "" {
+		filename = "audio.wav"
+	}
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, fmt.Errorf("create transcription request: %w", err)
+	}
+	_, _ = fw.Write(req.Audio)
+	for _, f := range []struct{ name, value string }{
+		{"model", req.Model},
+		{"language", req.Language},
+		{"prompt", req.Prompt},
+		{"response_format", "json"},
+	} {
+		if f.value != "" {
+			_ = mw.WriteField(f.name, f.value)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("create transcription request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, b.baseURL+"/v1/audio/transcriptions", &body)
+	if err != nil {
+		return nil, fmt.Errorf("create transcription request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", mw.FormDataContentType())
+
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("stt: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("stt: status %d: %s", resp.StatusCode, string(respBody))
+	}
+	var result TranscriptionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode transcription response: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,185 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/menezmethod/inferencia/internal/audio"
+)
+
+// ErrFormatUnsupported is returned when a TTS backend cannot produce the
+// requested response format and there is no encoder to convert to it.
+var ErrFormatUnsupported = errors.New("response format not supported")
+
+// TranscodeConfig describes what a TTS server does natively.
+type TranscodeConfig struct {
+	// Formats lists the response formats the server produces; empty means
+	// all of them.
+	Formats []string
+	// IgnoresSpeed is set for servers that do not apply the speed field.
+	IgnoresSpeed bool
+	// Encoder converts to and from compressed formats. Without one only WAV
+	// and PCM are converted.
+	Encoder audio.Encoder
+}
+
+// TranscodingTTS wraps a TTSBackend so that it appears to support every
+// response format and speed: it asks the server for a format it produces
+// and converts the audio, and resamples for speed when the server ignores
+// it.
+type TranscodingTTS struct {
+	TTSBackend
+	cfg TranscodeConfig
+}
+
+// NewTranscodingTTS wraps b. The result is a StreamingTTSBackend when b is.
+func NewTranscodingTTS(b TTSBackend, cfg TranscodeConfig) TTSBackend {
+	t := &TranscodingTTS{TTSBackend: b, cfg: cfg}
+	if sb, ok := b.(StreamingTTSBackend); ok {
+		return &streamingTranscodingTTS{TranscodingTTS: t, stream: sb}
+	}
+	return t
+}
+
+// transcodePlan is how to serve one request.
+type transcodePlan struct {
+	want     string  // requested format
+	upstream string  // format asked of the server
+	speed    float64 // speed to apply by resampling; 1 for none
+}
+
+func (p transcodePlan) passthrough() bool { return p.upstream == p.want && p.speed == 1 }
+
+// plan decides the format to ask the server for and what to convert.
+func (t *TranscodingTTS) plan(req TTSRequest) (transcodePlan, error) {
+	p := transcodePlan{want: strings.ToLower(req.ResponseFormat), speed: 1}
+	if p.want == "" {
+		p.want = "wav"
+	}
+	if t.cfg.IgnoresSpeed && req.Speed > 0 && req.Speed != 1 {
+		p.speed = req.Speed
+	}
+
+	switch {
+	case t.native(p.want) && p.speed == 1:
+		p.upstream = p.want
+	case t.native("wav"):
+		p.upstream = "wav"
+	case t.native("pcm"):
+		p.upstream = "pcm"
+	case t.native(p.want) && t.cfg.Encoder != nil:
+		p.upstream = p.want
+	default:
+		p.upstream = t.cfg.Formats[0]
+	}
+
+	if !p.passthrough() && t.cfg.Encoder == nil && (!isRaw(p.upstream) || !isRaw(p.want)) {
+		return p, fmt.Errorf("%w: %s cannot produce %s and no encoder is configured", ErrFormatUnsupported, t.Name(), p.want)
+	}
+	return p, nil
+}
+
+// native reports whether the server produces format itself.
+func (t *TranscodingTTS) native(format string) bool {
+	return len(t.cfg.Formats) == 0 || slices.Contains(t.cfg.Formats, format)
+}
+
+// isRaw reports whether format is uncompressed PCM that Go converts itself.
+func isRaw(format string) bool { return format == "wav" || format == "pcm" }
+
+// Synthesize asks the server for a format it produces and converts the audio
+// to the requested format and speed.
+func (t *TranscodingTTS) Synthesize(ctx context.Context, req TTSRequest) (*TTSResponse, error) {
+	p, err := t.plan(req)
+	if err != nil {
+		return nil, err
+	}
+	if p.passthrough() {
+		return t.TTSBackend.Synthesize(ctx, req)
+	}
+
+	up := req
+	up.ResponseFormat = p.upstream
+	if p.speed != 1 {
+		up.Speed = 1
+	}
+	resp, err := t.TTSBackend.Synthesize(ctx, up)
+	if err != nil {
+		return nil, err
+	}
+	data, err := t.convert(ctx, resp.Audio, p)
+	if err != nil {
+		return nil, fmt.Errorf("transcode %s to %s: %w", p.upstream, p.want, err)
+	}
+	return &TTSResponse{Audio: data, Format: mimeTypeForFormat(p.want)}, nil
+}
+
+// convert turns audio in p.upstream into p.want at p.speed.
+func (t *TranscodingTTS) convert(ctx context.Context, data []byte, p transcodePlan) ([]byte, error) {
+	if p.speed == 1 && !isRaw(p.upstream) && !isRaw(p.want) {
+		return t.cfg.Encoder.Transcode(ctx, data, p.want)
+	}
+
+	// Decode to samples, which Go can resample and write as WAV or PCM.
+	var f audio.Format
+	var pcm []byte
+	switch p.upstream {
+	case "pcm":
+		f, pcm = audio.PCMFormat, data
+	case "wav":
+		var err error
+		if f, pcm, err = audio.ParseWAV(data); err != nil {
+			return nil, err
+		}
+	default:
+		wav, err := t.cfg.Encoder.Transcode(ctx, data, "wav")
+		if err != nil {
+			return nil, err
+		}
+		if f, pcm, err = audio.ParseWAV(wav); err != nil {
+			return nil, err
+		}
+	}
+
+	pcm, err := audio.ChangeSpeed(f, pcm, p.speed)
+	if err != nil {
+		return nil, err
+	}
+	switch p.want {
+	case "wav":
+		return audio.EncodeWAV(f, pcm), nil
+	case "pcm":
+		return audio.ToPCM(f, pcm)
+	default:
+		return t.cfg.Encoder.Transcode(ctx, audio.EncodeWAV(f, pcm), p.want)
+	}
+}
+
+// streamingTranscodingTTS is a TranscodingTTS over a streaming server.
+type streamingTranscodingTTS struct {
+	*TranscodingTTS
+	stream StreamingTTSBackend
+}
+
+// SynthesizeStream streams from the server when it produces the requested
+// format and speed itself. Audio that has to be converted is synthesized in
+// full first.
+func (t *streamingTranscodingTTS) SynthesizeStream(ctx context.Context, req TTSRequest) (*TTSStream, error) {
+	p, err := t.plan(req)
+	if err != nil {
+		return nil, err
+	}
+	if p.passthrough() {
+		return t.stream.SynthesizeStream(ctx, req)
+	}
+	resp, err := t.Synthesize(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &TTSStream{Audio: io.NopCloser(bytes.NewReader(resp.Audio)), Format: resp.Format}, nil
+}
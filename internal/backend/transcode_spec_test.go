@@ -0,0 +1,113 @@
+package backend
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/audio"
+)
+
+// wavTTS is a TTS server that returns WAV of 24 kHz mono samples in whatever
+// format it is asked for, recording the requests it receives.
+type wavTTS struct {
+	frames int
+	reqs   []TTSRequest
+}
+
+func (w *wavTTS) Name() string                            { return "wav-only" }
+func (w *wavTTS) Health(context.Context) error            { return nil }
+func (w *wavTTS) Voices(context.Context) ([]Voice, error) { return nil, nil }
+func (w *wavTTS) Synthesize(_ context.Context, req TTSRequest) (*TTSResponse, error) {
+	w.reqs = append(w.reqs, req)
+	pcm := make([]byte, 2*w.frames)
+	for i := range w.frames {
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(i))
+	}
+	return &TTSResponse{Audio: audio.EncodeWAV(audio.PCMFormat, pcm), Format: "audio/wav"}, nil
+}
+
+// streamingWAVTTS is a wavTTS that can also stream.
+type streamingWAVTTS struct{ wavTTS }
+
+func (w *streamingWAVTTS) SynthesizeStream(ctx context.Context, req TTSRequest) (*TTSStream, error) {
+	resp, err := w.Synthesize(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &TTSStream{Audio: io.NopCloser(nil), Format: resp.Format}, nil
+}
+
+// tagEncoder is an Encoder that prefixes its input with the target format.
+type tagEncoder struct{ calls []string }
+
+func (e *tagEncoder) Transcode(_ context.Context, src []byte, format string) ([]byte, error) {
+	e.calls = append(e.calls, format)
+	return append([]byte(format+":"), src...), nil
+}
+
+var _ = Describe("TranscodingTTS", func() {
+	var tts *wavTTS
+
+	BeforeEach(func() {
+		tts = &wavTTS{frames: 100}
+	})
+
+	It("passes through formats the server produces", func() {
+		b := NewTranscodingTTS(tts, TranscodeConfig{Formats: []string{"wav", "mp3"}})
+		_, err := b.Synthesize(context.Background(), TTSRequest{Input: "hi", ResponseFormat: "mp3", Speed: 1.5})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tts.reqs[0].ResponseFormat).To(Equal("mp3"))
+		Expect(tts.reqs[0].Speed).To(Equal(1.5))
+	})
+
+	It("converts WAV to PCM without an encoder", func() {
+		b := NewTranscodingTTS(tts, TranscodeConfig{Formats: []string{"wav"}})
+		resp, err := b.Synthesize(context.Background(), TTSRequest{Input: "hi", ResponseFormat: "pcm"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tts.reqs[0].ResponseFormat).To(Equal("wav"))
+		Expect(resp.Audio).To(HaveLen(200))
+		Expect(resp.Format).To(Equal(mimeTypeForFormat("pcm")))
+	})
+
+	It("applies speed by resampling when the server ignores it", func() {
+		b := NewTranscodingTTS(tts, TranscodeConfig{IgnoresSpeed: true})
+		resp, err := b.Synthesize(context.Background(), TTSRequest{Input: "hi", ResponseFormat: "wav", Speed: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tts.reqs[0].Speed).To(Equal(1.0))
+		_, pcm, err := audio.ParseWAV(resp.Audio)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pcm).To(HaveLen(100))
+	})
+
+	It("rejects compressed formats the server lacks when there is no encoder", func() {
+		b := NewTranscodingTTS(tts, TranscodeConfig{Formats: []string{"wav"}})
+		_, err := b.Synthesize(context.Background(), TTSRequest{Input: "hi", ResponseFormat: "mp3"})
+		Expect(err).To(MatchError(ErrFormatUnsupported))
+		Expect(tts.reqs).To(BeEmpty())
+	})
+
+	It("encodes compressed formats the server lacks", func() {
+		enc := &tagEncoder{}
+		b := NewTranscodingTTS(tts, TranscodeConfig{Formats: []string{"wav"}, Encoder: enc})
+		resp, err := b.Synthesize(context.Background(), TTSRequest{Input: "hi", ResponseFormat: "opus"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(enc.calls).To(Equal([]string{"opus"}))
+		Expect(string(resp.Audio[:5])).To(Equal("opus:"))
+		Expect(resp.Format).To(Equal(mimeTypeForFormat("opus")))
+	})
+
+	It("stays a streaming backend and streams formats the server produces", func() {
+		stream := &streamingWAVTTS{wavTTS{frames: 10}}
+		b := NewTranscodingTTS(stream, TranscodeConfig{Formats: []string{"wav"}})
+		sb, ok := b.(StreamingTTSBackend)
+		Expect(ok).To(BeTrue())
+
+		s, err := sb.SynthesizeStream(context.Background(), TTSRequest{Input: "hi", ResponseFormat: "pcm"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(io.ReadAll(s.Audio)).To(HaveLen(20))
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -56,6 +57,26 @@ func (t *TTSHTTP) Health(ctx context.Context) error {
 // Synthesize calls POST /v1/audio/speech on the TTS server and returns the
 // raw audio bytes.
 func (t *TTSHTTP) Synthesize(ctx context.Context, req TTSRequest) (*TTSResponse, error) {
+	resp, err := t.speech(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	audio, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read tts audio: %w", err)
+	}
+
+	return &TTSResponse{
+		Audio:  audio,
+		Format: contentType(resp, req.ResponseFormat),
+	}, nil
+}
+
+// speech sends req to POST /v1/audio/speech and returns the successful
+// response with its body unread.
+func (t *TTSHTTP) speech(ctx context.Context, req TTSRequest) (*http.Response, error) {
 	// Copy to avoid mutating the caller's request.
 	local := req
 
@@ -85,33 +106,92 @@ func (t *TTSHTTP) Synthesize(ctx context.Context, req TTSRequest) (*TTSResponse,
 	if err != nil {
 		return nil, fmt.Errorf("tts synthesize: %w", err)
 	}
-	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
 		return nil, fmt.Errorf("tts synthesize: status %d: %s", resp.StatusCode, string(respBody))
 	}
+	return resp, nil
+}
 
-	audio, err := io.ReadAll(resp.Body)
+// contentType determines the audio type from the response header or the
+// requested format.
+func contentType(resp *http.Response, format string) string {
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		return ct
+	}
+	return mimeTypeForFormat(format)
+}
+
+// errNoVoiceList is returned by audioVoices when the server has no voice
+// listing endpoint.
+var errNoVoiceList = errors.New("tts server does not list voices")
+
+// Voices lists the server's voices from GET /v1/audio/voices, as served by
+// Kokoro-FastAPI, falling back to GET /v1/models for servers without it.
+func (t *TTSHTTP) Voices(ctx context.Context) ([]Voice, error) {
+	voices, err := t.audioVoices(ctx)
+	if errors.Is(err, errNoVoiceList) {
+		return t.modelVoices(ctx)
+	}
+	return voices, err
+}
+
+// audioVoices calls GET /v1/audio/voices. Voices may be listed as plain IDs
+// or as objects.
+func (t *TTSHTTP) audioVoices(ctx context.Context) ([]Voice, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.baseURL+"/v1/audio/voices", nil)
 	if err != nil {
-		return nil, fmt.Errorf("read tts audio: %w", err)
+		return nil, fmt.Errorf("create voices request: %w", err)
 	}
 
-	// Determine content type from response header or format.
-	format := resp.Header.Get("Content-Type")
-	if format == "" {
-		format = mimeTypeForFormat(req.ResponseFormat)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("tts list voices: %w", err)
 	}
+	defer func() { _ = resp.Body.Close() }()
 
-	return &TTSResponse{
-		Audio:  audio,
-		Format: format,
-	}, nil
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, errNoVoiceList
+	default:
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("tts list voices: status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var list struct {
+		Voices []json.RawMessage `json:"voices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decode voices response: %w", err)
+	}
+
+	voices := make([]Voice, 0, len(list.Voices))
+	for _, raw := range list.Voices {
+		var v Voice
+		if err := json.Unmarshal(raw, &v.ID); err != nil {
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return nil, fmt.Errorf("decode voice: %w", err)
+			}
+		}
+		if v.ID == "" {
+			continue
+		}
+		if v.Name == "" {
+			v.Name = v.ID
+		}
+		describeVoice(&v)
+		voices = append(voices, v)
+	}
+	return voices, nil
 }
 
-// Voices calls GET /v1/models on the TTS server and extracts voice info.
+// modelVoices calls GET /v1/models on the TTS server and extracts voice info.
 // Many TTS servers serve model entries as voice identifiers.
-func (t *TTSHTTP) Voices(ctx context.Context) ([]Voice, error) {
+func (t *TTSHTTP) modelVoices(ctx context.Context) ([]Voice, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.baseURL+"/v1/models", nil)
 	if err != nil {
 		return nil, fmt.Errorf("create voices request: %w", err)
@@ -143,6 +223,57 @@ func (t *TTSHTTP) Voices(ctx context.Context) ([]Voice, error) {
 	return voices, nil
 }
 
+// TTSStreamHTTP is a TTSHTTP backend whose server streams audio with
+// chunked transfer encoding when the request sets "stream": true, as
+// Kokoro-FastAPI does.
+type TTSStreamHTTP struct {
+	*TTSHTTP
+}
+
+// NewTTSStreamHTTP creates a TTS backend adapter for a streaming server.
+func NewTTSStreamHTTP(name, baseURL string, timeout time.Duration) *TTSStreamHTTP {
+	return &TTSStreamHTTP{TTSHTTP: NewTTSHTTP(name, baseURL, timeout)}
+}
+
+// SynthesizeStream calls POST /v1/audio/speech with "stream": true and
+// returns the response body for the caller to read as audio arrives.
+func (t *TTSStreamHTTP) SynthesizeStream(ctx context.Context, req TTSRequest) (*TTSStream, error) {
+	req.Stream = true
+	resp, err := t.speech(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &TTSStream{
+		Audio:  resp.Body,
+		Format: contentType(resp, req.ResponseFormat),
+	}, nil
+}
+
+// kokoroLanguages maps the first letter of a Kokoro voice ID to its language.
+var kokoroLanguages = map[byte]string{
+	'a': "en-US", 'b': "en-GB", 'e': "es", 'f': "fr", 'h': "hi",
+	'i': "it", 'j': "ja", 'p': "pt-BR", 'z': "zh",
+}
+
+// describeVoice fills in the language and gender of a voice named in
+// Kokoro's convention, where "bf_emma" is a British English female voice.
+func describeVoice(v *Voice) {
+	id := v.ID
+	if len(id) < 3 || id[2] != '_' || (id[1] != 'f' && id[1] != 'm') {
+		return
+	}
+	lang, ok := kokoroLanguages[id[0]]
+	if !ok {
+		return
+	}
+	if v.Language == "" {
+		v.Language = lang
+	}
+	if v.Gender == "" {
+		v.Gender = map[byte]string{'f': "female", 'm': "male"}[id[1]]
+	}
+}
+
 // mimeTypeForFormat maps the response_format string to a MIME type.
 func mimeTypeForFormat(format string) string {
 	switch strings.ToLower(format) {
@@ -152,6 +283,8 @@ func mimeTypeForFormat(format string) string {
 		return "audio/opus"
 	case "flac":
 		return "audio/flac"
+	case "aac":
+		return "audio/aac"
 	case "wav":
 		return "audio/wav"
 	case "pcm":
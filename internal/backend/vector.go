@@ -0,0 +1,77 @@
+package backend
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Vector is an embedding vector. It decodes from either a JSON array of
+// numbers or a base64 string of little-endian float32 values (what backends
+// return for encoding_format "base64"), and always encodes as an array of
+// numbers.
+type Vector []float64
+
+// UnmarshalJSON accepts both embedding encodings.
+func (v *Vector) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		raw, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("decode base64 embedding: %w", err)
+		}
+		if len(raw)%4 != 0 {
+			return fmt.Errorf("decode base64 embedding: %d bytes is not a whole number of float32 values", len(raw))
+		}
+		out := make(Vector, len(raw)/4)
+		for i := range out {
+			out[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:])))
+		}
+		*v = out
+		return nil
+	}
+	var f []float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	*v = f
+	return nil
+}
+
+// Base64 packs v as little-endian float32 values and encodes them in base64,
+// the format OpenAI uses for encoding_format "base64".
+func (v Vector) Base64() string {
+	raw := make([]byte, len(v)*4)
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(raw[i*4:], math.Float32bits(float32(f)))
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+// Truncate returns the first n dimensions of v scaled back to unit length.
+// This is how Matryoshka-trained models shorten their embeddings; v is
+// returned unchanged when it has n or fewer dimensions.
+func (v Vector) Truncate(n int) Vector {
+	if n <= 0 || len(v) <= n {
+		return v
+	}
+	out := make(Vector, n)
+	copy(out, v[:n])
+	var norm float64
+	for _, f := range out {
+		norm += f * f
+	}
+	if norm == 0 {
+		return out
+	}
+	norm = math.Sqrt(norm)
+	for i := range out {
+		out[i] /= norm
+	}
+	return out
+}
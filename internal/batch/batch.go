@@ -0,0 +1,215 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Status is a batch's place in its lifecycle.
+type Status string
+
+// Batch statuses, as in the OpenAI Batch API.
+const (
+	StatusValidating Status = "validating"
+	StatusFailed     Status = "failed"
+	StatusInProgress Status = "in_progress"
+	StatusFinalizing Status = "finalizing"
+	StatusCompleted  Status = "completed"
+	StatusExpired    Status = "expired"
+	StatusCancelling Status = "cancelling"
+	StatusCancelled  Status = "cancelled"
+)
+
+// Active reports whether a batch in status s still has work to do.
+func (s Status) Active() bool {
+	return s == StatusValidating || s == StatusInProgress || s == StatusFinalizing || s == StatusCancelling
+}
+
+// CompletionWindow is the only completion window accepted.
+const CompletionWindow = "24h"
+
+// Batch is a batch job in the OpenAI Batch API shape.
+type Batch struct {
+	ID               string            `json:"id"`
+	Object           string            `json:"object"`
+	Endpoint         string            `json:"endpoint"`
+	Errors           *Errors           `json:"errors,omitempty"`
+	InputFileID      string            `json:"input_file_id"`
+	CompletionWindow string            `json:"completion_window"`
+	Status           Status            `json:"status"`
+	OutputFileID     string            `json:"output_file_id,omitempty"`
+	ErrorFileID      string            `json:"error_file_id,omitempty"`
+	CreatedAt        int64             `json:"created_at"`
+	InProgressAt     int64             `json:"in_progress_at,omitempty"`
+	ExpiresAt        int64             `json:"expires_at"`
+	FinalizingAt     int64             `json:"finalizing_at,omitempty"`
+	CompletedAt      int64             `json:"completed_at,omitempty"`
+	FailedAt         int64             `json:"failed_at,omitempty"`
+	ExpiredAt        int64             `json:"expired_at,omitempty"`
+	CancellingAt     int64             `json:"cancelling_at,omitempty"`
+	CancelledAt      int64             `json:"cancelled_at,omitempty"`
+	RequestCounts    RequestCounts     `json:"request_counts"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// RequestCounts reports a batch's progress.
+type RequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// Errors lists the problems that failed a batch's validation.
+type Errors struct {
+	Object string      `json:"object"`
+	Data   []LineError `json:"data"`
+}
+
+// LineError describes a problem with one line of an input file, or with a
+// request that could not be made.
+type LineError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// CreateRequest is the body of POST /v1/batches.
+type CreateRequest struct {
+	InputFileID      string            `json:"input_file_id"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// InvalidError rejects a create request; Param names the offending field.
+type InvalidError struct {
+	Param   string
+	Message string
+}
+
+func (e *InvalidError) Error() string { return e.Message }
+
+// ErrFinished is returned when cancelling a batch that already finished.
+var ErrFinished = errors.New("batch already finished")
+
+// requestLine is one line of an input file.
+type requestLine struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// outputLine is one line of an output or error file.
+type outputLine struct {
+	ID       string        `json:"id"`
+	CustomID string        `json:"custom_id"`
+	Response *lineResponse `json:"response"`
+	Error    *LineError    `json:"error"`
+}
+
+type lineResponse struct {
+	StatusCode int             `json:"status_code"`
+	RequestID  string          `json:"request_id"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// maxLineErrors caps the validation errors reported for one input file.
+const maxLineErrors = 100
+
+// parseLines reads an input file for endpoint. It returns the requests and,
+// if any line is unusable, the problems found; blank lines are skipped.
+// maxRequests (when positive) bounds the number of requests.
+func parseLines(r io.Reader, endpoint string, maxRequests int) ([]requestLine, []LineError, error) {
+	var (
+		lines []requestLine
+		errs  []LineError
+		seen  = make(map[string]bool)
+	)
+	fail := func(n int, code, msg string) {
+		if len(errs) < maxLineErrors {
+			errs = append(errs, LineError{Code: code, Message: msg, Line: n})
+		}
+	}
+
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		raw, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, nil, fmt.Errorf("read input file: %w", err)
+		}
+		if data := bytes.TrimSpace(raw); len(data) > 0 {
+			var line requestLine
+			switch {
+			case json.Unmarshal(data, &line) != nil:
+				fail(n, "invalid_json", "Line is not a valid JSON object.")
+			case line.CustomID == "":
+				fail(n, "missing_custom_id", "custom_id is required.")
+			case seen[line.CustomID]:
+				fail(n, "duplicate_custom_id", fmt.Sprintf("custom_id %q is used more than once.", line.CustomID))
+			case line.Method != http.MethodPost:
+				fail(n, "invalid_method", "method must be POST.")
+			case line.URL != endpoint:
+				fail(n, "mismatched_url", fmt.Sprintf("url must be the batch endpoint %s.", endpoint))
+			default:
+				var body map[string]json.RawMessage
+				if json.Unmarshal(line.Body, &body) != nil || body == nil {
+					fail(n, "invalid_body", "body must be a JSON object.")
+				} else if string(body["stream"]) == "true" {
+					fail(n, "invalid_body", "Streaming is not supported in batches.")
+				}
+			}
+			if line.CustomID != "" {
+				seen[line.CustomID] = true
+			}
+			lines = append(lines, line)
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	switch {
+	case len(lines) == 0:
+		errs = append(errs, LineError{Code: "empty_file", Message: "The input file has no requests."})
+	case maxRequests > 0 && len(lines) > maxRequests:
+		errs = append(errs, LineError{Code: "too_many_requests", Message: fmt.Sprintf("A batch may contain at most %d requests; the input file has %d.", maxRequests, len(lines))})
+	}
+	return lines, errs, nil
+}
+
+// recorder captures the response of a handler served in-process.
+type recorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func newRecorder() *recorder {
+	return &recorder{header: make(http.Header)}
+}
+
+func (r *recorder) Header() http.Header { return r.header }
+
+func (r *recorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(p)
+}
+
+func (r *recorder) status() int {
+	if r.code == 0 {
+		return http.StatusOK
+	}
+	return r.code
+}
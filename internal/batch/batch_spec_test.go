@@ -0,0 +1,257 @@
+package batch_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/batch"
+	"github.com/menezmethod/inferencia/internal/middleware"
+)
+
+const owner = "owner-a"
+
+// echoHandler answers each line with its body, or with 400 when the body
+// asks to fail.
+func echoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["fail"] == true {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"bad"}}`))
+			return
+		}
+		body["api_key"] = middleware.APIKeyFromContext(r.Context())
+		body["key_id"] = middleware.KeyIDFromContext(r.Context())
+		_ = json.NewEncoder(w).Encode(body)
+	})
+}
+
+// blockingHandler holds every line until its request is cancelled.
+func blockingHandler(started chan<- struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-r.Context().Done()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+}
+
+func inputLine(id string, body string) string {
+	return `{"custom_id":"` + id + `","method":"POST","url":"/v1/chat/completions","body":` + body + "}\n"
+}
+
+func readLines(store *batch.Store, id string) []map[string]any {
+	f, err := store.Open(owner, id)
+	Expect(err).NotTo(HaveOccurred())
+	defer func() { _ = f.Close() }()
+	var out []map[string]any
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var line map[string]any
+		Expect(json.Unmarshal(sc.Bytes(), &line)).To(Succeed())
+		out = append(out, line)
+	}
+	return out
+}
+
+var _ = Describe("Manager", func() {
+	var (
+		dir    string
+		store  *batch.Store
+		logger *slog.Logger
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		var err error
+		store, err = batch.OpenStore(dir)
+		Expect(err).NotTo(HaveOccurred())
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	})
+
+	upload := func(content string) string {
+		f, err := store.CreateFile(owner, "input.jsonl", batch.PurposeBatch, strings.NewReader(content), 0)
+		Expect(err).NotTo(HaveOccurred())
+		return f.ID
+	}
+
+	newManager := func(h http.Handler, workers int) *batch.Manager {
+		m := batch.NewManager(store, map[string]http.Handler{"/v1/chat/completions": h}, batch.Config{Workers: workers}, logger)
+		m.Start()
+		return m
+	}
+
+	create := func(m *batch.Manager, fileID string) batch.Batch {
+		b, err := m.Create(owner, batch.CreateRequest{InputFileID: fileID, Endpoint: "/v1/chat/completions", CompletionWindow: "24h"})
+		Expect(err).NotTo(HaveOccurred())
+		return b
+	}
+
+	waitFor := func(m *batch.Manager, id string, status batch.Status) batch.Batch {
+		var b batch.Batch
+		Eventually(func() batch.Status {
+			b, _ = m.Batch(owner, id)
+			return b.Status
+		}, 5*time.Second, 10*time.Millisecond).Should(Equal(status))
+		return b
+	}
+
+	It("runs every line and splits results into output and error files", func() {
+		m := newManager(echoHandler(), 2)
+		defer m.Stop()
+		fileID := upload(inputLine("a", `{"n":1}`) + inputLine("b", `{"fail":true}`) + "\n" + inputLine("c", `{"n":3}`))
+
+		b := create(m, fileID)
+		Expect(b.Status).To(Equal(batch.StatusValidating))
+
+		b = waitFor(m, b.ID, batch.StatusCompleted)
+		Expect(b.RequestCounts).To(Equal(batch.RequestCounts{Total: 3, Completed: 2, Failed: 1}))
+		Expect(b.CompletedAt).NotTo(BeZero())
+
+		out := readLines(store, b.OutputFileID)
+		Expect(out).To(HaveLen(2))
+		Expect(out[0]["custom_id"]).To(Equal("a"))
+		Expect(out[1]["custom_id"]).To(Equal("c"))
+		resp := out[0]["response"].(map[string]any)
+		Expect(resp["status_code"]).To(BeEquivalentTo(200))
+		Expect(resp["body"]).To(HaveKeyWithValue("key_id", owner))
+		Expect(resp["body"]).To(HaveKeyWithValue("api_key", ""))
+
+		errs := readLines(store, b.ErrorFileID)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0]["custom_id"]).To(Equal("b"))
+		Expect(errs[0]["response"]).To(HaveKeyWithValue("status_code", BeEquivalentTo(400)))
+	})
+
+	It("fails a batch whose input file has invalid lines", func() {
+		m := newManager(echoHandler(), 1)
+		defer m.Stop()
+		fileID := upload(inputLine("a", `{}`) + "not json\n" + inputLine("a", `{}`) +
+			`{"custom_id":"d","method":"POST","url":"/v1/embeddings","body":{}}` + "\n" +
+			inputLine("e", `{"stream":true}`))
+
+		b := waitFor(m, create(m, fileID).ID, batch.StatusFailed)
+		Expect(b.Errors).NotTo(BeNil())
+		var codes []string
+		for _, e := range b.Errors.Data {
+			codes = append(codes, e.Code)
+		}
+		Expect(codes).To(Equal([]string{"invalid_json", "duplicate_custom_id", "mismatched_url", "invalid_body"}))
+		Expect(b.Errors.Data[0].Line).To(Equal(2))
+	})
+
+	It("rejects unknown endpoints and files of other keys", func() {
+		m := newManager(echoHandler(), 1)
+		defer m.Stop()
+		fileID := upload(inputLine("a", `{}`))
+
+		_, err := m.Create(owner, batch.CreateRequest{InputFileID: fileID, Endpoint: "/v1/audio/speech", CompletionWindow: "24h"})
+		var invalid *batch.InvalidError
+		Expect(err).To(BeAssignableToTypeOf(invalid))
+		Expect(err.(*batch.InvalidError).Param).To(Equal("endpoint"))
+
+		_, err = m.Create("owner-b", batch.CreateRequest{InputFileID: fileID, Endpoint: "/v1/chat/completions", CompletionWindow: "24h"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*batch.InvalidError).Param).To(Equal("input_file_id"))
+	})
+
+	It("keeps the other workers busy while one line is slow", func() {
+		release := make(chan struct{})
+		var served atomic.Int32
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["slow"] == true {
+				<-release
+			}
+			served.Add(1)
+			_, _ = w.Write([]byte(`{}`))
+		})
+		m := newManager(h, 2)
+		defer m.Stop()
+		b := create(m, upload(inputLine("a", `{"slow":true}`)+inputLine("b", `{}`)+inputLine("c", `{}`)+inputLine("d", `{}`)))
+
+		Eventually(served.Load, 5*time.Second).Should(BeEquivalentTo(3))
+		b, _ = m.Batch(owner, b.ID)
+		Expect(b.RequestCounts.Completed).To(BeZero())
+
+		close(release)
+		b = waitFor(m, b.ID, batch.StatusCompleted)
+		Expect(b.RequestCounts.Completed).To(Equal(4))
+		out := readLines(store, b.OutputFileID)
+		Expect(out).To(HaveLen(4))
+		Expect(out[0]["custom_id"]).To(Equal("a"))
+		Expect(out[3]["custom_id"]).To(Equal("d"))
+	})
+
+	It("cancels a running batch", func() {
+		started := make(chan struct{}, 1)
+		m := newManager(blockingHandler(started), 1)
+		defer m.Stop()
+		b := create(m, upload(inputLine("a", `{}`)+inputLine("b", `{}`)))
+		Eventually(started, 5*time.Second).Should(Receive())
+
+		cancelled, err := m.Cancel(owner, b.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cancelled.Status).To(Equal(batch.StatusCancelling))
+
+		b = waitFor(m, b.ID, batch.StatusCancelled)
+		Expect(b.CancelledAt).NotTo(BeZero())
+
+		_, err = m.Cancel(owner, b.ID)
+		Expect(err).To(MatchError(batch.ErrFinished))
+	})
+
+	It("resumes unfinished batches after a restart", func() {
+		started := make(chan struct{}, 1)
+		m := newManager(blockingHandler(started), 1)
+		b := create(m, upload(inputLine("a", `{"n":1}`)+inputLine("b", `{"n":2}`)))
+		Eventually(started, 5*time.Second).Should(Receive())
+		m.Stop()
+
+		var err error
+		store, err = batch.OpenStore(dir)
+		Expect(err).NotTo(HaveOccurred())
+		interrupted, err := store.Batch(owner, b.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(interrupted.Status).To(Equal(batch.StatusInProgress))
+
+		m = newManager(echoHandler(), 1)
+		defer m.Stop()
+		b = waitFor(m, b.ID, batch.StatusCompleted)
+		Expect(b.RequestCounts.Completed).To(Equal(2))
+		Expect(readLines(store, b.OutputFileID)).To(HaveLen(2))
+	})
+
+	It("hides batches from other keys", func() {
+		m := newManager(echoHandler(), 1)
+		defer m.Stop()
+		b := create(m, upload(inputLine("a", `{}`)))
+
+		_, err := m.Batch("owner-b", b.ID)
+		Expect(err).To(MatchError(batch.ErrNotFound))
+		Expect(m.List("owner-b")).To(BeEmpty())
+		Expect(m.List(owner)).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("Store", func() {
+	It("rejects files over the size limit", func() {
+		store, err := batch.OpenStore(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = store.CreateFile(owner, "big.jsonl", batch.PurposeBatch, strings.NewReader(strings.Repeat("x", 11)), 10)
+		Expect(err).To(MatchError(batch.ErrFileTooLarge))
+	})
+})
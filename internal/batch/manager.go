@@ -0,0 +1,497 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/menezmethod/inferencia/internal/middleware"
+	"github.com/menezmethod/inferencia/internal/priority"
+)
+
+// Config controls batch processing.
+type Config struct {
+	Workers     int // lines in flight across all batches
+	MaxRequests int // lines per batch; 0 = unlimited
+}
+
+// Manager runs batches. Each line is served in-process by the handler for the
+// batch's endpoint with priority.Batch, so it goes through the same routing,
+// wait queue and concurrency limits as other traffic and yields to it.
+//
+// Lines are handed to the workers as they free up, so one slow line does not
+// hold up the others. Results are saved in input order every Workers lines,
+// once all the lines before them have finished, and the last save is where an
+// interrupted batch resumes.
+type Manager struct {
+	store    *Store
+	handlers map[string]http.Handler
+	cfg      Config
+	logger   *slog.Logger
+	now      func() time.Time
+
+	ctx   context.Context
+	stop  context.CancelFunc
+	tasks chan task
+	wg    sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+}
+
+// task is one line waiting for a worker.
+type task struct {
+	ctx      context.Context
+	owner    string
+	endpoint string
+	line     requestLine
+	done     chan outputLine
+}
+
+// NewManager creates a Manager serving the endpoints in handlers, keyed by
+// path (e.g. "/v1/chat/completions"). Call Start to begin processing.
+func NewManager(store *Store, handlers map[string]http.Handler, cfg Config, logger *slog.Logger) *Manager {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	return &Manager{
+		store:    store,
+		handlers: handlers,
+		cfg:      cfg,
+		logger:   logger,
+		now:      time.Now,
+		ctx:      ctx,
+		stop:     stop,
+		tasks:    make(chan task),
+		running:  make(map[string]context.CancelFunc),
+	}
+}
+
+// Start launches the workers and resumes the batches left unfinished by a
+// previous process.
+func (m *Manager) Start() {
+	for range m.cfg.Workers {
+		m.wg.Add(1)
+		go m.work()
+	}
+	for _, rec := range m.store.records() {
+		if rec.Status.Active() {
+			m.logger.Info("resuming batch", "batch", rec.ID, "status", rec.Status, "offset", rec.Offset)
+			m.launch(rec.ID)
+		}
+	}
+}
+
+// Stop interrupts running batches, leaving them to be resumed by the next
+// Start, and waits for the workers to exit.
+func (m *Manager) Stop() {
+	m.stop()
+	m.wg.Wait()
+}
+
+// Create validates req and queues a new batch owned by owner.
+func (m *Manager) Create(owner string, req CreateRequest) (Batch, error) {
+	if _, ok := m.handlers[req.Endpoint]; !ok {
+		return Batch{}, &InvalidError{Param: "endpoint", Message: "endpoint must be one of " + strings.Join(m.Endpoints(), ", ")}
+	}
+	if req.CompletionWindow != CompletionWindow {
+		return Batch{}, &InvalidError{Param: "completion_window", Message: "completion_window must be 24h"}
+	}
+	f, err := m.store.File(owner, req.InputFileID)
+	if err != nil {
+		return Batch{}, &InvalidError{Param: "input_file_id", Message: "No such file: " + req.InputFileID}
+	}
+	if f.Purpose != PurposeBatch {
+		return Batch{}, &InvalidError{Param: "input_file_id", Message: "input file must have purpose batch"}
+	}
+
+	now := m.now()
+	rec := batchRecord{
+		Batch: Batch{
+			ID:               newID("batch_"),
+			Object:           "batch",
+			Endpoint:         req.Endpoint,
+			InputFileID:      req.InputFileID,
+			CompletionWindow: req.CompletionWindow,
+			Status:           StatusValidating,
+			CreatedAt:        now.Unix(),
+			ExpiresAt:        now.Add(24 * time.Hour).Unix(),
+			Metadata:         req.Metadata,
+		},
+		Owner: owner,
+	}
+	if err := m.store.insert(rec); err != nil {
+		return Batch{}, err
+	}
+	m.launch(rec.ID)
+	return rec.Batch, nil
+}
+
+// Cancel stops batch id. Lines already processed keep their results.
+func (m *Manager) Cancel(owner, id string) (Batch, error) {
+	if _, err := m.store.Batch(owner, id); err != nil {
+		return Batch{}, err
+	}
+	var finished bool
+	rec, err := m.store.update(id, func(r *batchRecord) bool {
+		switch r.Status {
+		case StatusValidating, StatusInProgress, StatusFinalizing:
+			r.Status = StatusCancelling
+			r.CancellingAt = m.now().Unix()
+			return true
+		case StatusCancelling:
+			return false
+		default:
+			finished = true
+			return false
+		}
+	})
+	if err != nil {
+		return Batch{}, err
+	}
+	if finished {
+		return rec.Batch, ErrFinished
+	}
+
+	m.mu.Lock()
+	cancel, running := m.running[id]
+	m.mu.Unlock()
+	if running {
+		cancel()
+		return rec.Batch, nil
+	}
+	return m.finish(id, StatusCancelled)
+}
+
+// Batch returns batch id if it belongs to owner.
+func (m *Manager) Batch(owner, id string) (Batch, error) {
+	return m.store.Batch(owner, id)
+}
+
+// List returns owner's batches, newest first.
+func (m *Manager) List(owner string) []Batch {
+	return m.store.Batches(owner)
+}
+
+// Endpoints lists the paths batches may target.
+func (m *Manager) Endpoints() []string {
+	out := make([]string, 0, len(m.handlers))
+	for path := range m.handlers {
+		out = append(out, path)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// launch runs batch id in the background.
+func (m *Manager) launch(id string) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.mu.Lock()
+	m.running[id] = cancel
+	middleware.BatchesRunning.Set(float64(len(m.running)))
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer func() {
+			cancel()
+			m.mu.Lock()
+			delete(m.running, id)
+			middleware.BatchesRunning.Set(float64(len(m.running)))
+			m.mu.Unlock()
+		}()
+		if err := m.run(ctx, id); err != nil {
+			m.logger.Error("batch failed", "batch", id, "err", err)
+			_, _ = m.fail(id, LineError{Code: "internal_error", Message: err.Error()})
+		}
+	}()
+}
+
+// run validates batch id if needed and processes its remaining lines.
+func (m *Manager) run(ctx context.Context, id string) error {
+	rec, ok := m.store.record(id)
+	if !ok {
+		return ErrNotFound
+	}
+	if rec.Status == StatusCancelling {
+		_, err := m.finish(id, StatusCancelled)
+		return err
+	}
+
+	in, err := m.store.Open(rec.Owner, rec.InputFileID)
+	if err != nil {
+		return fmt.Errorf("open input file: %w", err)
+	}
+	lines, lineErrs, err := parseLines(in, rec.Endpoint, m.cfg.MaxRequests)
+	_ = in.Close()
+	if err != nil {
+		return err
+	}
+
+	if rec.Status == StatusValidating {
+		if len(lineErrs) > 0 {
+			_, err := m.fail(id, lineErrs...)
+			return err
+		}
+		out, err := m.store.CreateFile(rec.Owner, id+"_output.jsonl", PurposeBatchOutput, strings.NewReader(""), 0)
+		if err != nil {
+			return err
+		}
+		if rec, err = m.store.update(id, func(r *batchRecord) bool {
+			if r.Status != StatusValidating {
+				return false
+			}
+			r.Status = StatusInProgress
+			r.InProgressAt = m.now().Unix()
+			r.OutputFileID = out.ID
+			r.RequestCounts.Total = len(lines)
+			return true
+		}); err != nil {
+			return err
+		}
+		if rec.Status != StatusInProgress {
+			_, err := m.finish(id, StatusCancelled)
+			return err
+		}
+		m.logger.Info("batch started", "batch", id, "endpoint", rec.Endpoint, "requests", len(lines))
+	} else if err := m.rewind(rec); err != nil {
+		return err
+	}
+
+	pending := make(chan chan outputLine, len(lines)-rec.Offset)
+	go m.feed(ctx, rec, lines[rec.Offset:], pending)
+	results := make([]outputLine, 0, m.cfg.Workers)
+	for done := range pending {
+		res := <-done
+		if ctx.Err() != nil {
+			return m.interrupted(id)
+		}
+		results = append(results, res)
+		if len(results) == m.cfg.Workers {
+			if rec, err = m.save(rec, results); err != nil {
+				return err
+			}
+			results = results[:0]
+		}
+	}
+	if ctx.Err() != nil {
+		return m.interrupted(id)
+	}
+	if len(results) > 0 {
+		if rec, err = m.save(rec, results); err != nil {
+			return err
+		}
+	}
+	if rec.Offset < len(lines) {
+		m.logger.Warn("batch expired", "batch", id, "completed", rec.Offset, "total", len(lines))
+		_, err := m.finish(id, StatusExpired)
+		return err
+	}
+
+	if _, err := m.store.update(id, func(r *batchRecord) bool {
+		if r.Status != StatusInProgress {
+			return false
+		}
+		r.Status = StatusFinalizing
+		r.FinalizingAt = m.now().Unix()
+		return true
+	}); err != nil {
+		return err
+	}
+	b, err := m.finish(id, StatusCompleted)
+	if err == nil {
+		m.logger.Info("batch finished", "batch", id, "status", b.Status,
+			"completed", b.RequestCounts.Completed, "failed", b.RequestCounts.Failed)
+	}
+	return err
+}
+
+// feed hands lines to the workers as they free up, passing on each line's
+// result channel in input order, until ctx ends or the batch expires.
+func (m *Manager) feed(ctx context.Context, rec batchRecord, lines []requestLine, pending chan<- chan outputLine) {
+	defer close(pending)
+	for _, line := range lines {
+		if m.now().Unix() >= rec.ExpiresAt {
+			return
+		}
+		done := make(chan outputLine, 1)
+		select {
+		case m.tasks <- task{ctx: ctx, owner: rec.Owner, endpoint: rec.Endpoint, line: line, done: done}:
+		case <-ctx.Done():
+			return
+		}
+		pending <- done
+	}
+}
+
+// save appends the results of the lines after Offset to the output and error files and records
+// the batch's progress.
+func (m *Manager) save(rec batchRecord, results []outputLine) (batchRecord, error) {
+	var ok, failed bytes.Buffer
+	var completed, errored int
+	for _, res := range results {
+		data, err := json.Marshal(res)
+		if err != nil {
+			return rec, fmt.Errorf("encode batch result: %w", err)
+		}
+		if res.Error == nil && res.Response.StatusCode < 300 {
+			ok.Write(data)
+			ok.WriteByte('\n')
+			completed++
+		} else {
+			failed.Write(data)
+			failed.WriteByte('\n')
+			errored++
+		}
+	}
+
+	if ok.Len() > 0 {
+		if err := m.store.appendFile(rec.OutputFileID, ok.Bytes()); err != nil {
+			return rec, err
+		}
+	}
+	errorFileID := rec.ErrorFileID
+	if failed.Len() > 0 {
+		if errorFileID == "" {
+			f, err := m.store.CreateFile(rec.Owner, rec.ID+"_error.jsonl", PurposeBatchOutput, strings.NewReader(""), 0)
+			if err != nil {
+				return rec, err
+			}
+			errorFileID = f.ID
+		}
+		if err := m.store.appendFile(errorFileID, failed.Bytes()); err != nil {
+			return rec, err
+		}
+	}
+
+	return m.store.update(rec.ID, func(r *batchRecord) bool {
+		r.Offset += len(results)
+		r.OutputSize += int64(ok.Len())
+		r.ErrorSize += int64(failed.Len())
+		r.ErrorFileID = errorFileID
+		r.RequestCounts.Completed += completed
+		r.RequestCounts.Failed += errored
+		return true
+	})
+}
+
+// rewind drops results written after the last save of a batch that was
+// interrupted during one, so that resuming does not duplicate them.
+func (m *Manager) rewind(rec batchRecord) error {
+	if rec.OutputFileID != "" {
+		if err := m.store.truncateFile(rec.OutputFileID, rec.OutputSize); err != nil {
+			return err
+		}
+	}
+	if rec.ErrorFileID != "" {
+		if err := m.store.truncateFile(rec.ErrorFileID, rec.ErrorSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interrupted ends a run whose context was cancelled: by Cancel, which
+// finishes the batch, or by Stop, which leaves it to be resumed.
+func (m *Manager) interrupted(id string) error {
+	rec, ok := m.store.record(id)
+	if ok && rec.Status == StatusCancelling {
+		_, err := m.finish(id, StatusCancelled)
+		return err
+	}
+	return nil
+}
+
+// finish moves batch id to a final status. A batch being cancelled always
+// ends up cancelled.
+func (m *Manager) finish(id string, status Status) (Batch, error) {
+	rec, err := m.store.update(id, func(r *batchRecord) bool {
+		if !r.Status.Active() {
+			return false
+		}
+		now := m.now().Unix()
+		if r.Status == StatusCancelling {
+			status = StatusCancelled
+		}
+		r.Status = status
+		switch status {
+		case StatusCompleted:
+			r.CompletedAt = now
+		case StatusExpired:
+			r.ExpiredAt = now
+		case StatusCancelled:
+			r.CancelledAt = now
+		}
+		return true
+	})
+	return rec.Batch, err
+}
+
+// fail marks batch id failed with errs.
+func (m *Manager) fail(id string, errs ...LineError) (Batch, error) {
+	rec, err := m.store.update(id, func(r *batchRecord) bool {
+		if !r.Status.Active() {
+			return false
+		}
+		r.Status = StatusFailed
+		r.FailedAt = m.now().Unix()
+		r.Errors = &Errors{Object: "list", Data: errs}
+		return true
+	})
+	return rec.Batch, err
+}
+
+// work serves lines until Stop.
+func (m *Manager) work() {
+	defer m.wg.Done()
+	for {
+		select {
+		case t := <-m.tasks:
+			t.done <- m.dispatch(t)
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+// dispatch serves one line with the endpoint's handler on behalf of the
+// batch's owner. The owner ID is the key's auth.KeyID, so per-key caches and
+// policies apply to batch lines as to the key's own requests.
+func (m *Manager) dispatch(t task) outputLine {
+	out := outputLine{ID: newID("batch_req_"), CustomID: t.line.CustomID}
+
+	ctx := priority.WithClass(t.ctx, priority.Batch)
+	ctx = middleware.WithKeyID(ctx, t.owner)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(t.line.Body))
+	if err != nil {
+		out.Error = &LineError{Code: "internal_error", Message: err.Error()}
+		middleware.BatchRequestsTotal.WithLabelValues(t.endpoint, "error").Inc()
+		return out
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := newRecorder()
+	m.handlers[t.endpoint].ServeHTTP(rec, req)
+
+	body := bytes.TrimSpace(rec.body.Bytes())
+	if !json.Valid(body) {
+		body, _ = json.Marshal(rec.body.String())
+	}
+	out.Response = &lineResponse{StatusCode: rec.status(), RequestID: newID("req_"), Body: body}
+
+	result := "success"
+	if rec.status() >= 300 {
+		result = "error"
+	}
+	middleware.BatchRequestsTotal.WithLabelValues(t.endpoint, result).Inc()
+	return out
+}
@@ -0,0 +1,366 @@
+// Package batch implements the OpenAI Batch API on top of a local job store:
+// uploaded JSONL files, batches over them, and a worker pool that sends each
+// line through the gateway's own handlers at batch priority.
+//
+// Files and batches live under one directory so they survive restarts;
+// batches that were running when the process stopped are resumed from the
+// last saved line.
+package batch
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/menezmethod/inferencia/internal/auth"
+)
+
+// ErrNotFound is returned for files and batches that do not exist or belong
+// to another API key.
+var ErrNotFound = errors.New("not found")
+
+// ErrFileTooLarge is returned when an upload exceeds the configured limit.
+var ErrFileTooLarge = errors.New("file too large")
+
+// File purposes.
+const (
+	PurposeBatch       = "batch"
+	PurposeBatchOutput = "batch_output"
+)
+
+// File is an uploaded or generated file in the OpenAI Files API shape.
+type File struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+}
+
+// fileRecord is a File as persisted, with the key that owns it.
+type fileRecord struct {
+	File
+	Owner string `json:"owner"`
+}
+
+// batchRecord is a Batch as persisted. Offset counts the input lines whose
+// results are in the output and error files, which were OutputSize and
+// ErrorSize bytes long at that point.
+type batchRecord struct {
+	Batch
+	Owner      string `json:"owner"`
+	Offset     int    `json:"offset"`
+	OutputSize int64  `json:"output_size"`
+	ErrorSize  int64  `json:"error_size"`
+}
+
+// Owner derives the owner ID stored with files and batches from an API key,
+// so keys are never written to disk.
+func Owner(apiKey string) string {
+	return auth.KeyID(apiKey)
+}
+
+// Store persists files and batches under a directory. It is safe for
+// concurrent use.
+type Store struct {
+	dir string
+
+	mu      sync.Mutex
+	files   map[string]*fileRecord
+	batches map[string]*batchRecord
+}
+
+// OpenStore opens the store in dir, creating it if needed, and loads the
+// files and batches left there by a previous process.
+func OpenStore(dir string) (*Store, error) {
+	s := &Store{
+		dir:     dir,
+		files:   make(map[string]*fileRecord),
+		batches: make(map[string]*batchRecord),
+	}
+	for _, sub := range []string{"files", "batches"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("create batch store: %w", err)
+		}
+	}
+	if err := loadRecords(filepath.Join(dir, "files"), func(data []byte) error {
+		var rec fileRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return err
+		}
+		s.files[rec.ID] = &rec
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("load files: %w", err)
+	}
+	if err := loadRecords(filepath.Join(dir, "batches"), func(data []byte) error {
+		var rec batchRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return err
+		}
+		s.batches[rec.ID] = &rec
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("load batches: %w", err)
+	}
+	return s, nil
+}
+
+// CreateFile stores the content read from r as a new file owned by owner.
+// Reading more than maxBytes (when positive) fails with ErrFileTooLarge.
+func (s *Store) CreateFile(owner, filename, purpose string, r io.Reader, maxBytes int64) (File, error) {
+	rec := &fileRecord{
+		File: File{
+			ID:        newID("file-"),
+			Object:    "file",
+			CreatedAt: time.Now().Unix(),
+			Filename:  filename,
+			Purpose:   purpose,
+		},
+		Owner: owner,
+	}
+
+	f, err := os.Create(s.contentPath(rec.ID))
+	if err != nil {
+		return File{}, fmt.Errorf("create file: %w", err)
+	}
+	if maxBytes > 0 {
+		r = io.LimitReader(r, maxBytes+1)
+	}
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && maxBytes > 0 && n > maxBytes {
+		err = ErrFileTooLarge
+	}
+	if err != nil {
+		_ = os.Remove(s.contentPath(rec.ID))
+		if errors.Is(err, ErrFileTooLarge) {
+			return File{}, err
+		}
+		return File{}, fmt.Errorf("write file: %w", err)
+	}
+	rec.Bytes = n
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writeRecord("files", rec.ID, rec); err != nil {
+		_ = os.Remove(s.contentPath(rec.ID))
+		return File{}, err
+	}
+	s.files[rec.ID] = rec
+	return rec.File, nil
+}
+
+// File returns the file id if it belongs to owner.
+func (s *Store) File(owner, id string) (File, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.files[id]
+	if !ok || rec.Owner != owner {
+		return File{}, ErrNotFound
+	}
+	return rec.File, nil
+}
+
+// Open opens the content of file id if it belongs to owner.
+func (s *Store) Open(owner, id string) (*os.File, error) {
+	if _, err := s.File(owner, id); err != nil {
+		return nil, err
+	}
+	return os.Open(s.contentPath(id))
+}
+
+// appendFile adds data to the end of file id and updates its size.
+func (s *Store) appendFile(id string, data []byte) error {
+	f, err := os.OpenFile(s.contentPath(id), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("append to file: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.files[id]
+	if !ok {
+		return ErrNotFound
+	}
+	rec.Bytes += int64(len(data))
+	return s.writeRecord("files", id, rec)
+}
+
+// truncateFile cuts file id back to size bytes, dropping results written
+// after the last saved offset of a batch that was interrupted.
+func (s *Store) truncateFile(id string, size int64) error {
+	if err := os.Truncate(s.contentPath(id), size); err != nil {
+		return fmt.Errorf("truncate file: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.files[id]
+	if !ok {
+		return ErrNotFound
+	}
+	rec.Bytes = size
+	return s.writeRecord("files", id, rec)
+}
+
+// Batch returns batch id if it belongs to owner.
+func (s *Store) Batch(owner, id string) (Batch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.batches[id]
+	if !ok || rec.Owner != owner {
+		return Batch{}, ErrNotFound
+	}
+	return rec.Batch, nil
+}
+
+// Batches returns owner's batches, newest first.
+func (s *Store) Batches(owner string) []Batch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Batch
+	for _, rec := range s.batches {
+		if rec.Owner == owner {
+			out = append(out, rec.Batch)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt > out[j].CreatedAt
+		}
+		return out[i].ID > out[j].ID
+	})
+	return out
+}
+
+// record returns a copy of batch id's record.
+func (s *Store) record(id string) (batchRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.batches[id]
+	if !ok {
+		return batchRecord{}, false
+	}
+	return *rec, true
+}
+
+// records returns copies of every batch record.
+func (s *Store) records() []batchRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]batchRecord, 0, len(s.batches))
+	for _, rec := range s.batches {
+		out = append(out, *rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt < out[j].CreatedAt })
+	return out
+}
+
+// update applies fn to batch id's record under the store lock and persists
+// the result. fn returning false leaves the record unchanged.
+func (s *Store) update(id string, fn func(*batchRecord) bool) (batchRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.batches[id]
+	if !ok {
+		return batchRecord{}, ErrNotFound
+	}
+	next := *rec
+	if !fn(&next) {
+		return *rec, nil
+	}
+	if err := s.writeRecord("batches", id, &next); err != nil {
+		return *rec, err
+	}
+	*rec = next
+	return next, nil
+}
+
+// insert persists a new batch record.
+func (s *Store) insert(rec batchRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writeRecord("batches", rec.ID, &rec); err != nil {
+		return err
+	}
+	s.batches[rec.ID] = &rec
+	return nil
+}
+
+func (s *Store) contentPath(id string) string {
+	return filepath.Join(s.dir, "files", id+".jsonl")
+}
+
+// writeRecord atomically replaces the JSON record for id under sub. The
+// caller must hold s.mu.
+func (s *Store) writeRecord(sub, id string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode %s record: %w", sub, err)
+	}
+	dir := filepath.Join(s.dir, sub)
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("write %s record: %w", sub, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("write %s record: %w", sub, err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("write %s record: %w", sub, err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, id+".json")); err != nil {
+		return fmt.Errorf("write %s record: %w", sub, err)
+	}
+	return nil
+}
+
+// loadRecords calls load with the contents of every record in dir.
+func loadRecords(dir string, load func([]byte) error) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		if err := load(data); err != nil {
+			return fmt.Errorf("%s: %w", e.Name(), err)
+		}
+	}
+	return nil
+}
+
+// newID returns prefix followed by 24 random hex digits.
+func newID(prefix string) string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return prefix + hex.EncodeToString(b[:])
+}
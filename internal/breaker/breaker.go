@@ -0,0 +1,300 @@
+// Package breaker implements per-backend circuit breakers fed by the outcomes of
+// real requests. Where the watchdog learns about a dead backend only after
+// several failed probes, a breaker opens as soon as live traffic starts failing,
+// lets a few trial requests through after a cooldown, and closes again once
+// they succeed.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/middleware"
+)
+
+// State is the position of a circuit breaker.
+type State int
+
+const (
+	// Closed lets all traffic through.
+	Closed State = iota
+	// HalfOpen lets a limited number of trial requests through.
+	HalfOpen
+	// Open rejects all traffic until the cooldown elapses.
+	Open
+)
+
+// String returns the lower-case name of the state.
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half_open"
+	case Open:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// Config controls when breakers trip and how they recover.
+type Config struct {
+	ConsecutiveFailures int           // open after this many failures in a row (default 5)
+	ErrorRate           float64       // open when the failure ratio within Window reaches this; 0 disables
+	Window              time.Duration // sliding window for ErrorRate (default 60s)
+	MinRequests         int           // outcomes required in Window before ErrorRate applies (default 10)
+	Cooldown            time.Duration // how long to stay open before trials (default 15s)
+	HalfOpenRequests    int           // concurrent trials while half-open; this many successes close (default 1)
+}
+
+// DefaultConfig returns production-grade defaults.
+func DefaultConfig() Config {
+	return Config{
+		ConsecutiveFailures: 5,
+		ErrorRate:           0.5,
+		Window:              60 * time.Second,
+		MinRequests:         10,
+		Cooldown:            15 * time.Second,
+		HalfOpenRequests:    1,
+	}
+}
+
+// outcome is one completed request inside the error-rate window.
+type outcome struct {
+	at     time.Time
+	failed bool
+}
+
+// circuit is the state of one backend's breaker.
+type circuit struct {
+	state       State
+	consecutive int
+	window      []outcome
+	openedAt    time.Time
+	trials      int // trial requests in flight while half-open
+	successes   int // successful trials since entering half-open
+}
+
+// Set holds one circuit breaker per backend name. It implements
+// backend.PassiveHealth so it can be combined with the watchdog.
+type Set struct {
+	cfg    Config
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// New creates a Set. Zero fields in cfg fall back to DefaultConfig values.
+func New(cfg Config, logger *slog.Logger) *Set {
+	def := DefaultConfig()
+	if cfg.ConsecutiveFailures <= 0 {
+		cfg.ConsecutiveFailures = def.ConsecutiveFailures
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = def.Window
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = def.MinRequests
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = def.Cooldown
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = def.HalfOpenRequests
+	}
+	return &Set{
+		cfg:      cfg,
+		logger:   logger,
+		now:      time.Now,
+		circuits: make(map[string]*circuit),
+	}
+}
+
+// IsHealthy reports whether name may receive a new request: always when closed,
+// never while open within the cooldown, and only while trial slots are free
+// when half-open. It does not change the breaker, so it may be asked about
+// backends that are then not chosen.
+func (s *Set) IsHealthy(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.circuits[name]
+	if !ok {
+		return true
+	}
+	switch c.state {
+	case Open:
+		return s.now().Sub(c.openedAt) >= s.cfg.Cooldown
+	case HalfOpen:
+		return c.trials < s.cfg.HalfOpenRequests
+	default:
+		return true
+	}
+}
+
+// OnDispatch reserves a request to name and reports whether the breaker lets
+// it through. A closed breaker always does. A half-open one takes a trial
+// slot, refusing once all are taken, so concurrent selections cannot exceed
+// HalfOpenRequests. The first request dispatched to an open breaker whose
+// cooldown has elapsed moves it to half-open and is its first trial.
+func (s *Set) OnDispatch(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.circuits[name]
+	if !ok {
+		return true
+	}
+	if c.state == Open {
+		if s.now().Sub(c.openedAt) < s.cfg.Cooldown {
+			return false
+		}
+		s.transitionLocked(name, c, HalfOpen)
+	}
+	if c.state == HalfOpen {
+		if c.trials >= s.cfg.HalfOpenRequests {
+			return false
+		}
+		c.trials++
+	}
+	return true
+}
+
+// RecordOutcome feeds the result of a request into name's breaker. Errors that
+// do not reflect on the backend (client cancellations, 4xx responses) release a
+// trial slot but otherwise leave the breaker alone.
+func (s *Set) RecordOutcome(name string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.circuitLocked(name)
+	failed := IsFailure(err)
+	neutral := err != nil && !failed
+
+	switch c.state {
+	case HalfOpen:
+		if c.trials > 0 {
+			c.trials--
+		}
+		if neutral {
+			return
+		}
+		if failed {
+			s.transitionLocked(name, c, Open)
+			return
+		}
+		c.successes++
+		if c.successes >= s.cfg.HalfOpenRequests {
+			s.transitionLocked(name, c, Closed)
+		}
+	case Closed:
+		if neutral {
+			return
+		}
+		now := s.now()
+		c.window = append(pruneBefore(c.window, now.Add(-s.cfg.Window)), outcome{at: now, failed: failed})
+		if !failed {
+			c.consecutive = 0
+			return
+		}
+		c.consecutive++
+		if c.consecutive >= s.cfg.ConsecutiveFailures || s.errorRateExceededLocked(c) {
+			s.transitionLocked(name, c, Open)
+		}
+	case Open:
+		// Late results from requests dispatched before the breaker opened.
+	}
+}
+
+// State returns the current state of name's breaker.
+func (s *Set) State(name string) State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.circuits[name]; ok {
+		return c.state
+	}
+	return Closed
+}
+
+// IsFailure reports whether err indicates that the backend misbehaved. Client
+// cancellations, requests for formats the backend cannot produce, requests
+// answered without the backend, and upstream 4xx responses (other than 408
+// and 429) are the caller's problem, not the backend's.
+func IsFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, backend.ErrFormatUnsupported) || errors.Is(err, backend.ErrNotSent) {
+		return false
+	}
+	if status, ok := apierror.UpstreamStatus(err); ok && status < 500 {
+		return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+	}
+	return true
+}
+
+func (s *Set) circuitLocked(name string) *circuit {
+	c, ok := s.circuits[name]
+	if !ok {
+		c = &circuit{}
+		s.circuits[name] = c
+	}
+	return c
+}
+
+func (s *Set) errorRateExceededLocked(c *circuit) bool {
+	if s.cfg.ErrorRate <= 0 || len(c.window) < s.cfg.MinRequests {
+		return false
+	}
+	failures := 0
+	for _, o := range c.window {
+		if o.failed {
+			failures++
+		}
+	}
+	return float64(failures)/float64(len(c.window)) >= s.cfg.ErrorRate
+}
+
+// transitionLocked moves c to state, resetting counters and updating metrics.
+func (s *Set) transitionLocked(name string, c *circuit, state State) {
+	prev := c.state
+	c.state = state
+	c.trials = 0
+	c.successes = 0
+
+	switch state {
+	case Open:
+		c.openedAt = s.now()
+		s.logger.Warn("circuit opened",
+			"backend", name,
+			"from", prev.String(),
+			"consecutive_failures", c.consecutive,
+			"cooldown", s.cfg.Cooldown,
+		)
+	case HalfOpen:
+		s.logger.Info("circuit half-open, sending trial requests", "backend", name)
+	case Closed:
+		c.consecutive = 0
+		c.window = nil
+		s.logger.Info("circuit closed", "backend", name)
+	}
+
+	middleware.CircuitState.WithLabelValues(name).Set(float64(state))
+	middleware.CircuitTransitionsTotal.WithLabelValues(name, state.String()).Inc()
+}
+
+// pruneBefore drops outcomes older than cutoff. The slice is ordered by time.
+func pruneBefore(window []outcome, cutoff time.Time) []outcome {
+	i := 0
+	for i < len(window) && window[i].at.Before(cutoff) {
+		i++
+	}
+	return window[i:]
+}
@@ -0,0 +1,166 @@
+package breaker_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/breaker"
+)
+
+var errUpstream = errors.New("ollama chat: status 500: internal error")
+
+var _ = Describe("Set", func() {
+	var (
+		logger *slog.Logger
+		cb     *breaker.Set
+	)
+
+	BeforeEach(func() {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		cb = breaker.New(breaker.Config{
+			ConsecutiveFailures: 3,
+			ErrorRate:           0.5,
+			Window:              time.Minute,
+			MinRequests:         4,
+			Cooldown:            30 * time.Millisecond,
+			HalfOpenRequests:    1,
+		}, logger)
+	})
+
+	It("implements backend.PassiveHealth", func() {
+		var _ backend.PassiveHealth = cb
+	})
+
+	It("starts closed for unknown backends", func() {
+		Expect(cb.IsHealthy("a")).To(BeTrue())
+		Expect(cb.State("a")).To(Equal(breaker.Closed))
+	})
+
+	It("opens after consecutive failures", func() {
+		for range 3 {
+			cb.RecordOutcome("a", errUpstream)
+		}
+		Expect(cb.State("a")).To(Equal(breaker.Open))
+		Expect(cb.IsHealthy("a")).To(BeFalse())
+		Expect(cb.IsHealthy("b")).To(BeTrue())
+	})
+
+	It("resets the consecutive count on success", func() {
+		cb = breaker.New(breaker.Config{ConsecutiveFailures: 3}, logger)
+		cb.RecordOutcome("a", errUpstream)
+		cb.RecordOutcome("a", errUpstream)
+		cb.RecordOutcome("a", nil)
+		cb.RecordOutcome("a", errUpstream)
+		Expect(cb.State("a")).To(Equal(breaker.Closed))
+	})
+
+	It("opens when the error rate in the window reaches the threshold", func() {
+		cb.RecordOutcome("a", errUpstream)
+		cb.RecordOutcome("a", nil)
+		cb.RecordOutcome("a", errUpstream)
+		Expect(cb.State("a")).To(Equal(breaker.Closed))
+		cb.RecordOutcome("a", nil)
+		cb.RecordOutcome("a", errUpstream)
+		Expect(cb.State("a")).To(Equal(breaker.Open))
+	})
+
+	It("ignores client cancellations and upstream 4xx responses", func() {
+		for range 5 {
+			cb.RecordOutcome("a", context.Canceled)
+			cb.RecordOutcome("a", errors.New("ollama chat: status 400: bad request"))
+		}
+		Expect(cb.State("a")).To(Equal(breaker.Closed))
+	})
+
+	It("lets one trial through after the cooldown and closes on success", func() {
+		for range 3 {
+			cb.RecordOutcome("a", errUpstream)
+		}
+		Eventually(func() bool { return cb.IsHealthy("a") }, time.Second, 5*time.Millisecond).Should(BeTrue())
+		Expect(cb.State("a")).To(Equal(breaker.Open), "asking does not start the trial")
+
+		Expect(cb.OnDispatch("a")).To(BeTrue())
+		Expect(cb.State("a")).To(Equal(breaker.HalfOpen))
+		Expect(cb.IsHealthy("a")).To(BeFalse(), "trial slot is taken")
+		Expect(cb.OnDispatch("a")).To(BeFalse(), "a second selection cannot take it")
+
+		cb.RecordOutcome("a", nil)
+		Expect(cb.State("a")).To(Equal(breaker.Closed))
+		Expect(cb.IsHealthy("a")).To(BeTrue())
+	})
+
+	It("refuses dispatches while open within the cooldown", func() {
+		for range 3 {
+			cb.RecordOutcome("a", errUpstream)
+		}
+		Expect(cb.OnDispatch("a")).To(BeFalse())
+		Expect(cb.State("a")).To(Equal(breaker.Open))
+	})
+
+	It("lets no more concurrent trials through than configured", func() {
+		for range 3 {
+			cb.RecordOutcome("a", errUpstream)
+		}
+		Eventually(func() bool { return cb.IsHealthy("a") }, time.Second, 5*time.Millisecond).Should(BeTrue())
+
+		var admitted atomic.Int32
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if cb.IsHealthy("a") && cb.OnDispatch("a") {
+					admitted.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		Expect(admitted.Load()).To(Equal(int32(1)))
+	})
+
+	It("reopens when the trial fails", func() {
+		for range 3 {
+			cb.RecordOutcome("a", errUpstream)
+		}
+		Eventually(func() bool { return cb.IsHealthy("a") }, time.Second, 5*time.Millisecond).Should(BeTrue())
+		cb.OnDispatch("a")
+		cb.RecordOutcome("a", errUpstream)
+		Expect(cb.State("a")).To(Equal(breaker.Open))
+		Expect(cb.IsHealthy("a")).To(BeFalse())
+	})
+
+	It("frees the trial slot when the trial ends neutrally", func() {
+		for range 3 {
+			cb.RecordOutcome("a", errUpstream)
+		}
+		Eventually(func() bool { return cb.IsHealthy("a") }, time.Second, 5*time.Millisecond).Should(BeTrue())
+		cb.OnDispatch("a")
+		cb.RecordOutcome("a", context.Canceled)
+		Expect(cb.State("a")).To(Equal(breaker.HalfOpen))
+		Expect(cb.IsHealthy("a")).To(BeTrue())
+	})
+})
+
+var _ = Describe("IsFailure", func() {
+	DescribeTable("classifies errors",
+		func(err error, want bool) {
+			Expect(breaker.IsFailure(err)).To(Equal(want))
+		},
+		Entry("nil", nil, false),
+		Entry("client canceled", context.Canceled, false),
+		Entry("deadline exceeded", context.DeadlineExceeded, true),
+		Entry("upstream 500", errUpstream, true),
+		Entry("upstream 429", errors.New("mlx chat: status 429: slow down"), true),
+		Entry("upstream 404", errors.New("mlx chat: status 404: no such model"), false),
+		Entry("connection refused", errors.New("dial tcp 127.0.0.1:1: connection refused"), true),
+	)
+})
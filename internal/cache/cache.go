@@ -0,0 +1,339 @@
+// Package cache implements the response cache: a size-bounded LRU with
+// per-entry TTL whose values live either in memory or, when a directory is
+// configured, on disk so they survive restarts.
+//
+// Keys are opaque strings; Key derives one from a request so that requests
+// differing only in JSON key order or whitespace share an entry.
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config bounds the cache. Zero MaxEntries or MaxBytes means no limit on that
+// dimension; zero TTL means entries never expire.
+type Config struct {
+	MaxEntries int
+	MaxBytes   int64
+	TTL        time.Duration
+	Dir        string // store values on disk under Dir instead of in memory
+}
+
+// entry is one cached value. value is nil when the value is on disk.
+type entry struct {
+	key     string
+	value   []byte
+	size    int64
+	expires time.Time
+}
+
+// Cache is safe for concurrent use.
+type Cache struct {
+	cfg Config
+	now func() time.Time
+
+	mu    sync.Mutex
+	ll    *list.List // front is most recently used
+	items map[string]*list.Element
+	bytes int64
+}
+
+// New creates a Cache. With cfg.Dir set, the directory is created if needed
+// and entries left there by a previous process are indexed, oldest first, so
+// they count towards the limits and can be served again.
+func New(cfg Config) (*Cache, error) {
+	c := &Cache{
+		cfg:   cfg,
+		now:   time.Now,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+	if cfg.Dir == "" {
+		return c, nil
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+	if err := c.loadDir(); err != nil {
+		return nil, fmt.Errorf("index cache dir: %w", err)
+	}
+	return c, nil
+}
+
+// Get returns the value stored under key, if present and not expired.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	el, ok := c.items[key]
+	if !ok {
+		c.mu.Unlock()
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if c.expiredLocked(e) {
+		c.removeLocked(el)
+		c.mu.Unlock()
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	value := e.value
+	c.mu.Unlock()
+
+	if c.cfg.Dir == "" {
+		return value, true
+	}
+	_, value, err := readFile(c.path(key))
+	if err != nil {
+		c.Delete(key)
+		return nil, false
+	}
+	return value, true
+}
+
+// Set stores value under key, evicting least recently used entries to stay
+// within the limits. Values larger than MaxBytes are not stored. On disk, a
+// value that cannot be written is dropped silently: the cache is best effort.
+func (c *Cache) Set(key string, value []byte) {
+	size := int64(len(value))
+	if c.cfg.MaxBytes > 0 && size > c.cfg.MaxBytes {
+		return
+	}
+	e := &entry{key: key, size: size}
+	if c.cfg.TTL > 0 {
+		e.expires = c.now().Add(c.cfg.TTL)
+	}
+	if c.cfg.Dir == "" {
+		e.value = value
+	} else if err := writeFile(c.path(key), key, e.expires, value); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.bytes -= el.Value.(*entry).size
+		el.Value = e
+		c.ll.MoveToFront(el)
+	} else {
+		c.items[key] = c.ll.PushFront(e)
+	}
+	c.bytes += size
+	c.evictLocked()
+}
+
+// Delete removes key from the cache.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeLocked(el)
+	}
+}
+
+// Purge removes every entry and returns how many there were.
+func (c *Cache) Purge() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.ll.Len()
+	for el := c.ll.Front(); el != nil; {
+		next := el.Next()
+		c.removeLocked(el)
+		el = next
+	}
+	return n
+}
+
+// Len returns the number of entries, including expired ones not yet removed.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Bytes returns the total size of the stored values.
+func (c *Cache) Bytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+func (c *Cache) expiredLocked(e *entry) bool {
+	return !e.expires.IsZero() && !c.now().Before(e.expires)
+}
+
+func (c *Cache) evictLocked() {
+	for c.ll.Len() > 0 &&
+		((c.cfg.MaxEntries > 0 && c.ll.Len() > c.cfg.MaxEntries) ||
+			(c.cfg.MaxBytes > 0 && c.bytes > c.cfg.MaxBytes)) {
+		c.removeLocked(c.ll.Back())
+	}
+}
+
+func (c *Cache) removeLocked(el *list.Element) {
+	e := el.Value.(*entry)
+	c.ll.Remove(el)
+	delete(c.items, e.key)
+	c.bytes -= e.size
+	if c.cfg.Dir != "" {
+		_ = os.Remove(c.path(e.key))
+	}
+}
+
+// path returns the file for key. Keys are hashed so any string is safe to use.
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.cfg.Dir, name[:2], name)
+}
+
+// loadDir indexes the entries found under cfg.Dir, dropping expired and
+// unreadable files.
+func (c *Cache) loadDir() error {
+	type found struct {
+		e       *entry
+		modTime time.Time
+	}
+	var entries []found
+	err := filepath.WalkDir(c.cfg.Dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		h, value, err := readFile(path)
+		if err != nil || path != c.path(h.key) {
+			_ = os.Remove(path)
+			return nil
+		}
+		e := &entry{key: h.key, size: int64(len(value)), expires: h.expires}
+		if c.expiredLocked(e) {
+			_ = os.Remove(path)
+			return nil
+		}
+		entries = append(entries, found{e: e, modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range entries {
+		c.items[f.e.key] = c.ll.PushFront(f.e)
+		c.bytes += f.e.size
+	}
+	c.evictLocked()
+	return nil
+}
+
+// --- On-disk format ---
+//
+// Each file holds: expiry as Unix nanoseconds (int64, 0 = never), key length
+// (uint16), the key, then the value.
+
+type fileHeader struct {
+	key     string
+	expires time.Time
+}
+
+var errCorrupt = errors.New("corrupt cache file")
+
+func writeFile(path, key string, expires time.Time, value []byte) error {
+	if len(key) > 0xffff {
+		return errors.New("cache key too long")
+	}
+	var buf bytes.Buffer
+	var nanos int64
+	if !expires.IsZero() {
+		nanos = expires.UnixNano()
+	}
+	_ = binary.Write(&buf, binary.BigEndian, nanos)
+	_ = binary.Write(&buf, binary.BigEndian, uint16(len(key)))
+	buf.WriteString(key)
+	buf.Write(value)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func readFile(path string) (fileHeader, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fileHeader{}, nil, err
+	}
+	if len(data) < 10 {
+		return fileHeader{}, nil, errCorrupt
+	}
+	nanos := int64(binary.BigEndian.Uint64(data[:8]))
+	n := int(binary.BigEndian.Uint16(data[8:10]))
+	if len(data) < 10+n {
+		return fileHeader{}, nil, errCorrupt
+	}
+	h := fileHeader{key: string(data[10 : 10+n])}
+	if nanos != 0 {
+		h.expires = time.Unix(0, nanos)
+	}
+	return h, data[10+n:], nil
+}
+
+// --- Keys ---
+
+// Key returns a hex digest identifying v within namespace. v is encoded to
+// JSON and re-encoded canonically (sorted object keys, no insignificant
+// whitespace, numbers as written), so embedded json.RawMessage fields that
+// differ only in formatting produce the same key.
+func Key(namespace string, v any) (string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("encode cache key: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return "", fmt.Errorf("decode cache key: %w", err)
+	}
+	canonical, err := json.Marshal(generic)
+	if err != nil {
+		return "", fmt.Errorf("encode cache key: %w", err)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(namespace))
+	h.Write([]byte{0})
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,124 @@
+package cache_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/cache"
+)
+
+var _ = Describe("Cache", func() {
+	Context("in memory", func() {
+		It("returns stored values", func() {
+			c, err := cache.New(cache.Config{})
+			Expect(err).NotTo(HaveOccurred())
+
+			c.Set("a", []byte("one"))
+			v, ok := c.Get("a")
+			Expect(ok).To(BeTrue())
+			Expect(string(v)).To(Equal("one"))
+
+			_, ok = c.Get("b")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("evicts the least recently used entry beyond MaxEntries", func() {
+			c, _ := cache.New(cache.Config{MaxEntries: 2})
+			c.Set("a", []byte("1"))
+			c.Set("b", []byte("2"))
+			_, _ = c.Get("a")
+			c.Set("c", []byte("3"))
+
+			Expect(c.Len()).To(Equal(2))
+			_, ok := c.Get("b")
+			Expect(ok).To(BeFalse())
+			_, ok = c.Get("a")
+			Expect(ok).To(BeTrue())
+		})
+
+		It("evicts to stay within MaxBytes and skips oversized values", func() {
+			c, _ := cache.New(cache.Config{MaxBytes: 10})
+			c.Set("a", []byte("123456"))
+			c.Set("b", []byte("123456"))
+			Expect(c.Len()).To(Equal(1))
+			Expect(c.Bytes()).To(Equal(int64(6)))
+
+			c.Set("huge", make([]byte, 11))
+			_, ok := c.Get("huge")
+			Expect(ok).To(BeFalse())
+			_, ok = c.Get("b")
+			Expect(ok).To(BeTrue())
+		})
+
+		It("expires entries after TTL", func() {
+			c, _ := cache.New(cache.Config{TTL: 20 * time.Millisecond})
+			c.Set("a", []byte("1"))
+			Eventually(func() bool {
+				_, ok := c.Get("a")
+				return ok
+			}).WithTimeout(time.Second).Should(BeFalse())
+			Expect(c.Len()).To(BeZero())
+		})
+
+		It("purges every entry", func() {
+			c, _ := cache.New(cache.Config{})
+			c.Set("a", []byte("1"))
+			c.Set("b", []byte("2"))
+			Expect(c.Purge()).To(Equal(2))
+			Expect(c.Len()).To(BeZero())
+			Expect(c.Bytes()).To(BeZero())
+		})
+	})
+
+	Context("on disk", func() {
+		It("serves entries written by a previous instance", func() {
+			dir := GinkgoT().TempDir()
+			c, err := cache.New(cache.Config{Dir: dir, TTL: time.Hour})
+			Expect(err).NotTo(HaveOccurred())
+			c.Set("a", []byte("persisted"))
+
+			reopened, err := cache.New(cache.Config{Dir: dir, TTL: time.Hour})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reopened.Len()).To(Equal(1))
+			v, ok := reopened.Get("a")
+			Expect(ok).To(BeTrue())
+			Expect(string(v)).To(Equal("persisted"))
+		})
+
+		It("removes evicted entries from disk", func() {
+			dir := GinkgoT().TempDir()
+			c, _ := cache.New(cache.Config{Dir: dir, MaxEntries: 1})
+			c.Set("a", []byte("1"))
+			c.Set("b", []byte("2"))
+
+			reopened, _ := cache.New(cache.Config{Dir: dir})
+			Expect(reopened.Len()).To(Equal(1))
+			_, ok := reopened.Get("a")
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
+
+var _ = Describe("Key", func() {
+	It("ignores key order and whitespace in embedded JSON", func() {
+		type req struct {
+			Model  string          `json:"model"`
+			Format json.RawMessage `json:"format"`
+		}
+		k1, err := cache.Key("chat", req{Model: "m", Format: json.RawMessage(`{"type":"json","strict":true}`)})
+		Expect(err).NotTo(HaveOccurred())
+		k2, _ := cache.Key("chat", req{Model: "m", Format: json.RawMessage(`{ "strict": true, "type": "json" }`)})
+		Expect(k1).To(Equal(k2))
+	})
+
+	It("separates namespaces and values", func() {
+		k1, _ := cache.Key("chat", map[string]any{"model": "m"})
+		k2, _ := cache.Key("embed", map[string]any{"model": "m"})
+		k3, _ := cache.Key("chat", map[string]any{"model": "n"})
+		Expect(k1).NotTo(Equal(k2))
+		Expect(k1).NotTo(Equal(k3))
+	})
+})
@@ -9,23 +9,255 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/menezmethod/inferencia/internal/priority"
 )
 
 // Config holds the complete application configuration.
 type Config struct {
-	Server        Server        `yaml:"server"`
-	Auth          Auth          `yaml:"auth"`
-	Backends      []Backend     `yaml:"backends"`
-	TTSBackends   []TTSBackend  `yaml:"tts_backends"`
-	RateLimit     RateLimit     `yaml:"ratelimit"`
-	Log           Log           `yaml:"log"`
-	Observability Observability `yaml:"observability"`
-	Watchdog      Watchdog      `yaml:"watchdog"`
+	Server         Server          `yaml:"server"`
+	Auth           Auth            `yaml:"auth"`
+	Backends       []Backend       `yaml:"backends"`
+	TTSBackends    []TTSBackend    `yaml:"tts_backends"`
+	RerankBackends []RerankBackend `yaml:"rerank_backends"`
+	STTBackends    []STTBackend    `yaml:"stt_backends"`
+	RateLimit      RateLimit       `yaml:"ratelimit"`
+	Log            Log             `yaml:"log"`
+	Observability  Observability   `yaml:"observability"`
+	Watchdog       Watchdog        `yaml:"watchdog"`
+	LoadBalancing  LoadBalancing   `yaml:"load_balancing"`
+	CircuitBreaker CircuitBreaker  `yaml:"circuit_breaker"`
+	Admin          Admin           `yaml:"admin"`
+	Queue          Queue           `yaml:"queue"`
+	Priority       Priority        `yaml:"priority"`
+	Cache          Cache           `yaml:"cache"`
+	SemanticCache  SemanticCache   `yaml:"semantic_cache"`
+	EmbedBatching  EmbedBatching   `yaml:"embed_batching"`
+	Batch          Batch           `yaml:"batch"`
+	Async          Async           `yaml:"async"`
+	Streams        Streams         `yaml:"resumable_streams"`
+	Streaming      Streaming       `yaml:"streaming"`
+	TTS            TTS             `yaml:"tts"`
+	Realtime       Realtime        `yaml:"realtime"`
+	GRPC           GRPC            `yaml:"grpc"`
+}
+
+// GRPC configures the gRPC API, served on Port of the server's host next to
+// the HTTP API. Reflection registers the server reflection service for
+// tools like grpcurl.
+type GRPC struct {
+	Enabled    bool `yaml:"enabled"`
+	Port       int  `yaml:"port"`
+	Reflection bool `yaml:"reflection"`
+}
+
+// Realtime configures the realtime voice endpoint (GET /v1/realtime), which
+// transcribes a caller's speech on the STT backends, answers on the chat
+// backends and speaks the answer on the TTS backends. A turn's audio is
+// capped at MaxBuffer and a session at MaxDuration (0 = unlimited). With
+// server VAD, audio louder than VADThreshold (RMS, 0-1) is speech and
+// VADSilence of quieter audio ends the turn; sessions may override both.
+type Realtime struct {
+	Enabled      bool          `yaml:"enabled"`
+	MaxBuffer    time.Duration `yaml:"max_buffer"`
+	MaxDuration  time.Duration `yaml:"max_duration"`
+	VADThreshold float64       `yaml:"vad_threshold"`
+	VADSilence   time.Duration `yaml:"vad_silence"`
+}
+
+// TTS configures speech synthesis requests. The voices of every TTS backend
+// are cached for VoiceCatalogTTL; requests naming a voice are routed to a
+// backend that offers it. Encoder ("ffmpeg", or empty for none) converts
+// audio to formats a backend does not produce itself; without one only WAV
+// and PCM are converted.
+type TTS struct {
+	VoiceCatalogTTL time.Duration `yaml:"voice_catalog_ttl"`
+	Encoder         string        `yaml:"encoder"`
+	FFmpegPath      string        `yaml:"ffmpeg_path"`
+	LongForm        TTSLongForm   `yaml:"long_form"`
+	Cache           TTSCache      `yaml:"cache"`
+	// Lexicon maps terms to the spelling TTS models should read instead,
+	// e.g. "nginx": "engine x". Lexicons are named sets layered over it for
+	// the API keys whose lexicon attribute names them.
+	Lexicon  map[string]string            `yaml:"lexicon"`
+	Lexicons map[string]map[string]string `yaml:"lexicons"`
+}
+
+// TTSCache stores synthesized audio under Dir, keyed by model, voice, speed,
+// format and input, so repeated phrases are served without a backend. The
+// least recently used audio is evicted beyond MaxSizeMB.
+type TTSCache struct {
+	Enabled   bool          `yaml:"enabled"`
+	Dir       string        `yaml:"dir"`
+	MaxSizeMB int           `yaml:"max_size_mb"` // 0 = unlimited
+	TTL       time.Duration `yaml:"ttl"`         // 0 = audio never expires
+}
+
+// TTSLongForm accepts speech input beyond the usual 4096 characters, up to
+// MaxInputLength. Such input is split into segments of at most SegmentLength
+// characters on sentence and paragraph boundaries, synthesized Parallelism
+// at a time across the healthy backends for the model, and joined in order;
+// WAV and PCM segments are crossfaded over Crossfade.
+type TTSLongForm struct {
+	Enabled        bool          `yaml:"enabled"`
+	MaxInputLength int           `yaml:"max_input_length"`
+	SegmentLength  int           `yaml:"segment_length"`
+	Parallelism    int           `yaml:"parallelism"`
+	Crossfade      time.Duration `yaml:"crossfade"`
+}
+
+// Streaming bounds SSE responses. While the backend is silent (e.g. during
+// prompt processing on a long context), a ": keep-alive" comment is sent
+// every HeartbeatInterval so proxies keep the connection open. A backend that
+// sends nothing for FirstChunkTimeout, which allows for prompt processing, or
+// goes silent for IdleTimeout after its first chunk has its generation
+// aborted, and no stream runs longer than MaxDuration, which replaces
+// server.write_timeout for streams. Zero disables each.
+type Streaming struct {
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+	FirstChunkTimeout time.Duration `yaml:"first_chunk_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	MaxDuration       time.Duration `yaml:"max_duration"`
+}
+
+// Streams configures resumable chat streams. Streamed completions are
+// generated into a server-side buffer and sent with SSE event IDs; after a
+// dropped connection, generation continues for Grace and a request carrying
+// Last-Event-ID resumes after that event. At most MaxStreams are buffered;
+// further streams are sent directly, without resumption. Each stream keeps at
+// most MaxBufferKB of events; older ones are dropped and can no longer be
+// resumed from.
+type Streams struct {
+	Enabled     bool          `yaml:"enabled"`
+	Grace       time.Duration `yaml:"grace"`
+	MaxStreams  int           `yaml:"max_streams"`   // 0 = unlimited
+	MaxBufferKB int           `yaml:"max_buffer_kb"` // per stream; 0 = unlimited
+}
+
+// Async configures asynchronous chat requests ("Prefer: respond-async" or
+// POST /v1/async/chat/completions). Jobs run in the background for up to
+// Timeout and their results are kept in memory for TTL. MaxPending bounds the
+// jobs running at once.
+type Async struct {
+	Enabled    bool          `yaml:"enabled"`
+	TTL        time.Duration `yaml:"ttl"`
+	Timeout    time.Duration `yaml:"timeout"`     // 0 = no limit
+	MaxPending int           `yaml:"max_pending"` // 0 = unlimited
+	Webhook    AsyncWebhook  `yaml:"webhook"`
+}
+
+// AsyncWebhook configures completion callbacks requested with the
+// X-Inferencia-Webhook header. Callbacks are signed with Secret and refused
+// when it is empty; AllowedHosts, when set, restricts where they may go.
+// Without it, callbacks may only go to public addresses, never loopback,
+// private or link-local ones.
+type AsyncWebhook struct {
+	Secret       string        `yaml:"secret"`
+	AllowedHosts []string      `yaml:"allowed_hosts"`
+	Timeout      time.Duration `yaml:"timeout"`
+	MaxAttempts  int           `yaml:"max_attempts"`
+}
+
+// Batch configures the OpenAI-compatible Batch API (/v1/files and
+// /v1/batches). Input files, results and batch state are kept under Dir so
+// batches resume after a restart. Workers bounds the batch lines in flight
+// across all batches; they run at batch priority behind interactive traffic.
+type Batch struct {
+	Enabled       bool   `yaml:"enabled"`
+	Dir           string `yaml:"dir"`
+	Workers       int    `yaml:"workers"`
+	MaxFileSizeMB int    `yaml:"max_file_size_mb"` // 0 = unlimited
+	MaxRequests   int    `yaml:"max_requests"`     // lines per batch; 0 = unlimited
+}
+
+// EmbedBatching configures the micro-batcher in front of embedding backends.
+// Concurrent text requests for the same model arriving within Window are sent
+// upstream as one call of at most MaxBatchSize inputs, before a backend is
+// chosen. A backend's max_embed_batch splits larger calls to it, even when
+// batching is disabled.
+type EmbedBatching struct {
+	Enabled      bool          `yaml:"enabled"`
+	Window       time.Duration `yaml:"window"`
+	MaxBatchSize int           `yaml:"max_batch_size"` // 0 = unlimited
+}
+
+// SemanticCache configures reuse of chat answers for similar questions. The
+// last user message is embedded with EmbeddingModel on the embedding backends
+// and compared with earlier questions from the same API key and model; an
+// answer is reused when the cosine similarity reaches Threshold.
+type SemanticCache struct {
+	Enabled        bool          `yaml:"enabled"`
+	Threshold      float64       `yaml:"threshold"`
+	MaxEntries     int           `yaml:"max_entries"`
+	TTL            time.Duration `yaml:"ttl"` // 0 = entries never expire
+	EmbeddingModel string        `yaml:"embedding_model"`
+	EmbedTimeout   time.Duration `yaml:"embed_timeout"` // lookups slower than this skip the cache
+}
+
+// Cache configures the exact-match response cache for deterministic chat
+// requests (temperature 0 or a fixed seed) and embeddings. Entries are kept in
+// memory, or under Dir when set so they survive restarts.
+type Cache struct {
+	Enabled    bool          `yaml:"enabled"`
+	MaxEntries int           `yaml:"max_entries"` // 0 = unlimited
+	MaxSizeMB  int           `yaml:"max_size_mb"` // total size of cached responses; 0 = unlimited
+	TTL        time.Duration `yaml:"ttl"`         // 0 = entries never expire
+	Dir        string        `yaml:"dir"`
+}
+
+// Priority configures priority classes (batch, normal, interactive). Keys get
+// a class from their policy in the keys file; the wait queue serves higher
+// classes first. Reserved holds back slots on every backend with
+// max_concurrency for the named class and above.
+type Priority struct {
+	Reserved map[string]int `yaml:"reserved"`
+}
+
+// Queue configures the wait queue in front of backends with max_concurrency.
+// When every eligible backend is at its limit, requests wait up to Timeout in a
+// queue of at most MaxSize; otherwise they get 503 with Retry-After.
+type Queue struct {
+	MaxSize    int           `yaml:"max_size"`
+	Timeout    time.Duration `yaml:"timeout"`
+	RetryAfter time.Duration `yaml:"retry_after"` // back-off suggested to rejected clients
+}
+
+// Admin configures the operator API under /admin/. It is only served when at
+// least one key is configured; admin keys are separate from API keys.
+type Admin struct {
+	Keys []string `yaml:"keys"`
+}
+
+// LoadBalancing selects the backend selection strategy per capability.
+// Valid strategies: least_connections, ewma, peak_ewma, p2c, weighted.
+type LoadBalancing struct {
+	Chat   string        `yaml:"chat"`
+	Embed  string        `yaml:"embed"`
+	TTS    string        `yaml:"tts"`
+	Rerank string        `yaml:"rerank"`
+	STT    string        `yaml:"stt"`
+	Decay  time.Duration `yaml:"decay"` // EWMA time constant for latency-aware strategies
+}
+
+// CircuitBreaker configures passive health checking from live request outcomes.
+// A backend's circuit opens after ConsecutiveFailures failures in a row, or when
+// the failure ratio within Window reaches ErrorRate (once MinRequests outcomes
+// were seen). After Cooldown, HalfOpenRequests trial requests decide whether it
+// closes again. Breakers are off unless Enabled.
+type CircuitBreaker struct {
+	Enabled             bool          `yaml:"enabled"`
+	ConsecutiveFailures int           `yaml:"consecutive_failures"`
+	ErrorRate           float64       `yaml:"error_rate"` // 0 disables the error-rate trigger
+	Window              time.Duration `yaml:"window"`
+	MinRequests         int           `yaml:"min_requests"`
+	Cooldown            time.Duration `yaml:"cooldown"`
+	HalfOpenRequests    int           `yaml:"half_open_requests"`
 }
 
 // Watchdog configures the background health-check loop.
@@ -50,18 +282,67 @@ type Auth struct {
 
 // Backend configures a single LLM backend.
 type Backend struct {
-	Name          string        `yaml:"name"`
-	Type          string        `yaml:"type"`
-	URL           string        `yaml:"url"`
-	Timeout       time.Duration `yaml:"timeout"`        // inference timeout (chat, embeddings); 0 disables client timeout
-	HealthTimeout time.Duration `yaml:"health_timeout"` // health probes and model listing
+	Name           string        `yaml:"name"`
+	Type           string        `yaml:"type"`
+	URL            string        `yaml:"url"`
+	Timeout        time.Duration `yaml:"timeout"`         // inference timeout (chat, embeddings); 0 disables client timeout
+	HealthTimeout  time.Duration `yaml:"health_timeout"`  // health probes and model listing
+	Weight         float64       `yaml:"weight"`          // relative share for the weighted strategy (default 1)
+	Drain          bool          `yaml:"drain"`           // take out of rotation; re-applied on SIGUSR1
+	MaxConcurrency int           `yaml:"max_concurrency"` // max in-flight requests; 0 = unlimited
+	MaxEmbedBatch  int           `yaml:"max_embed_batch"` // inputs per upstream embedding call; 0 = embed_batching.max_batch_size
 }
 
 // TTSBackend configures a single TTS backend.
 type TTSBackend struct {
-	Name    string        `yaml:"name"`
-	URL     string        `yaml:"url"`
-	Timeout time.Duration `yaml:"timeout"`
+	Name           string        `yaml:"name"`
+	URL            string        `yaml:"url"`
+	Timeout        time.Duration `yaml:"timeout"`
+	Weight         float64       `yaml:"weight"`          // relative share for the weighted strategy (default 1)
+	Drain          bool          `yaml:"drain"`           // take out of rotation; re-applied on SIGUSR1
+	MaxConcurrency int           `yaml:"max_concurrency"` // max in-flight requests; 0 = unlimited
+	Streaming      bool          `yaml:"streaming"`       // server streams audio for "stream": true requests
+	DefaultVoice   string        `yaml:"default_voice"`   // voice for requests naming none; default: the server's first voice
+	Formats        []string      `yaml:"formats"`         // response formats the server produces; others are transcoded. Empty = all
+	IgnoresSpeed   bool          `yaml:"ignores_speed"`   // server does not apply speed; audio is resampled instead
+}
+
+// ttsFormats are the response formats a TTS request may ask for.
+var ttsFormats = []string{"wav", "pcm", "mp3", "opus", "flac", "aac"}
+
+// knownDefaultVoices are the default voices of TTS servers commonly run under
+// these names, used when a tts_backends entry sets no default_voice.
+var knownDefaultVoices = map[string]string{
+	"kokoro":     "af_bella",
+	"chatterbox": "chatterbox-default",
+}
+
+// RerankBackend configures a single rerank backend. API selects the request
+// shape: "cohere" (default) for POST /v1/rerank as served by llama.cpp,
+// Infinity and vLLM, or "tei" for Text Embeddings Inference's POST /rerank.
+// Requests naming one of Models are routed to this backend.
+type RerankBackend struct {
+	Name           string        `yaml:"name"`
+	URL            string        `yaml:"url"`
+	API            string        `yaml:"api"`
+	Models         []string      `yaml:"models"`
+	Timeout        time.Duration `yaml:"timeout"`
+	Weight         float64       `yaml:"weight"`          // relative share for the weighted strategy (default 1)
+	Drain          bool          `yaml:"drain"`           // take out of rotation; re-applied on SIGUSR1
+	MaxConcurrency int           `yaml:"max_concurrency"` // max in-flight requests; 0 = unlimited
+}
+
+// STTBackend configures a single speech-to-text backend with an
+// OpenAI-compatible POST /v1/audio/transcriptions endpoint. Requests naming
+// one of Models are routed to this backend.
+type STTBackend struct {
+	Name           string        `yaml:"name"`
+	URL            string        `yaml:"url"`
+	Models         []string      `yaml:"models"`
+	Timeout        time.Duration `yaml:"timeout"`
+	Weight         float64       `yaml:"weight"`          // relative share for the weighted strategy (default 1)
+	Drain          bool          `yaml:"drain"`           // take out of rotation; re-applied on SIGUSR1
+	MaxConcurrency int           `yaml:"max_concurrency"` // max in-flight requests; 0 = unlimited
 }
 
 // RateLimit configures the token bucket rate limiter.
@@ -124,6 +405,97 @@ func Defaults() Config {
 			FailThreshold:  3,
 			RequestTimeout: 5 * time.Second,
 		},
+		LoadBalancing: LoadBalancing{
+			Chat:   "least_connections",
+			Embed:  "least_connections",
+			TTS:    "least_connections",
+			Rerank: "least_connections",
+			STT:    "least_connections",
+			Decay:  10 * time.Second,
+		},
+		Queue: Queue{
+			MaxSize:    100,
+			Timeout:    30 * time.Second,
+			RetryAfter: 5 * time.Second,
+		},
+		Cache: Cache{
+			Enabled:    false,
+			MaxEntries: 10000,
+			MaxSizeMB:  256,
+			TTL:        time.Hour,
+		},
+		SemanticCache: SemanticCache{
+			Enabled:      false,
+			Threshold:    0.95,
+			MaxEntries:   10000,
+			TTL:          24 * time.Hour,
+			EmbedTimeout: 2 * time.Second,
+		},
+		EmbedBatching: EmbedBatching{
+			Enabled:      false,
+			Window:       5 * time.Millisecond,
+			MaxBatchSize: 64,
+		},
+		Batch: Batch{
+			Enabled:       false,
+			Dir:           "./data/batch",
+			Workers:       4,
+			MaxFileSizeMB: 100,
+			MaxRequests:   50000,
+		},
+		Streaming: Streaming{
+			HeartbeatInterval: 15 * time.Second,
+			FirstChunkTimeout: 5 * time.Minute,
+		},
+		TTS: TTS{
+			VoiceCatalogTTL: 5 * time.Minute,
+			LongForm: TTSLongForm{
+				Enabled:        false,
+				MaxInputLength: 100000,
+				SegmentLength:  1000,
+				Parallelism:    4,
+			},
+			Cache: TTSCache{
+				Enabled:   false,
+				Dir:       "./data/tts-cache",
+				MaxSizeMB: 1024,
+			},
+		},
+		Realtime: Realtime{
+			Enabled:      false,
+			MaxBuffer:    60 * time.Second,
+			VADThreshold: 0.02,
+			VADSilence:   500 * time.Millisecond,
+		},
+		GRPC: GRPC{
+			Enabled:    false,
+			Port:       9090,
+			Reflection: true,
+		},
+		Streams: Streams{
+			Enabled:     false,
+			Grace:       30 * time.Second,
+			MaxStreams:  1000,
+			MaxBufferKB: 1024,
+		},
+		Async: Async{
+			Enabled:    false,
+			TTL:        time.Hour,
+			Timeout:    30 * time.Minute,
+			MaxPending: 100,
+			Webhook: AsyncWebhook{
+				Timeout:     10 * time.Second,
+				MaxAttempts: 3,
+			},
+		},
+		CircuitBreaker: CircuitBreaker{
+			ConsecutiveFailures: 5,
+			ErrorRate:           0.5,
+			Window:              60 * time.Second,
+			MinRequests:         10,
+			Cooldown:            15 * time.Second,
+			HalfOpenRequests:    1,
+		},
 	}
 }
 
@@ -145,6 +517,12 @@ func Load(path string) (Config, error) {
 
 	applyEnvOverrides(&cfg)
 
+	for i, t := range cfg.TTSBackends {
+		if t.DefaultVoice == "" {
+			cfg.TTSBackends[i].DefaultVoice = knownDefaultVoices[t.Name]
+		}
+	}
+
 	if err := validate(cfg); err != nil {
 		return cfg, fmt.Errorf("invalid config: %w", err)
 	}
@@ -266,6 +644,128 @@ func applyEnvOverrides(cfg *Config) {
 			slog.Warn("invalid INFERENCIA_WATCHDOG_TIMEOUT, using default", "value", v, "err", err)
 		}
 	}
+
+	// Load-balancing env var applies one strategy to every capability.
+	if v := os.Getenv("INFERENCIA_LB_STRATEGY"); v != "" {
+		strategy := strings.ToLower(strings.TrimSpace(v))
+		cfg.LoadBalancing.Chat = strategy
+		cfg.LoadBalancing.Embed = strategy
+		cfg.LoadBalancing.TTS = strategy
+		cfg.LoadBalancing.Rerank = strategy
+		cfg.LoadBalancing.STT = strategy
+	}
+
+	if v := os.Getenv("INFERENCIA_CIRCUIT_BREAKER_ENABLED"); v != "" {
+		cfg.CircuitBreaker.Enabled = strings.ToLower(v) == "true" || v == "1"
+	}
+
+	if v := os.Getenv("INFERENCIA_QUEUE_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.Queue.Timeout = d
+		} else {
+			slog.Warn("invalid INFERENCIA_QUEUE_TIMEOUT, using default", "value", v, "err", err)
+		}
+	}
+
+	if v := os.Getenv("INFERENCIA_CACHE_ENABLED"); v != "" {
+		cfg.Cache.Enabled = strings.ToLower(v) == "true" || v == "1"
+	}
+	if v := os.Getenv("INFERENCIA_CACHE_DIR"); v != "" {
+		cfg.Cache.Dir = strings.TrimSpace(v)
+	}
+
+	if v := os.Getenv("INFERENCIA_EMBED_BATCHING_ENABLED"); v != "" {
+		cfg.EmbedBatching.Enabled = strings.ToLower(v) == "true" || v == "1"
+	}
+
+	if v := os.Getenv("INFERENCIA_SEMANTIC_CACHE_ENABLED"); v != "" {
+		cfg.SemanticCache.Enabled = strings.ToLower(v) == "true" || v == "1"
+	}
+
+	if v := os.Getenv("INFERENCIA_BATCH_ENABLED"); v != "" {
+		cfg.Batch.Enabled = strings.ToLower(v) == "true" || v == "1"
+	}
+	if v := os.Getenv("INFERENCIA_BATCH_DIR"); v != "" {
+		cfg.Batch.Dir = strings.TrimSpace(v)
+	}
+
+	if v := os.Getenv("INFERENCIA_STREAM_HEARTBEAT_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.Streaming.HeartbeatInterval = d
+		} else {
+			slog.Warn("invalid INFERENCIA_STREAM_HEARTBEAT_INTERVAL, using default", "value", v, "err", err)
+		}
+	}
+	if v := os.Getenv("INFERENCIA_STREAM_FIRST_CHUNK_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.Streaming.FirstChunkTimeout = d
+		} else {
+			slog.Warn("invalid INFERENCIA_STREAM_FIRST_CHUNK_TIMEOUT, using default", "value", v, "err", err)
+		}
+	}
+	if v := os.Getenv("INFERENCIA_STREAM_IDLE_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.Streaming.IdleTimeout = d
+		} else {
+			slog.Warn("invalid INFERENCIA_STREAM_IDLE_TIMEOUT, using default", "value", v, "err", err)
+		}
+	}
+	if v := os.Getenv("INFERENCIA_STREAM_MAX_DURATION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.Streaming.MaxDuration = d
+		} else {
+			slog.Warn("invalid INFERENCIA_STREAM_MAX_DURATION, using default", "value", v, "err", err)
+		}
+	}
+
+	if v := os.Getenv("INFERENCIA_TTS_ENCODER"); v != "" {
+		cfg.TTS.Encoder = strings.ToLower(strings.TrimSpace(v))
+	}
+	if v := os.Getenv("INFERENCIA_TTS_LONG_FORM_ENABLED"); v != "" {
+		cfg.TTS.LongForm.Enabled = strings.ToLower(v) == "true" || v == "1"
+	}
+	if v := os.Getenv("INFERENCIA_TTS_CACHE_ENABLED"); v != "" {
+		cfg.TTS.Cache.Enabled = strings.ToLower(v) == "true" || v == "1"
+	}
+	if v := os.Getenv("INFERENCIA_TTS_CACHE_DIR"); v != "" {
+		cfg.TTS.Cache.Dir = strings.TrimSpace(v)
+	}
+
+	if v := os.Getenv("INFERENCIA_REALTIME_ENABLED"); v != "" {
+		cfg.Realtime.Enabled = strings.ToLower(v) == "true" || v == "1"
+	}
+
+	if v := os.Getenv("INFERENCIA_GRPC_ENABLED"); v != "" {
+		cfg.GRPC.Enabled = strings.ToLower(v) == "true" || v == "1"
+	}
+	if v := os.Getenv("INFERENCIA_GRPC_PORT"); v != "" {
+		if port, err := strconv.Atoi(v); err == nil {
+			cfg.GRPC.Port = port
+		} else {
+			slog.Warn("invalid INFERENCIA_GRPC_PORT, using default", "value", v, "err", err)
+		}
+	}
+
+	if v := os.Getenv("INFERENCIA_RESUMABLE_STREAMS_ENABLED"); v != "" {
+		cfg.Streams.Enabled = strings.ToLower(v) == "true" || v == "1"
+	}
+
+	if v := os.Getenv("INFERENCIA_ASYNC_ENABLED"); v != "" {
+		cfg.Async.Enabled = strings.ToLower(v) == "true" || v == "1"
+	}
+	if v := os.Getenv("INFERENCIA_ASYNC_WEBHOOK_SECRET"); v != "" {
+		cfg.Async.Webhook.Secret = v
+	}
+
+	// Admin keys: comma-separated, replaces any keys from the file.
+	if v := os.Getenv("INFERENCIA_ADMIN_KEYS"); v != "" {
+		cfg.Admin.Keys = nil
+		for _, k := range strings.Split(v, ",") {
+			if key := strings.TrimSpace(k); key != "" {
+				cfg.Admin.Keys = append(cfg.Admin.Keys, key)
+			}
+		}
+	}
 }
 
 // ttsBackendExists checks whether a TTS backend with the given name is already
@@ -300,6 +800,77 @@ func validate(cfg Config) error {
 		if b.URL == "" {
 			errs = append(errs, fmt.Errorf("backends[%d].url is required", i))
 		}
+		if b.Weight < 0 {
+			errs = append(errs, fmt.Errorf("backends[%d].weight must not be negative", i))
+		}
+		if b.MaxConcurrency < 0 {
+			errs = append(errs, fmt.Errorf("backends[%d].max_concurrency must not be negative", i))
+		}
+		if b.MaxEmbedBatch < 0 {
+			errs = append(errs, fmt.Errorf("backends[%d].max_embed_batch must not be negative", i))
+		}
+	}
+	for i, t := range cfg.TTSBackends {
+		if t.Weight < 0 {
+			errs = append(errs, fmt.Errorf("tts_backends[%d].weight must not be negative", i))
+		}
+		if t.MaxConcurrency < 0 {
+			errs = append(errs, fmt.Errorf("tts_backends[%d].max_concurrency must not be negative", i))
+		}
+		for _, f := range t.Formats {
+			if !slices.Contains(ttsFormats, f) {
+				errs = append(errs, fmt.Errorf("tts_backends[%d].formats: unknown format %q", i, f))
+			}
+		}
+	}
+	for i, rb := range cfg.RerankBackends {
+		if rb.Name == "" {
+			errs = append(errs, fmt.Errorf("rerank_backends[%d].name is required", i))
+		}
+		if rb.URL == "" {
+			errs = append(errs, fmt.Errorf("rerank_backends[%d].url is required", i))
+		}
+		if rb.API != "" && rb.API != "cohere" && rb.API != "tei" {
+			errs = append(errs, fmt.Errorf("rerank_backends[%d].api must be cohere or tei; got %q", i, rb.API))
+		}
+		if rb.Weight < 0 {
+			errs = append(errs, fmt.Errorf("rerank_backends[%d].weight must not be negative", i))
+		}
+		if rb.MaxConcurrency < 0 {
+			errs = append(errs, fmt.Errorf("rerank_backends[%d].max_concurrency must not be negative", i))
+		}
+	}
+	for i, sb := range cfg.STTBackends {
+		if sb.Name == "" {
+			errs = append(errs, fmt.Errorf("stt_backends[%d].name is required", i))
+		}
+		if sb.URL == "" {
+			errs = append(errs, fmt.Errorf("stt_backends[%d].url is required", i))
+		}
+		if sb.Weight < 0 {
+			errs = append(errs, fmt.Errorf("stt_backends[%d].weight must not be negative", i))
+		}
+		if sb.MaxConcurrency < 0 {
+			errs = append(errs, fmt.Errorf("stt_backends[%d].max_concurrency must not be negative", i))
+		}
+	}
+	if rt := cfg.Realtime; rt.Enabled {
+		if len(cfg.STTBackends) == 0 || len(cfg.TTSBackends) == 0 {
+			errs = append(errs, errors.New("realtime needs at least one stt_backends and one tts_backends entry"))
+		}
+		if rt.MaxBuffer <= 0 || rt.MaxDuration < 0 || rt.VADSilence <= 0 {
+			errs = append(errs, errors.New("realtime.max_buffer and realtime.vad_silence must be positive and realtime.max_duration not negative"))
+		}
+		if rt.VADThreshold <= 0 || rt.VADThreshold >= 1 {
+			errs = append(errs, fmt.Errorf("realtime.vad_threshold must be between 0 and 1, got %g", rt.VADThreshold))
+		}
+	}
+	if g := cfg.GRPC; g.Enabled {
+		if g.Port < 1 || g.Port > 65535 {
+			errs = append(errs, fmt.Errorf("grpc.port must be between 1 and 65535, got %d", g.Port))
+		} else if g.Port == cfg.Server.Port {
+			errs = append(errs, fmt.Errorf("grpc.port must differ from server.port (%d)", g.Port))
+		}
 	}
 	if cfg.RateLimit.RequestsPerSecond <= 0 {
 		errs = append(errs, errors.New("ratelimit.requests_per_second must be positive"))
@@ -324,6 +895,129 @@ func validate(cfg Config) error {
 		errs = append(errs, errors.New("observability.otel_endpoint is required when otel_enabled is true"))
 	}
 
+	validStrategies := map[string]bool{"": true, "least_connections": true, "ewma": true, "peak_ewma": true, "p2c": true, "weighted": true}
+	for _, lb := range []struct{ name, strategy string }{
+		{"chat", cfg.LoadBalancing.Chat},
+		{"embed", cfg.LoadBalancing.Embed},
+		{"tts", cfg.LoadBalancing.TTS},
+		{"rerank", cfg.LoadBalancing.Rerank},
+		{"stt", cfg.LoadBalancing.STT},
+	} {
+		if !validStrategies[lb.strategy] {
+			errs = append(errs, fmt.Errorf("load_balancing.%s must be one of least_connections, ewma, peak_ewma, p2c, weighted; got %q", lb.name, lb.strategy))
+		}
+	}
+	if cfg.LoadBalancing.Decay < 0 {
+		errs = append(errs, errors.New("load_balancing.decay must not be negative"))
+	}
+
+	if cfg.Queue.MaxSize < 0 || cfg.Queue.Timeout < 0 || cfg.Queue.RetryAfter < 0 {
+		errs = append(errs, errors.New("queue settings must not be negative"))
+	}
+
+	for class, n := range cfg.Priority.Reserved {
+		if _, err := priority.Parse(class); err != nil {
+			errs = append(errs, fmt.Errorf("priority.reserved: %w", err))
+		}
+		if n < 0 {
+			errs = append(errs, fmt.Errorf("priority.reserved.%s must not be negative", class))
+		}
+	}
+
+	if cfg.Cache.MaxEntries < 0 || cfg.Cache.MaxSizeMB < 0 || cfg.Cache.TTL < 0 {
+		errs = append(errs, errors.New("cache limits must not be negative"))
+	}
+
+	if cfg.EmbedBatching.Window < 0 || cfg.EmbedBatching.MaxBatchSize < 0 {
+		errs = append(errs, errors.New("embed_batching settings must not be negative"))
+	}
+
+	sc := cfg.SemanticCache
+	if sc.Enabled && (sc.Threshold <= 0 || sc.Threshold > 1) {
+		errs = append(errs, fmt.Errorf("semantic_cache.threshold must be in (0, 1], got %g", sc.Threshold))
+	}
+	if sc.MaxEntries < 0 || sc.TTL < 0 || sc.EmbedTimeout < 0 {
+		errs = append(errs, errors.New("semantic_cache limits must not be negative"))
+	}
+
+	if b := cfg.Batch; b.Enabled {
+		if b.Dir == "" {
+			errs = append(errs, errors.New("batch.dir is required when batch is enabled"))
+		}
+		if b.Workers < 1 {
+			errs = append(errs, fmt.Errorf("batch.workers must be at least 1, got %d", b.Workers))
+		}
+	}
+	if cfg.Batch.MaxFileSizeMB < 0 || cfg.Batch.MaxRequests < 0 {
+		errs = append(errs, errors.New("batch limits must not be negative"))
+	}
+
+	if st := cfg.Streaming; st.HeartbeatInterval < 0 || st.FirstChunkTimeout < 0 || st.IdleTimeout < 0 || st.MaxDuration < 0 {
+		errs = append(errs, errors.New("streaming durations must not be negative"))
+	}
+
+	if cfg.TTS.Encoder != "" && cfg.TTS.Encoder != "ffmpeg" {
+		errs = append(errs, fmt.Errorf("tts.encoder must be ffmpeg or empty; got %q", cfg.TTS.Encoder))
+	}
+	if cfg.TTS.VoiceCatalogTTL <= 0 {
+		errs = append(errs, errors.New("tts.voice_catalog_ttl must be positive"))
+	}
+	if lf := cfg.TTS.LongForm; lf.Enabled {
+		if lf.SegmentLength < 1 || lf.SegmentLength > 4096 {
+			errs = append(errs, fmt.Errorf("tts.long_form.segment_length must be between 1 and 4096, got %d", lf.SegmentLength))
+		}
+		if lf.MaxInputLength < lf.SegmentLength {
+			errs = append(errs, errors.New("tts.long_form.max_input_length must be at least segment_length"))
+		}
+		if lf.Parallelism < 1 {
+			errs = append(errs, fmt.Errorf("tts.long_form.parallelism must be at least 1, got %d", lf.Parallelism))
+		}
+		if lf.Crossfade < 0 {
+			errs = append(errs, errors.New("tts.long_form.crossfade must not be negative"))
+		}
+	}
+	if _, ok := cfg.TTS.Lexicon[""]; ok {
+		errs = append(errs, errors.New("tts.lexicon: terms must not be empty"))
+	}
+	for name, entries := range cfg.TTS.Lexicons {
+		if _, ok := entries[""]; ok {
+			errs = append(errs, fmt.Errorf("tts.lexicons.%s: terms must not be empty", name))
+		}
+	}
+	if tc := cfg.TTS.Cache; tc.Enabled {
+		if tc.Dir == "" {
+			errs = append(errs, errors.New("tts.cache.dir is required when the TTS cache is enabled"))
+		}
+		if tc.MaxSizeMB < 0 || tc.TTL < 0 {
+			errs = append(errs, errors.New("tts.cache limits must not be negative"))
+		}
+	}
+
+	if cfg.Streams.Enabled && cfg.Streams.Grace <= 0 {
+		errs = append(errs, errors.New("resumable_streams.grace must be positive when resumable streams are enabled"))
+	}
+	if cfg.Streams.MaxStreams < 0 || cfg.Streams.MaxBufferKB < 0 {
+		errs = append(errs, errors.New("resumable_streams.max_streams and max_buffer_kb must not be negative"))
+	}
+
+	if cfg.Async.Enabled && cfg.Async.TTL <= 0 {
+		errs = append(errs, errors.New("async.ttl must be positive when async is enabled"))
+	}
+	if cfg.Async.Timeout < 0 || cfg.Async.MaxPending < 0 || cfg.Async.Webhook.Timeout < 0 || cfg.Async.Webhook.MaxAttempts < 0 {
+		errs = append(errs, errors.New("async settings must not be negative"))
+	}
+
+	cb := cfg.CircuitBreaker
+	if cb.ErrorRate < 0 || cb.ErrorRate > 1 {
+		errs = append(errs, fmt.Errorf("circuit_breaker.error_rate must be between 0 and 1, got %g", cb.ErrorRate))
+	}
+	if cb.ConsecutiveFailures < 0 || cb.MinRequests < 0 || cb.HalfOpenRequests < 0 {
+		errs = append(errs, errors.New("circuit_breaker counts must not be negative"))
+	}
+	if cb.Window < 0 || cb.Cooldown < 0 {
+		errs = append(errs, errors.New("circuit_breaker durations must not be negative"))
+	}
+
 	return errors.Join(errs...)
 }
 
@@ -41,6 +41,7 @@ backends:
     type: "mlx"
     url: "http://localhost:5555"
     timeout: 30s
+    drain: true
 ratelimit:
   requests_per_second: 5
   burst: 10
@@ -57,6 +58,7 @@ log:
 			Expect(cfg.Server.Port).To(Equal(9090))
 			Expect(cfg.Server.WriteTimeout).To(Equal(60 * time.Second))
 			Expect(cfg.Backends[0].Name).To(Equal("test-mlx"))
+			Expect(cfg.Backends[0].Drain).To(BeTrue())
 			Expect(cfg.RateLimit.Burst).To(Equal(10))
 			Expect(cfg.Log.Level).To(Equal("debug"))
 		})
@@ -108,6 +110,66 @@ log:
 		})
 	})
 
+	When("INFERENCIA_LB_STRATEGY is set", func() {
+		It("applies the strategy to every capability", func() {
+			_ = os.Setenv("INFERENCIA_LB_STRATEGY", "peak_ewma")
+			defer func() { _ = os.Unsetenv("INFERENCIA_LB_STRATEGY") }()
+
+			cfg, err := Load("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.LoadBalancing.Chat).To(Equal("peak_ewma"))
+			Expect(cfg.LoadBalancing.Embed).To(Equal("peak_ewma"))
+			Expect(cfg.LoadBalancing.TTS).To(Equal("peak_ewma"))
+		})
+	})
+
+	When("INFERENCIA_CIRCUIT_BREAKER_ENABLED is not set", func() {
+		It("leaves the circuit breaker off", func() {
+			cfg, err := Load("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.CircuitBreaker.Enabled).To(BeFalse())
+		})
+	})
+
+	When("INFERENCIA_CIRCUIT_BREAKER_ENABLED is true", func() {
+		It("enables the circuit breaker", func() {
+			_ = os.Setenv("INFERENCIA_CIRCUIT_BREAKER_ENABLED", "true")
+			defer func() { _ = os.Unsetenv("INFERENCIA_CIRCUIT_BREAKER_ENABLED") }()
+
+			cfg, err := Load("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.CircuitBreaker.Enabled).To(BeTrue())
+		})
+	})
+
+	When("INFERENCIA_ADMIN_KEYS is set", func() {
+		It("replaces the admin keys", func() {
+			_ = os.Setenv("INFERENCIA_ADMIN_KEYS", "sk-admin-1, ,sk-admin-2")
+			defer func() { _ = os.Unsetenv("INFERENCIA_ADMIN_KEYS") }()
+
+			cfg, err := Load("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Admin.Keys).To(Equal([]string{"sk-admin-1", "sk-admin-2"}))
+		})
+	})
+
+	When("INFERENCIA_CACHE_ENABLED and INFERENCIA_CACHE_DIR are set", func() {
+		It("enables the on-disk response cache", func() {
+			_ = os.Setenv("INFERENCIA_CACHE_ENABLED", "true")
+			_ = os.Setenv("INFERENCIA_CACHE_DIR", "/var/cache/inferencia")
+			defer func() {
+				_ = os.Unsetenv("INFERENCIA_CACHE_ENABLED")
+				_ = os.Unsetenv("INFERENCIA_CACHE_DIR")
+			}()
+
+			cfg, err := Load("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Cache.Enabled).To(BeTrue())
+			Expect(cfg.Cache.Dir).To(Equal("/var/cache/inferencia"))
+			Expect(cfg.Cache.TTL).To(Equal(time.Hour))
+		})
+	})
+
 	When("config file path does not exist", func() {
 		It("returns an error", func() {
 			_, err := Load("/nonexistent/config.yaml")
@@ -213,6 +275,166 @@ var _ = Describe("Validation", func() {
 		})
 	})
 
+	When("a load-balancing strategy is unknown", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.LoadBalancing.Chat = "fastest"
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("load_balancing.chat")))
+		})
+	})
+
+	When("a backend weight is negative", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.Backends[0].Weight = -1
+			Expect(validate(cfg)).To(HaveOccurred())
+		})
+	})
+
+	When("circuit breaker error rate is above 1", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.CircuitBreaker.ErrorRate = 1.5
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("circuit_breaker.error_rate")))
+		})
+	})
+
+	When("a backend max_concurrency is negative", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.Backends[0].MaxConcurrency = -1
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("max_concurrency")))
+		})
+	})
+
+	When("a reserved priority class is unknown", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.Priority.Reserved = map[string]int{"urgent": 1}
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("priority.reserved")))
+		})
+	})
+
+	When("a cache limit is negative", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.Cache.TTL = -time.Second
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("cache")))
+		})
+	})
+
+	When("the semantic cache threshold is out of range", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.SemanticCache.Enabled = true
+			cfg.SemanticCache.Threshold = 1.2
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("semantic_cache.threshold")))
+		})
+	})
+
+	When("a rerank backend has an unknown api", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.RerankBackends = []RerankBackend{{Name: "tei", URL: "http://localhost:8081", API: "jina"}}
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("rerank_backends[0].api")))
+		})
+	})
+
+	When("batch is enabled without workers", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.Batch.Enabled = true
+			cfg.Batch.Workers = 0
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("batch.workers")))
+		})
+	})
+
+	When("async is enabled with no ttl", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.Async.Enabled = true
+			cfg.Async.TTL = 0
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("async.ttl")))
+		})
+	})
+
+	When("resumable streams are enabled with no grace period", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.Streams.Enabled = true
+			cfg.Streams.Grace = 0
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("resumable_streams.grace")))
+		})
+	})
+
+	When("a streaming duration is negative", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.Streaming.IdleTimeout = -time.Second
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("streaming durations")))
+		})
+	})
+
+	When("a TTS backend lists an unknown format", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.TTSBackends = []TTSBackend{{Name: "kokoro", URL: "http://localhost:1", Formats: []string{"wav", "ogg"}}}
+			Expect(validate(cfg)).To(MatchError(ContainSubstring(`unknown format "ogg"`)))
+		})
+	})
+
+	When("realtime is enabled without an STT backend", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.Realtime.Enabled = true
+			cfg.TTSBackends = []TTSBackend{{Name: "kokoro", URL: "http://localhost:1"}}
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("realtime needs at least one stt_backends")))
+		})
+	})
+
+	When("the gRPC API shares the HTTP port", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.GRPC.Enabled = true
+			cfg.GRPC.Port = cfg.Server.Port
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("grpc.port must differ from server.port")))
+		})
+	})
+
+	When("a pronunciation lexicon has an empty term", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.TTS.Lexicons = map[string]map[string]string{"support": {"": "nothing"}}
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("tts.lexicons.support: terms must not be empty")))
+		})
+	})
+
+	When("the TTS cache is enabled without a directory", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.TTS.Cache.Enabled = true
+			cfg.TTS.Cache.Dir = ""
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("tts.cache.dir is required")))
+		})
+	})
+
+	When("the voice catalog TTL is not positive", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.TTS.VoiceCatalogTTL = 0
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("voice_catalog_ttl")))
+		})
+	})
+
+	When("long-form TTS segments exceed the per-request cap", func() {
+		It("returns an error", func() {
+			cfg := Defaults()
+			cfg.TTS.LongForm.Enabled = true
+			cfg.TTS.LongForm.SegmentLength = 5000
+			Expect(validate(cfg)).To(MatchError(ContainSubstring("segment_length")))
+		})
+	})
+
 	When("rate limit burst is zero", func() {
 		It("returns an error", func() {
 			cfg := Defaults()
@@ -0,0 +1,263 @@
+// Package embedbatch coalesces embedding requests. Concurrent requests for
+// the same model that arrive within a short window are combined into one
+// upstream call and the results are fanned back out; requests with more
+// inputs than the upstream accepts are split.
+//
+// A Batcher sends its calls with a SendFunc, so it can sit in front of
+// backend selection and have each combined call take a single backend slot.
+// New wraps one backend's embedding endpoint, which is how a backend's own
+// input limit is enforced.
+//
+// Only text inputs (a string or an array of strings) are batched. Token-array
+// inputs are sent as they are.
+package embedbatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/middleware"
+)
+
+// Config controls coalescing and splitting.
+type Config struct {
+	Window       time.Duration // how long a batch waits for more requests; 0 disables coalescing
+	MaxBatchSize int           // inputs per upstream call; 0 = unlimited
+}
+
+// SendFunc makes one upstream embedding call.
+type SendFunc func(ctx context.Context, req backend.EmbedRequest) (*backend.EmbedResponse, error)
+
+// Batcher coalesces and splits embedding requests, sending the upstream
+// calls with a SendFunc.
+type Batcher struct {
+	send SendFunc
+	cfg  Config
+
+	mu      sync.Mutex
+	pending map[batchKey]*batch
+}
+
+// batchKey groups requests that can share an upstream call.
+type batchKey struct {
+	model      string
+	format     string
+	dimensions int // 0 when the request does not set dimensions
+}
+
+// batch collects the requests coalesced into one upstream call.
+type batch struct {
+	key    batchKey
+	ctx    context.Context
+	calls  []*call
+	inputs int
+}
+
+type call struct {
+	inputs []string
+	done   chan result
+}
+
+type result struct {
+	resp *backend.EmbedResponse
+	err  error
+}
+
+// NewBatcher returns a Batcher that sends with send. With a zero Config it
+// passes every request through.
+func NewBatcher(send SendFunc, cfg Config) *Batcher {
+	return &Batcher{
+		send:    send,
+		cfg:     cfg,
+		pending: make(map[batchKey]*batch),
+	}
+}
+
+// Backend wraps a backend so that its embedding calls go through a Batcher.
+// Every other method is passed through unchanged.
+type Backend struct {
+	backend.Backend
+	batcher *Batcher
+}
+
+// New wraps b. With a zero Config every request is passed through.
+func New(b backend.Backend, cfg Config) *Backend {
+	w := &Backend{Backend: b}
+	w.batcher = NewBatcher(w.embed, cfg)
+	return w
+}
+
+// CreateEmbedding sends req to the wrapped backend through the Batcher.
+func (w *Backend) CreateEmbedding(ctx context.Context, req backend.EmbedRequest) (*backend.EmbedResponse, error) {
+	return w.batcher.CreateEmbedding(ctx, req)
+}
+
+// embed makes one upstream call on the wrapped backend.
+func (w *Backend) embed(ctx context.Context, req backend.EmbedRequest) (*backend.EmbedResponse, error) {
+	if inputs, ok := req.Texts(); ok {
+		middleware.EmbedBatchInputs.WithLabelValues(w.Name()).Observe(float64(len(inputs)))
+	}
+	return w.Backend.CreateEmbedding(ctx, req)
+}
+
+// CreateEmbedding splits req if it has more inputs than MaxBatchSize, and
+// otherwise queues it to be sent together with other requests for the same
+// model. The upstream call is not cancelled when one caller goes away, since
+// other callers may still be waiting on it.
+func (b *Batcher) CreateEmbedding(ctx context.Context, req backend.EmbedRequest) (*backend.EmbedResponse, error) {
+	inputs, ok := req.Texts()
+	if !ok {
+		return b.send(ctx, req)
+	}
+	if b.cfg.MaxBatchSize > 0 && len(inputs) > b.cfg.MaxBatchSize {
+		return b.split(ctx, req, inputs)
+	}
+	if b.cfg.Window <= 0 {
+		return b.send(ctx, req)
+	}
+	return b.coalesce(ctx, req, inputs)
+}
+
+// coalesce adds the request to the pending batch for its model and waits for
+// the batch's result.
+func (b *Batcher) coalesce(ctx context.Context, req backend.EmbedRequest, inputs []string) (*backend.EmbedResponse, error) {
+	c := &call{inputs: inputs, done: make(chan result, 1)}
+	key := batchKey{model: req.Model, format: req.EncodingFormat}
+	if req.Dimensions != nil {
+		key.dimensions = *req.Dimensions
+	}
+
+	b.mu.Lock()
+	bt := b.pending[key]
+	if bt != nil && b.cfg.MaxBatchSize > 0 && bt.inputs+len(inputs) > b.cfg.MaxBatchSize {
+		// No room left: send what we have and start a new batch.
+		delete(b.pending, key)
+		go b.flush(bt)
+		bt = nil
+	}
+	if bt == nil {
+		bt = &batch{key: key, ctx: context.WithoutCancel(ctx)}
+		b.pending[key] = bt
+		time.AfterFunc(b.cfg.Window, func() { b.flushIfPending(bt) })
+	}
+	bt.calls = append(bt.calls, c)
+	bt.inputs += len(inputs)
+	if b.cfg.MaxBatchSize > 0 && bt.inputs >= b.cfg.MaxBatchSize {
+		delete(b.pending, key)
+		go b.flush(bt)
+	}
+	b.mu.Unlock()
+
+	select {
+	case r := <-c.done:
+		return r.resp, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// flushIfPending sends bt when its window closes, unless it was already sent
+// because it filled up.
+func (b *Batcher) flushIfPending(bt *batch) {
+	b.mu.Lock()
+	if b.pending[bt.key] != bt {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, bt.key)
+	b.mu.Unlock()
+	b.flush(bt)
+}
+
+// flush sends one upstream request with the inputs of every call in bt and
+// hands each call its share of the embeddings and usage.
+func (b *Batcher) flush(bt *batch) {
+	var all []string
+	for _, c := range bt.calls {
+		all = append(all, c.inputs...)
+	}
+	middleware.EmbedBatchRequests.WithLabelValues(bt.key.model).Observe(float64(len(bt.calls)))
+
+	req := backend.EmbedRequest{Model: bt.key.model, EncodingFormat: bt.key.format}
+	if bt.key.dimensions > 0 {
+		req.Dimensions = &bt.key.dimensions
+	}
+	resp, err := b.sendTexts(bt.ctx, req, all)
+	if err != nil {
+		for _, c := range bt.calls {
+			c.done <- result{err: err}
+		}
+		return
+	}
+
+	tokens := backend.SplitPromptTokens(resp.Usage, all)
+	offset := 0
+	for _, c := range bt.calls {
+		out := &backend.EmbedResponse{Object: "list", Model: resp.Model, Usage: &backend.Usage{}}
+		for i := range c.inputs {
+			e := resp.Data[offset+i]
+			e.Index = i
+			out.Data = append(out.Data, e)
+			out.Usage.PromptTokens += tokens[offset+i]
+		}
+		out.Usage.TotalTokens = out.Usage.PromptTokens
+		offset += len(c.inputs)
+		c.done <- result{resp: out}
+	}
+}
+
+// split sends inputs in chunks of MaxBatchSize, one after another, and joins
+// the results in order.
+func (b *Batcher) split(ctx context.Context, req backend.EmbedRequest, inputs []string) (*backend.EmbedResponse, error) {
+	out := &backend.EmbedResponse{Object: "list", Model: req.Model, Usage: &backend.Usage{}}
+	for start := 0; start < len(inputs); start += b.cfg.MaxBatchSize {
+		chunk := inputs[start:min(start+b.cfg.MaxBatchSize, len(inputs))]
+		resp, err := b.sendTexts(ctx, req, chunk)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range resp.Data {
+			e.Index += start
+			out.Data = append(out.Data, e)
+		}
+		if resp.Usage != nil {
+			out.Usage.PromptTokens += resp.Usage.PromptTokens
+			out.Usage.TotalTokens += resp.Usage.TotalTokens
+		}
+		out.Model = resp.Model
+	}
+	return out, nil
+}
+
+// sendTexts embeds inputs in one upstream call and returns the embeddings
+// ordered by index.
+func (b *Batcher) sendTexts(ctx context.Context, req backend.EmbedRequest, inputs []string) (*backend.EmbedResponse, error) {
+	raw, err := json.Marshal(inputs)
+	if err != nil {
+		return nil, fmt.Errorf("marshal embedding batch: %w", err)
+	}
+	req.Input = raw
+
+	resp, err := b.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(inputs) {
+		return nil, fmt.Errorf("embedding batch: backend returned %d embeddings for %d inputs", len(resp.Data), len(inputs))
+	}
+	ordered := make([]backend.Embedding, len(inputs))
+	seen := make([]bool, len(inputs))
+	for _, e := range resp.Data {
+		if e.Index < 0 || e.Index >= len(inputs) || seen[e.Index] {
+			return nil, fmt.Errorf("embedding batch: backend returned invalid index %d", e.Index)
+		}
+		seen[e.Index] = true
+		ordered[e.Index] = e
+	}
+	resp.Data = ordered
+	return resp, nil
+}
@@ -0,0 +1,174 @@
+package embedbatch_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/embedbatch"
+)
+
+// fakeBackend embeds each text input to [len(input)] at one token per byte and
+// records the inputs of every call.
+type fakeBackend struct {
+	backend.Backend
+
+	mu    sync.Mutex
+	calls [][]string
+}
+
+func (f *fakeBackend) Name() string { return "fake" }
+
+func (f *fakeBackend) CreateEmbedding(_ context.Context, req backend.EmbedRequest) (*backend.EmbedResponse, error) {
+	inputs, ok := req.Texts()
+	if !ok {
+		inputs = []string{string(req.Input)}
+	}
+	f.mu.Lock()
+	f.calls = append(f.calls, inputs)
+	f.mu.Unlock()
+
+	resp := &backend.EmbedResponse{Object: "list", Model: req.Model, Usage: &backend.Usage{}}
+	// Reversed, to check that results are matched up by index.
+	for i := len(inputs) - 1; i >= 0; i-- {
+		resp.Data = append(resp.Data, backend.Embedding{Object: "embedding", Index: i, Embedding: []float64{float64(len(inputs[i]))}})
+		resp.Usage.PromptTokens += len(inputs[i])
+	}
+	resp.Usage.TotalTokens = resp.Usage.PromptTokens
+	return resp, nil
+}
+
+func (f *fakeBackend) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.calls)
+}
+
+func embedReq(model string, inputs ...string) backend.EmbedRequest {
+	var raw []byte
+	if len(inputs) == 1 {
+		raw, _ = json.Marshal(inputs[0])
+	} else {
+		raw, _ = json.Marshal(inputs)
+	}
+	return backend.EmbedRequest{Model: model, Input: raw}
+}
+
+var _ = Describe("Batcher", func() {
+	var fake *fakeBackend
+
+	BeforeEach(func() {
+		fake = &fakeBackend{}
+	})
+
+	embedConcurrently := func(b interface {
+		CreateEmbedding(context.Context, backend.EmbedRequest) (*backend.EmbedResponse, error)
+	}, reqs ...backend.EmbedRequest) []*backend.EmbedResponse {
+		out := make([]*backend.EmbedResponse, len(reqs))
+		var wg sync.WaitGroup
+		for i, req := range reqs {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				resp, err := b.CreateEmbedding(context.Background(), req)
+				Expect(err).NotTo(HaveOccurred())
+				out[i] = resp
+			}()
+		}
+		wg.Wait()
+		return out
+	}
+
+	It("coalesces concurrent requests for the same model into one call", func() {
+		b := embedbatch.New(fake, embedbatch.Config{Window: 50 * time.Millisecond})
+		resps := embedConcurrently(b,
+			embedReq("m", "a"),
+			embedReq("m", "bb", "ccc"),
+			embedReq("m", "dddd"),
+		)
+
+		Expect(fake.callCount()).To(Equal(1))
+		Expect(fake.calls[0]).To(ConsistOf("a", "bb", "ccc", "dddd"))
+
+		Expect(resps[1].Data).To(HaveLen(2))
+		Expect(resps[1].Data[0].Index).To(Equal(0))
+		Expect(resps[1].Data[0].Embedding).To(Equal(backend.Vector{2}))
+		Expect(resps[1].Data[1].Index).To(Equal(1))
+		Expect(resps[1].Data[1].Embedding).To(Equal(backend.Vector{3}))
+		Expect(resps[1].Usage.PromptTokens).To(Equal(5))
+		Expect(resps[2].Data[0].Embedding).To(Equal(backend.Vector{4}))
+	})
+
+	It("keeps models in separate batches", func() {
+		b := embedbatch.New(fake, embedbatch.Config{Window: 30 * time.Millisecond})
+		embedConcurrently(b, embedReq("m", "a"), embedReq("n", "b"))
+		Expect(fake.callCount()).To(Equal(2))
+	})
+
+	It("sends a batch as soon as it reaches the maximum size", func() {
+		b := embedbatch.New(fake, embedbatch.Config{Window: time.Hour, MaxBatchSize: 2})
+		resps := embedConcurrently(b, embedReq("m", "a"), embedReq("m", "b"))
+		Expect(fake.callCount()).To(Equal(1))
+		Expect(resps[0].Data).To(HaveLen(1))
+	})
+
+	It("splits requests larger than the maximum batch size", func() {
+		b := embedbatch.New(fake, embedbatch.Config{MaxBatchSize: 2})
+		resp, err := b.CreateEmbedding(context.Background(), embedReq("m", "a", "bb", "ccc", "dddd", "eeeee"))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(fake.calls).To(Equal([][]string{{"a", "bb"}, {"ccc", "dddd"}, {"eeeee"}}))
+		Expect(resp.Data).To(HaveLen(5))
+		for i, e := range resp.Data {
+			Expect(e.Index).To(Equal(i))
+			Expect(e.Embedding).To(Equal(backend.Vector{float64(i + 1)}))
+		}
+		Expect(resp.Usage.PromptTokens).To(Equal(15))
+	})
+
+	It("still answers other callers when one gives up", func() {
+		b := embedbatch.New(fake, embedbatch.Config{Window: 50 * time.Millisecond})
+		ctx, cancel := context.WithCancel(context.Background())
+		errc := make(chan error, 1)
+		go func() {
+			_, err := b.CreateEmbedding(ctx, embedReq("m", "a"))
+			errc <- err
+		}()
+		time.Sleep(5 * time.Millisecond)
+		cancel()
+		Expect(<-errc).To(MatchError(context.Canceled))
+
+		resp, err := b.CreateEmbedding(context.Background(), embedReq("m", "b"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Data[0].Embedding).To(Equal(backend.Vector{1}))
+		Expect(fake.callCount()).To(Equal(1))
+	})
+
+	It("sends coalesced calls with its SendFunc", func() {
+		var mu sync.Mutex
+		var sent []backend.EmbedRequest
+		b := embedbatch.NewBatcher(func(ctx context.Context, req backend.EmbedRequest) (*backend.EmbedResponse, error) {
+			mu.Lock()
+			sent = append(sent, req)
+			mu.Unlock()
+			return fake.CreateEmbedding(ctx, req)
+		}, embedbatch.Config{Window: 50 * time.Millisecond})
+		resps := embedConcurrently(b, embedReq("m", "a"), embedReq("m", "bb"))
+
+		Expect(sent).To(HaveLen(1))
+		Expect(resps[1].Data[0].Embedding).To(Equal(backend.Vector{2}))
+	})
+
+	It("passes token-array inputs straight through", func() {
+		b := embedbatch.New(fake, embedbatch.Config{Window: time.Hour, MaxBatchSize: 1})
+		_, err := b.CreateEmbedding(context.Background(), backend.EmbedRequest{Model: "m", Input: json.RawMessage(`[[1,2],[3]]`)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fake.callCount()).To(Equal(1))
+	})
+})
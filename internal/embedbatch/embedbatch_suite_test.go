@@ -0,0 +1,13 @@
+package embedbatch_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestEmbedbatch(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Embedbatch Suite")
+}
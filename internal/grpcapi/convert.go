@@ -0,0 +1,222 @@
+package grpcapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	inferenciav1 "github.com/menezmethod/inferencia/api/inferencia/v1"
+	"github.com/menezmethod/inferencia/internal/backend"
+)
+
+// chatRequest converts a chat request to the HTTP API's.
+func chatRequest(req *inferenciav1.ChatCompletionRequest, stream bool) (*backend.ChatRequest, error) {
+	out := &backend.ChatRequest{
+		Model:               req.GetModel(),
+		Temperature:         req.Temperature,
+		TopP:                req.TopP,
+		N:                   optionalInt(req.N),
+		MaxTokens:           optionalInt(req.MaxTokens),
+		MaxCompletionTokens: optionalInt(req.MaxCompletionTokens),
+		Stream:              stream,
+		PresencePenalty:     req.PresencePenalty,
+		FrequencyPenalty:    req.FrequencyPenalty,
+		User:                req.GetUser(),
+	}
+	if req.Seed != nil {
+		seed := int(req.GetSeed())
+		out.Seed = &seed
+	}
+	if len(req.GetStop()) > 0 {
+		out.Stop, _ = json.Marshal(req.GetStop())
+	}
+	for _, m := range req.GetMessages() {
+		out.Messages = append(out.Messages, message(m))
+	}
+	for _, t := range req.GetTools() {
+		fn := t.GetFunction()
+		tool := backend.Tool{Type: t.GetType(), Function: backend.ToolFunction{
+			Name:        fn.GetName(),
+			Description: fn.GetDescription(),
+			Parameters:  structJSON(fn.GetParameters()),
+		}}
+		if tool.Type == "" {
+			tool.Type = "function"
+		}
+		out.Tools = append(out.Tools, tool)
+	}
+	switch choice := req.GetToolChoice(); choice {
+	case "":
+	case "none", "auto", "required":
+		out.ToolChoice, _ = json.Marshal(choice)
+	default:
+		out.ToolChoice, _ = json.Marshal(map[string]any{"type": "function", "function": map[string]string{"name": choice}})
+	}
+	out.ResponseFormat = structJSON(req.GetResponseFormat())
+	if a := req.GetAudio(); a != nil {
+		if a.GetVoice() == "" {
+			return nil, status.Error(codes.InvalidArgument, "audio.voice is required")
+		}
+		out.Modalities = []string{"text", "audio"}
+		out.Audio = &backend.ChatAudio{Voice: a.GetVoice(), Format: a.GetFormat(), Model: a.GetModel()}
+	}
+	return out, nil
+}
+
+// message converts a conversation message to the HTTP API's, with text
+// content as a string and parts as an array of content parts.
+func message(m *inferenciav1.ChatMessage) backend.Message {
+	out := backend.Message{Role: m.GetRole(), Name: m.GetName(), ToolCallID: m.GetToolCallId()}
+	switch {
+	case len(m.GetParts()) > 0:
+		var parts []map[string]any
+		for _, p := range m.GetParts() {
+			switch v := p.GetPart().(type) {
+			case *inferenciav1.ContentPart_Text:
+				parts = append(parts, map[string]any{"type": "text", "text": v.Text})
+			case *inferenciav1.ContentPart_ImageUrl:
+				parts = append(parts, map[string]any{"type": "image_url", "image_url": map[string]string{"url": v.ImageUrl}})
+			}
+		}
+		out.Content, _ = json.Marshal(parts)
+	case m.GetContent() == "" && len(m.GetToolCalls()) > 0:
+		out.Content = json.RawMessage("null")
+	default:
+		out.Content, _ = json.Marshal(m.GetContent())
+	}
+	for _, tc := range m.GetToolCalls() {
+		call := backend.ToolCall{ID: tc.GetId(), Type: tc.GetType(), Function: backend.ToolCallFunction{
+			Name:      tc.GetFunction().GetName(),
+			Arguments: tc.GetFunction().GetArguments(),
+		}}
+		if call.Type == "" {
+			call.Type = "function"
+		}
+		out.ToolCalls = append(out.ToolCalls, call)
+	}
+	if a := m.GetAudio(); a.GetId() != "" {
+		out.Audio = &backend.MessageAudio{ID: a.GetId()}
+	}
+	return out
+}
+
+// chatCompletion converts a chat completion from the HTTP API's.
+func chatCompletion(resp *backend.ChatResponse) *inferenciav1.ChatCompletion {
+	out := &inferenciav1.ChatCompletion{Id: resp.ID, Created: resp.Created, Model: resp.Model, Usage: usage(resp.Usage)}
+	for _, c := range resp.Choices {
+		out.Choices = append(out.Choices, &inferenciav1.ChatChoice{
+			Index:        int32(c.Index),
+			Message:      chatMessage(c.Message),
+			FinishReason: finishReason(c.FinishReason),
+		})
+	}
+	return out
+}
+
+// chatCompletionChunk converts a stream chunk from the HTTP API's.
+func chatCompletionChunk(chunk *backend.ChatResponse) *inferenciav1.ChatCompletionChunk {
+	out := &inferenciav1.ChatCompletionChunk{Id: chunk.ID, Created: chunk.Created, Model: chunk.Model, Usage: usage(chunk.Usage)}
+	for _, c := range chunk.Choices {
+		out.Choices = append(out.Choices, &inferenciav1.ChatChunkChoice{
+			Index:        int32(c.Index),
+			Delta:        chatMessage(c.Delta),
+			FinishReason: finishReason(c.FinishReason),
+		})
+	}
+	return out
+}
+
+// chatMessage converts a generated message or delta from the HTTP API's.
+func chatMessage(m *backend.Message) *inferenciav1.ChatMessage {
+	if m == nil {
+		return nil
+	}
+	out := &inferenciav1.ChatMessage{Role: m.Role, Name: m.Name, ToolCallId: m.ToolCallID}
+	if json.Unmarshal(m.Content, &out.Content) != nil {
+		var parts []struct {
+			Type     string `json:"type"`
+			Text     string `json:"text"`
+			ImageURL struct {
+				URL string `json:"url"`
+			} `json:"image_url"`
+		}
+		_ = json.Unmarshal(m.Content, &parts)
+		for _, p := range parts {
+			switch p.Type {
+			case "text":
+				out.Parts = append(out.Parts, &inferenciav1.ContentPart{Part: &inferenciav1.ContentPart_Text{Text: p.Text}})
+			case "image_url":
+				out.Parts = append(out.Parts, &inferenciav1.ContentPart{Part: &inferenciav1.ContentPart_ImageUrl{ImageUrl: p.ImageURL.URL}})
+			}
+		}
+	}
+	for _, tc := range m.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, &inferenciav1.ToolCall{
+			Id:       tc.ID,
+			Type:     tc.Type,
+			Function: &inferenciav1.FunctionCall{Name: tc.Function.Name, Arguments: tc.Function.Arguments},
+		})
+	}
+	if a := m.Audio; a != nil {
+		data, _ := base64.StdEncoding.DecodeString(a.Data)
+		out.Audio = &inferenciav1.MessageAudio{Id: a.ID, Data: data, Transcript: a.Transcript}
+	}
+	return out
+}
+
+// embedRequest converts an embedding request to the HTTP API's.
+func embedRequest(req *inferenciav1.EmbeddingRequest) *backend.EmbedRequest {
+	input, _ := json.Marshal(req.GetInput())
+	return &backend.EmbedRequest{Model: req.GetModel(), Input: input, Dimensions: optionalInt(req.Dimensions)}
+}
+
+// embeddingResponse converts embeddings from the HTTP API's.
+func embeddingResponse(resp *backend.EmbedResponse) *inferenciav1.EmbeddingResponse {
+	out := &inferenciav1.EmbeddingResponse{Model: resp.Model, Usage: usage(resp.Usage)}
+	for _, d := range resp.Data {
+		vec := make([]float32, len(d.Embedding))
+		for i, v := range d.Embedding {
+			vec[i] = float32(v)
+		}
+		out.Data = append(out.Data, &inferenciav1.Embedding{Index: int32(d.Index), Embedding: vec})
+	}
+	return out
+}
+
+func usage(u *backend.Usage) *inferenciav1.Usage {
+	if u == nil {
+		return nil
+	}
+	return &inferenciav1.Usage{
+		PromptTokens:     int32(u.PromptTokens),
+		CompletionTokens: int32(u.CompletionTokens),
+		TotalTokens:      int32(u.TotalTokens),
+	}
+}
+
+func finishReason(r *string) string {
+	if r == nil {
+		return ""
+	}
+	return *r
+}
+
+func optionalInt(v *int32) *int {
+	if v == nil {
+		return nil
+	}
+	n := int(*v)
+	return &n
+}
+
+// structJSON returns s as JSON, or nil when s is unset.
+func structJSON(s *structpb.Struct) json.RawMessage {
+	if s == nil {
+		return nil
+	}
+	data, _ := s.MarshalJSON()
+	return data
+}
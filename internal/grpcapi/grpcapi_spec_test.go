@@ -0,0 +1,206 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	inferenciav1 "github.com/menezmethod/inferencia/api/inferencia/v1"
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/auth"
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/middleware"
+)
+
+// recordingHandler answers like an HTTP API handler and records the
+// requests it serves.
+type recordingHandler struct {
+	serve func(w http.ResponseWriter, body []byte)
+
+	mu   sync.Mutex
+	keys []string
+	reqs [][]byte
+}
+
+func (h *recordingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	h.mu.Lock()
+	h.keys = append(h.keys, middleware.APIKeyFromContext(r.Context()))
+	h.reqs = append(h.reqs, body)
+	h.mu.Unlock()
+	h.serve(w, body)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+var _ = Describe("Server", func() {
+	const key = "sk-test-key-12345678"
+	var (
+		chat   *recordingHandler
+		client inferenciav1.InferenceServiceClient
+		conn   *grpc.ClientConn
+		ctx    context.Context
+		burst  int
+	)
+
+	BeforeEach(func() {
+		burst = 100
+		stop := "stop"
+		chat = &recordingHandler{serve: func(w http.ResponseWriter, body []byte) {
+			var req backend.ChatRequest
+			_ = json.Unmarshal(body, &req)
+			if len(req.Messages) == 0 {
+				apierror.Write(w, apierror.InvalidParam("messages", "messages is required and must not be empty"))
+				return
+			}
+			if !req.Stream {
+				writeJSON(w, backend.ChatResponse{
+					ID:      "chatcmpl-1",
+					Model:   req.Model,
+					Choices: []backend.Choice{{Message: &backend.Message{Role: "assistant", Content: json.RawMessage(`"It is noon."`)}, FinishReason: &stop}},
+					Usage:   &backend.Usage{PromptTokens: 5, CompletionTokens: 3, TotalTokens: 8},
+				})
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			for _, c := range []string{"It is", " noon."} {
+				fmt.Fprintf(w, "data: {\"id\":\"chatcmpl-1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", c)
+			}
+			fmt.Fprint(w, ": keep-alive\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		}}
+	})
+
+	JustBeforeEach(func() {
+		ks, err := auth.NewKeyStoreFromKeys([]string{key})
+		Expect(err).NotTo(HaveOccurred())
+		embeddings := &recordingHandler{serve: func(w http.ResponseWriter, _ []byte) {
+			writeJSON(w, backend.EmbedResponse{Model: "nomic", Data: []backend.Embedding{{Index: 0, Embedding: backend.Vector{0.5, -0.25}}}})
+		}}
+		models := &recordingHandler{serve: func(w http.ResponseWriter, _ []byte) {
+			writeJSON(w, backend.ModelsResponse{Data: []backend.Model{{ID: "llama", OwnedBy: "mlx"}}})
+		}}
+		rl := middleware.NewRateLimiter(1, burst)
+		DeferCleanup(rl.Stop)
+
+		s := New(Handlers{Chat: chat, Embeddings: embeddings, Models: models}, ks, rl, true, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		lis := bufconn.Listen(1 << 20)
+		go func() { _ = s.Serve(lis) }()
+		DeferCleanup(s.Stop)
+
+		conn, err = grpc.NewClient("passthrough:///bufnet",
+			grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(conn.Close)
+		client = inferenciav1.NewInferenceServiceClient(conn)
+		ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+key)
+	})
+
+	question := &inferenciav1.ChatCompletionRequest{
+		Model:    "llama",
+		Messages: []*inferenciav1.ChatMessage{{Role: "user", Content: "What time is it?"}},
+	}
+
+	It("answers chat completions through the chat handler", func() {
+		resp, err := client.CreateChatCompletion(ctx, question)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.GetChoices()[0].GetMessage().GetContent()).To(Equal("It is noon."))
+		Expect(resp.GetChoices()[0].GetFinishReason()).To(Equal("stop"))
+		Expect(resp.GetUsage().GetTotalTokens()).To(BeEquivalentTo(8))
+
+		Expect(chat.keys).To(Equal([]string{key}))
+		var req backend.ChatRequest
+		Expect(json.Unmarshal(chat.reqs[0], &req)).To(Succeed())
+		Expect(req.Stream).To(BeFalse())
+		Expect(string(req.Messages[0].Content)).To(Equal(`"What time is it?"`))
+	})
+
+	It("streams chat completion chunks", func() {
+		stream, err := client.StreamChatCompletion(ctx, question)
+		Expect(err).NotTo(HaveOccurred())
+		var text string
+		for {
+			chunk, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+			text += chunk.GetChoices()[0].GetDelta().GetContent()
+		}
+		Expect(text).To(Equal("It is noon."))
+	})
+
+	It("serves embeddings and models", func() {
+		emb, err := client.CreateEmbedding(ctx, &inferenciav1.EmbeddingRequest{Model: "nomic", Input: []string{"hello"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(emb.GetData()[0].GetEmbedding()).To(Equal([]float32{0.5, -0.25}))
+
+		models, err := client.ListModels(ctx, &inferenciav1.ListModelsRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(models.GetModels()[0].GetId()).To(Equal("llama"))
+	})
+
+	It("maps handler errors to gRPC codes", func() {
+		_, err := client.CreateChatCompletion(ctx, &inferenciav1.ChatCompletionRequest{Model: "llama"})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(status.Convert(err).Message()).To(Equal("messages is required and must not be empty"))
+
+		_, err = client.CreateSpeech(ctx, &inferenciav1.SpeechRequest{Input: "hi"})
+		Expect(status.Code(err)).To(Equal(codes.Unimplemented))
+	})
+
+	It("rejects calls without a valid API key", func() {
+		_, err := client.ListModels(context.Background(), &inferenciav1.ListModelsRequest{})
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+
+		bad := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer sk-wrong")
+		_, err = client.ListModels(bad, &inferenciav1.ListModelsRequest{})
+		Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+	})
+
+	When("the key's rate limit is spent", func() {
+		BeforeEach(func() { burst = 1 })
+
+		It("rejects the call", func() {
+			_, err := client.ListModels(ctx, &inferenciav1.ListModelsRequest{})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = client.ListModels(ctx, &inferenciav1.ListModelsRequest{})
+			Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+		})
+	})
+
+	It("serves reflection without authentication", func() {
+		stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stream.Send(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+		})).To(Succeed())
+		resp, err := stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, s := range resp.GetListServicesResponse().GetService() {
+			names = append(names, s.GetName())
+		}
+		Expect(names).To(ContainElement("inferencia.v1.InferenceService"))
+	})
+})
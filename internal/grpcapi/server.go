@@ -0,0 +1,221 @@
+// Package grpcapi serves the inferencia gRPC API (api/inferencia/v1). Each
+// call runs in-process through the HTTP API's handlers, so routing, caches
+// and backend limits are shared; the server authenticates, prioritizes and
+// rate-limits calls itself, as the middleware does for HTTP requests.
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	inferenciav1 "github.com/menezmethod/inferencia/api/inferencia/v1"
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/auth"
+	"github.com/menezmethod/inferencia/internal/middleware"
+	"github.com/menezmethod/inferencia/internal/priority"
+)
+
+// priorityMetadata picks a priority class for a call, like the
+// X-Inferencia-Priority header.
+const priorityMetadata = "x-inferencia-priority"
+
+// Handlers are the HTTP API handlers calls run through, built like the
+// routes in server.New but without middleware. Speech is nil when no TTS
+// backends are configured.
+type Handlers struct {
+	Chat       http.Handler
+	Embeddings http.Handler
+	Models     http.Handler
+	Speech     http.Handler
+}
+
+// New returns a gRPC server for the inferencia API. Calls authenticate with
+// a Bearer token from ks in the authorization metadata and count against
+// rl's per-key limit. With withReflection, the server reflection service is
+// registered (without authentication) for tools like grpcurl.
+func New(h Handlers, ks *auth.KeyStore, rl *middleware.RateLimiter, withReflection bool, logger *slog.Logger) *grpc.Server {
+	g := &gate{ks: ks, rl: rl, logger: logger}
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(g.unary),
+		grpc.StreamInterceptor(g.stream),
+	)
+	inferenciav1.RegisterInferenceServiceServer(s, &service{h: h, logger: logger})
+	if withReflection {
+		reflection.Register(s)
+	}
+	return s
+}
+
+// Shutdown stops s gracefully, cutting off the calls still running when ctx
+// ends.
+func Shutdown(ctx context.Context, s *grpc.Server, logger *slog.Logger) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Error("grpc server shutdown timed out, stopping")
+		s.Stop()
+	}
+}
+
+// gate admits calls and records them: the gRPC counterpart of the Auth,
+// Priority, RateLimit, Metrics and Logging middleware.
+type gate struct {
+	ks     *auth.KeyStore
+	rl     *middleware.RateLimiter
+	logger *slog.Logger
+}
+
+func (g *gate) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if isReflection(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	start := time.Now()
+	ctx, err := g.admit(ctx)
+	var resp any
+	if err == nil {
+		resp, err = handler(ctx, req)
+	}
+	g.record(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func (g *gate) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if isReflection(info.FullMethod) {
+		return handler(srv, ss)
+	}
+	start := time.Now()
+	ctx, err := g.admit(ss.Context())
+	if err == nil {
+		err = handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+	g.record(ctx, info.FullMethod, start, err)
+	return err
+}
+
+// admit authenticates the call, assigns its priority class and takes a token
+// from its key's rate limit. The returned context carries the key and class.
+func (g *gate) admit(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	key, ok := middleware.BearerToken(first(md, "authorization"))
+	if !ok {
+		return ctx, status.Error(codes.Unauthenticated, "Missing or malformed authorization metadata. Expected: Bearer <api_key>")
+	}
+	if err := g.ks.Validate(key); err != nil {
+		return ctx, status.Error(codes.Unauthenticated, "Invalid API key.")
+	}
+	ctx = middleware.WithAPIKey(ctx, key)
+
+	policy := g.ks.Policy(key)
+	class := policy.Priority
+	if v := first(md, priorityMetadata); v != "" {
+		requested, err := priority.Parse(v)
+		if err != nil {
+			return ctx, status.Error(codes.InvalidArgument, "Invalid "+priorityMetadata+" metadata: "+err.Error())
+		}
+		if !policy.Allows(requested) {
+			return ctx, status.Error(codes.PermissionDenied, "This API key may not use priority class "+requested.String()+".")
+		}
+		class = requested
+	}
+
+	if _, ok := g.rl.Allow(key); !ok {
+		middleware.RateLimitRejections.Inc()
+		return ctx, status.Error(codes.ResourceExhausted, apierror.RateLimited().Message)
+	}
+	return priority.WithClass(ctx, class), nil
+}
+
+// record counts the call and writes its canonical log line.
+func (g *gate) record(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	elapsed := time.Since(start)
+	middleware.GRPCRequestsTotal.WithLabelValues(method, code.String()).Inc()
+	middleware.GRPCRequestDuration.WithLabelValues(method).Observe(elapsed.Seconds())
+
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Int64("duration_ms", elapsed.Milliseconds()),
+	}
+	if key := middleware.APIKeyFromContext(ctx); key != "" {
+		attrs = append(attrs, slog.String("api_key", middleware.MaskKey(key)))
+	}
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK, codes.Canceled:
+	case codes.Internal, codes.Unavailable, codes.Unknown, codes.DeadlineExceeded:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+	g.logger.LogAttrs(ctx, level, "grpc request", attrs...)
+}
+
+// serverStream is a ServerStream with the context admit returned.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
+
+func isReflection(method string) bool {
+	return strings.HasPrefix(method, "/grpc.reflection.")
+}
+
+func first(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// errorStatus returns the gRPC status for a handler's error response: the
+// code matching the HTTP status, with the OpenAI error's message.
+func errorStatus(code int, body []byte) error {
+	msg := strings.TrimSpace(string(body))
+	if apiErr, ok := decodeError(body); ok {
+		msg = apiErr.Message
+	}
+	return status.Error(grpcCode(code), msg)
+}
+
+// grpcCode maps an HTTP status to a gRPC code.
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	if httpStatus >= 500 {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
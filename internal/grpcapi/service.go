@@ -0,0 +1,239 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	inferenciav1 "github.com/menezmethod/inferencia/api/inferencia/v1"
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/backend"
+)
+
+// service implements InferenceService by running each call through the
+// HTTP handler of the matching endpoint.
+type service struct {
+	inferenciav1.UnimplementedInferenceServiceServer
+	h      Handlers
+	logger *slog.Logger
+}
+
+func (s *service) CreateChatCompletion(ctx context.Context, req *inferenciav1.ChatCompletionRequest) (*inferenciav1.ChatCompletion, error) {
+	body, err := chatRequest(req, false)
+	if err != nil {
+		return nil, err
+	}
+	var resp backend.ChatResponse
+	if err := s.call(ctx, s.h.Chat, http.MethodPost, "/v1/chat/completions", body, &resp); err != nil {
+		return nil, err
+	}
+	return chatCompletion(&resp), nil
+}
+
+func (s *service) StreamChatCompletion(req *inferenciav1.ChatCompletionRequest, stream grpc.ServerStreamingServer[inferenciav1.ChatCompletionChunk]) error {
+	body, err := chatRequest(req, true)
+	if err != nil {
+		return err
+	}
+	ctx := stream.Context()
+	r, err := newRequest(ctx, http.MethodPost, "/v1/chat/completions", body)
+	if err != nil {
+		return err
+	}
+
+	w := &eventWriter{header: make(http.Header)}
+	w.send = func(data []byte) error {
+		if string(data) == "[DONE]" {
+			w.done = true
+			return nil
+		}
+		if apiErr, ok := decodeError(data); ok {
+			w.err = status.Error(eventCode(apiErr), apiErr.Message)
+			return nil
+		}
+		var chunk backend.ChatResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			s.logger.Warn("skipping malformed stream chunk", "err", err)
+			return nil
+		}
+		return stream.Send(chatCompletionChunk(&chunk))
+	}
+	s.h.Chat.ServeHTTP(w, r)
+
+	switch {
+	case w.status != http.StatusOK:
+		return errorStatus(w.status, w.buf.Bytes())
+	case w.err != nil:
+		return w.err
+	case w.done:
+		return nil
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return status.Error(codes.Unavailable, "The stream ended before the answer was complete.")
+}
+
+func (s *service) CreateEmbedding(ctx context.Context, req *inferenciav1.EmbeddingRequest) (*inferenciav1.EmbeddingResponse, error) {
+	var resp backend.EmbedResponse
+	if err := s.call(ctx, s.h.Embeddings, http.MethodPost, "/v1/embeddings", embedRequest(req), &resp); err != nil {
+		return nil, err
+	}
+	return embeddingResponse(&resp), nil
+}
+
+func (s *service) ListModels(ctx context.Context, _ *inferenciav1.ListModelsRequest) (*inferenciav1.ListModelsResponse, error) {
+	var resp backend.ModelsResponse
+	if err := s.call(ctx, s.h.Models, http.MethodGet, "/v1/models", nil, &resp); err != nil {
+		return nil, err
+	}
+	out := &inferenciav1.ListModelsResponse{}
+	for _, m := range resp.Data {
+		out.Models = append(out.Models, &inferenciav1.Model{Id: m.ID, Created: m.Created, OwnedBy: m.OwnedBy})
+	}
+	return out, nil
+}
+
+func (s *service) CreateSpeech(ctx context.Context, req *inferenciav1.SpeechRequest) (*inferenciav1.SpeechResponse, error) {
+	if s.h.Speech == nil {
+		return nil, status.Error(codes.Unimplemented, "Speech is not available: the server has no TTS backends.")
+	}
+	body := backend.TTSRequest{
+		Model:          req.GetModel(),
+		Input:          req.GetInput(),
+		Voice:          req.GetVoice(),
+		ResponseFormat: req.GetResponseFormat(),
+		Speed:          req.GetSpeed(),
+	}
+	w, err := s.serve(ctx, s.h.Speech, http.MethodPost, "/v1/audio/speech", body)
+	if err != nil {
+		return nil, err
+	}
+	return &inferenciav1.SpeechResponse{Audio: w.buf.Bytes(), ContentType: w.header.Get("Content-Type")}, nil
+}
+
+// call serves a request with h and decodes its JSON response into out.
+func (s *service) call(ctx context.Context, h http.Handler, method, path string, body, out any) error {
+	w, err := s.serve(ctx, h, method, path, body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(w.buf.Bytes(), out); err != nil {
+		s.logger.Error("failed to decode handler response", "path", path, "err", err)
+		return status.Error(codes.Internal, "Failed to decode the response.")
+	}
+	return nil
+}
+
+// serve serves a request with h and returns its successful response. Error
+// responses become the matching gRPC status, and a Retry-After header the
+// call's retry-after metadata.
+func (s *service) serve(ctx context.Context, h http.Handler, method, path string, body any) (*eventWriter, error) {
+	r, err := newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	w := &eventWriter{header: make(http.Header)}
+	h.ServeHTTP(w, r)
+	if w.status >= 300 {
+		if v := w.header.Get("Retry-After"); v != "" {
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", v))
+		}
+		return nil, errorStatus(w.status, w.buf.Bytes())
+	}
+	return w, nil
+}
+
+// newRequest builds the in-process HTTP request for a call. The context
+// carries the caller's API key and priority class.
+func newRequest(ctx context.Context, method, path string, body any) (*http.Request, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "Invalid request: "+err.Error())
+		}
+	}
+	r, err := http.NewRequestWithContext(ctx, method, path, bytes.NewReader(data))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to start the request.")
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	return r, nil
+}
+
+// eventWriter is the ResponseWriter calls are served to. The response is
+// buffered; with send set, the data of each SSE event of a successful
+// stream is passed to send instead as soon as it is written.
+type eventWriter struct {
+	header http.Header
+	status int
+	buf    bytes.Buffer
+	send   func(data []byte) error
+	done   bool  // the stream ended with "[DONE]"
+	err    error // the stream's error event
+}
+
+func (w *eventWriter) Header() http.Header { return w.header }
+
+func (w *eventWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *eventWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	w.buf.Write(p)
+	if w.send == nil || w.status != http.StatusOK || !strings.HasPrefix(w.header.Get("Content-Type"), "text/event-stream") {
+		return len(p), nil
+	}
+	for {
+		i := bytes.Index(w.buf.Bytes(), []byte("\n\n"))
+		if i < 0 {
+			return len(p), nil
+		}
+		for _, line := range bytes.Split(w.buf.Next(i+2), []byte("\n")) {
+			if data, ok := bytes.CutPrefix(line, []byte("data: ")); ok {
+				if err := w.send(data); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+}
+
+// Flush is a no-op: events are passed on as they are written.
+func (w *eventWriter) Flush() {}
+
+// decodeError returns the OpenAI error in an error response or event.
+func decodeError(data []byte) (*apierror.Error, bool) {
+	var body struct {
+		Error *apierror.Error `json:"error"`
+	}
+	if json.Unmarshal(data, &body) != nil || body.Error == nil {
+		return nil, false
+	}
+	return body.Error, true
+}
+
+// eventCode returns the gRPC code for an error event ending a stream.
+func eventCode(apiErr *apierror.Error) codes.Code {
+	switch apiErr.Code {
+	case "first_chunk_timeout", "idle_timeout", "max_duration":
+		return codes.DeadlineExceeded
+	}
+	if apiErr.Type == apierror.TypeInvalidRequest {
+		return codes.InvalidArgument
+	}
+	return codes.Internal
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/auth"
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/middleware"
+	"github.com/menezmethod/inferencia/internal/router"
+	"github.com/menezmethod/inferencia/internal/semcache"
+)
+
+// BackendDrainStatus describes one backend's place in rotation.
+type BackendDrainStatus struct {
+	Name     string             `json:"name"`
+	Type     string             `json:"type"`  // "chat", "tts", "rerank" or "stt"
+	State    backend.DrainState `json:"state"` // active, draining, or drained
+	InFlight int                `json:"in_flight"`
+}
+
+// AdminBackendsResponse is the JSON response for GET /admin/backends.
+type AdminBackendsResponse struct {
+	Object string               `json:"object"`
+	Data   []BackendDrainStatus `json:"data"`
+}
+
+// AdminBackends lists every backend with its drain state and in-flight count.
+//
+//	GET /admin/backends
+func AdminBackends(reg *backend.Registry, ttsReg *router.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var data []BackendDrainStatus
+		for _, b := range reg.All() {
+			data = append(data, drainStatus(reg, ttsReg, b.Name()))
+		}
+		if ttsReg != nil {
+			for _, info := range ttsReg.All() {
+				data = append(data, drainStatus(reg, ttsReg, info.Name))
+			}
+		}
+		sort.Slice(data, func(i, j int) bool { return data[i].Name < data[j].Name })
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(AdminBackendsResponse{Object: "list", Data: data})
+	}
+}
+
+// DrainBackend takes a backend out of rotation. New requests go elsewhere while
+// in-flight requests finish; the response reports whether it is already drained.
+//
+//	POST /admin/backends/{name}/drain
+func DrainBackend(reg *backend.Registry, ttsReg *router.Registry, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if !setDrain(reg, ttsReg, name, true) {
+			apierror.Write(w, apierror.NotFound("Backend "+name+" does not exist."))
+			return
+		}
+		logger.Info("backend drain requested", "backend", name, "source", "admin_api")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(drainStatus(reg, ttsReg, name))
+	}
+}
+
+// ResumeBackend puts a draining or drained backend back into rotation.
+//
+//	POST /admin/backends/{name}/resume
+func ResumeBackend(reg *backend.Registry, ttsReg *router.Registry, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if !setDrain(reg, ttsReg, name, false) {
+			apierror.Write(w, apierror.NotFound("Backend "+name+" does not exist."))
+			return
+		}
+		logger.Info("backend resume requested", "backend", name, "source", "admin_api")
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(drainStatus(reg, ttsReg, name))
+	}
+}
+
+// setDrain drains (drain=true) or resumes the named backend in whichever
+// registry holds it. It reports false if no registry knows the name.
+func setDrain(reg *backend.Registry, ttsReg *router.Registry, name string, drain bool) bool {
+	if _, err := reg.Get(name); err == nil {
+		if drain {
+			_ = reg.Drain(name)
+		} else {
+			_ = reg.Resume(name)
+		}
+		return true
+	}
+	if ttsReg == nil {
+		return false
+	}
+	if drain {
+		return ttsReg.Drain(name) == nil
+	}
+	return ttsReg.Resume(name) == nil
+}
+
+func drainStatus(reg *backend.Registry, ttsReg *router.Registry, name string) BackendDrainStatus {
+	if _, err := reg.Get(name); err == nil {
+		return BackendDrainStatus{
+			Name:     name,
+			Type:     "chat",
+			State:    reg.DrainState(name),
+			InFlight: reg.Balancer().InFlight(name),
+		}
+	}
+	info, _ := ttsReg.Get(name)
+	return BackendDrainStatus{
+		Name:     name,
+		Type:     routerBackendType(info),
+		State:    ttsReg.DrainState(name),
+		InFlight: ttsReg.Balancer().InFlight(name),
+	}
+}
+
+// routerBackendType names what a router backend serves, checking in the order
+// its health probe is chosen.
+func routerBackendType(info router.BackendInfo) string {
+	switch {
+	case info.TTSBackend != nil:
+		return router.CapTTS.String()
+	case info.RerankBackend != nil:
+		return router.CapRerank.String()
+	case info.STTBackend != nil:
+		return router.CapSTT.String()
+	default:
+		return "unknown"
+	}
+}
+
+// SemanticCachePurgeRequest selects the semantic cache entries to purge. Empty
+// fields match everything, so an empty body purges the whole cache.
+type SemanticCachePurgeRequest struct {
+	APIKey string `json:"api_key,omitempty"`
+	Model  string `json:"model,omitempty"`
+}
+
+// SemanticCachePurgeResponse is the JSON response for a semantic cache purge.
+type SemanticCachePurgeResponse struct {
+	Purged  int `json:"purged"`
+	Entries int `json:"entries"` // entries left
+}
+
+// SemanticCacheStats reports the semantic cache's size and lookup counts.
+//
+//	GET /admin/cache/semantic
+func SemanticCacheStats(c *semcache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(c.Stats())
+	}
+}
+
+// PurgeSemanticCache removes cached answers, optionally only those of one API
+// key and/or model.
+//
+//	POST /admin/cache/semantic/purge
+func PurgeSemanticCache(c *semcache.Cache, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req SemanticCachePurgeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			apierror.Write(w, apierror.InvalidRequest("Invalid JSON in request body: "+err.Error()))
+			return
+		}
+
+		var keyID string
+		if req.APIKey != "" {
+			keyID = auth.KeyID(req.APIKey)
+		}
+		n := c.Purge(keyID, req.Model)
+		middleware.SemanticCacheEntries.Set(float64(c.Len()))
+		logger.Info("semantic cache purged", "entries", n, "key_id", keyID, "model", req.Model, "source", "admin_api")
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(SemanticCachePurgeResponse{Purged: n, Entries: c.Len()})
+	}
+}
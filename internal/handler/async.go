@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/async"
+	"github.com/menezmethod/inferencia/internal/middleware"
+)
+
+// webhookHeader names the URL an async job's result is POSTed to.
+const webhookHeader = "X-Inferencia-Webhook"
+
+// SubmitAsync runs every request as an async job served by next as if sent to
+// endpoint, answering 202 with the job.
+//
+//	POST /v1/async/chat/completions
+func SubmitAsync(m *async.Manager, endpoint string, next http.Handler, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		submitAsync(w, r, m, endpoint, next, logger)
+	}
+}
+
+// GetAsyncJob returns an async job: its status and, once finished, the
+// endpoint's response. Finished jobs are kept for the configured TTL.
+//
+//	GET /v1/async/{id}
+func GetAsyncJob(m *async.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		j, err := m.Job(middleware.APIKeyFromContext(r.Context()), r.PathValue("id"))
+		if err != nil {
+			apierror.Write(w, apierror.NotFound("No such async job: "+r.PathValue("id")))
+			return
+		}
+		writeJSON(w, j)
+	}
+}
+
+// submitAsync validates r and starts it as a job on m.
+func submitAsync(w http.ResponseWriter, r *http.Request, m *async.Manager, endpoint string, next http.Handler, logger *slog.Logger) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		apierror.Write(w, apierror.InvalidRequest("Failed to read request body: "+err.Error()))
+		return
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		apierror.Write(w, apierror.InvalidRequest("Invalid JSON in request body: the body must be a JSON object"))
+		return
+	}
+	if string(fields["stream"]) == "true" {
+		apierror.Write(w, apierror.InvalidParam("stream", "Streaming is not supported for async requests."))
+		return
+	}
+	webhook := r.Header.Get(webhookHeader)
+	if webhook != "" {
+		if err := m.CheckWebhook(webhook); err != nil {
+			apierror.Write(w, apierror.InvalidParam(webhookHeader, err.Error()))
+			return
+		}
+	}
+
+	j, err := m.Submit(r, endpoint, body, next, webhook)
+	if err != nil {
+		if errors.Is(err, async.ErrTooManyJobs) {
+			apierror.Write(w, apierror.TooManyJobs())
+			return
+		}
+		logger.Error("failed to submit async job", "err", err)
+		apierror.Write(w, apierror.Internal("Failed to submit async job."))
+		return
+	}
+	logger.Info("async job submitted", "job", j.ID, "endpoint", endpoint, "webhook", webhook != "")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/v1/async/"+j.ID)
+	w.Header().Set("Preference-Applied", "respond-async")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(j)
+}
@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -12,6 +15,8 @@ import (
 	"github.com/menezmethod/inferencia/internal/backend"
 	"github.com/menezmethod/inferencia/internal/middleware"
 	"github.com/menezmethod/inferencia/internal/router"
+	"github.com/menezmethod/inferencia/internal/speech"
+	"github.com/menezmethod/inferencia/internal/voices"
 )
 
 // Default TTS model fallback.
@@ -27,8 +32,16 @@ const (
 //	POST /v1/audio/speech
 //
 // Uses the router registry to select the appropriate TTS backend.
-// Accepts the standard OpenAI-compatible TTS request body.
-func Audio(rtr *router.Registry, hc backend.HealthChecker, logger *slog.Logger) http.HandlerFunc {
+// Accepts the standard OpenAI-compatible TTS request body. With
+// "stream": true, audio is sent as it is synthesized (see streamSpeech).
+// With WithLongSpeech, input beyond the usual cap is synthesized in segments
+// (see longSpeech). Input may be SSML (see speech.Script); with pauses or
+// rate changes it is synthesized in pieces (see scriptedSpeech). With
+// WithSpeechCache, audio for input within the usual
+// cap is served from and stored in the cache; streamed requests are served
+// from it but not stored.
+func Audio(rtr *router.Registry, hc backend.HealthChecker, logger *slog.Logger, opts ...Option) http.HandlerFunc {
+	o := newOptions(opts)
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req backend.TTSRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -40,37 +53,19 @@ func Audio(rtr *router.Registry, hc backend.HealthChecker, logger *slog.Logger)
 			apierror.Write(w, apierror.InvalidParam("input", "input is required and must not be empty"))
 			return
 		}
-		if len(req.Input) > maxTTSInputLength {
-			apierror.Write(w, apierror.InvalidParam("input", "input must be at most 4096 characters"))
+		if len(req.Input) > maxTTSInputLength && len(req.Input) > o.longSpeech.maxInput {
+			limit := max(maxTTSInputLength, o.longSpeech.maxInput)
+			apierror.Write(w, apierror.InvalidParam("input", fmt.Sprintf("input must be at most %d characters", limit)))
 			return
 		}
 
-		// Apply defaults.
-		if strings.TrimSpace(req.Model) == "" {
+		// Apply defaults. With a voice catalog, a request that names a voice
+		// but no model goes to whichever backend offers the voice.
+		req.Voice = strings.TrimSpace(req.Voice)
+		if strings.TrimSpace(req.Model) == "" && (o.voices == nil || req.Voice == "") {
 			req.Model = defaultTTSModel
 		}
 
-		info, err := rtr.SelectHealthyBackend(router.CapTTS, req.Model, hc)
-		if err != nil {
-			logger.Error("no TTS backend available", "err", err)
-			apierror.Write(w, apierror.BackendUnavailable(req.Model))
-			return
-		}
-		defer rtr.ReleaseBackend(info.Name)
-
-		// Apply voice default based on the selected backend.
-		// Each TTS backend has its own set of known voices.
-		if strings.TrimSpace(req.Voice) == "" {
-			switch info.Name {
-			case "kokoro":
-				req.Voice = "af_bella"
-			case "chatterbox":
-				req.Voice = "chatterbox-default"
-			default:
-				req.Voice = "default"
-			}
-		}
-
 		// Default omitted speed (JSON unmarshals to 0) before clamping.
 		if req.Speed <= 0 {
 			req.Speed = 1.0
@@ -82,25 +77,81 @@ func Audio(rtr *router.Registry, hc backend.HealthChecker, logger *slog.Logger)
 			req.Speed = 4.0
 		}
 
-		middleware.RoutingDecisionsTotal.WithLabelValues("tts", info.Name).Inc()
+		// SSML and the pronunciation lexicon become plain text; pauses and
+		// rate changes are synthesized piece by piece.
+		parts, err := speech.Script(req.Input, o.lexiconFor(r.Context()))
+		if err != nil {
+			apierror.Write(w, apierror.InvalidParam("input", err.Error()))
+			return
+		}
+		if !slices.ContainsFunc(parts, func(p speech.Part) bool { return p.Text != "" }) {
+			apierror.Write(w, apierror.InvalidParam("input", "input has no text to speak"))
+			return
+		}
+		if len(parts) > 1 || parts[0].Pause > 0 || parts[0].Rate != 1 {
+			scriptedSpeech(w, r, rtr, hc, req, parts, o, logger)
+			return
+		}
+		req.Input = parts[0].Text
+
+		if len(req.Input) > maxTTSInputLength && o.longSpeech.maxInput > 0 {
+			longSpeech(w, r, rtr, hc, req, o, logger)
+			return
+		}
+
+		only, ok := routeVoice(w, r, o.voices, req)
+		if !ok {
+			return
+		}
+
+		// The cache is keyed by the model and voice the backend would be
+		// sent, so they are resolved first, but a hit needs no backend slot.
+		var cached *cachedSpeech
+		resolved := req
+		if o.speechCache != nil && resolveTTS(r.Context(), rtr, o.voices, only, &resolved) {
+			var hit bool
+			if cached, hit = lookupSpeech(o.speechCache, w, r, resolved, logger); hit {
+				return
+			}
+		}
+
+		info, err := admitTTS(r.Context(), rtr, hc, o.voices, only, &req, logger)
+		if err != nil {
+			writeAdmitTTSError(w, rtr, req.Model, err)
+			return
+		}
+		defer rtr.ReleaseBackend(info.Name)
 
 		if info.TTSBackend == nil {
 			logger.Error("selected backend has no TTS backend", "name", info.Name)
+			backend.ReportOutcome(hc, info.Name, backend.ErrBackendNotFound)
 			apierror.Write(w, apierror.BackendUnavailable(info.Name))
 			return
 		}
+		// A backend that fills in another model or voice produces audio
+		// for another key.
+		if req.Model != resolved.Model || req.Voice != resolved.Voice {
+			cached = nil
+		}
+
+		if req.Stream {
+			streamSpeech(w, r, rtr, hc, info, req, logger)
+			return
+		}
 
 		start := time.Now()
 		resp, err := info.TTSBackend.Synthesize(r.Context(), req)
 		elapsed := time.Since(start)
+		backend.ReportOutcome(hc, info.Name, err)
 
 		if err != nil {
 			middleware.TTSRequestsTotal.WithLabelValues(info.Name, "error").Inc()
 			logger.Error("tts synthesis failed", "backend", info.Name, "err", err)
-			apierror.Write(w, apierror.FromBackendError(info.Name, err))
+			apierror.Write(w, ttsError(info.Name, err))
 			return
 		}
 
+		rtr.ObserveLatency(router.CapTTS, info.Name, elapsed)
 		middleware.TTSRequestsTotal.WithLabelValues(info.Name, "success").Inc()
 		middleware.TTSRequestDuration.WithLabelValues(info.Name).Observe(elapsed.Seconds())
 		middleware.TTSCharactersTotal.WithLabelValues(info.Name).Add(float64(len(req.Input)))
@@ -111,6 +162,8 @@ func Audio(rtr *router.Registry, hc backend.HealthChecker, logger *slog.Logger)
 			contentType = mimeTypeFromFormat(req.ResponseFormat)
 		}
 
+		cached.save(contentType, resp.Audio)
+
 		w.Header().Set("Content-Type", contentType)
 		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(resp.Audio)))
 		if _, err := w.Write(resp.Audio); err != nil {
@@ -119,6 +172,169 @@ func Audio(rtr *router.Registry, hc backend.HealthChecker, logger *slog.Logger)
 	}
 }
 
+// routeVoice returns the backends that offer req's voice, or nil when any
+// backend will do. It answers 400 with suggestions and returns false when
+// the catalog knows no such voice.
+func routeVoice(w http.ResponseWriter, r *http.Request, catalog *voices.Catalog, req backend.TTSRequest) ([]string, bool) {
+	if catalog == nil {
+		return nil, true
+	}
+	only, err := catalog.Route(r.Context(), req.Model, req.Voice)
+	var unknown *voices.UnknownVoiceError
+	if errors.As(err, &unknown) {
+		apierror.Write(w, apierror.UnknownVoice(unknown.Voice, unknown.Model, unknown.Suggestions))
+		return nil, false
+	}
+	return only, true
+}
+
+// admitTTS selects and admits a TTS backend for req among only (any, if
+// nil), filling in the model and voice when req names none. The caller must
+// release the backend.
+func admitTTS(ctx context.Context, rtr *router.Registry, hc backend.HealthChecker, catalog *voices.Catalog, only []string, req *backend.TTSRequest, logger *slog.Logger) (router.BackendInfo, error) {
+	info, err := rtr.AdmitHealthyBackendAmong(ctx, router.CapTTS, req.Model, only, hc)
+	if err != nil {
+		logger.Error("no TTS backend available", "err", err)
+		return info, err
+	}
+
+	if req.Model == "" && len(info.Models) > 0 {
+		req.Model = info.Models[0].ID
+	}
+	if req.Voice == "" {
+		req.Voice = defaultVoice(ctx, info, catalog)
+	}
+
+	middleware.RoutingDecisionsTotal.WithLabelValues("tts", info.Name).Inc()
+	return info, nil
+}
+
+// resolveTTS fills in the model and voice of req as admitTTS would if it
+// chose the first backend among only (any, if nil) that serves req, without
+// admitting it. It reports false when no such backend is registered.
+func resolveTTS(ctx context.Context, rtr *router.Registry, catalog *voices.Catalog, only []string, req *backend.TTSRequest) bool {
+	for _, info := range rtr.BackendsByCapability(router.CapTTS) {
+		if only != nil && !slices.Contains(only, info.Name) {
+			continue
+		}
+		if req.Model != "" && !info.Serves(router.CapTTS, req.Model) {
+			continue
+		}
+		if req.Model == "" && len(info.Models) > 0 {
+			req.Model = info.Models[0].ID
+		}
+		if req.Voice == "" {
+			req.Voice = defaultVoice(ctx, info, catalog)
+		}
+		return true
+	}
+	return false
+}
+
+// synthesize speaks req on a TTS backend chosen as for /v1/audio/speech,
+// recording the usual metrics, for callers that need the audio rather than
+// a response. req's model defaults as in Audio. Errors are *apierror.Error.
+func synthesize(ctx context.Context, rtr *router.Registry, hc backend.HealthChecker, catalog *voices.Catalog, req backend.TTSRequest, logger *slog.Logger) (*backend.TTSResponse, error) {
+	if req.Model == "" && (catalog == nil || req.Voice == "") {
+		req.Model = defaultTTSModel
+	}
+	var only []string
+	if catalog != nil {
+		var err error
+		only, err = catalog.Route(ctx, req.Model, req.Voice)
+		var unknown *voices.UnknownVoiceError
+		if errors.As(err, &unknown) {
+			return nil, apierror.UnknownVoice(unknown.Voice, unknown.Model, unknown.Suggestions)
+		}
+	}
+	info, err := admitTTS(ctx, rtr, hc, catalog, only, &req, logger)
+	if err != nil {
+		return nil, admitTTSError(req.Model, err)
+	}
+	defer rtr.ReleaseBackend(info.Name)
+	if info.TTSBackend == nil {
+		logger.Error("selected backend has no TTS backend", "name", info.Name)
+		backend.ReportOutcome(hc, info.Name, backend.ErrBackendNotFound)
+		return nil, apierror.BackendUnavailable(info.Name)
+	}
+
+	start := time.Now()
+	resp, err := info.TTSBackend.Synthesize(ctx, req)
+	elapsed := time.Since(start)
+	backend.ReportOutcome(hc, info.Name, err)
+	if err != nil {
+		middleware.TTSRequestsTotal.WithLabelValues(info.Name, "error").Inc()
+		if ctx.Err() == nil {
+			logger.Error("tts synthesis failed", "backend", info.Name, "err", err)
+		}
+		return nil, ttsError(info.Name, err)
+	}
+	rtr.ObserveLatency(router.CapTTS, info.Name, elapsed)
+	middleware.TTSRequestsTotal.WithLabelValues(info.Name, "success").Inc()
+	middleware.TTSRequestDuration.WithLabelValues(info.Name).Observe(elapsed.Seconds())
+	middleware.TTSCharactersTotal.WithLabelValues(info.Name).Add(float64(len(req.Input)))
+	return resp, nil
+}
+
+// defaultVoice returns the voice used on info when a request names none: the
+// backend's configured default, else the first voice it lists.
+func defaultVoice(ctx context.Context, info router.BackendInfo, catalog *voices.Catalog) string {
+	if info.DefaultVoice != "" {
+		return info.DefaultVoice
+	}
+	if catalog != nil {
+		if v := catalog.First(ctx, info.Name); v != "" {
+			return v
+		}
+	}
+	return "default"
+}
+
+// Voices lists the voices offered by the healthy TTS backends.
+//
+//	GET /v1/audio/voices
+func Voices(catalog *voices.Catalog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, VoiceList{Object: "list", Data: catalog.List(r.Context())})
+	}
+}
+
+// VoiceList is the response body of GET /v1/audio/voices.
+type VoiceList struct {
+	Object string         `json:"object"`
+	Data   []voices.Entry `json:"data"`
+}
+
+// writeAdmitTTSError answers a request for which no TTS backend could be
+// admitted.
+func writeAdmitTTSError(w http.ResponseWriter, rtr *router.Registry, model string, err error) {
+	if errors.Is(err, backend.ErrQueueFull) || errors.Is(err, backend.ErrQueueTimeout) {
+		setRetryAfter(w, rtr.Balancer().RetryAfter())
+	}
+	apierror.Write(w, admitTTSError(model, err))
+}
+
+// admitTTSError maps a failed TTS admission to an API error.
+func admitTTSError(model string, err error) *apierror.Error {
+	switch {
+	case errors.Is(err, backend.ErrQueueFull):
+		return apierror.QueueFull(model)
+	case errors.Is(err, backend.ErrQueueTimeout):
+		return apierror.QueueTimeout(model)
+	default:
+		return apierror.BackendUnavailable(model)
+	}
+}
+
+// ttsError maps a synthesis error to an API error. A format the backend
+// cannot produce is the caller's mistake; everything else is the backend's.
+func ttsError(name string, err error) *apierror.Error {
+	if errors.Is(err, backend.ErrFormatUnsupported) {
+		return apierror.InvalidParam("response_format", "Backend "+name+" cannot produce this response_format.")
+	}
+	return apierror.FromBackendError(name, err)
+}
+
 // mimeTypeFromFormat maps the OpenAI TTS response_format to a MIME type.
 func mimeTypeFromFormat(format string) string {
 	switch strings.ToLower(format) {
@@ -128,6 +344,8 @@ func mimeTypeFromFormat(format string) string {
 		return "audio/opus"
 	case "flac":
 		return "audio/flac"
+	case "aac":
+		return "audio/aac"
 	case "wav":
 		return "audio/wav"
 	case "pcm":
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/cache"
+	"github.com/menezmethod/inferencia/internal/middleware"
+)
+
+// cachedSpeech is a speech request's handle on the audio cache. A nil
+// *cachedSpeech stores nothing, so callers need not check.
+type cachedSpeech struct {
+	c      *cache.Cache
+	key    string
+	store  bool
+	logger *slog.Logger
+}
+
+// speechCacheKey identifies the audio for req. Inputs that differ only in
+// surrounding or repeated whitespace share an entry, as do format names that
+// differ only in case.
+func speechCacheKey(req backend.TTSRequest) (string, error) {
+	format := strings.ToLower(req.ResponseFormat)
+	if format == "" {
+		format = "wav"
+	}
+	return cache.Key("tts", struct {
+		Model  string  `json:"model"`
+		Voice  string  `json:"voice"`
+		Speed  float64 `json:"speed"`
+		Format string  `json:"format"`
+		Input  string  `json:"input"`
+	}{req.Model, req.Voice, req.Speed, format, strings.Join(strings.Fields(req.Input), " ")})
+}
+
+// lookupSpeech looks up the audio for req, honouring Cache-Control as for
+// the response cache. On a hit it writes the audio and returns true.
+func lookupSpeech(c *cache.Cache, w http.ResponseWriter, r *http.Request, req backend.TTSRequest, logger *slog.Logger) (*cachedSpeech, bool) {
+	key, err := speechCacheKey(req)
+	if err != nil {
+		logger.Warn("tts cache key failed", "err", err)
+		return nil, false
+	}
+	noCache, noStore := cacheControl(r)
+	cs := &cachedSpeech{c: c, key: key, store: !noStore, logger: logger}
+	if noCache || noStore {
+		middleware.TTSCacheRequestsTotal.WithLabelValues("bypass").Inc()
+		w.Header().Set(CacheHeader, "miss")
+		return cs, false
+	}
+
+	data, ok := c.Get(key)
+	if !ok {
+		middleware.TTSCacheRequestsTotal.WithLabelValues("miss").Inc()
+		w.Header().Set(CacheHeader, "miss")
+		return cs, false
+	}
+	contentType, audio, ok := bytes.Cut(data, []byte{'\n'})
+	if !ok {
+		logger.Warn("tts cache entry is unreadable", "key", key)
+		c.Delete(key)
+		middleware.TTSCacheRequestsTotal.WithLabelValues("miss").Inc()
+		w.Header().Set(CacheHeader, "miss")
+		return cs, false
+	}
+	middleware.TTSCacheRequestsTotal.WithLabelValues("hit").Inc()
+	w.Header().Set(CacheHeader, "hit")
+	w.Header().Set("Content-Type", string(contentType))
+	w.Header().Set("Content-Length", strconv.Itoa(len(audio)))
+	if _, err := w.Write(audio); err != nil {
+		logger.Error("failed to write audio response", "err", err)
+	}
+	return cs, true
+}
+
+// save stores synthesized audio with its content type.
+func (cs *cachedSpeech) save(contentType string, audio []byte) {
+	if cs == nil || !cs.store {
+		return
+	}
+	data := make([]byte, 0, len(contentType)+1+len(audio))
+	data = append(append(append(data, contentType...), '\n'), audio...)
+	cs.c.Set(cs.key, data)
+	middleware.TTSCacheSizeBytes.Set(float64(cs.c.Bytes()))
+}
@@ -0,0 +1,280 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/audio"
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/middleware"
+	"github.com/menezmethod/inferencia/internal/router"
+	"github.com/menezmethod/inferencia/internal/speech"
+	"github.com/menezmethod/inferencia/internal/voices"
+)
+
+// longSpeechConfig holds the settings from WithLongSpeech.
+type longSpeechConfig struct {
+	maxInput    int
+	segmentLen  int
+	parallelism int
+	crossfade   time.Duration
+}
+
+// speechPiece is one backend request of a speech synthesized in pieces: a
+// pause, then text spoken at speed.
+type speechPiece struct {
+	pause time.Duration
+	text  string
+	speed float64
+}
+
+// segmentResult is the outcome of synthesizing one segment of long input.
+type segmentResult struct {
+	resp     *backend.TTSResponse
+	backend  string
+	err      error
+	admitErr bool // no backend could be admitted
+}
+
+// longSpeech synthesizes input longer than maxTTSInputLength. The input is
+// packed into segments on sentence and paragraph boundaries and synthesized
+// by speakPieces.
+func longSpeech(w http.ResponseWriter, r *http.Request, rtr *router.Registry, hc backend.HealthChecker, req backend.TTSRequest, o options, logger *slog.Logger) {
+	var pieces []speechPiece
+	for _, text := range speech.Pack(req.Input, o.longSpeech.segmentLen) {
+		pieces = append(pieces, speechPiece{text: text, speed: req.Speed})
+	}
+	speakPieces(w, r, rtr, hc, req, pieces, 0, o, logger)
+}
+
+// scriptedSpeech synthesizes SSML with pauses or rate changes. Each part is
+// spoken at the request's speed times its rate, in pieces of at most
+// maxTTSInputLength, by speakPieces.
+func scriptedSpeech(w http.ResponseWriter, r *http.Request, rtr *router.Registry, hc backend.HealthChecker, req backend.TTSRequest, parts []speech.Part, o options, logger *slog.Logger) {
+	var (
+		pieces   []speechPiece
+		trailing time.Duration
+	)
+	for _, p := range parts {
+		if p.Text == "" {
+			trailing += p.Pause
+			continue
+		}
+		speed := min(max(req.Speed*p.Rate, 0.25), 4.0)
+		for i, text := range speech.Pack(p.Text, maxTTSInputLength) {
+			piece := speechPiece{text: text, speed: speed}
+			if i == 0 {
+				piece.pause = p.Pause
+			}
+			pieces = append(pieces, piece)
+		}
+	}
+	speakPieces(w, r, rtr, hc, req, pieces, trailing, o, logger)
+}
+
+// speakPieces synthesizes pieces in parallel, each admitted separately so
+// they spread across the healthy backends for the model, and joins the audio
+// in order: WAV and PCM into one continuous stream of samples with silence
+// for each pause, MP3 and Opus end to end (where a pause only separates the
+// pieces). trailing is silence after the last piece. With "stream": true
+// each piece is sent as soon as it and those before it are ready.
+func speakPieces(w http.ResponseWriter, r *http.Request, rtr *router.Registry, hc backend.HealthChecker, req backend.TTSRequest, pieces []speechPiece, trailing time.Duration, o options, logger *slog.Logger) {
+	cfg := o.longSpeech
+	format := strings.ToLower(req.ResponseFormat)
+	if format == "" {
+		format = "wav"
+	}
+	upstream := format
+	switch format {
+	case "wav", "pcm":
+		upstream = "wav"
+	case "mp3", "opus":
+	default:
+		apierror.Write(w, apierror.InvalidParam("response_format", "long input and SSML with breaks or prosody can be synthesized as wav, pcm, mp3 or opus"))
+		return
+	}
+	// Every segment must use the same voice, so segments only go to the
+	// backends that offer it.
+	if req.Voice == "" {
+		for _, info := range rtr.BackendsByCapability(router.CapTTS) {
+			if info.Serves(router.CapTTS, req.Model) {
+				req.Voice = defaultVoice(r.Context(), info, o.voices)
+				break
+			}
+		}
+	}
+	only, ok := routeVoice(w, r, o.voices, req)
+	if !ok {
+		return
+	}
+	req.ResponseFormat = upstream
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	results := synthesizeParallel(ctx, rtr, hc, o.voices, only, req, pieces, max(cfg.parallelism, 1), logger)
+
+	sw := &speechWriter{w: w, start: time.Now()}
+	var (
+		buf         []byte
+		contentType = mimeTypeFromFormat(format)
+		joiner      *audio.Joiner
+	)
+	emit := func(data []byte) error {
+		if req.Stream {
+			return sw.write(contentType, data)
+		}
+		buf = append(buf, data...)
+		return nil
+	}
+
+	for i, ch := range results {
+		res := <-ch
+		if sw.backend == "" {
+			sw.backend = res.backend
+		}
+		if res.err == nil && upstream == "wav" {
+			res.err = joinSegment(&joiner, res.resp.Audio, pieces[i].pause, cfg.crossfade, format == "pcm", format == "wav" && req.Stream, emit)
+		} else if res.err == nil {
+			if res.resp.Format != "" {
+				contentType = res.resp.Format
+			}
+			res.err = emit(res.resp.Audio)
+		}
+		if res.err != nil {
+			logger.Error("long tts synthesis failed", "backend", res.backend, "segment", i, "segments", len(pieces), "err", res.err)
+			switch {
+			case sw.started:
+			case res.admitErr:
+				writeAdmitTTSError(w, rtr, req.Model, res.err)
+			default:
+				apierror.Write(w, ttsError(res.backend, res.err))
+			}
+			return
+		}
+	}
+	if joiner != nil {
+		if trailing > 0 {
+			if err := emit(joiner.Add(audio.Silence(joiner.Format(), trailing))); err != nil {
+				return
+			}
+		}
+		if err := emit(joiner.Flush()); err != nil {
+			return
+		}
+	}
+	if req.Stream {
+		return
+	}
+
+	if format == "wav" && joiner != nil {
+		buf = append(audio.WAVHeader(joiner.Format(), len(buf)), buf...)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(buf)))
+	if _, err := w.Write(buf); err != nil {
+		logger.Error("failed to write audio response", "err", err)
+	}
+}
+
+// joinSegment adds pause of silence and then a WAV segment's samples to the
+// joined stream, creating the joiner from the first segment's format. With
+// toPCM set, samples are first converted to 24 kHz 16-bit mono. With header
+// set, the first segment is preceded by a WAV header for a stream of unknown
+// length.
+func joinSegment(joiner **audio.Joiner, wav []byte, pause, crossfade time.Duration, toPCM, header bool, emit func([]byte) error) error {
+	f, pcm, err := audio.ParseWAV(wav)
+	if err != nil {
+		return err
+	}
+	if toPCM {
+		if pcm, err = audio.ToPCM(f, pcm); err != nil {
+			return err
+		}
+		f = audio.PCMFormat
+	}
+	if *joiner == nil {
+		*joiner = audio.NewJoiner(f, crossfade)
+		if header {
+			if err := emit(audio.WAVHeader(f, -1)); err != nil {
+				return err
+			}
+		}
+	} else if f != (*joiner).Format() {
+		return fmt.Errorf("segment audio is %d Hz %d-bit %d channel, want %d Hz %d-bit %d channel",
+			f.SampleRate, f.BitsPerSample, f.Channels, (*joiner).Format().SampleRate, (*joiner).Format().BitsPerSample, (*joiner).Format().Channels)
+	}
+	if pause > 0 {
+		if err := emit((*joiner).Add(audio.Silence(f, pause))); err != nil {
+			return err
+		}
+	}
+	return emit((*joiner).Add(pcm))
+}
+
+// synthesizeParallel synthesizes pieces at most parallelism at a time and
+// returns one channel per piece, in order, each receiving its result.
+// Pieces not yet started when ctx ends get ctx's error.
+func synthesizeParallel(ctx context.Context, rtr *router.Registry, hc backend.HealthChecker, catalog *voices.Catalog, only []string, req backend.TTSRequest, pieces []speechPiece, parallelism int, logger *slog.Logger) []chan segmentResult {
+	results := make([]chan segmentResult, len(pieces))
+	for i := range results {
+		results[i] = make(chan segmentResult, 1)
+	}
+	go func() {
+		sem := make(chan struct{}, parallelism)
+		for i, piece := range pieces {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				for _, ch := range results[i:] {
+					ch <- segmentResult{err: ctx.Err()}
+				}
+				return
+			}
+			go func() {
+				defer func() { <-sem }()
+				seg := req
+				seg.Input = piece.text
+				seg.Speed = piece.speed
+				seg.Stream = false
+				results[i] <- synthesizeSegment(ctx, rtr, hc, catalog, only, seg, logger)
+			}()
+		}
+	}()
+	return results
+}
+
+// synthesizeSegment admits a backend for one segment and synthesizes it.
+func synthesizeSegment(ctx context.Context, rtr *router.Registry, hc backend.HealthChecker, catalog *voices.Catalog, only []string, req backend.TTSRequest, logger *slog.Logger) segmentResult {
+	info, err := admitTTS(ctx, rtr, hc, catalog, only, &req, logger)
+	if err != nil {
+		return segmentResult{err: err, admitErr: true}
+	}
+	defer rtr.ReleaseBackend(info.Name)
+
+	if info.TTSBackend == nil {
+		backend.ReportOutcome(hc, info.Name, backend.ErrBackendNotFound)
+		return segmentResult{backend: info.Name, err: errors.New("selected backend has no TTS backend")}
+	}
+
+	start := time.Now()
+	resp, err := info.TTSBackend.Synthesize(ctx, req)
+	elapsed := time.Since(start)
+	backend.ReportOutcome(hc, info.Name, err)
+	if err != nil {
+		middleware.TTSRequestsTotal.WithLabelValues(info.Name, "error").Inc()
+		return segmentResult{backend: info.Name, err: err}
+	}
+
+	rtr.ObserveLatency(router.CapTTS, info.Name, elapsed)
+	middleware.TTSRequestsTotal.WithLabelValues(info.Name, "success").Inc()
+	middleware.TTSRequestDuration.WithLabelValues(info.Name).Observe(elapsed.Seconds())
+	middleware.TTSCharactersTotal.WithLabelValues(info.Name).Add(float64(len(req.Input)))
+	return segmentResult{resp: resp, backend: info.Name}
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/audio"
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/middleware"
+	"github.com/menezmethod/inferencia/internal/router"
+	"github.com/menezmethod/inferencia/internal/speech"
+)
+
+// streamSegmentChars bounds the text of one segment when a backend cannot
+// stream and the input is synthesized sentence by sentence.
+const streamSegmentChars = 400
+
+// streamSpeech answers a "stream": true speech request. Audio from a
+// StreamingTTSBackend is proxied as it arrives; for other backends the input
+// is split into sentences that are synthesized and written one at a time, so
+// the client can start playing after the first sentence.
+func streamSpeech(w http.ResponseWriter, r *http.Request, rtr *router.Registry, hc backend.HealthChecker, info router.BackendInfo, req backend.TTSRequest, logger *slog.Logger) {
+	start := time.Now()
+	sw := &speechWriter{w: w, start: start, backend: info.Name}
+
+	var err error
+	if sb, ok := info.TTSBackend.(backend.StreamingTTSBackend); ok {
+		err = proxySpeech(r, sb, req, sw)
+	} else {
+		err = synthesizeSegments(r, info.TTSBackend, req, sw)
+	}
+	elapsed := time.Since(start)
+	backend.ReportOutcome(hc, info.Name, err)
+
+	if err != nil {
+		middleware.TTSRequestsTotal.WithLabelValues(info.Name, "error").Inc()
+		logger.Error("tts synthesis failed", "backend", info.Name, "streaming", true, "err", err)
+		if !sw.started {
+			apierror.Write(w, ttsError(info.Name, err))
+		}
+		return
+	}
+
+	rtr.ObserveLatency(router.CapTTS, info.Name, elapsed)
+	middleware.TTSRequestsTotal.WithLabelValues(info.Name, "success").Inc()
+	middleware.TTSRequestDuration.WithLabelValues(info.Name).Observe(elapsed.Seconds())
+	middleware.TTSCharactersTotal.WithLabelValues(info.Name).Add(float64(len(req.Input)))
+}
+
+// proxySpeech copies a backend's audio stream to the client.
+func proxySpeech(r *http.Request, sb backend.StreamingTTSBackend, req backend.TTSRequest, sw *speechWriter) error {
+	stream, err := sb.SynthesizeStream(r.Context(), req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stream.Audio.Close() }()
+
+	contentType := stream.Format
+	if contentType == "" {
+		contentType = mimeTypeFromFormat(req.ResponseFormat)
+	}
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := stream.Audio.Read(buf)
+		if n > 0 {
+			if werr := sw.write(contentType, buf[:n]); werr != nil {
+				return nil // client went away
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// synthesizeSegments synthesizes req sentence by sentence. WAV and PCM
+// segments are requested as WAV and joined into one stream of samples,
+// converted to 24 kHz 16-bit mono for PCM; WAV segments must all match the
+// first. MP3 and Opus segments concatenate as they are. Other formats cannot
+// be joined and are synthesized in one piece.
+func synthesizeSegments(r *http.Request, tts backend.TTSBackend, req backend.TTSRequest, sw *speechWriter) error {
+	format := strings.ToLower(req.ResponseFormat)
+	if format == "" {
+		format = "wav"
+	}
+	upstream := format
+	segments := []string{req.Input}
+	switch format {
+	case "wav", "pcm":
+		upstream = "wav"
+		segments = speech.Split(req.Input, streamSegmentChars)
+	case "mp3", "opus":
+		segments = speech.Split(req.Input, streamSegmentChars)
+	}
+
+	var want *audio.Format // the first WAV segment's format, which the header declares
+	for _, text := range segments {
+		seg := req
+		seg.Input = text
+		seg.ResponseFormat = upstream
+		seg.Stream = false
+		resp, err := tts.Synthesize(r.Context(), seg)
+		if err != nil {
+			return err
+		}
+
+		contentType, data := resp.Format, resp.Audio
+		if upstream == "wav" {
+			f, pcm, err := audio.ParseWAV(resp.Audio)
+			if err != nil {
+				return err
+			}
+			contentType, data = mimeTypeFromFormat(format), pcm
+			switch {
+			case format == "pcm":
+				if data, err = audio.ToPCM(f, pcm); err != nil {
+					return err
+				}
+			case want == nil:
+				want = &f
+				data = append(audio.WAVHeader(f, -1), pcm...)
+			case f != *want:
+				return fmt.Errorf("segment audio is %d Hz %d-bit %d channel, want %d Hz %d-bit %d channel",
+					f.SampleRate, f.BitsPerSample, f.Channels, want.SampleRate, want.BitsPerSample, want.Channels)
+			}
+		}
+		if contentType == "" {
+			contentType = mimeTypeFromFormat(format)
+		}
+		if err := sw.write(contentType, data); err != nil {
+			return nil // client went away
+		}
+	}
+	return nil
+}
+
+// speechWriter writes audio chunks to the client, sending the headers with
+// the first one and flushing after each.
+type speechWriter struct {
+	w       http.ResponseWriter
+	start   time.Time
+	backend string
+	started bool
+}
+
+func (sw *speechWriter) write(contentType string, data []byte) error {
+	if !sw.started {
+		sw.started = true
+		middleware.TTSTimeToFirstAudio.WithLabelValues(sw.backend).Observe(time.Since(sw.start).Seconds())
+		sw.w.Header().Set("Content-Type", contentType)
+		sw.w.Header().Set("X-Accel-Buffering", "no")
+		sw.w.WriteHeader(http.StatusOK)
+	}
+	if _, err := sw.w.Write(data); err != nil {
+		return err
+	}
+	if f, ok := sw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/batch"
+	"github.com/menezmethod/inferencia/internal/middleware"
+)
+
+// BatchList is the response of GET /v1/batches.
+type BatchList struct {
+	Object  string        `json:"object"`
+	Data    []batch.Batch `json:"data"`
+	FirstID string        `json:"first_id,omitempty"`
+	LastID  string        `json:"last_id,omitempty"`
+	HasMore bool          `json:"has_more"`
+}
+
+// UploadFile stores a JSONL file for use as batch input. The body is
+// multipart/form-data with a "file" part and purpose "batch".
+//
+//	POST /v1/files
+func UploadFile(store *batch.Store, maxBytes int64, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if maxBytes > 0 {
+			// Leave room for the multipart framing around the file.
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes+1<<20)
+		}
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				apierror.Write(w, apierror.InvalidParam("file", fmt.Sprintf("file must be at most %d bytes", maxBytes)))
+				return
+			}
+			apierror.Write(w, apierror.InvalidRequest("Expected a multipart/form-data body: "+err.Error()))
+			return
+		}
+		defer func() { _ = r.MultipartForm.RemoveAll() }()
+
+		purpose := r.FormValue("purpose")
+		if purpose != batch.PurposeBatch {
+			apierror.Write(w, apierror.InvalidParam("purpose", "purpose must be batch"))
+			return
+		}
+		file, hdr, err := r.FormFile("file")
+		if err != nil {
+			apierror.Write(w, apierror.InvalidParam("file", "file is required"))
+			return
+		}
+		defer func() { _ = file.Close() }()
+
+		owner := batch.Owner(middleware.APIKeyFromContext(r.Context()))
+		f, err := store.CreateFile(owner, hdr.Filename, purpose, file, maxBytes)
+		if err != nil {
+			if errors.Is(err, batch.ErrFileTooLarge) {
+				apierror.Write(w, apierror.InvalidParam("file", fmt.Sprintf("file must be at most %d bytes", maxBytes)))
+				return
+			}
+			logger.Error("failed to store file", "err", err)
+			apierror.Write(w, apierror.Internal("Failed to store file."))
+			return
+		}
+		logger.Info("file uploaded", "file", f.ID, "bytes", f.Bytes)
+		writeJSON(w, f)
+	}
+}
+
+// GetFile returns a file's metadata.
+//
+//	GET /v1/files/{id}
+func GetFile(store *batch.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner := batch.Owner(middleware.APIKeyFromContext(r.Context()))
+		f, err := store.File(owner, r.PathValue("id"))
+		if err != nil {
+			apierror.Write(w, apierror.NotFound("No such file: "+r.PathValue("id")))
+			return
+		}
+		writeJSON(w, f)
+	}
+}
+
+// FileContent downloads a file: a batch's input, output or error file.
+//
+//	GET /v1/files/{id}/content
+func FileContent(store *batch.Store, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner := batch.Owner(middleware.APIKeyFromContext(r.Context()))
+		f, err := store.Open(owner, r.PathValue("id"))
+		if err != nil {
+			apierror.Write(w, apierror.NotFound("No such file: "+r.PathValue("id")))
+			return
+		}
+		defer func() { _ = f.Close() }()
+
+		w.Header().Set("Content-Type", "application/jsonl")
+		if _, err := io.Copy(w, f); err != nil {
+			logger.Error("failed to write file content", "err", err)
+		}
+	}
+}
+
+// CreateBatch starts a batch over an uploaded input file.
+//
+//	POST /v1/batches
+func CreateBatch(m *batch.Manager, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req batch.CreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, apierror.InvalidRequest("Invalid JSON in request body: "+err.Error()))
+			return
+		}
+
+		owner := batch.Owner(middleware.APIKeyFromContext(r.Context()))
+		b, err := m.Create(owner, req)
+		if err != nil {
+			var invalid *batch.InvalidError
+			if errors.As(err, &invalid) {
+				apierror.Write(w, apierror.InvalidParam(invalid.Param, invalid.Message))
+				return
+			}
+			logger.Error("failed to create batch", "err", err)
+			apierror.Write(w, apierror.Internal("Failed to create batch."))
+			return
+		}
+		logger.Info("batch created", "batch", b.ID, "endpoint", b.Endpoint, "input_file", b.InputFileID)
+		writeJSON(w, b)
+	}
+}
+
+// GetBatch returns a batch with its status and progress counts.
+//
+//	GET /v1/batches/{id}
+func GetBatch(m *batch.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner := batch.Owner(middleware.APIKeyFromContext(r.Context()))
+		b, err := m.Batch(owner, r.PathValue("id"))
+		if err != nil {
+			apierror.Write(w, apierror.NotFound("No such batch: "+r.PathValue("id")))
+			return
+		}
+		writeJSON(w, b)
+	}
+}
+
+// ListBatches lists the caller's batches, newest first. Pages hold limit
+// batches (default 20, at most 100) and continue after the batch ID given as
+// after.
+//
+//	GET /v1/batches
+func ListBatches(m *batch.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := 20
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 100 {
+				apierror.Write(w, apierror.InvalidParam("limit", "limit must be between 1 and 100"))
+				return
+			}
+			limit = n
+		}
+
+		owner := batch.Owner(middleware.APIKeyFromContext(r.Context()))
+		all := m.List(owner)
+		if after := r.URL.Query().Get("after"); after != "" {
+			for i, b := range all {
+				if b.ID == after {
+					all = all[i+1:]
+					break
+				}
+			}
+		}
+
+		list := BatchList{Object: "list", Data: all, HasMore: len(all) > limit}
+		if list.HasMore {
+			list.Data = all[:limit]
+		}
+		if list.Data == nil {
+			list.Data = []batch.Batch{}
+		}
+		if len(list.Data) > 0 {
+			list.FirstID = list.Data[0].ID
+			list.LastID = list.Data[len(list.Data)-1].ID
+		}
+		writeJSON(w, list)
+	}
+}
+
+// CancelBatch stops a batch. Results of lines already processed remain in
+// its output and error files.
+//
+//	POST /v1/batches/{id}/cancel
+func CancelBatch(m *batch.Manager, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner := batch.Owner(middleware.APIKeyFromContext(r.Context()))
+		b, err := m.Cancel(owner, r.PathValue("id"))
+		switch {
+		case errors.Is(err, batch.ErrNotFound):
+			apierror.Write(w, apierror.NotFound("No such batch: "+r.PathValue("id")))
+			return
+		case errors.Is(err, batch.ErrFinished):
+			apierror.Write(w, apierror.InvalidRequest(fmt.Sprintf("Batch %s cannot be cancelled: its status is %s.", b.ID, b.Status)))
+			return
+		case err != nil:
+			logger.Error("failed to cancel batch", "batch", r.PathValue("id"), "err", err)
+			apierror.Write(w, apierror.Internal("Failed to cancel batch."))
+			return
+		}
+		logger.Info("batch cancelled", "batch", b.ID)
+		writeJSON(w, b)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
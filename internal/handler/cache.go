@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/cache"
+	"github.com/menezmethod/inferencia/internal/middleware"
+)
+
+// CacheHeader reports whether a response came from the response cache:
+// "hit" or "miss". It is absent for requests the cache does not apply to.
+const CacheHeader = "X-Inferencia-Cache"
+
+// cachedRequest is a cacheable request's handle on the response cache. A nil
+// *cachedRequest stores nothing, so callers need not check.
+type cachedRequest struct {
+	c      *cache.Cache
+	key    string
+	store  bool
+	logger *slog.Logger
+}
+
+// lookupCache looks up v under kind, honouring the request's Cache-Control:
+// no-cache skips the lookup but stores the fresh response, no-store does
+// neither. It sets CacheHeader and returns the cached bytes on a hit.
+func lookupCache(c *cache.Cache, w http.ResponseWriter, r *http.Request, kind string, v any, logger *slog.Logger) (*cachedRequest, []byte) {
+	key, err := cache.Key(kind, v)
+	if err != nil {
+		logger.Warn("cache key failed", "kind", kind, "err", err)
+		return nil, nil
+	}
+	noCache, noStore := cacheControl(r)
+	cr := &cachedRequest{c: c, key: key, store: !noStore, logger: logger}
+
+	if !noCache && !noStore {
+		if data, ok := c.Get(key); ok {
+			middleware.CacheLookupsTotal.WithLabelValues(kind, "hit").Inc()
+			w.Header().Set(CacheHeader, "hit")
+			return cr, data
+		}
+		middleware.CacheLookupsTotal.WithLabelValues(kind, "miss").Inc()
+	} else {
+		middleware.CacheLookupsTotal.WithLabelValues(kind, "bypass").Inc()
+	}
+	w.Header().Set(CacheHeader, "miss")
+	return cr, nil
+}
+
+// save stores resp as the cached response for the request.
+func (cr *cachedRequest) save(resp any) {
+	if cr == nil || !cr.store {
+		return
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		cr.logger.Warn("failed to encode response for cache", "err", err)
+		return
+	}
+	cr.c.Set(cr.key, data)
+}
+
+// chatCaches are the caches a chat response is stored in once it completes.
+type chatCaches struct {
+	exact    *cachedRequest
+	semantic *semanticRequest
+}
+
+func (c chatCaches) enabled() bool { return c.exact != nil || c.semantic != nil }
+
+func (c chatCaches) save(resp *backend.ChatResponse) {
+	c.exact.save(resp)
+	c.semantic.save(resp)
+}
+
+// cacheControl reports the no-cache and no-store request directives.
+func cacheControl(r *http.Request) (noCache, noStore bool) {
+	for _, v := range r.Header.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			switch strings.ToLower(strings.TrimSpace(d)) {
+			case "no-cache":
+				noCache = true
+			case "no-store":
+				noStore = true
+			}
+		}
+	}
+	return noCache, noStore
+}
+
+// chatCacheKey is what a chat response is cached under: the request, minus
+// whether it streams, and the backend that serves it, since backends may serve
+// different weights under one model name.
+func chatCacheKey(b backend.Backend, req backend.ChatRequest) any {
+	req.Stream = false
+	return struct {
+		Backend string              `json:"backend"`
+		Request backend.ChatRequest `json:"request"`
+	}{b.Name(), req}
+}
+
+// chatCacheable reports whether req is deterministic enough to cache: greedy
+// sampling (temperature 0) or a fixed seed.
+func chatCacheable(req backend.ChatRequest) bool {
+	return (req.Temperature != nil && *req.Temperature == 0) || req.Seed != nil
+}
+
+// writeCachedChat serves a cached chat completion, as JSON or, when the
+// request asked for a stream, replayed as SSE chunks.
+func writeCachedChat(w http.ResponseWriter, data []byte, stream bool, logger *slog.Logger) {
+	if !stream {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+		return
+	}
+
+	var resp backend.ChatResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		logger.Error("failed to decode cached chat response", "err", err)
+		apierror.Write(w, apierror.Internal("Cached response is unreadable."))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, chunk := range replayChunks(resp) {
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", chunk); err != nil {
+			return
+		}
+	}
+	_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// streamChoice and streamToolCall mirror OpenAI's chunk shapes, which carry an
+// index on each tool call so clients can merge partial calls.
+type streamChoice struct {
+	Index        int         `json:"index"`
+	Delta        streamDelta `json:"delta"`
+	FinishReason *string     `json:"finish_reason"`
+}
+
+type streamDelta struct {
+	Role      string           `json:"role,omitempty"`
+	Content   json.RawMessage  `json:"content,omitempty"`
+	ToolCalls []streamToolCall `json:"tool_calls,omitempty"`
+}
+
+type streamToolCall struct {
+	Index int `json:"index"`
+	backend.ToolCall
+}
+
+type streamChunk struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []streamChoice `json:"choices"`
+	Usage   *backend.Usage `json:"usage,omitempty"`
+}
+
+// replayChunks turns a complete chat response into the chunks a backend would
+// have streamed: the whole message per choice, then the finish reasons, then
+// usage.
+func replayChunks(resp backend.ChatResponse) [][]byte {
+	chunk := func(choices []streamChoice, usage *backend.Usage) []byte {
+		data, _ := json.Marshal(streamChunk{
+			ID:      resp.ID,
+			Object:  "chat.completion.chunk",
+			Created: resp.Created,
+			Model:   resp.Model,
+			Choices: choices,
+			Usage:   usage,
+		})
+		return data
+	}
+
+	var chunks [][]byte
+	for _, c := range resp.Choices {
+		if c.Message == nil {
+			continue
+		}
+		d := streamDelta{Role: c.Message.Role, Content: c.Message.Content}
+		for i, tc := range c.Message.ToolCalls {
+			d.ToolCalls = append(d.ToolCalls, streamToolCall{Index: i, ToolCall: tc})
+		}
+		chunks = append(chunks, chunk([]streamChoice{{Index: c.Index, Delta: d}}, nil))
+	}
+	for _, c := range resp.Choices {
+		chunks = append(chunks, chunk([]streamChoice{{Index: c.Index, FinishReason: c.FinishReason}}, nil))
+	}
+	if resp.Usage != nil {
+		chunks = append(chunks, chunk([]streamChoice{}, resp.Usage))
+	}
+	return chunks
+}
+
+// streamAccumulator rebuilds a complete chat response from streamed chunks so
+// a streamed completion can be cached. Streams with tool calls or non-text
+// content are not reassembled.
+type streamAccumulator struct {
+	resp    backend.ChatResponse
+	text    map[int]*strings.Builder
+	choices map[int]*backend.Choice
+	done    bool
+	invalid bool
+}
+
+func newStreamAccumulator() *streamAccumulator {
+	return &streamAccumulator{
+		text:    make(map[int]*strings.Builder),
+		choices: make(map[int]*backend.Choice),
+	}
+}
+
+// add records one chunk as passed to the stream callback.
+func (a *streamAccumulator) add(data []byte) {
+	if a.invalid {
+		return
+	}
+	if string(data) == "[DONE]" {
+		a.done = true
+		return
+	}
+	var chunk backend.ChatResponse
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		a.invalid = true
+		return
+	}
+	if a.resp.ID == "" {
+		a.resp.ID, a.resp.Created, a.resp.Model = chunk.ID, chunk.Created, chunk.Model
+	}
+	if chunk.Usage != nil {
+		a.resp.Usage = chunk.Usage
+	}
+	for _, c := range chunk.Choices {
+		choice, ok := a.choices[c.Index]
+		if !ok {
+			choice = &backend.Choice{Index: c.Index, Message: &backend.Message{Role: "assistant"}}
+			a.choices[c.Index] = choice
+			a.text[c.Index] = &strings.Builder{}
+		}
+		if c.FinishReason != nil {
+			choice.FinishReason = c.FinishReason
+		}
+		if c.Delta == nil {
+			continue
+		}
+		if len(c.Delta.ToolCalls) > 0 {
+			a.invalid = true
+			return
+		}
+		if c.Delta.Role != "" {
+			choice.Message.Role = c.Delta.Role
+		}
+		if len(c.Delta.Content) > 0 && string(c.Delta.Content) != "null" {
+			var s string
+			if err := json.Unmarshal(c.Delta.Content, &s); err != nil {
+				a.invalid = true
+				return
+			}
+			a.text[c.Index].WriteString(s)
+		}
+	}
+}
+
+// response returns the reassembled response, or false if the stream did not
+// complete cleanly.
+func (a *streamAccumulator) response() (*backend.ChatResponse, bool) {
+	if !a.done || a.invalid || len(a.choices) == 0 {
+		return nil, false
+	}
+	resp := a.resp
+	resp.Object = "chat.completion"
+	indexes := make([]int, 0, len(a.choices))
+	for i := range a.choices {
+		indexes = append(indexes, i)
+	}
+	slices.Sort(indexes)
+	for _, i := range indexes {
+		c := *a.choices[i]
+		content, _ := json.Marshal(a.text[i].String())
+		c.Message.Content = content
+		resp.Choices = append(resp.Choices, c)
+	}
+	return &resp, true
+}
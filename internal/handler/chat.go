@@ -1,15 +1,20 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/async"
 	"github.com/menezmethod/inferencia/internal/backend"
 	"github.com/menezmethod/inferencia/internal/middleware"
 )
@@ -17,11 +22,32 @@ import (
 const defaultChatModel = "qwen3.6:35b-a3b-coding-bf16"
 
 // ChatCompletions handles chat completion requests, supporting both
-// standard JSON responses and streaming SSE responses.
+// standard JSON responses and streaming SSE responses. With WithCache,
+// deterministic requests (temperature 0 or a fixed seed) are answered from the
+// response cache, replayed as SSE when streaming; entries are kept per
+// backend, so they are looked up once one is admitted. With WithSemanticCache,
+// questions similar to an earlier one reuse its answer. With WithAsync,
+// requests preferring respond-async become background jobs. With
+// WithResumableStreams, streams carry event IDs and survive a dropped
+// connection; a request with Last-Event-ID resumes one. With WithChatAudio,
+// a request with "audio" also gets the answer spoken: as base64 in the
+// message's audio, or streamed as audio chunks sentence by sentence while the
+// text is generated. Such requests bypass the caches and resumable streams.
 //
 //	POST /v1/chat/completions
-func ChatCompletions(reg *backend.Registry, hc backend.HealthChecker, logger *slog.Logger) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+func ChatCompletions(reg *backend.Registry, hc backend.HealthChecker, logger *slog.Logger, opts ...Option) http.HandlerFunc {
+	o := newOptions(opts)
+	var h http.HandlerFunc
+	h = func(w http.ResponseWriter, r *http.Request) {
+		if o.async != nil && async.Preferred(r.Header) {
+			submitAsync(w, r, o.async, "/v1/chat/completions", h, logger)
+			return
+		}
+		if last := r.Header.Get("Last-Event-ID"); o.streams != nil && last != "" {
+			resumeStream(w, r, o.streams, last, o.limits, logger)
+			return
+		}
+
 		var req backend.ChatRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			apierror.Write(w, apierror.InvalidRequest("Invalid JSON in request body: "+err.Error()))
@@ -35,87 +61,222 @@ func ChatCompletions(reg *backend.Registry, hc backend.HealthChecker, logger *sl
 		if strings.TrimSpace(req.Model) == "" {
 			req.Model = defaultChatModel
 		}
+		spoken, apiErr := newChatSpeech(o, hc, r, &req, logger)
+		if apiErr != nil {
+			apierror.Write(w, apiErr)
+			return
+		}
 
-		b, err := reg.PrimaryHealthy(hc)
+		var caches chatCaches
+		if o.semantic != nil && spoken == nil {
+			var hit []byte
+			if caches.semantic, hit = lookupSemantic(o, reg, hc, w, r, req, logger); hit != nil {
+				writeCachedChat(w, hit, req.Stream, logger)
+				return
+			}
+		}
+
+		b, err := reg.AdmitHealthy(r.Context(), backend.KindChat, hc)
 		if err != nil {
-			apierror.Write(w, backendSelectError(reg, err))
+			writeBackendSelectError(w, reg, err)
+			return
+		}
+		if o.cache != nil && chatCacheable(req) && spoken == nil {
+			var hit []byte
+			if caches.exact, hit = lookupCache(o.cache, w, r, "chat", chatCacheKey(b, req), logger); hit != nil {
+				reg.ReleaseBackend(b.Name())
+				backend.ReportOutcome(hc, b.Name(), backend.ErrNotSent)
+				writeCachedChat(w, hit, req.Stream, logger)
+				return
+			}
+		}
+		// A resumable stream outlives the request and releases the backend
+		// when its generation ends.
+		if req.Stream && o.streams != nil && spoken == nil && handleResumableStream(w, r, reg, hc, b, req, caches, o.streams, o.limits, logger) {
 			return
 		}
 		defer reg.ReleaseBackend(b.Name())
 
 		if req.Stream {
-			handleStream(w, r, b, req, logger)
-			return
+			err = handleStream(w, r, reg, b, req, caches, o.limits, spoken, logger)
+		} else {
+			err = handleJSON(w, r, b, req, caches, spoken, logger)
 		}
-
-		handleJSON(w, r, b, req, logger)
+		backend.ReportOutcome(hc, b.Name(), err)
 	}
+	return h
 }
 
 // handleJSON processes a non-streaming chat completion request.
-func handleJSON(w http.ResponseWriter, r *http.Request, b backend.Backend, req backend.ChatRequest, logger *slog.Logger) {
+// The round trip covers the whole generation, not the time to first token
+// the load balancer's chat latency estimate tracks, so it is only recorded
+// as a metric. With spoken set, the answer is spoken into the message's
+// audio. The returned error is the backend's, for passive
+// health checking.
+func handleJSON(w http.ResponseWriter, r *http.Request, b backend.Backend, req backend.ChatRequest, caches chatCaches, spoken *chatSpeech, logger *slog.Logger) error {
+	start := time.Now()
 	resp, err := b.ChatCompletion(r.Context(), req)
 	if err != nil {
 		logger.Error("chat completion failed", "backend", b.Name(), "err", err)
 		apierror.Write(w, apierror.FromBackendError(b.Name(), err))
-		return
+		return err
 	}
+	elapsed := time.Since(start)
+	middleware.BackendRequestDuration.WithLabelValues(b.Name(), "chat").Observe(elapsed.Seconds())
 
 	if resp.Usage != nil {
 		middleware.TokensTotal.WithLabelValues(resp.Model, "prompt").Add(float64(resp.Usage.PromptTokens))
 		middleware.TokensTotal.WithLabelValues(resp.Model, "completion").Add(float64(resp.Usage.CompletionTokens))
 	}
+	caches.save(resp)
+
+	if spoken != nil {
+		if err := spoken.attach(r.Context(), resp); err != nil {
+			apierror.Write(w, speechError(err))
+			return nil
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		logger.Error("failed to encode chat response", "err", err)
 	}
+	return nil
 }
 
 // handleStream processes a streaming chat completion request using SSE.
-func handleStream(w http.ResponseWriter, r *http.Request, b backend.Backend, req backend.ChatRequest, logger *slog.Logger) {
+// The arrival of the first chunk is reported to the load balancer as the
+// backend's time to first token. The returned error is the backend's; a client
+// that goes away mid-stream is not held against the backend. A stream that
+// completes is reassembled and stored in the enabled caches. With spoken set,
+// each sentence's audio follows the text chunks that complete it, and
+// "[DONE]" is held back until the last sentence has been spoken.
+func handleStream(w http.ResponseWriter, r *http.Request, reg *backend.Registry, b backend.Backend, req backend.ChatRequest, caches chatCaches, limits streamLimits, spoken *chatSpeech, logger *slog.Logger) error {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		apierror.Write(w, apierror.Internal("Streaming not supported by this server."))
-		return
+		return nil
 	}
 
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("X-Accel-Buffering", "no")
+	limits.extendWriteDeadline(w)
 	w.WriteHeader(http.StatusOK)
 	flusher.Flush()
 
+	ctx, cancel := limits.context(r.Context())
+	defer cancel(nil)
+	touch, stopIdle := limits.watchIdle(cancel)
+	defer stopIdle()
+	sse := newSSEWriter(w, flusher)
+	defer sse.keepAlive(limits.heartbeat)()
+
+	var acc *streamAccumulator
+	if caches.enabled() {
+		acc = newStreamAccumulator()
+	}
+
 	var mu sync.Mutex
+	start := time.Now()
+	first := true
+	clientGone := false
 	send := func(data []byte) error {
-		if r.Context().Err() != nil {
-			return r.Context().Err()
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
 		}
+		touch()
 
 		mu.Lock()
 		defer mu.Unlock()
 
-		if string(data) == "[DONE]" {
-			_, err := fmt.Fprintf(w, "data: [DONE]\n\n")
-			if err != nil {
-				return fmt.Errorf("client disconnected: %w", err)
-			}
-			flusher.Flush()
-			return nil
+		if first {
+			first = false
+			reg.ObserveLatency(backend.KindChat, b.Name(), time.Since(start))
+		}
+		if acc != nil {
+			acc.add(data)
 		}
 
-		_, err := fmt.Fprintf(w, "data: %s\n\n", data)
-		if err != nil {
+		if err := sse.event("", data); err != nil {
+			clientGone = true
 			return fmt.Errorf("client disconnected: %w", err)
 		}
-		flusher.Flush()
 		return nil
 	}
 
-	if err := b.ChatCompletionStream(r.Context(), req, send); err != nil {
+	var speaking *chatSpeechStream
+	heldDone := false
+	if spoken != nil {
+		speaking = spoken.stream(ctx, sse)
+		write := send
+		send = func(data []byte) error {
+			if string(data) == "[DONE]" {
+				heldDone = true
+				return nil
+			}
+			if err := write(data); err != nil {
+				return err
+			}
+			speaking.add(data)
+			return nil
+		}
+	}
+
+	if err := b.ChatCompletionStream(ctx, req, send); err != nil {
+		if speaking != nil {
+			_ = speaking.finish(false)
+		}
+		if cause := streamAbort(ctx); cause != nil {
+			logger.Warn("stream aborted", "backend", b.Name(), "reason", cause)
+			_ = sse.event("", abortEvent(cause))
+			if errors.Is(cause, errStreamIdle) {
+				return err
+			}
+			return nil
+		}
 		logger.Error("stream error", "backend", b.Name(), "err", err)
+		mu.Lock()
+		defer mu.Unlock()
+		if clientGone || r.Context().Err() != nil {
+			return nil
+		}
+		return err
+	}
+	middleware.BackendRequestDuration.WithLabelValues(b.Name(), "chat_stream").Observe(time.Since(start).Seconds())
+	if speaking != nil {
+		if err := speaking.finish(true); err != nil && r.Context().Err() == nil {
+			logger.Error("speaking chat answer failed", "err", err)
+			_ = sse.event("", speechErrorEvent(err))
+		}
+		if heldDone {
+			_ = sse.event("", []byte("[DONE]"))
+		}
+	}
+	if acc != nil {
+		mu.Lock()
+		resp, ok := acc.response()
+		mu.Unlock()
+		if ok {
+			caches.save(resp)
+		}
 	}
+	return nil
+}
+
+// writeBackendSelectError writes the error for a failed backend selection,
+// suggesting a back-off when the request was turned away by the wait queue.
+func writeBackendSelectError(w http.ResponseWriter, reg *backend.Registry, err error) {
+	if errors.Is(err, backend.ErrQueueFull) || errors.Is(err, backend.ErrQueueTimeout) {
+		setRetryAfter(w, reg.Balancer().RetryAfter())
+	}
+	apierror.Write(w, backendSelectError(reg, err))
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds, at least 1.
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(d.Seconds())))))
 }
 
 func backendSelectError(reg *backend.Registry, err error) *apierror.Error {
@@ -123,6 +284,12 @@ func backendSelectError(reg *backend.Registry, err error) *apierror.Error {
 	if name == "" {
 		name = "default"
 	}
+	switch {
+	case errors.Is(err, backend.ErrQueueFull):
+		return apierror.QueueFull(name)
+	case errors.Is(err, backend.ErrQueueTimeout):
+		return apierror.QueueTimeout(name)
+	}
 	if errors.Is(err, backend.ErrNoHealthyBackend) || errors.Is(err, backend.ErrBackendNotFound) {
 		return apierror.BackendUnavailable(name)
 	}
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/audio"
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/router"
+	"github.com/menezmethod/inferencia/internal/speech"
+	"github.com/menezmethod/inferencia/internal/voices"
+)
+
+// chatAudioFormats maps the audio formats a chat answer can be spoken in to
+// the TTS response format. Like long speech input, an answer may be
+// synthesized in segments, so only formats that join are offered.
+var chatAudioFormats = map[string]string{
+	"wav":   "wav",
+	"pcm16": "pcm",
+	"mp3":   "mp3",
+	"opus":  "opus",
+}
+
+// chatSpeech speaks the answer to a chat request with audio output.
+type chatSpeech struct {
+	rtr     *router.Registry
+	hc      backend.HealthChecker
+	catalog *voices.Catalog
+	lexicon *speech.Lexicon
+	req     backend.TTSRequest // model, voice and format; input is set per segment
+	id      string
+	logger  *slog.Logger
+}
+
+// newChatSpeech returns how to speak the answer to req, or nil when req
+// does not ask for audio. The audio options are removed from req either
+// way, so they do not reach the chat backend.
+func newChatSpeech(o options, hc backend.HealthChecker, r *http.Request, req *backend.ChatRequest, logger *slog.Logger) (*chatSpeech, *apierror.Error) {
+	a := req.Audio
+	wanted := a != nil || slices.Contains(req.Modalities, "audio")
+	req.Audio, req.Modalities = nil, nil
+	if !wanted {
+		return nil, nil
+	}
+	switch {
+	case o.chatAudio == nil:
+		return nil, apierror.InvalidParam("modalities", "Audio output is not enabled on this server.")
+	case a == nil:
+		return nil, apierror.InvalidParam("audio", "audio is required when modalities include audio")
+	case req.N != nil && *req.N > 1:
+		return nil, apierror.InvalidParam("n", "audio output supports only n=1")
+	}
+
+	format := strings.ToLower(a.Format)
+	if format == "" {
+		format = "wav"
+		if req.Stream {
+			format = "pcm16"
+		}
+	}
+	upstream, ok := chatAudioFormats[format]
+	if !ok {
+		return nil, apierror.InvalidParam("audio.format", "audio.format must be wav, mp3, opus or pcm16")
+	}
+	if req.Stream && format != "pcm16" {
+		return nil, apierror.InvalidParam("audio.format", "streamed audio must be pcm16")
+	}
+
+	return &chatSpeech{
+		rtr:     o.chatAudio,
+		hc:      hc,
+		catalog: o.voices,
+		lexicon: o.lexiconFor(r.Context()),
+		req: backend.TTSRequest{
+			Model:          strings.TrimSpace(a.Model),
+			Voice:          strings.TrimSpace(a.Voice),
+			ResponseFormat: upstream,
+			Speed:          1,
+		},
+		id:     newID("audio_"),
+		logger: logger,
+	}, nil
+}
+
+// speak returns text spoken in the requested format. Text beyond what a TTS
+// backend accepts is synthesized in segments and joined; WAV and PCM are
+// 24 kHz mono.
+func (c *chatSpeech) speak(ctx context.Context, text string) ([]byte, error) {
+	raw := c.req.ResponseFormat == "wav" || c.req.ResponseFormat == "pcm"
+	var out []byte
+	for _, seg := range speech.Pack(c.lexicon.Apply(text), maxTTSInputLength) {
+		req := c.req
+		req.Input = seg
+		if raw {
+			req.ResponseFormat = "wav"
+		}
+		resp, err := synthesize(ctx, c.rtr, c.hc, c.catalog, req, c.logger)
+		if err != nil {
+			return nil, err
+		}
+		if !raw {
+			out = append(out, resp.Audio...)
+			continue
+		}
+		f, pcm, err := audio.ParseWAV(resp.Audio)
+		if err != nil {
+			return nil, err
+		}
+		if pcm, err = audio.ToPCM(f, pcm); err != nil {
+			return nil, err
+		}
+		out = append(out, pcm...)
+	}
+	if c.req.ResponseFormat == "wav" {
+		return audio.EncodeWAV(audio.PCMFormat, out), nil
+	}
+	return out, nil
+}
+
+// attach speaks the text of each choice of resp and adds it as the
+// message's audio.
+func (c *chatSpeech) attach(ctx context.Context, resp *backend.ChatResponse) error {
+	for _, choice := range resp.Choices {
+		if choice.Message == nil {
+			continue
+		}
+		var text string
+		if json.Unmarshal(choice.Message.Content, &text) != nil || strings.TrimSpace(text) == "" {
+			continue
+		}
+		data, err := c.speak(ctx, text)
+		if err != nil {
+			return err
+		}
+		choice.Message.Audio = &backend.MessageAudio{
+			ID:         c.id,
+			Data:       base64.StdEncoding.EncodeToString(data),
+			Transcript: text,
+		}
+	}
+	return nil
+}
+
+// chatSpeechStream speaks a streamed answer sentence by sentence while it is
+// generated, writing each sentence's audio as a chunk of its own after the
+// text chunks that completed the sentence.
+type chatSpeechStream struct {
+	c         *chatSpeech
+	sse       *sseWriter
+	split     *speech.Sentences
+	sentences chan string
+	done      chan error
+	cancel    context.CancelFunc
+
+	mu    sync.Mutex
+	chunk backend.ChatResponse // id, created and model of the answer
+}
+
+// stream starts speaking a streamed answer written to sse.
+func (c *chatSpeech) stream(ctx context.Context, sse *sseWriter) *chatSpeechStream {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &chatSpeechStream{
+		c:         c,
+		sse:       sse,
+		split:     speech.NewSentences(streamSegmentChars),
+		sentences: make(chan string, 16),
+		done:      make(chan error, 1),
+		cancel:    cancel,
+	}
+	go func() { s.done <- s.run(ctx) }()
+	return s
+}
+
+// add takes a chunk of the answer as sent to the client, queueing the
+// sentences it completes.
+func (s *chatSpeechStream) add(data []byte) {
+	var chunk backend.ChatResponse
+	if json.Unmarshal(data, &chunk) != nil {
+		return
+	}
+	s.mu.Lock()
+	if s.chunk.ID == "" {
+		s.chunk.ID, s.chunk.Created, s.chunk.Model = chunk.ID, chunk.Created, chunk.Model
+	}
+	s.mu.Unlock()
+	if len(chunk.Choices) == 0 || chunk.Choices[0].Delta == nil {
+		return
+	}
+	var text string
+	if json.Unmarshal(chunk.Choices[0].Delta.Content, &text) != nil {
+		return
+	}
+	for _, sentence := range s.split.Write(text) {
+		s.sentences <- sentence
+	}
+}
+
+// finish waits for the audio of a complete answer, speaking the text after
+// its last sentence. When the answer failed (complete false), the sentences
+// not yet spoken are dropped.
+func (s *chatSpeechStream) finish(complete bool) error {
+	if !complete {
+		s.cancel()
+	} else if rest := s.split.Flush(); rest != "" {
+		s.sentences <- rest
+	}
+	close(s.sentences)
+	err := <-s.done
+	s.cancel()
+	return err
+}
+
+// run speaks the queued sentences in order until the queue is closed. After
+// a failure the remaining sentences are discarded.
+func (s *chatSpeechStream) run(ctx context.Context) error {
+	var err error
+	for text := range s.sentences {
+		if err != nil || ctx.Err() != nil {
+			continue
+		}
+		var data []byte
+		if data, err = s.c.speak(ctx, text); err != nil {
+			continue
+		}
+		s.mu.Lock()
+		chunk := s.chunk
+		s.mu.Unlock()
+		chunk.Object = "chat.completion.chunk"
+		chunk.Choices = []backend.Choice{{Delta: &backend.Message{
+			Role: "assistant",
+			Audio: &backend.MessageAudio{
+				ID:         s.c.id,
+				Data:       base64.StdEncoding.EncodeToString(data),
+				Transcript: text,
+			},
+		}}}
+		event, _ := json.Marshal(chunk)
+		err = s.sse.event("", event)
+	}
+	return err
+}
+
+// speechError maps an error speaking an answer to an API error.
+func speechError(err error) *apierror.Error {
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) {
+		apiErr = apierror.Internal("The answer could not be spoken.")
+	}
+	return apiErr
+}
+
+// speechErrorEvent is the SSE event for an answer that could not be spoken.
+func speechErrorEvent(err error) []byte {
+	data, _ := json.Marshal(map[string]*apierror.Error{"error": speechError(err)})
+	return data
+}
@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/coder/websocket"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/middleware"
+)
+
+// chatSocketReadLimit bounds one client message, leaving room for images
+// in base64.
+const chatSocketReadLimit = 16 << 20
+
+// errChatCancelled is the cause of a request cancelled by the client.
+var errChatCancelled = errors.New("cancelled by the client")
+
+// ChatCompletionsWebSocket serves chat completions over a WebSocket, for
+// clients behind proxies that buffer SSE.
+//
+//	GET /v1/chat/completions/ws
+//
+// Each text message is a chat completion request, always streamed: the
+// chunks arrive as one message each, followed by "[DONE]", exactly as the
+// data of the SSE stream. A request that fails ends with an {"error": ...}
+// message instead. {"type": "cancel"} stops the request in progress, which
+// then ends with a request_cancelled error. Requests on a connection run
+// one at a time; chat is the POST /v1/chat/completions handler, which serves
+// each of them on behalf of the connection's API key.
+func ChatCompletionsWebSocket(chat http.Handler, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := acceptWebSocket(w, r)
+		if err != nil {
+			logger.Warn("websocket upgrade failed", "path", r.URL.Path, "err", err)
+			return
+		}
+		conn.SetReadLimit(chatSocketReadLimit)
+
+		middleware.ChatWebSocketsActive.Inc()
+		defer middleware.ChatWebSocketsActive.Dec()
+
+		ctx, cancel := context.WithCancel(r.Context())
+		s := &chatSocket{ctx: ctx, conn: conn, chat: chat, logger: logger}
+		s.run()
+
+		// Stop the request in progress before closing, so nothing is
+		// written to a closed connection.
+		cancel()
+		s.wait()
+		_ = conn.Close(websocket.StatusNormalClosure, "")
+	}
+}
+
+// chatSocket is one chat WebSocket connection. run reads the client's
+// messages; each request is served on a goroutine of its own.
+type chatSocket struct {
+	ctx    context.Context
+	conn   *websocket.Conn
+	chat   http.Handler
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	cancel context.CancelCauseFunc // of the request in progress, nil when idle
+	done   chan struct{}           // closed when the last request ends
+}
+
+// run handles the client's messages until the connection closes.
+func (s *chatSocket) run() {
+	for {
+		typ, data, err := s.conn.Read(s.ctx)
+		if err != nil {
+			if websocket.CloseStatus(err) == -1 && s.ctx.Err() == nil {
+				s.logger.Debug("chat websocket closed", "err", err)
+			}
+			return
+		}
+		if typ != websocket.MessageText {
+			s.sendError(apierror.InvalidRequest("Messages must be JSON text."))
+			continue
+		}
+		var msg struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			s.sendError(apierror.InvalidRequest("Invalid JSON message: " + err.Error()))
+			continue
+		}
+		switch msg.Type {
+		case "":
+			s.start(data)
+		case "cancel":
+			s.mu.Lock()
+			if s.cancel != nil {
+				s.cancel(errChatCancelled)
+			}
+			s.mu.Unlock()
+		default:
+			s.sendError(apierror.InvalidParam("type", fmt.Sprintf("Unknown message type %q.", msg.Type)))
+		}
+	}
+}
+
+// start serves the chat request data unless one is already in progress.
+func (s *chatSocket) start(data []byte) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		s.sendError(apierror.InvalidRequest("Invalid JSON message: a request must be a JSON object"))
+		return
+	}
+	fields["stream"] = json.RawMessage("true")
+	body, _ := json.Marshal(fields)
+
+	s.mu.Lock()
+	busy := s.cancel != nil
+	ctx, cancel := context.WithCancelCause(s.ctx)
+	done := make(chan struct{})
+	if !busy {
+		s.cancel, s.done = cancel, done
+	}
+	s.mu.Unlock()
+	if busy {
+		cancel(nil)
+		s.sendError(apierror.InvalidRequest("A request is already in progress on this connection; cancel it or wait for it to finish."))
+		return
+	}
+	go func() {
+		defer close(done)
+		s.serve(ctx, body)
+		s.mu.Lock()
+		s.cancel = nil
+		s.mu.Unlock()
+		cancel(nil)
+	}()
+}
+
+// serve runs one request through the chat handler, sending its stream.
+func (s *chatSocket) serve(ctx context.Context, body []byte) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		s.sendError(apierror.Internal("Failed to start the request."))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	w := &chatSocketWriter{ctx: ctx, s: s, header: make(http.Header)}
+	s.chat.ServeHTTP(w, req)
+
+	switch {
+	case w.done:
+		middleware.ChatWebSocketRequestsTotal.WithLabelValues("completed").Inc()
+	case ctx.Err() != nil:
+		middleware.ChatWebSocketRequestsTotal.WithLabelValues("cancelled").Inc()
+		if errors.Is(context.Cause(ctx), errChatCancelled) {
+			s.sendError(&apierror.Error{
+				Message: "The request was cancelled.",
+				Type:    apierror.TypeInvalidRequest,
+				Code:    "request_cancelled",
+			})
+		}
+	default:
+		middleware.ChatWebSocketRequestsTotal.WithLabelValues("failed").Inc()
+		// A stream that broke off without an error event still needs an
+		// end the client can see.
+		if body := bytes.TrimSpace(w.buf.Bytes()); len(body) > 0 {
+			_ = s.send(body)
+		} else if !w.failed {
+			s.sendError(apierror.Internal("The stream ended before the answer was complete."))
+		}
+	}
+}
+
+// send writes one message.
+func (s *chatSocket) send(data []byte) error {
+	err := s.conn.Write(s.ctx, websocket.MessageText, data)
+	if err != nil && s.ctx.Err() == nil {
+		s.logger.Debug("chat websocket message not sent", "err", err)
+	}
+	return err
+}
+
+// sendError writes an error message, shaped like an SSE error event.
+func (s *chatSocket) sendError(apiErr *apierror.Error) {
+	data, _ := json.Marshal(map[string]*apierror.Error{"error": apiErr})
+	_ = s.send(data)
+}
+
+// wait returns when the request in progress has ended.
+func (s *chatSocket) wait() {
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// chatSocketWriter is the ResponseWriter a request is served to. The data
+// of each SSE event is sent as a message as soon as it is written; any other
+// response, such as an error, is buffered for serve to send. Once the
+// request is cancelled nothing more is sent, and writes fail as they would
+// for a client that went away.
+type chatSocketWriter struct {
+	ctx    context.Context
+	s      *chatSocket
+	header http.Header
+	status int
+	buf    bytes.Buffer
+	done   bool // "[DONE]" was sent
+	failed bool // an error event was sent
+}
+
+func (w *chatSocketWriter) Header() http.Header { return w.header }
+
+func (w *chatSocketWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *chatSocketWriter) Write(p []byte) (int, error) {
+	if w.ctx.Err() != nil {
+		return 0, context.Cause(w.ctx)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.buf.Write(p)
+	if w.status != http.StatusOK || !strings.HasPrefix(w.header.Get("Content-Type"), "text/event-stream") {
+		return len(p), nil
+	}
+	for {
+		i := bytes.Index(w.buf.Bytes(), []byte("\n\n"))
+		if i < 0 {
+			return len(p), nil
+		}
+		for _, line := range bytes.Split(w.buf.Next(i+2), []byte("\n")) {
+			data, ok := bytes.CutPrefix(line, []byte("data: "))
+			if !ok {
+				continue // id, comment or blank line
+			}
+			if err := w.s.send(data); err != nil {
+				return 0, err
+			}
+			w.done = w.done || string(data) == "[DONE]"
+			w.failed = w.failed || bytes.HasPrefix(data, []byte(`{"error"`))
+		}
+	}
+}
+
+// Flush is a no-op: every event is sent as it is written.
+func (w *chatSocketWriter) Flush() {}
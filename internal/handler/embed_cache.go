@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/cache"
+	"github.com/menezmethod/inferencia/internal/middleware"
+)
+
+// embedCacheEntry is the cached embedding of one input string.
+type embedCacheEntry struct {
+	Model     string         `json:"model"`
+	Embedding backend.Vector `json:"embedding"`
+	Tokens    int            `json:"tokens"` // the input's share of prompt tokens
+}
+
+// embedPerInput answers an embedding request input by input: cached inputs are
+// served from c, the rest are sent to the backend in one request and cached.
+// The response lists every input in its original order with usage summed over
+// all inputs. CacheHeader is "hit" when nothing was sent to the backend,
+// "partial" when some inputs were, and "miss" when all were. Embeddings are
+// cached already shortened to req.Dimensions.
+func embedPerInput(w http.ResponseWriter, r *http.Request, e *embedder, c *cache.Cache, req backend.EmbedRequest, inputs []string, format string, logger *slog.Logger) {
+	noCache, noStore := cacheControl(r)
+	entries := make([]*embedCacheEntry, len(inputs))
+	keys := make([]string, len(inputs))
+
+	// Identical inputs within a batch are fetched once.
+	var fetch []int
+	positions := make(map[string][]int)
+	misses := 0
+	for i, input := range inputs {
+		key, err := cache.Key("embed", struct {
+			Model      string `json:"model"`
+			Input      string `json:"input"`
+			Dimensions *int   `json:"dimensions,omitempty"`
+		}{req.Model, input, req.Dimensions})
+		if err != nil {
+			logger.Warn("cache key failed", "kind", "embed", "err", err)
+		}
+		keys[i] = key
+
+		if noCache || noStore {
+			middleware.CacheLookupsTotal.WithLabelValues("embed", "bypass").Inc()
+		} else if e, ok := lookupEmbedding(c, key); ok {
+			middleware.CacheLookupsTotal.WithLabelValues("embed", "hit").Inc()
+			entries[i] = e
+			continue
+		} else {
+			middleware.CacheLookupsTotal.WithLabelValues("embed", "miss").Inc()
+		}
+
+		misses++
+		if _, seen := positions[input]; !seen {
+			fetch = append(fetch, i)
+		}
+		positions[input] = append(positions[input], i)
+	}
+
+	switch {
+	case misses == 0:
+		w.Header().Set(CacheHeader, "hit")
+	case misses < len(inputs):
+		w.Header().Set(CacheHeader, "partial")
+	default:
+		w.Header().Set(CacheHeader, "miss")
+	}
+
+	model := req.Model
+	if len(fetch) > 0 {
+		texts := make([]string, len(fetch))
+		for j, i := range fetch {
+			texts[j] = inputs[i]
+		}
+		sub := req
+		if len(texts) < len(inputs) {
+			sub.Input, _ = json.Marshal(texts)
+		}
+		resp, ok := createEmbedding(w, r, e, sub)
+		if !ok {
+			return
+		}
+		if len(resp.Data) != len(texts) {
+			logger.Error("embedding count mismatch", "inputs", len(texts), "embeddings", len(resp.Data))
+			apierror.Write(w, apierror.Internal(fmt.Sprintf("Backend returned %d embeddings for %d inputs.", len(resp.Data), len(texts))))
+			return
+		}
+		if !shortenEmbeddings(w, resp, req.Dimensions) {
+			return
+		}
+
+		tokens := backend.SplitPromptTokens(resp.Usage, texts)
+		for _, d := range resp.Data {
+			if d.Index < 0 || d.Index >= len(texts) {
+				logger.Error("embedding index out of range", "index", d.Index, "inputs", len(texts))
+				apierror.Write(w, apierror.Internal("Backend returned an embedding with an invalid index."))
+				return
+			}
+			e := &embedCacheEntry{Model: resp.Model, Embedding: d.Embedding, Tokens: tokens[d.Index]}
+			for _, i := range positions[texts[d.Index]] {
+				entries[i] = e
+			}
+			if data, err := json.Marshal(e); err == nil && !noStore && keys[fetch[d.Index]] != "" {
+				c.Set(keys[fetch[d.Index]], data)
+			}
+		}
+		model = resp.Model
+	}
+
+	out := &backend.EmbedResponse{Object: "list", Model: model, Usage: &backend.Usage{}}
+	for i, e := range entries {
+		if e == nil {
+			logger.Error("embedding missing from backend response", "index", i)
+			apierror.Write(w, apierror.Internal("Backend response is missing embeddings."))
+			return
+		}
+		if out.Model == "" {
+			out.Model = e.Model
+		}
+		out.Data = append(out.Data, backend.Embedding{Object: "embedding", Index: i, Embedding: e.Embedding})
+		out.Usage.PromptTokens += e.Tokens
+	}
+	out.Usage.TotalTokens = out.Usage.PromptTokens
+	writeEmbedResponse(w, out, format, logger)
+}
+
+func lookupEmbedding(c *cache.Cache, key string) (*embedCacheEntry, bool) {
+	if key == "" {
+		return nil, false
+	}
+	data, ok := c.Get(key)
+	if !ok {
+		return nil, false
+	}
+	var e embedCacheEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, false
+	}
+	return &e, true
+}
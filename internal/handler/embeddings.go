@@ -1,18 +1,34 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/menezmethod/inferencia/internal/apierror"
 	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/embedbatch"
+	"github.com/menezmethod/inferencia/internal/middleware"
 )
 
-// Embeddings handles embedding creation requests.
+// Embeddings handles embedding creation requests. With WithCache, text inputs
+// are cached one by one, so a batch only sends the inputs not seen before to
+// the backend; other inputs (token arrays) are cached per request.
+//
+// Backends are always asked for floats and may answer in either encoding; the
+// response is encoded as the client asked. When the backend ignores
+// dimensions, embeddings are truncated and re-normalized here. With
+// WithEmbedBatching, concurrent text requests are coalesced before a backend
+// is chosen.
 //
 //	POST /v1/embeddings
-func Embeddings(reg *backend.Registry, hc backend.HealthChecker, logger *slog.Logger) http.HandlerFunc {
+func Embeddings(reg *backend.Registry, hc backend.HealthChecker, logger *slog.Logger, opts ...Option) http.HandlerFunc {
+	o := newOptions(opts)
+	e := o.embedBatching.embedder(reg, hc, logger)
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req backend.EmbedRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -24,24 +40,160 @@ func Embeddings(reg *backend.Registry, hc backend.HealthChecker, logger *slog.Lo
 			apierror.Write(w, apierror.InvalidParam("input", "input is required"))
 			return
 		}
-
-		b, err := reg.PrimaryHealthy(hc)
-		if err != nil {
-			apierror.Write(w, backendSelectError(reg, err))
+		format := req.EncodingFormat
+		if format != "" && format != "float" && format != "base64" {
+			apierror.Write(w, apierror.InvalidParam("encoding_format", "encoding_format must be float or base64"))
 			return
 		}
-		defer reg.ReleaseBackend(b.Name())
+		if req.Dimensions != nil && *req.Dimensions < 1 {
+			apierror.Write(w, apierror.InvalidParam("dimensions", "dimensions must be at least 1"))
+			return
+		}
+		req.EncodingFormat = ""
+
+		if o.cache != nil {
+			if inputs, ok := req.Texts(); ok {
+				embedPerInput(w, r, e, o.cache, req, inputs, format, logger)
+				return
+			}
+		}
+
+		var cr *cachedRequest
+		if o.cache != nil {
+			var hit []byte
+			if cr, hit = lookupCache(o.cache, w, r, "embed", req, logger); hit != nil {
+				var resp backend.EmbedResponse
+				if err := json.Unmarshal(hit, &resp); err == nil {
+					writeEmbedResponse(w, &resp, format, logger)
+					return
+				}
+				logger.Warn("discarding undecodable cache entry", "kind", "embed")
+			}
+		}
 
-		resp, err := b.CreateEmbedding(r.Context(), req)
-		if err != nil {
-			logger.Error("create embedding failed", "backend", b.Name(), "err", err)
-			apierror.Write(w, apierror.FromBackendError(b.Name(), err))
+		resp, ok := createEmbedding(w, r, e, req)
+		if !ok {
+			return
+		}
+		if !shortenEmbeddings(w, resp, req.Dimensions) {
 			return
 		}
+		cr.save(resp)
+		writeEmbedResponse(w, resp, format, logger)
+	}
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(resp); err != nil {
-			logger.Error("failed to encode embedding response", "err", err)
+// createEmbedding sends req to a healthy embedding backend. On failure it
+// writes the error response and returns false.
+func createEmbedding(w http.ResponseWriter, r *http.Request, e *embedder, req backend.EmbedRequest) (*backend.EmbedResponse, bool) {
+	resp, err := e.embed(r.Context(), req)
+	if err != nil {
+		var sel *selectError
+		var apiErr *apierror.Error
+		switch {
+		case errors.As(err, &sel):
+			writeBackendSelectError(w, e.reg, sel.err)
+		case errors.As(err, &apiErr):
+			apierror.Write(w, apiErr)
+		default:
+			apierror.Write(w, apierror.FromBackendError(e.reg.PrimaryName(), err))
 		}
+		return nil, false
+	}
+	return resp, true
+}
+
+// embedder sends embedding requests to the backends. With batching, text
+// requests are coalesced before a backend is admitted, so each upstream call
+// takes one concurrency slot however many requests it carries.
+type embedder struct {
+	reg     *backend.Registry
+	hc      backend.HealthChecker
+	logger  *slog.Logger
+	batcher *embedbatch.Batcher // nil without WithEmbedBatching
+}
+
+// selectError is an embedding request that no backend was admitted for.
+type selectError struct{ err error }
+
+func (e *selectError) Error() string { return e.err.Error() }
+func (e *selectError) Unwrap() error { return e.err }
+
+// embed sends req through the batcher, if any, else straight to a backend.
+func (e *embedder) embed(ctx context.Context, req backend.EmbedRequest) (*backend.EmbedResponse, error) {
+	if e.batcher != nil {
+		return e.batcher.CreateEmbedding(ctx, req)
+	}
+	return e.send(ctx, req)
+}
+
+// send admits a healthy embedding backend and makes one call on it. Backend
+// errors are returned as API errors.
+func (e *embedder) send(ctx context.Context, req backend.EmbedRequest) (*backend.EmbedResponse, error) {
+	b, err := e.reg.AdmitHealthy(ctx, backend.KindEmbed, e.hc)
+	if err != nil {
+		return nil, &selectError{err: err}
+	}
+	defer e.reg.ReleaseBackend(b.Name())
+
+	start := time.Now()
+	resp, err := b.CreateEmbedding(ctx, req)
+	backend.ReportOutcome(e.hc, b.Name(), err)
+	if err != nil {
+		e.logger.Error("create embedding failed", "backend", b.Name(), "err", err)
+		return nil, apierror.FromBackendError(b.Name(), err)
+	}
+	elapsed := time.Since(start)
+	e.reg.ObserveLatency(backend.KindEmbed, b.Name(), elapsed)
+	middleware.BackendRequestDuration.WithLabelValues(b.Name(), "embed").Observe(elapsed.Seconds())
+	return resp, nil
+}
+
+// shortenEmbeddings truncates resp's embeddings to dimensions when the
+// backend returned longer ones. It writes an error response and returns false
+// when they are shorter, since they cannot be extended.
+func shortenEmbeddings(w http.ResponseWriter, resp *backend.EmbedResponse, dimensions *int) bool {
+	if dimensions == nil {
+		return true
+	}
+	for i := range resp.Data {
+		v := resp.Data[i].Embedding
+		if len(v) < *dimensions {
+			apierror.Write(w, apierror.InvalidParam("dimensions",
+				fmt.Sprintf("dimensions %d is larger than the model's %d-dimensional embeddings", *dimensions, len(v))))
+			return false
+		}
+		resp.Data[i].Embedding = v.Truncate(*dimensions)
+	}
+	return true
+}
+
+// base64Embedding is an Embedding encoded for encoding_format "base64".
+type base64Embedding struct {
+	Object    string `json:"object"`
+	Index     int    `json:"index"`
+	Embedding string `json:"embedding"`
+}
+
+// writeEmbedResponse writes resp with its embeddings as arrays of floats, or
+// as base64-packed float32 values when format is "base64".
+func writeEmbedResponse(w http.ResponseWriter, resp *backend.EmbedResponse, format string, logger *slog.Logger) {
+	var body any = resp
+	if format == "base64" {
+		data := make([]base64Embedding, len(resp.Data))
+		for i, e := range resp.Data {
+			data[i] = base64Embedding{Object: e.Object, Index: e.Index, Embedding: e.Embedding.Base64()}
+		}
+		body = struct {
+			Object string            `json:"object"`
+			Data   []base64Embedding `json:"data"`
+			Model  string            `json:"model"`
+			Usage  *backend.Usage    `json:"usage,omitempty"`
+		}{resp.Object, data, resp.Model, resp.Usage}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error("failed to encode embedding response", "err", err)
 	}
 }
@@ -1,16 +1,40 @@
 package handler
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"io"
+	"maps"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/menezmethod/inferencia/internal/async"
+	"github.com/menezmethod/inferencia/internal/audio"
+	"github.com/menezmethod/inferencia/internal/auth"
 	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/batch"
+	"github.com/menezmethod/inferencia/internal/breaker"
+	"github.com/menezmethod/inferencia/internal/cache"
+	"github.com/menezmethod/inferencia/internal/embedbatch"
+	"github.com/menezmethod/inferencia/internal/middleware"
+	"github.com/menezmethod/inferencia/internal/router"
+	"github.com/menezmethod/inferencia/internal/semcache"
+	"github.com/menezmethod/inferencia/internal/speech"
+	"github.com/menezmethod/inferencia/internal/streams"
+	"github.com/menezmethod/inferencia/internal/voices"
 )
 
 var _ = Describe("Health", func() {
@@ -72,6 +96,25 @@ var _ = Describe("HealthStatus", func() {
 			Expect(resp.Services["mock"].Status).To(Equal("healthy"))
 			Expect(resp.Version).NotTo(BeEmpty())
 		})
+
+		It("reports load-balancing scores per capability", func() {
+			mock := &mockBackend{}
+			reg := newTestRegistry(mock)
+			reg.Balancer().SetStrategy(backend.KindChat, backend.StrategyEWMA)
+			reg.ObserveLatency(backend.KindChat, "mock", 250*time.Millisecond)
+			h := HealthStatus(reg, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/health/status", nil)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			var resp HealthStatusResponse
+			Expect(json.NewDecoder(rec.Body).Decode(&resp)).NotTo(HaveOccurred())
+			chat := resp.Services["mock"].LoadBalancing[backend.KindChat]
+			Expect(chat.Strategy).To(Equal(backend.StrategyEWMA))
+			Expect(chat.LatencyMs).To(BeNumerically("~", 250, 0.001))
+			Expect(resp.Services["mock"].LoadBalancing).To(HaveKey(backend.KindEmbed))
+		})
 	})
 
 	When("a chat backend is unhealthy", func() {
@@ -93,6 +136,26 @@ var _ = Describe("HealthStatus", func() {
 		})
 	})
 
+	When("a drained chat backend is down for maintenance", func() {
+		It("reports the drain state without degrading overall status", func() {
+			mock := &mockBackend{healthErr: errors.New("connection refused")}
+			reg := newTestRegistry(mock)
+			Expect(reg.Drain("mock")).To(Succeed())
+			h := HealthStatus(reg, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/health/status", nil)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			var resp HealthStatusResponse
+			Expect(json.NewDecoder(rec.Body).Decode(&resp)).NotTo(HaveOccurred())
+			Expect(resp.Status).To(Equal("healthy"))
+			Expect(resp.Services["mock"].Status).To(Equal("unhealthy"))
+			Expect(resp.Services["mock"].Drain).To(Equal(backend.DrainDrained))
+		})
+	})
+
 	When("a TTS backend is unhealthy", func() {
 		It("returns 503 and reports TTS as degraded", func() {
 			mock := &mockBackend{healthErr: nil}
@@ -329,6 +392,18 @@ var _ = Describe("ChatCompletions", func() {
 			Expect(json.NewDecoder(rec.Body).Decode(&resp)).NotTo(HaveOccurred())
 			Expect(resp.ID).To(Equal("chatcmpl-test"))
 		})
+
+		It("keeps the full round trip out of the time-to-first-token estimate", func() {
+			mock := &mockBackend{chatResp: &backend.ChatResponse{ID: "chatcmpl-test"}}
+			reg := newTestRegistry(mock)
+			h := ChatCompletions(reg, nil, discardLogger())
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"test","messages":[{"role":"user","content":"hi"}]}`))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(reg.Balancer().Score(backend.KindChat, "mock").Samples).To(BeZero())
+		})
 	})
 
 	When("messages are empty", func() {
@@ -501,6 +576,51 @@ var _ = Describe("ChatCompletions", func() {
 		})
 	})
 
+	When("the backend keeps failing behind a circuit breaker", func() {
+		It("opens the circuit and stops calling the backend", func() {
+			mock := &mockBackend{chatErr: errors.New("ollama chat: status 500: boom")}
+			reg := newTestRegistry(mock)
+			cb := breaker.New(breaker.Config{ConsecutiveFailures: 2, Cooldown: time.Minute}, discardLogger())
+			h := ChatCompletions(reg, backend.CombineHealth(stubHealthChecker{}, cb), discardLogger())
+			body := `{"model":"test","messages":[{"role":"user","content":"hi"}]}`
+
+			for range 2 {
+				req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+			}
+			Expect(cb.State("mock")).To(Equal(breaker.Open))
+
+			mock.lastChatReq = backend.ChatRequest{}
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(mock.lastChatReq.Model).To(BeEmpty())
+		})
+	})
+
+	When("the backend is at its concurrency limit", func() {
+		It("returns 503 with Retry-After once the queue times out", func() {
+			reg := newTestRegistry(&mockBackend{chatResp: &backend.ChatResponse{ID: "x"}})
+			reg.Balancer().SetLimit("mock", 1)
+			reg.Balancer().SetQueue(1, 10*time.Millisecond, 2*time.Second)
+			reg.Balancer().Acquire("mock")
+			defer reg.ReleaseBackend("mock")
+
+			h := ChatCompletions(reg, nil, discardLogger())
+			body := `{"model":"test","messages":[{"role":"user","content":"hi"}]}`
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(rec.Header().Get("Retry-After")).To(Equal("2"))
+			Expect(rec.Body.String()).To(ContainSubstring("queue_timeout"))
+		})
+	})
+
 	When("stream is true but ResponseWriter is not a Flusher", func() {
 		It("returns 500 Internal", func() {
 			reg := newTestRegistry(&mockBackend{})
@@ -545,6 +665,45 @@ var _ = Describe("Embeddings", func() {
 		})
 	})
 
+	When("embedding batching is enabled", func() {
+		It("coalesces requests before taking a backend slot", func() {
+			var mu sync.Mutex
+			var calls [][]string
+			mock := &mockBackend{embedFn: func(req backend.EmbedRequest) (*backend.EmbedResponse, error) {
+				inputs, _ := req.Texts()
+				mu.Lock()
+				calls = append(calls, inputs)
+				mu.Unlock()
+				resp := &backend.EmbedResponse{Object: "list", Model: req.Model}
+				for i, in := range inputs {
+					resp.Data = append(resp.Data, backend.Embedding{Object: "embedding", Index: i, Embedding: []float64{float64(len(in))}})
+				}
+				return resp, nil
+			}}
+			reg := newTestRegistry(mock)
+			reg.Balancer().SetLimit("mock", 1)
+			h := Embeddings(reg, nil, discardLogger(), WithEmbedBatching(embedbatch.Config{Window: 50 * time.Millisecond}))
+
+			var wg sync.WaitGroup
+			codes := make([]int, 3)
+			for i, input := range []string{"a", "bb", "ccc"} {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(`{"model":"m","input":`+jsonString(input)+`}`))
+					rec := httptest.NewRecorder()
+					h.ServeHTTP(rec, req)
+					codes[i] = rec.Code
+				}()
+			}
+			wg.Wait()
+
+			Expect(codes).To(HaveEach(http.StatusOK))
+			Expect(calls).To(HaveLen(1))
+			Expect(calls[0]).To(ConsistOf("a", "bb", "ccc"))
+		})
+	})
+
 	When("input is missing", func() {
 		It("returns 400", func() {
 			reg := newTestRegistry(&mockBackend{})
@@ -583,6 +742,79 @@ var _ = Describe("Embeddings", func() {
 		})
 	})
 
+	Describe("encoding_format and dimensions", func() {
+		var (
+			mock *mockBackend
+			sent backend.EmbedRequest
+		)
+
+		BeforeEach(func() {
+			mock = &mockBackend{embedFn: func(req backend.EmbedRequest) (*backend.EmbedResponse, error) {
+				sent = req
+				return &backend.EmbedResponse{
+					Object: "list",
+					Data:   []backend.Embedding{{Object: "embedding", Embedding: backend.Vector{3, 4, 12}}},
+					Model:  "test-embed",
+				}, nil
+			}}
+		})
+
+		embed := func(body string) *httptest.ResponseRecorder {
+			h := Embeddings(newTestRegistry(mock), nil, discardLogger())
+			req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			return rec
+		}
+
+		It("returns base64-packed float32 values and asks the backend for floats", func() {
+			rec := embed(`{"model":"m","input":"hi","encoding_format":"base64"}`)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(sent.EncodingFormat).To(BeEmpty())
+
+			var raw struct {
+				Data []struct {
+					Embedding string `json:"embedding"`
+				} `json:"data"`
+			}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &raw)).To(Succeed())
+			Expect(raw.Data).To(HaveLen(1))
+			Expect(raw.Data[0].Embedding).To(Equal(backend.Vector{3, 4, 12}.Base64()))
+		})
+
+		It("truncates and re-normalizes when the backend ignores dimensions", func() {
+			rec := embed(`{"model":"m","input":"hi","dimensions":2}`)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(sent.Dimensions).NotTo(BeNil())
+			Expect(*sent.Dimensions).To(Equal(2))
+
+			var resp backend.EmbedResponse
+			Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+			Expect(resp.Data[0].Embedding).To(HaveLen(2))
+			Expect(resp.Data[0].Embedding[0]).To(BeNumerically("~", 0.6, 1e-9))
+			Expect(resp.Data[0].Embedding[1]).To(BeNumerically("~", 0.8, 1e-9))
+		})
+
+		It("rejects dimensions larger than the embeddings", func() {
+			rec := embed(`{"model":"m","input":"hi","dimensions":8}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("dimensions"))
+		})
+
+		It("rejects an unknown encoding_format", func() {
+			rec := embed(`{"model":"m","input":"hi","encoding_format":"int8"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(mock.embedCalls).To(BeZero())
+		})
+
+		It("rejects dimensions below 1", func() {
+			rec := embed(`{"model":"m","input":"hi","dimensions":0}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(mock.embedCalls).To(BeZero())
+		})
+	})
+
 	When("no primary backend is registered", func() {
 		It("returns 503 BackendUnavailable", func() {
 			reg := backend.NewRegistry()
@@ -597,6 +829,270 @@ var _ = Describe("Embeddings", func() {
 	})
 })
 
+var _ = Describe("Response cache", func() {
+	var (
+		mock *mockBackend
+		reg  *backend.Registry
+		c    *cache.Cache
+	)
+
+	BeforeEach(func() {
+		finish := "stop"
+		mock = &mockBackend{
+			chatResp: &backend.ChatResponse{
+				ID:      "chatcmpl-cached",
+				Object:  "chat.completion",
+				Model:   "test",
+				Choices: []backend.Choice{{Index: 0, Message: &backend.Message{Role: "assistant", Content: json.RawMessage(`"Hello!"`)}, FinishReason: &finish}},
+				Usage:   &backend.Usage{PromptTokens: 2, CompletionTokens: 3, TotalTokens: 5},
+			},
+			embedResp: &backend.EmbedResponse{
+				Object: "list",
+				Data:   []backend.Embedding{{Object: "embedding", Embedding: []float64{0.1, 0.2}}},
+				Model:  "test-embed",
+			},
+		}
+		reg = newTestRegistry(mock)
+		var err error
+		c, err = cache.New(cache.Config{MaxEntries: 10})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	chat := func(body string, header http.Header) *httptest.ResponseRecorder {
+		h := ChatCompletions(reg, nil, discardLogger(), WithCache(c))
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("serves a repeated deterministic request from the cache", func() {
+		body := `{"model":"test","temperature":0,"messages":[{"role":"user","content":"hi"}]}`
+		first := chat(body, nil)
+		Expect(first.Code).To(Equal(http.StatusOK))
+		Expect(first.Header().Get(CacheHeader)).To(Equal("miss"))
+
+		// Same request with different key order and whitespace.
+		second := chat(`{"messages":[{"content":"hi","role":"user"}], "temperature":0, "model":"test"}`, nil)
+		Expect(second.Code).To(Equal(http.StatusOK))
+		Expect(second.Header().Get(CacheHeader)).To(Equal("hit"))
+		Expect(mock.chatCalls).To(Equal(1))
+
+		var resp backend.ChatResponse
+		Expect(json.Unmarshal(second.Body.Bytes(), &resp)).To(Succeed())
+		Expect(resp.ID).To(Equal("chatcmpl-cached"))
+	})
+
+	It("keeps entries apart per backend", func() {
+		body := `{"model":"test","temperature":0,"messages":[{"role":"user","content":"hi"}]}`
+		chat(body, nil)
+		other := &renamedBackend{mockBackend: &mockBackend{chatResp: mock.chatResp}, name: "other"}
+		reg = newTestRegistry(other)
+		rec := chat(body, nil)
+		Expect(rec.Header().Get(CacheHeader)).To(Equal("miss"))
+		Expect(other.chatCalls).To(Equal(1))
+	})
+
+	It("does not cache sampled requests", func() {
+		body := `{"model":"test","temperature":0.7,"messages":[{"role":"user","content":"hi"}]}`
+		Expect(chat(body, nil).Header().Get(CacheHeader)).To(BeEmpty())
+		chat(body, nil)
+		Expect(mock.chatCalls).To(Equal(2))
+	})
+
+	It("bypasses the lookup with Cache-Control: no-cache but refreshes the entry", func() {
+		body := `{"model":"test","seed":7,"messages":[{"role":"user","content":"hi"}]}`
+		chat(body, nil)
+		rec := chat(body, http.Header{"Cache-Control": {"no-cache"}})
+		Expect(rec.Header().Get(CacheHeader)).To(Equal("miss"))
+		Expect(mock.chatCalls).To(Equal(2))
+		Expect(chat(body, nil).Header().Get(CacheHeader)).To(Equal("hit"))
+	})
+
+	It("replays a cached completion as SSE when the request streams", func() {
+		chat(`{"model":"test","temperature":0,"messages":[{"role":"user","content":"hi"}]}`, nil)
+		rec := chat(`{"model":"test","temperature":0,"stream":true,"messages":[{"role":"user","content":"hi"}]}`, nil)
+
+		Expect(rec.Header().Get(CacheHeader)).To(Equal("hit"))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("text/event-stream"))
+		Expect(rec.Body.String()).To(ContainSubstring(`"delta":{"role":"assistant","content":"Hello!"}`))
+		Expect(rec.Body.String()).To(ContainSubstring(`"finish_reason":"stop"`))
+		Expect(rec.Body.String()).To(HaveSuffix("data: [DONE]\n\n"))
+		Expect(mock.chatCalls).To(Equal(1))
+	})
+
+	It("caches a completed stream for later JSON requests", func() {
+		stream := chat(`{"model":"test","temperature":0,"stream":true,"messages":[{"role":"user","content":"hi"}]}`, nil)
+		Expect(stream.Header().Get(CacheHeader)).To(Equal("miss"))
+
+		rec := chat(`{"model":"test","temperature":0,"messages":[{"role":"user","content":"hi"}]}`, nil)
+		Expect(rec.Header().Get(CacheHeader)).To(Equal("hit"))
+		var resp backend.ChatResponse
+		Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+		Expect(resp.Object).To(Equal("chat.completion"))
+		Expect(string(resp.Choices[0].Message.Content)).To(Equal(`"hi"`))
+	})
+
+	It("caches embeddings", func() {
+		h := Embeddings(reg, nil, discardLogger(), WithCache(c))
+		for range 2 {
+			req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(`{"model":"test-embed","input":"hello"}`))
+			h.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		Expect(mock.embedCalls).To(Equal(1))
+	})
+
+	Context("with a batch of embedding inputs", func() {
+		var sent [][]string
+
+		BeforeEach(func() {
+			sent = nil
+			// Each input embeds to [len(input)] and costs len(input) tokens.
+			mock.embedFn = func(req backend.EmbedRequest) (*backend.EmbedResponse, error) {
+				inputs, _ := req.Texts()
+				sent = append(sent, inputs)
+				resp := &backend.EmbedResponse{Object: "list", Model: "test-embed", Usage: &backend.Usage{}}
+				for i, in := range inputs {
+					resp.Usage.PromptTokens += len(in)
+					resp.Data = append(resp.Data, backend.Embedding{Object: "embedding", Index: i, Embedding: []float64{float64(len(in))}})
+				}
+				return resp, nil
+			}
+		})
+
+		embed := func(body string) (*httptest.ResponseRecorder, backend.EmbedResponse) {
+			h := Embeddings(reg, nil, discardLogger(), WithCache(c))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(body)))
+			var resp backend.EmbedResponse
+			Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+			return rec, resp
+		}
+
+		It("sends only uncached inputs and reassembles the response in order", func() {
+			embed(`{"model":"test-embed","input":["a","bbb"]}`)
+			rec, resp := embed(`{"model":"test-embed","input":["cc","a","dddd","bbb","cc"]}`)
+
+			Expect(rec.Header().Get(CacheHeader)).To(Equal("partial"))
+			Expect(sent).To(Equal([][]string{{"a", "bbb"}, {"cc", "dddd"}}))
+			Expect(resp.Data).To(HaveLen(5))
+			for i, want := range []float64{2, 1, 4, 3, 2} {
+				Expect(resp.Data[i].Index).To(Equal(i))
+				Expect(resp.Data[i].Embedding).To(Equal(backend.Vector{want}))
+			}
+			Expect(resp.Usage.PromptTokens).To(Equal(12))
+			Expect(resp.Usage.TotalTokens).To(Equal(12))
+		})
+
+		It("does not call the backend when every input is cached", func() {
+			embed(`{"model":"test-embed","input":["a","bbb"]}`)
+			rec, resp := embed(`{"model":"test-embed","input":"bbb"}`)
+
+			Expect(rec.Header().Get(CacheHeader)).To(Equal("hit"))
+			Expect(mock.embedCalls).To(Equal(1))
+			Expect(resp.Model).To(Equal("test-embed"))
+			Expect(resp.Data[0].Embedding).To(Equal(backend.Vector{3}))
+		})
+
+		It("keeps models apart", func() {
+			embed(`{"model":"test-embed","input":["a"]}`)
+			rec, _ := embed(`{"model":"other-embed","input":["a"]}`)
+			Expect(rec.Header().Get(CacheHeader)).To(Equal("miss"))
+			Expect(mock.embedCalls).To(Equal(2))
+		})
+	})
+})
+
+var _ = Describe("Semantic cache", func() {
+	var (
+		mock *mockBackend
+		h    http.Handler
+		sc   *semcache.Cache
+	)
+
+	// Questions about refunds embed close to each other, anything else far away.
+	BeforeEach(func() {
+		finish := "stop"
+		mock = &mockBackend{
+			chatResp: &backend.ChatResponse{
+				ID:      "chatcmpl-faq",
+				Object:  "chat.completion",
+				Model:   "test",
+				Choices: []backend.Choice{{Index: 0, Message: &backend.Message{Role: "assistant", Content: json.RawMessage(`"30 days."`)}, FinishReason: &finish}},
+			},
+			embedFn: func(req backend.EmbedRequest) (*backend.EmbedResponse, error) {
+				var text string
+				_ = json.Unmarshal(req.Input, &text)
+				vec := []float64{0, 1}
+				if strings.Contains(strings.ToLower(text), "refund") {
+					vec = []float64{1, float64(len(text)) / 1000}
+				}
+				return &backend.EmbedResponse{Data: []backend.Embedding{{Embedding: vec}}}, nil
+			},
+		}
+		sc = semcache.New(semcache.Config{Threshold: 0.99})
+		ks, err := auth.NewKeyStoreFromKeys([]string{"sk-a", "sk-b"})
+		Expect(err).NotTo(HaveOccurred())
+		h = middleware.Auth(ks)(ChatCompletions(newTestRegistry(mock), nil, discardLogger(), WithSemanticCache(sc, "embed-model", time.Second)))
+	})
+
+	ask := func(key, question string) *httptest.ResponseRecorder {
+		body := `{"model":"test","messages":[{"role":"user","content":` + jsonString(question) + `}]}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("reuses the answer to a similar question from the same key", func() {
+		Expect(ask("sk-a", "What is your refund policy?").Header().Get(CacheHeader)).To(Equal("miss"))
+
+		rec := ask("sk-a", "whats the refund policy")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get(CacheHeader)).To(Equal("semantic"))
+		Expect(rec.Header().Get(SimilarityHeader)).NotTo(BeEmpty())
+		Expect(rec.Body.String()).To(ContainSubstring("30 days."))
+		Expect(mock.chatCalls).To(Equal(1))
+	})
+
+	It("does not reuse answers across keys or for unrelated questions", func() {
+		ask("sk-a", "What is your refund policy?")
+		Expect(ask("sk-b", "What is your refund policy?").Header().Get(CacheHeader)).To(Equal("miss"))
+		Expect(ask("sk-a", "Where are you located?").Header().Get(CacheHeader)).To(Equal("miss"))
+		Expect(mock.chatCalls).To(Equal(3))
+	})
+
+	It("skips the cache when the question cannot be embedded", func() {
+		mock.embedFn = func(backend.EmbedRequest) (*backend.EmbedResponse, error) { return nil, errors.New("down") }
+		rec := ask("sk-a", "What is your refund policy?")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get(CacheHeader)).To(BeEmpty())
+		Expect(sc.Len()).To(BeZero())
+	})
+
+	It("purges entries through the admin endpoint", func() {
+		ask("sk-a", "What is your refund policy?")
+		ask("sk-b", "What is your refund policy?")
+
+		purge := PurgeSemanticCache(sc, discardLogger())
+		rec := httptest.NewRecorder()
+		purge.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/cache/semantic/purge", strings.NewReader(`{"api_key":"sk-a"}`)))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var resp SemanticCachePurgeResponse
+		Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+		Expect(resp).To(Equal(SemanticCachePurgeResponse{Purged: 1, Entries: 1}))
+
+		rec = httptest.NewRecorder()
+		purge.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/cache/semantic/purge", http.NoBody))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(sc.Len()).To(BeZero())
+	})
+})
+
 var _ = Describe("Audio", func() {
 	When("speed is omitted", func() {
 		It("defaults to 1.0 before synthesis", func() {
@@ -629,6 +1125,955 @@ var _ = Describe("Audio", func() {
 			Expect(ttsMock.lastTTSReq.Speed).To(Equal(0.25))
 		})
 	})
+
+	When("the backend cannot produce the response format", func() {
+		It("returns 400 naming response_format", func() {
+			ttsMock := &mockTTSBackend{name: "kokoro"}
+			ttsReg := router.NewRegistry()
+			ttsReg.Register(router.BackendInfo{
+				Name:         "kokoro",
+				TTSBackend:   backend.NewTranscodingTTS(ttsMock, backend.TranscodeConfig{Formats: []string{"wav"}}),
+				Capabilities: []router.Capability{router.CapTTS},
+				Models:       []router.ModelInfo{{ID: "kokoro", Kind: router.CapTTS}},
+			})
+			h := Audio(ttsReg, nil, discardLogger())
+			body := `{"input":"hello","model":"kokoro","response_format":"flac"}`
+			req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring(`"param":"response_format"`))
+		})
+	})
+})
+
+// segmentTTSBackend returns a one-sample WAV per request and records the
+// text of each.
+type segmentTTSBackend struct {
+	*mockTTSBackend
+	inputs   []string
+	speeds   []float64
+	failAt   int  // 1-based request that fails; 0 never
+	stereo   bool // answer with the sample on both channels
+	stereoAt int  // 1-based request answered as if stereo were set; 0 never
+}
+
+func (m *segmentTTSBackend) Synthesize(_ context.Context, req backend.TTSRequest) (*backend.TTSResponse, error) {
+	m.inputs = append(m.inputs, req.Input)
+	m.speeds = append(m.speeds, req.Speed)
+	if len(m.inputs) == m.failAt {
+		return nil, errors.New("tts down")
+	}
+	f := audio.Format{SampleRate: 24000, Channels: 1, BitsPerSample: 16}
+	sample := []byte{byte(len(m.inputs)), 0}
+	if m.stereo || len(m.inputs) == m.stereoAt {
+		f.Channels = 2
+		sample = append(sample, sample...)
+	}
+	return &backend.TTSResponse{Audio: audio.EncodeWAV(f, sample), Format: "audio/wav"}, nil
+}
+
+// streamingTTSBackend streams a fixed body.
+type streamingTTSBackend struct {
+	*mockTTSBackend
+	lastStreamReq backend.TTSRequest
+}
+
+func (m *streamingTTSBackend) SynthesizeStream(_ context.Context, req backend.TTSRequest) (*backend.TTSStream, error) {
+	m.lastStreamReq = req
+	return &backend.TTSStream{Audio: io.NopCloser(strings.NewReader("opus-frames")), Format: "audio/opus"}, nil
+}
+
+var _ = Describe("Speech cache", func() {
+	var (
+		ttsMock *mockTTSBackend
+		rtr     *router.Registry
+		h       http.HandlerFunc
+	)
+
+	BeforeEach(func() {
+		c, err := cache.New(cache.Config{Dir: GinkgoT().TempDir()})
+		Expect(err).NotTo(HaveOccurred())
+		ttsMock = &mockTTSBackend{name: "kokoro"}
+		rtr = newTestTTSRegistry(ttsMock)
+		h = Audio(rtr, nil, discardLogger(), WithSpeechCache(c))
+	})
+
+	speak := func(body string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("serves repeated phrases without the backend", func() {
+		first := speak(`{"input":"Door open.","model":"kokoro","voice":"af_bella"}`)
+		Expect(first.Code).To(Equal(http.StatusOK))
+		Expect(first.Header().Get(CacheHeader)).To(Equal("miss"))
+		ttsMock.lastTTSReq = backend.TTSRequest{}
+
+		second := speak(`{"input":"  Door   open. ","model":"kokoro","voice":"af_bella"}`)
+		Expect(second.Code).To(Equal(http.StatusOK))
+		Expect(second.Header().Get(CacheHeader)).To(Equal("hit"))
+		Expect(second.Header().Get("Content-Type")).To(Equal("audio/wav"))
+		Expect(second.Body.String()).To(Equal("mock-audio"))
+		Expect(ttsMock.lastTTSReq.Input).To(BeEmpty())
+	})
+
+	It("keeps voices, speeds and formats apart", func() {
+		speak(`{"input":"Door open.","model":"kokoro","voice":"af_bella"}`)
+		for _, body := range []string{
+			`{"input":"Door open.","model":"kokoro","voice":"am_adam"}`,
+			`{"input":"Door open.","model":"kokoro","voice":"af_bella","speed":1.5}`,
+			`{"input":"Door open.","model":"kokoro","voice":"af_bella","response_format":"mp3"}`,
+		} {
+			Expect(speak(body).Header().Get(CacheHeader)).To(Equal("miss"), body)
+		}
+	})
+
+	It("keys the audio by the voice the backend is sent", func() {
+		speak(`{"input":"Door open.","model":"kokoro"}`)
+		Expect(ttsMock.lastTTSReq.Voice).To(Equal("default"))
+		rec := speak(`{"input":"Door open.","model":"kokoro","voice":"default"}`)
+		Expect(rec.Header().Get(CacheHeader)).To(Equal("hit"))
+	})
+
+	It("serves hits without admitting a backend", func() {
+		speak(`{"input":"Door open.","model":"kokoro"}`)
+		Expect(rtr.Drain("kokoro")).To(Succeed())
+		rec := speak(`{"input":"Door open.","model":"kokoro"}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get(CacheHeader)).To(Equal("hit"))
+	})
+
+	It("skips the lookup for Cache-Control: no-cache", func() {
+		speak(`{"input":"Door open.","model":"kokoro"}`)
+		ttsMock.lastTTSReq = backend.TTSRequest{}
+		rec := speak(`{"input":"Door open.","model":"kokoro"}`, "Cache-Control", "no-cache")
+		Expect(rec.Header().Get(CacheHeader)).To(Equal("miss"))
+		Expect(ttsMock.lastTTSReq.Input).To(Equal("Door open."))
+	})
+})
+
+var _ = Describe("Streaming speech", func() {
+	speak := func(b backend.TTSBackend, body string) *httptest.ResponseRecorder {
+		reg := router.NewRegistry()
+		reg.Register(router.BackendInfo{
+			Name:         "kokoro",
+			TTSBackend:   b,
+			Capabilities: []router.Capability{router.CapTTS},
+			Models:       []router.ModelInfo{{ID: "kokoro", Kind: router.CapTTS}},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		Audio(reg, nil, discardLogger()).ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("proxies audio from a backend that streams", func() {
+		b := &streamingTTSBackend{mockTTSBackend: &mockTTSBackend{name: "kokoro"}}
+		rec := speak(b, `{"input":"Hello. World.","response_format":"opus","stream":true}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("audio/opus"))
+		Expect(rec.Header().Get("Content-Length")).To(BeEmpty())
+		Expect(rec.Body.String()).To(Equal("opus-frames"))
+		Expect(b.lastStreamReq.Input).To(Equal("Hello. World."))
+	})
+
+	It("synthesizes sentence by sentence into one WAV stream otherwise", func() {
+		b := &segmentTTSBackend{mockTTSBackend: &mockTTSBackend{name: "kokoro"}}
+		rec := speak(b, `{"input":"Hello there. How are you?","stream":true}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(b.inputs).To(Equal([]string{"Hello there.", "How are you?"}))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("audio/wav"))
+
+		f, pcm, err := audio.ParseWAV(rec.Body.Bytes())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.SampleRate).To(Equal(24000))
+		Expect(pcm).To(Equal([]byte{1, 0, 2, 0}))
+	})
+
+	It("joins raw samples for pcm", func() {
+		b := &segmentTTSBackend{mockTTSBackend: &mockTTSBackend{name: "kokoro"}}
+		rec := speak(b, `{"input":"One. Two.","response_format":"pcm","stream":true}`)
+		Expect(rec.Header().Get("Content-Type")).To(Equal("audio/L16"))
+		Expect(rec.Body.Bytes()).To(Equal([]byte{1, 0, 2, 0}))
+	})
+
+	It("converts segments to 24 kHz mono for pcm", func() {
+		b := &segmentTTSBackend{mockTTSBackend: &mockTTSBackend{name: "kokoro"}, stereo: true}
+		rec := speak(b, `{"input":"One. Two.","response_format":"pcm","stream":true}`)
+		Expect(rec.Body.Bytes()).To(Equal([]byte{1, 0, 2, 0}))
+	})
+
+	It("stops a WAV stream at a segment in another format", func() {
+		b := &segmentTTSBackend{mockTTSBackend: &mockTTSBackend{name: "kokoro"}, stereoAt: 2}
+		rec := speak(b, `{"input":"One. Two. Three.","stream":true}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		_, pcm, err := audio.ParseWAV(rec.Body.Bytes())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pcm).To(Equal([]byte{1, 0}))
+		Expect(b.inputs).To(HaveLen(2))
+	})
+
+	It("returns an API error when the first sentence fails", func() {
+		b := &segmentTTSBackend{mockTTSBackend: &mockTTSBackend{name: "kokoro"}, failAt: 1}
+		rec := speak(b, `{"input":"One. Two.","stream":true}`)
+		Expect(rec.Code).To(BeNumerically(">=", 500))
+		Expect(b.inputs).To(HaveLen(1))
+	})
+
+	It("stops the stream when a later sentence fails", func() {
+		b := &segmentTTSBackend{mockTTSBackend: &mockTTSBackend{name: "kokoro"}, failAt: 2}
+		rec := speak(b, `{"input":"One. Two. Three.","response_format":"pcm","stream":true}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.Bytes()).To(Equal([]byte{1, 0}))
+		Expect(b.inputs).To(HaveLen(2))
+	})
+})
+
+// textTTSBackend returns a WAV whose samples are the request's text, padded
+// to whole samples, and tracks how many requests run at once.
+type textTTSBackend struct {
+	*mockTTSBackend
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	voices      []string
+}
+
+func (m *textTTSBackend) Synthesize(_ context.Context, req backend.TTSRequest) (*backend.TTSResponse, error) {
+	m.mu.Lock()
+	m.inFlight++
+	m.maxInFlight = max(m.maxInFlight, m.inFlight)
+	m.voices = append(m.voices, req.Voice)
+	m.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	m.mu.Lock()
+	m.inFlight--
+	m.mu.Unlock()
+
+	f := audio.Format{SampleRate: 24000, Channels: 1, BitsPerSample: 16}
+	return &backend.TTSResponse{Audio: audio.EncodeWAV(f, []byte(padEven(req.Input))), Format: "audio/wav"}, nil
+}
+
+func padEven(s string) string {
+	if len(s)%2 == 1 {
+		return s + " "
+	}
+	return s
+}
+
+var _ = Describe("SSML speech", func() {
+	var (
+		b   *segmentTTSBackend
+		reg *router.Registry
+	)
+
+	BeforeEach(func() {
+		b = &segmentTTSBackend{mockTTSBackend: &mockTTSBackend{name: "kokoro"}}
+		reg = router.NewRegistry()
+		reg.Register(router.BackendInfo{
+			Name:         "kokoro",
+			TTSBackend:   b,
+			Capabilities: []router.Capability{router.CapTTS},
+			Models:       []router.ModelInfo{{ID: "kokoro", Kind: router.CapTTS}},
+		})
+	})
+
+	speak := func(input string, opts ...Option) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]any{"input": input, "speed": 2})
+		req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		Audio(reg, nil, discardLogger(), opts...).ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("sends SSML without pauses as plain text in one request", func() {
+		rec := speak(`<speak>Ask <sub alias="the W three C">W3C</sub>.</speak>`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(b.inputs).To(Equal([]string{"Ask the W three C."}))
+	})
+
+	It("synthesizes pauses as silence and rates as speeds", func() {
+		rec := speak(`<speak>One.<break time="100ms"/><prosody rate="50%">Two.</prosody></speak>`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(b.inputs).To(Equal([]string{"One.", "Two."}))
+		Expect(b.speeds).To(Equal([]float64{2, 1}))
+
+		_, pcm, err := audio.ParseWAV(rec.Body.Bytes())
+		Expect(err).NotTo(HaveOccurred())
+		// One sample, 100ms of silence at 24 kHz, one sample.
+		Expect(pcm).To(HaveLen(2 + 4800 + 2))
+		Expect(pcm[2 : 2+4800]).To(Equal(make([]byte, 4800)))
+	})
+
+	It("converts pieces to 24 kHz mono for pcm", func() {
+		b.stereo = true
+		body := `{"input":"<speak>One.<break time=\"100ms\"/>Two.</speak>","response_format":"pcm"}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		Audio(reg, nil, discardLogger()).ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		pcm := rec.Body.Bytes()
+		Expect(pcm).To(HaveLen(2 + 4800 + 2))
+		Expect(pcm[:2]).To(Equal([]byte{1, 0}))
+		Expect(pcm[2+4800:]).To(Equal([]byte{2, 0}))
+	})
+
+	It("applies the key's pronunciation lexicon over the global one", func() {
+		GinkgoT().Setenv("INFERENCIA_API_KEYS", "sk-ops lexicon=ops,sk-other")
+		ks, err := auth.NewKeyStore("")
+		Expect(err).NotTo(HaveOccurred())
+		global := map[string]string{"nginx": "engine x", "k8s": "kates"}
+		opt := WithLexicons(speech.NewLexicon(global), map[string]*speech.Lexicon{
+			"ops": speech.NewLexicon(global, map[string]string{"k8s": "kubernetes"}),
+		}, ks)
+
+		for _, key := range []string{"sk-ops", "sk-other"} {
+			body := `{"input":"Restart nginx on k8s."}`
+			req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(body))
+			req = req.WithContext(middleware.WithAPIKey(req.Context(), key))
+			Audio(reg, nil, discardLogger(), opt).ServeHTTP(httptest.NewRecorder(), req)
+		}
+		Expect(b.inputs).To(Equal([]string{"Restart engine x on kubernetes.", "Restart engine x on kates."}))
+	})
+
+	It("rejects malformed SSML", func() {
+		rec := speak(`<speak>Unclosed`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring("invalid SSML"))
+		Expect(b.inputs).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Long speech", func() {
+	var (
+		b        *textTTSBackend
+		reg      *router.Registry
+		long     string
+		segments []string
+	)
+
+	BeforeEach(func() {
+		b = &textTTSBackend{mockTTSBackend: &mockTTSBackend{name: "kokoro"}}
+		reg = router.NewRegistry()
+		reg.Register(router.BackendInfo{
+			Name:         "kokoro",
+			TTSBackend:   b,
+			Capabilities: []router.Capability{router.CapTTS},
+			Models:       []router.ModelInfo{{ID: "kokoro", Kind: router.CapTTS}},
+			DefaultVoice: "af_bella",
+		})
+		long = strings.Repeat("This sentence is here to make the input long. ", 100)
+		segments = speech.Pack(long, 1000)
+	})
+
+	speak := func(body string, opts ...Option) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		Audio(reg, nil, discardLogger(), opts...).ServeHTTP(rec, req)
+		return rec
+	}
+	longOpt := WithLongSpeech(10000, 1000, 2, 0)
+
+	It("rejects input over 4096 characters without long-form speech", func() {
+		rec := speak(`{"input":"` + long + `"}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring("at most 4096"))
+	})
+
+	It("synthesizes segments in parallel and joins them into one WAV", func() {
+		rec := speak(`{"input":"`+long+`"}`, longOpt)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("audio/wav"))
+		Expect(rec.Header().Get("Content-Length")).To(Equal(strconv.Itoa(rec.Body.Len())))
+
+		_, pcm, err := audio.ParseWAV(rec.Body.Bytes())
+		Expect(err).NotTo(HaveOccurred())
+		var want strings.Builder
+		for _, seg := range segments {
+			want.WriteString(padEven(seg))
+		}
+		Expect(string(pcm)).To(Equal(want.String()))
+		Expect(len(segments)).To(BeNumerically(">", 2))
+		Expect(b.maxInFlight).To(Equal(2))
+		Expect(b.voices).To(HaveEach("af_bella"))
+	})
+
+	It("streams joined samples in order", func() {
+		rec := speak(`{"input":"`+long+`","response_format":"pcm","stream":true}`, longOpt)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("audio/L16"))
+		Expect(rec.Body.String()).To(HavePrefix(padEven(segments[0]) + padEven(segments[1])))
+	})
+
+	It("rejects formats that cannot be joined", func() {
+		rec := speak(`{"input":"`+long+`","response_format":"flac"}`, longOpt)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring("response_format"))
+	})
+
+	It("rejects input over the long-form limit", func() {
+		rec := speak(`{"input":"`+long+`"}`, WithLongSpeech(4500, 1000, 2, 0))
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring("at most 4500"))
+	})
+})
+
+var _ = Describe("Voices", func() {
+	var (
+		kokoro, piper *mockTTSBackend
+		reg           *router.Registry
+		catalog       *voices.Catalog
+	)
+
+	BeforeEach(func() {
+		kokoro = &mockTTSBackend{name: "kokoro", voices: []backend.Voice{{ID: "af_bella"}, {ID: "bf_emma"}}}
+		piper = &mockTTSBackend{name: "piper", voices: []backend.Voice{{ID: "amy"}}}
+		reg = router.NewRegistry()
+		for _, m := range []*mockTTSBackend{kokoro, piper} {
+			reg.Register(router.BackendInfo{
+				Name:         m.name,
+				TTSBackend:   m,
+				Capabilities: []router.Capability{router.CapTTS},
+				Models:       []router.ModelInfo{{ID: m.name, Kind: router.CapTTS}},
+			})
+		}
+		catalog = voices.New(reg, nil, time.Minute, discardLogger())
+	})
+
+	speak := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		Audio(reg, nil, discardLogger(), WithVoices(catalog)).ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("lists the voices of every backend", func() {
+		rec := httptest.NewRecorder()
+		Voices(catalog).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/audio/voices", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var list VoiceList
+		Expect(json.Unmarshal(rec.Body.Bytes(), &list)).To(Succeed())
+		Expect(list.Data).To(HaveLen(3))
+		Expect(list.Data[1].ID).To(Equal("amy"))
+		Expect(list.Data[1].Backends).To(Equal([]string{"piper"}))
+	})
+
+	It("routes a voice without a model to the backend that offers it", func() {
+		rec := speak(`{"input":"hi","voice":"amy"}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(piper.lastTTSReq.Voice).To(Equal("amy"))
+		Expect(piper.lastTTSReq.Model).To(Equal("piper"))
+		Expect(kokoro.lastTTSReq.Input).To(BeEmpty())
+	})
+
+	It("uses the backend's first voice when none is named or configured", func() {
+		rec := speak(`{"input":"hi"}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(kokoro.lastTTSReq.Voice).To(Equal("af_bella"))
+	})
+
+	It("rejects an unknown voice with suggestions", func() {
+		rec := speak(`{"input":"hi","voice":"bf_ema"}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring(`"code":"voice_not_found"`))
+		Expect(rec.Body.String()).To(ContainSubstring("Did you mean bf_emma"))
+	})
+
+	It("rejects a voice the named model does not offer", func() {
+		rec := speak(`{"input":"hi","model":"kokoro","voice":"amy"}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring(`not offered by model \"kokoro\"`))
+	})
+})
+
+var _ = Describe("Rerank", func() {
+	var mock *mockRerankBackend
+
+	BeforeEach(func() {
+		mock = &mockRerankBackend{
+			name: "tei",
+			resp: &backend.RerankResponse{Results: []backend.RerankResult{
+				{Index: 0, RelevanceScore: 0.1},
+				{Index: 1, RelevanceScore: 0.9},
+				{Index: 2, RelevanceScore: 0.5},
+			}},
+		}
+	})
+
+	rerank := func(reg *router.Registry, body string) *httptest.ResponseRecorder {
+		h := Rerank(reg, nil, discardLogger())
+		req := httptest.NewRequest(http.MethodPost, "/v1/rerank", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("returns results by relevance, cut to top_n, with the documents", func() {
+		rec := rerank(newTestRerankRegistry([]string{"bge"}, mock),
+			`{"model":"bge","query":"q","documents":["a",{"text":"b"},"c"],"top_n":2,"return_documents":true}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(mock.lastReq.Documents).To(Equal([]backend.RerankDocument{{Text: "a"}, {Text: "b"}, {Text: "c"}}))
+
+		var resp backend.RerankResponse
+		Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+		Expect(resp.Model).To(Equal("bge"))
+		Expect(resp.Results).To(HaveLen(2))
+		Expect(resp.Results[0].Index).To(Equal(1))
+		Expect(resp.Results[0].Document).To(Equal(&backend.RerankDocument{Text: "b"}))
+		Expect(resp.Results[1].Index).To(Equal(2))
+	})
+
+	It("omits documents unless return_documents is set", func() {
+		mock.resp.Results[0].Document = &backend.RerankDocument{Text: "a"}
+		rec := rerank(newTestRerankRegistry(nil, mock), `{"query":"q","documents":["a","b","c"]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).NotTo(ContainSubstring(`"document"`))
+	})
+
+	It("routes to the backend that lists the model", func() {
+		other := &mockRerankBackend{name: "infinity", resp: &backend.RerankResponse{}}
+		rec := rerank(newTestRerankRegistry([]string{"bge", "jina"}, mock, other), `{"model":"jina","query":"q","documents":["a"]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(other.lastReq.Query).To(Equal("q"))
+		Expect(mock.lastReq.Query).To(BeEmpty())
+	})
+
+	It("accepts a prefix of a listed model, as routing does", func() {
+		rec := rerank(newTestRerankRegistry([]string{"bge-reranker-v2-m3"}, mock), `{"model":"bge-reranker","query":"q","documents":["a","b","c"]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(mock.lastReq.Query).To(Equal("q"))
+	})
+
+	It("returns 404 for a model no backend lists", func() {
+		rec := rerank(newTestRerankRegistry([]string{"bge"}, mock), `{"model":"jina","query":"q","documents":["a"]}`)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(rec.Body.String()).To(ContainSubstring(`"code":"model_not_found"`))
+		Expect(mock.lastReq.Query).To(BeEmpty())
+	})
+
+	It("rejects a missing query or empty documents", func() {
+		reg := newTestRerankRegistry(nil, mock)
+		Expect(rerank(reg, `{"documents":["a"]}`).Code).To(Equal(http.StatusBadRequest))
+		Expect(rerank(reg, `{"query":"q","documents":[]}`).Code).To(Equal(http.StatusBadRequest))
+		Expect(rerank(reg, `{"query":"q","documents":[{"title":"a"}]}`).Code).To(Equal(http.StatusBadRequest))
+		Expect(rerank(reg, `{"query":"q","documents":["a"],"top_n":0}`).Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("rejects results that point past the documents", func() {
+		rec := rerank(newTestRerankRegistry(nil, mock), `{"query":"q","documents":["a"]}`)
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("returns 503 when no rerank backend is registered", func() {
+		rec := rerank(router.NewRegistry(), `{"query":"q","documents":["a"]}`)
+		Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+	})
+})
+
+var _ = Describe("Batches", func() {
+	var (
+		mux   *http.ServeMux
+		store *batch.Store
+		mock  *mockBackend
+	)
+
+	BeforeEach(func() {
+		var err error
+		store, err = batch.OpenStore(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		mock = &mockBackend{chatResp: &backend.ChatResponse{ID: "chatcmpl-1", Object: "chat.completion", Model: "llama"}}
+
+		logger := discardLogger()
+		m := batch.NewManager(store, map[string]http.Handler{
+			"/v1/chat/completions": ChatCompletions(newTestRegistry(mock), nil, logger),
+		}, batch.Config{Workers: 1}, logger)
+		m.Start()
+		DeferCleanup(m.Stop)
+
+		mux = http.NewServeMux()
+		mux.Handle("POST /v1/files", UploadFile(store, 1<<10, logger))
+		mux.Handle("GET /v1/files/{id}", GetFile(store))
+		mux.Handle("GET /v1/files/{id}/content", FileContent(store, logger))
+		mux.Handle("POST /v1/batches", CreateBatch(m, logger))
+		mux.Handle("GET /v1/batches", ListBatches(m))
+		mux.Handle("GET /v1/batches/{id}", GetBatch(m))
+		mux.Handle("POST /v1/batches/{id}/cancel", CancelBatch(m, logger))
+	})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req = req.WithContext(middleware.WithAPIKey(req.Context(), "sk-test"))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	upload := func(purpose, content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		_ = mw.WriteField("purpose", purpose)
+		fw, _ := mw.CreateFormFile("file", "input.jsonl")
+		_, _ = fw.Write([]byte(content))
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/v1/files", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return serve(req)
+	}
+
+	It("runs an uploaded file and serves the results", func() {
+		rec := upload("batch", `{"custom_id":"q1","method":"POST","url":"/v1/chat/completions","body":{"model":"llama","messages":[{"role":"user","content":"hi"}]}}`+"\n")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var f batch.File
+		Expect(json.Unmarshal(rec.Body.Bytes(), &f)).To(Succeed())
+		Expect(f.Purpose).To(Equal("batch"))
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/v1/batches",
+			strings.NewReader(`{"input_file_id":"`+f.ID+`","endpoint":"/v1/chat/completions","completion_window":"24h"}`)))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var b batch.Batch
+		Expect(json.Unmarshal(rec.Body.Bytes(), &b)).To(Succeed())
+
+		Eventually(func() batch.Status {
+			rec := serve(httptest.NewRequest(http.MethodGet, "/v1/batches/"+b.ID, nil))
+			_ = json.Unmarshal(rec.Body.Bytes(), &b)
+			return b.Status
+		}, 5*time.Second, 10*time.Millisecond).Should(Equal(batch.StatusCompleted))
+		Expect(b.RequestCounts.Completed).To(Equal(1))
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/v1/files/"+b.OutputFileID+"/content", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"custom_id":"q1"`))
+		Expect(rec.Body.String()).To(ContainSubstring(`"status_code":200`))
+		Expect(rec.Body.String()).To(ContainSubstring(`chatcmpl-1`))
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/v1/batches?limit=1", nil))
+		var list BatchList
+		Expect(json.Unmarshal(rec.Body.Bytes(), &list)).To(Succeed())
+		Expect(list.Data).To(HaveLen(1))
+		Expect(list.FirstID).To(Equal(b.ID))
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/v1/batches/"+b.ID+"/cancel", nil))
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("rejects uploads with another purpose or over the size limit", func() {
+		Expect(upload("fine-tune", "{}\n").Code).To(Equal(http.StatusBadRequest))
+		Expect(upload("batch", strings.Repeat("x", 2<<10)).Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("rejects batches for unsupported endpoints", func() {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/v1/batches",
+			strings.NewReader(`{"input_file_id":"file-x","endpoint":"/v1/rerank","completion_window":"24h"}`)))
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring(`"param":"endpoint"`))
+	})
+
+	It("returns 404 for batches and files of other keys", func() {
+		Expect(serve(httptest.NewRequest(http.MethodGet, "/v1/batches/batch_missing", nil)).Code).To(Equal(http.StatusNotFound))
+		Expect(serve(httptest.NewRequest(http.MethodGet, "/v1/files/file-missing", nil)).Code).To(Equal(http.StatusNotFound))
+	})
+})
+
+var _ = Describe("Async", func() {
+	var (
+		m    *async.Manager
+		mock *mockBackend
+		chat http.HandlerFunc
+	)
+
+	BeforeEach(func() {
+		m = async.New(async.Config{TTL: time.Minute}, discardLogger())
+		DeferCleanup(m.Stop)
+		mock = &mockBackend{chatResp: &backend.ChatResponse{ID: "chatcmpl-1", Object: "chat.completion", Model: "llama"}}
+		chat = ChatCompletions(newTestRegistry(mock), nil, discardLogger(), WithAsync(m))
+	})
+
+	post := func(h http.Handler, body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		maps.Copy(req.Header, header)
+		req = req.WithContext(middleware.WithAPIKey(req.Context(), "sk-test"))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	poll := func(key, id string) async.Job {
+		var j async.Job
+		Eventually(func() async.Status {
+			req := httptest.NewRequest(http.MethodGet, "/v1/async/"+id, nil)
+			req.SetPathValue("id", id)
+			req = req.WithContext(middleware.WithAPIKey(req.Context(), key))
+			rec := httptest.NewRecorder()
+			GetAsyncJob(m).ServeHTTP(rec, req)
+			_ = json.Unmarshal(rec.Body.Bytes(), &j)
+			return j.Status
+		}, 5*time.Second, 10*time.Millisecond).Should(Equal(async.StatusCompleted))
+		return j
+	}
+
+	It("answers 202 for Prefer: respond-async and serves the result by polling", func() {
+		rec := post(chat, `{"model":"llama","messages":[{"role":"user","content":"hi"}]}`, http.Header{"Prefer": {"respond-async"}})
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		Expect(rec.Header().Get("Preference-Applied")).To(Equal("respond-async"))
+		var j async.Job
+		Expect(json.Unmarshal(rec.Body.Bytes(), &j)).To(Succeed())
+		Expect(rec.Header().Get("Location")).To(Equal("/v1/async/" + j.ID))
+
+		j = poll("sk-test", j.ID)
+		Expect(j.Response.StatusCode).To(Equal(http.StatusOK))
+		Expect(string(j.Response.Body)).To(ContainSubstring("chatcmpl-1"))
+		Expect(mock.chatCalls).To(Equal(1))
+	})
+
+	It("answers synchronously without the preference", func() {
+		rec := post(chat, `{"model":"llama","messages":[{"role":"user","content":"hi"}]}`, nil)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring("chatcmpl-1"))
+	})
+
+	It("runs every request on the async endpoint as a job", func() {
+		rec := post(SubmitAsync(m, "/v1/chat/completions", chat, discardLogger()), `{"model":"llama","messages":[{"role":"user","content":"hi"}]}`, nil)
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+	})
+
+	It("rejects streaming requests and webhooks when none are configured", func() {
+		prefer := http.Header{"Prefer": {"respond-async"}}
+		rec := post(chat, `{"stream":true,"messages":[{"role":"user","content":"hi"}]}`, prefer)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+
+		prefer.Set("X-Inferencia-Webhook", "https://hooks.example.com/done")
+		rec = post(chat, `{"messages":[{"role":"user","content":"hi"}]}`, prefer)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring("webhooks are not enabled"))
+	})
+
+	It("returns 404 for jobs of other keys", func() {
+		rec := post(chat, `{"messages":[{"role":"user","content":"hi"}]}`, http.Header{"Prefer": {"respond-async"}})
+		var j async.Job
+		Expect(json.Unmarshal(rec.Body.Bytes(), &j)).To(Succeed())
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/async/"+j.ID, nil)
+		req.SetPathValue("id", j.ID)
+		req = req.WithContext(middleware.WithAPIKey(req.Context(), "sk-other"))
+		other := httptest.NewRecorder()
+		GetAsyncJob(m).ServeHTTP(other, req)
+		Expect(other.Code).To(Equal(http.StatusNotFound))
+		poll("sk-test", j.ID)
+	})
+})
+
+// gatedStreamBackend streams "one", waits for release, then streams "two".
+type gatedStreamBackend struct {
+	*mockBackend
+	sent    chan struct{}
+	release chan struct{}
+	prefill time.Duration // silence before the first chunk
+}
+
+func (g *gatedStreamBackend) ChatCompletionStream(ctx context.Context, _ backend.ChatRequest, send backend.StreamFunc) error {
+	select {
+	case <-time.After(g.prefill):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err := send([]byte(`{"chunk":"one"}`)); err != nil {
+		return err
+	}
+	close(g.sent)
+	select {
+	case <-g.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err := send([]byte(`{"chunk":"two"}`)); err != nil {
+		return err
+	}
+	return send([]byte("[DONE]"))
+}
+
+var _ = Describe("Resumable streams", func() {
+	var (
+		gated *gatedStreamBackend
+		chat  http.HandlerFunc
+	)
+
+	BeforeEach(func() {
+		gated = &gatedStreamBackend{mockBackend: &mockBackend{}, sent: make(chan struct{}), release: make(chan struct{})}
+		store := streams.New(streams.Config{Grace: time.Minute})
+		chat = ChatCompletions(newTestRegistry(gated), nil, discardLogger(), WithResumableStreams(store))
+	})
+
+	serve := func(ctx context.Context, lastEventID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"llama","stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		req = req.WithContext(middleware.WithAPIKey(ctx, "sk-test"))
+		rec := httptest.NewRecorder()
+		chat.ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("keeps generating after a disconnect and resumes after the last event", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		first := make(chan *httptest.ResponseRecorder)
+		go func() { first <- serve(ctx, "") }()
+		Eventually(gated.sent).Should(BeClosed())
+		cancel()
+		var rec *httptest.ResponseRecorder
+		Eventually(first).Should(Receive(&rec))
+
+		id := rec.Header().Get("X-Inferencia-Stream-ID")
+		Expect(id).NotTo(BeEmpty())
+		Expect(rec.Body.String()).To(Equal("id: " + id + `:1` + "\n" + `data: {"chunk":"one"}` + "\n\n"))
+
+		close(gated.release)
+		rec = serve(context.Background(), id+":1")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(Equal(
+			"id: " + id + `:2` + "\n" + `data: {"chunk":"two"}` + "\n\n" +
+				"id: " + id + ":3\ndata: [DONE]\n\n"))
+	})
+
+	It("returns 404 for streams that are gone", func() {
+		rec := serve(context.Background(), "stream_missing:3")
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+	})
+})
+
+var _ = Describe("Stream limits", func() {
+	var gated *gatedStreamBackend
+
+	BeforeEach(func() {
+		gated = &gatedStreamBackend{mockBackend: &mockBackend{}, sent: make(chan struct{}), release: make(chan struct{})}
+	})
+
+	stream := func(opts ...Option) *httptest.ResponseRecorder {
+		h := ChatCompletions(newTestRegistry(gated), nil, discardLogger(), opts...)
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"llama","stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("sends keep-alive comments while the backend is silent", func() {
+		time.AfterFunc(100*time.Millisecond, func() { close(gated.release) })
+		rec := stream(WithStreamLimits(20*time.Millisecond, 0, 0, 0))
+		body := rec.Body.String()
+		Expect(body).To(ContainSubstring(": keep-alive\n\n"))
+		Expect(body).To(HaveSuffix("data: [DONE]\n\n"))
+	})
+
+	It("aborts a stream whose backend stalls past the idle timeout", func() {
+		rec := stream(WithStreamLimits(0, 0, 50*time.Millisecond, 0))
+		body := rec.Body.String()
+		Expect(body).To(ContainSubstring(`{"chunk":"one"}`))
+		Expect(body).To(ContainSubstring(`"code":"idle_timeout"`))
+		Expect(body).NotTo(ContainSubstring("[DONE]"))
+	})
+
+	It("does not hold a slow first chunk against the idle timeout", func() {
+		gated.prefill = 150 * time.Millisecond
+		close(gated.release)
+		body := stream(WithStreamLimits(0, 0, 50*time.Millisecond, 0)).Body.String()
+		Expect(body).NotTo(ContainSubstring("idle_timeout"))
+		Expect(body).To(HaveSuffix("data: [DONE]\n\n"))
+	})
+
+	It("aborts a stream whose backend sends nothing within the first chunk timeout", func() {
+		gated.prefill = time.Second
+		body := stream(WithStreamLimits(0, 50*time.Millisecond, time.Second, 0)).Body.String()
+		Expect(body).To(ContainSubstring(`"code":"first_chunk_timeout"`))
+		Expect(body).NotTo(ContainSubstring(`{"chunk":"one"}`))
+	})
+
+	It("aborts a stream that runs past its maximum duration", func() {
+		rec := stream(WithStreamLimits(0, 0, 0, 50*time.Millisecond))
+		Expect(rec.Body.String()).To(ContainSubstring(`"code":"max_duration"`))
+	})
+})
+
+var _ = Describe("Admin backends", func() {
+	var (
+		reg    *backend.Registry
+		ttsReg *router.Registry
+		mux    *http.ServeMux
+	)
+
+	BeforeEach(func() {
+		reg = newTestRegistry(&mockBackend{})
+		ttsReg = newTestTTSRegistry(&mockTTSBackend{name: "kokoro"})
+		mux = http.NewServeMux()
+		mux.Handle("GET /admin/backends", AdminBackends(reg, ttsReg))
+		mux.Handle("POST /admin/backends/{name}/drain", DrainBackend(reg, ttsReg, discardLogger()))
+		mux.Handle("POST /admin/backends/{name}/resume", ResumeBackend(reg, ttsReg, discardLogger()))
+	})
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	It("drains a busy chat backend and reports drained once idle", func() {
+		b, err := reg.PrimaryHealthy(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		rec := serve(http.MethodPost, "/admin/backends/mock/drain")
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		var st BackendDrainStatus
+		Expect(json.NewDecoder(rec.Body).Decode(&st)).NotTo(HaveOccurred())
+		Expect(st.State).To(Equal(backend.DrainDraining))
+		Expect(st.InFlight).To(Equal(1))
+
+		reg.ReleaseBackend(b.Name())
+
+		rec = serve(http.MethodGet, "/admin/backends")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var list AdminBackendsResponse
+		Expect(json.NewDecoder(rec.Body).Decode(&list)).NotTo(HaveOccurred())
+		Expect(list.Data).To(ConsistOf(
+			BackendDrainStatus{Name: "kokoro", Type: "tts", State: backend.DrainActive},
+			BackendDrainStatus{Name: "mock", Type: "chat", State: backend.DrainDrained},
+		))
+	})
+
+	It("reports the capability of each router backend", func() {
+		ttsReg.Register(router.BackendInfo{
+			Name:          "reranker",
+			RerankBackend: &mockRerankBackend{name: "reranker"},
+			Capabilities:  []router.Capability{router.CapRerank},
+		})
+
+		rec := serve(http.MethodGet, "/admin/backends")
+		var list AdminBackendsResponse
+		Expect(json.NewDecoder(rec.Body).Decode(&list)).NotTo(HaveOccurred())
+		Expect(list.Data).To(ContainElement(BackendDrainStatus{Name: "reranker", Type: "rerank", State: backend.DrainActive}))
+	})
+
+	It("drains and resumes a TTS backend", func() {
+		Expect(serve(http.MethodPost, "/admin/backends/kokoro/drain").Code).To(Equal(http.StatusAccepted))
+		Expect(ttsReg.DrainState("kokoro")).To(Equal(backend.DrainDrained))
+
+		Expect(serve(http.MethodPost, "/admin/backends/kokoro/resume").Code).To(Equal(http.StatusOK))
+		Expect(ttsReg.DrainState("kokoro")).To(Equal(backend.DrainActive))
+	})
+
+	It("returns 404 for an unknown backend", func() {
+		Expect(serve(http.MethodPost, "/admin/backends/nope/drain").Code).To(Equal(http.StatusNotFound))
+	})
 })
 
 var _ = Describe("VersionInfo", func() {
@@ -672,3 +2117,493 @@ var _ = Describe("SwaggerUI", func() {
 		Expect(rec.Body.String()).To(ContainSubstring("swagger-ui"))
 	})
 })
+
+// realtimeSTTBackend transcribes any audio to text.
+type realtimeSTTBackend struct {
+	text string
+
+	mu   sync.Mutex
+	reqs []backend.TranscriptionRequest
+}
+
+func (m *realtimeSTTBackend) Name() string { return "whisper" }
+
+func (m *realtimeSTTBackend) Health(context.Context) error { return nil }
+
+func (m *realtimeSTTBackend) Transcribe(_ context.Context, req backend.TranscriptionRequest) (*backend.TranscriptionResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reqs = append(m.reqs, req)
+	return &backend.TranscriptionResponse{Text: m.text}, nil
+}
+
+// realtimeChatBackend streams chunks of an answer. With hold set it stops
+// after the first chunk until its context ends.
+type realtimeChatBackend struct {
+	*mockBackend
+	chunks []string
+	hold   bool
+
+	mu   sync.Mutex
+	reqs []backend.ChatRequest
+}
+
+func (m *realtimeChatBackend) ChatCompletionStream(ctx context.Context, req backend.ChatRequest, send backend.StreamFunc) error {
+	m.mu.Lock()
+	m.reqs = append(m.reqs, req)
+	m.mu.Unlock()
+	for i, c := range m.chunks {
+		if err := send([]byte(`{"choices":[{"index":0,"delta":{"content":` + jsonString(c) + `}}]}`)); err != nil {
+			return err
+		}
+		if i == 0 && m.hold {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+	}
+	return send([]byte("[DONE]"))
+}
+
+// realtimeTTSBackend speaks each input as 10 ms of 24 kHz audio.
+type realtimeTTSBackend struct {
+	*mockTTSBackend
+
+	mu     sync.Mutex
+	inputs []string
+}
+
+func (m *realtimeTTSBackend) Synthesize(_ context.Context, req backend.TTSRequest) (*backend.TTSResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inputs = append(m.inputs, req.Input)
+	return &backend.TTSResponse{Audio: audio.EncodeWAV(audio.PCMFormat, make([]byte, 480)), Format: "audio/wav"}, nil
+}
+
+// tone returns d of session audio at a constant amplitude.
+func tone(d time.Duration, amplitude int16) []byte {
+	pcm := make([]byte, int(d.Seconds()*24000)*2)
+	for i := 0; i < len(pcm); i += 2 {
+		pcm[i], pcm[i+1] = byte(amplitude), byte(amplitude>>8)
+	}
+	return pcm
+}
+
+var _ = Describe("Realtime", func() {
+	var (
+		stt  *realtimeSTTBackend
+		chat *realtimeChatBackend
+		tts  *realtimeTTSBackend
+		hc   *outcomeRecorder
+		rl   *middleware.RateLimiter
+		srv  *httptest.Server
+		conn *websocket.Conn
+		ctx  context.Context
+	)
+
+	BeforeEach(func() {
+		stt = &realtimeSTTBackend{text: "What time is it?"}
+		chat = &realtimeChatBackend{mockBackend: &mockBackend{}, chunks: []string{"It is", " noon. Anything", " else?"}}
+		tts = &realtimeTTSBackend{mockTTSBackend: &mockTTSBackend{name: "kokoro"}}
+		rtr := router.NewRegistry()
+		rtr.Register(router.BackendInfo{
+			Name:         "kokoro",
+			TTSBackend:   tts,
+			Capabilities: []router.Capability{router.CapTTS},
+			Models:       []router.ModelInfo{{ID: "kokoro", Kind: router.CapTTS}},
+		})
+		rtr.Register(router.BackendInfo{
+			Name:         "whisper",
+			STTBackend:   stt,
+			Capabilities: []router.Capability{router.CapSTT},
+		})
+		hc = &outcomeRecorder{}
+		rl = middleware.NewRateLimiter(0.001, 2)
+		DeferCleanup(rl.Stop)
+		h := Realtime(newTestRegistry(chat), rtr, hc, discardLogger(),
+			WithRealtime(10*time.Second, 0, 0.02, 200*time.Millisecond), WithRealtimeRateLimit(rl))
+		srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(w, r.WithContext(middleware.WithAPIKey(r.Context(), "sk-test")))
+		}))
+		DeferCleanup(srv.Close)
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		DeferCleanup(cancel)
+		var err error
+		conn, _, err = websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"?model=llama", nil)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() { _ = conn.CloseNow() })
+	})
+
+	send := func(ev map[string]any) {
+		Expect(wsjson.Write(ctx, conn, ev)).To(Succeed())
+	}
+	// readUntil returns the events received up to and including one of type
+	// typ.
+	readUntil := func(typ string) []map[string]any {
+		var events []map[string]any
+		for {
+			var ev map[string]any
+			Expect(wsjson.Read(ctx, conn, &ev)).To(Succeed())
+			events = append(events, ev)
+			if ev["type"] == typ {
+				return events
+			}
+		}
+	}
+	types := func(events []map[string]any) []string {
+		var out []string
+		for _, ev := range events {
+			if t := ev["type"].(string); t != "response.audio.delta" && t != "response.audio_transcript.delta" {
+				out = append(out, t)
+			}
+		}
+		return out
+	}
+	appendAudio := func(pcm []byte) {
+		send(map[string]any{"type": "input_audio_buffer.append", "audio": base64.StdEncoding.EncodeToString(pcm)})
+	}
+
+	It("starts the session with the model from the URL and server VAD", func() {
+		ev := readUntil("session.created")[0]
+		session := ev["session"].(map[string]any)
+		Expect(session["model"]).To(Equal("llama"))
+		Expect(session["turn_detection"]).To(HaveKeyWithValue("type", "server_vad"))
+	})
+
+	It("transcribes a spoken turn, answers it and speaks the answer", func() {
+		readUntil("session.created")
+		appendAudio(tone(100*time.Millisecond, 0))
+		appendAudio(tone(300*time.Millisecond, 8000))
+		appendAudio(tone(300*time.Millisecond, 0))
+
+		events := readUntil("response.done")
+		Expect(types(events)).To(Equal([]string{
+			"input_audio_buffer.speech_started",
+			"input_audio_buffer.speech_stopped",
+			"input_audio_buffer.committed",
+			"conversation.item.input_audio_transcription.completed",
+			"response.created",
+			"response.audio_transcript.done",
+			"response.audio.done",
+			"response.done",
+		}))
+		Expect(events[3]["transcript"]).To(Equal("What time is it?"))
+		Expect(events[len(events)-1]["response"]).To(HaveKeyWithValue("status", "completed"))
+
+		var transcript string
+		audioBytes := 0
+		for _, ev := range events {
+			switch ev["type"] {
+			case "response.audio_transcript.delta":
+				transcript += ev["delta"].(string)
+			case "response.audio.delta":
+				pcm, err := base64.StdEncoding.DecodeString(ev["delta"].(string))
+				Expect(err).NotTo(HaveOccurred())
+				audioBytes += len(pcm)
+			}
+		}
+		Expect(transcript).To(Equal("It is noon. Anything else?"))
+		Expect(audioBytes).To(Equal(2 * 480))
+
+		stt.mu.Lock()
+		Expect(stt.reqs).To(HaveLen(1))
+		f, pcm, err := audio.ParseWAV(stt.reqs[0].Audio)
+		stt.mu.Unlock()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f).To(Equal(audio.PCMFormat))
+		// The turn keeps 300 ms of padding before the speech.
+		Expect(len(pcm)).To(BeNumerically(">=", len(tone(500*time.Millisecond, 0))))
+
+		chat.mu.Lock()
+		Expect(chat.reqs[0].Model).To(Equal("llama"))
+		Expect(chat.reqs[0].Messages).To(HaveLen(1))
+		Expect(string(chat.reqs[0].Messages[0].Content)).To(Equal(jsonString("What time is it?")))
+		chat.mu.Unlock()
+
+		tts.mu.Lock()
+		Expect(tts.inputs).To(Equal([]string{"It is noon.", "Anything else?"}))
+		tts.mu.Unlock()
+	})
+
+	It("answers text items on request when turn detection is off", func() {
+		readUntil("session.created")
+		send(map[string]any{"type": "session.update", "session": map[string]any{"turn_detection": nil, "instructions": "Be brief."}})
+		Expect(readUntil("session.updated")[0]["session"]).To(HaveKeyWithValue("turn_detection", BeNil()))
+
+		send(map[string]any{"type": "conversation.item.create", "item": map[string]any{
+			"type": "message", "role": "user", "content": []map[string]any{{"type": "input_text", "text": "Hi"}},
+		}})
+		readUntil("conversation.item.created")
+		send(map[string]any{"type": "response.create"})
+		Expect(readUntil("response.done")).NotTo(BeEmpty())
+
+		chat.mu.Lock()
+		defer chat.mu.Unlock()
+		Expect(chat.reqs[0].Messages).To(HaveLen(2))
+		Expect(chat.reqs[0].Messages[0].Role).To(Equal("system"))
+		Expect(string(chat.reqs[0].Messages[1].Content)).To(Equal(jsonString("Hi")))
+		stt.mu.Lock()
+		defer stt.mu.Unlock()
+		Expect(stt.reqs).To(BeEmpty())
+	})
+
+	It("cancels the answer when the caller starts speaking", func() {
+		chat.hold = true
+		readUntil("session.created")
+		send(map[string]any{"type": "conversation.item.create", "item": map[string]any{
+			"type": "message", "role": "user", "content": []map[string]any{{"type": "input_text", "text": "Tell me a story"}},
+		}})
+		send(map[string]any{"type": "response.create"})
+		readUntil("response.audio_transcript.delta")
+
+		appendAudio(tone(100*time.Millisecond, 8000))
+		events := readUntil("response.done")
+		Expect(types(events)).To(ContainElement("input_audio_buffer.speech_started"))
+		Expect(events[len(events)-1]["response"]).To(HaveKeyWithValue("status", "cancelled"))
+	})
+
+	It("reports a cancelled answer's outcome so a breaker trial is returned", func() {
+		chat.hold = true
+		readUntil("session.created")
+		send(map[string]any{"type": "response.create"})
+		readUntil("response.audio_transcript.delta")
+		send(map[string]any{"type": "response.cancel"})
+		readUntil("response.done")
+
+		Eventually(func() []error { return hc.reported("mock") }).Should(ConsistOf(MatchError(context.Canceled)))
+	})
+
+	It("charges each turn against the key's rate limit", func() {
+		readUntil("session.created")
+		send(map[string]any{"type": "response.create"})
+		readUntil("response.done")
+		send(map[string]any{"type": "response.create"})
+		readUntil("response.done")
+
+		send(map[string]any{"type": "response.create"})
+		ev := readUntil("error")[0]
+		Expect(ev["error"]).To(HaveKeyWithValue("code", "rate_limit_exceeded"))
+		chat.mu.Lock()
+		defer chat.mu.Unlock()
+		Expect(chat.reqs).To(HaveLen(2))
+	})
+
+	It("reports invalid events without closing the session", func() {
+		readUntil("session.created")
+		send(map[string]any{"type": "input_audio_buffer.commit"})
+		ev := readUntil("error")[0]
+		Expect(ev["error"]).To(HaveKeyWithValue("type", "invalid_request_error"))
+
+		send(map[string]any{"type": "session.update", "session": map[string]any{"input_audio_format": "g711_ulaw"}})
+		ev = readUntil("error")[0]
+		Expect(ev["error"]).To(HaveKeyWithValue("param", "session.input_audio_format"))
+
+		send(map[string]any{"type": "session.update", "session": map[string]any{"voice": "af_bella"}})
+		Expect(readUntil("session.updated")[0]["session"]).To(HaveKeyWithValue("voice", "af_bella"))
+	})
+})
+
+var _ = Describe("Chat audio", func() {
+	var (
+		chat *realtimeChatBackend
+		tts  *realtimeTTSBackend
+		h    http.HandlerFunc
+	)
+
+	BeforeEach(func() {
+		finish := "stop"
+		chat = &realtimeChatBackend{
+			mockBackend: &mockBackend{chatResp: &backend.ChatResponse{
+				ID:      "chatcmpl-1",
+				Object:  "chat.completion",
+				Choices: []backend.Choice{{Message: &backend.Message{Role: "assistant", Content: json.RawMessage(`"It is noon."`)}, FinishReason: &finish}},
+			}},
+			chunks: []string{"It is", " noon. Anything", " else?"},
+		}
+		tts = &realtimeTTSBackend{mockTTSBackend: &mockTTSBackend{name: "kokoro"}}
+		rtr := router.NewRegistry()
+		rtr.Register(router.BackendInfo{
+			Name:         "kokoro",
+			TTSBackend:   tts,
+			Capabilities: []router.Capability{router.CapTTS},
+			Models:       []router.ModelInfo{{ID: "kokoro", Kind: router.CapTTS}},
+		})
+		h = ChatCompletions(newTestRegistry(chat), nil, discardLogger(), WithChatAudio(rtr))
+	})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("speaks the answer into the message's audio", func() {
+		rec := post(`{"model":"llama","modalities":["text","audio"],"audio":{"voice":"af_bella","format":"wav"},"messages":[{"role":"user","content":"hi"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var resp backend.ChatResponse
+		Expect(json.NewDecoder(rec.Body).Decode(&resp)).To(Succeed())
+		a := resp.Choices[0].Message.Audio
+		Expect(a).NotTo(BeNil())
+		Expect(a.ID).To(HavePrefix("audio_"))
+		Expect(a.Transcript).To(Equal("It is noon."))
+		data, err := base64.StdEncoding.DecodeString(a.Data)
+		Expect(err).NotTo(HaveOccurred())
+		f, pcm, err := audio.ParseWAV(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f).To(Equal(audio.PCMFormat))
+		Expect(pcm).To(HaveLen(480))
+
+		Expect(tts.inputs).To(Equal([]string{"It is noon."}))
+		Expect(chat.lastChatReq.Audio).To(BeNil())
+		Expect(chat.lastChatReq.Modalities).To(BeNil())
+	})
+
+	It("streams each sentence's audio after its text and ends with [DONE]", func() {
+		rec := post(`{"model":"llama","stream":true,"audio":{"voice":"af_bella"},"messages":[{"role":"user","content":"hi"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var kinds, transcripts []string
+		for _, line := range strings.Split(rec.Body.String(), "\n") {
+			data, ok := strings.CutPrefix(line, "data: ")
+			if !ok {
+				continue
+			}
+			if data == "[DONE]" {
+				kinds = append(kinds, "done")
+				continue
+			}
+			var chunk backend.ChatResponse
+			Expect(json.Unmarshal([]byte(data), &chunk)).To(Succeed())
+			delta := chunk.Choices[0].Delta
+			if delta.Audio == nil {
+				kinds = append(kinds, "text")
+				continue
+			}
+			kinds = append(kinds, "audio")
+			transcripts = append(transcripts, delta.Audio.Transcript)
+			pcm, err := base64.StdEncoding.DecodeString(delta.Audio.Data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pcm).To(HaveLen(480))
+		}
+		Expect(transcripts).To(Equal([]string{"It is noon.", "Anything else?"}))
+		Expect(kinds[len(kinds)-1]).To(Equal("done"))
+		Expect(kinds).To(HaveLen(6))
+		Expect(kinds[:2]).To(Equal([]string{"text", "text"}))
+		Expect(chat.reqs[0].Audio).To(BeNil())
+	})
+
+	DescribeTable("rejects invalid audio options",
+		func(body, param string) {
+			rec := post(body)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring(`"param":"` + param + `"`))
+			Expect(chat.chatCalls).To(BeZero())
+		},
+		Entry("audio missing", `{"modalities":["audio"],"messages":[{"role":"user","content":"hi"}]}`, "audio"),
+		Entry("unknown format", `{"audio":{"voice":"af_bella","format":"flac"},"messages":[{"role":"user","content":"hi"}]}`, "audio.format"),
+		Entry("streamed wav", `{"stream":true,"audio":{"voice":"af_bella","format":"wav"},"messages":[{"role":"user","content":"hi"}]}`, "audio.format"),
+		Entry("several choices", `{"n":2,"audio":{"voice":"af_bella"},"messages":[{"role":"user","content":"hi"}]}`, "n"),
+	)
+
+	It("rejects audio output when it is not enabled", func() {
+		h = ChatCompletions(newTestRegistry(chat), nil, discardLogger())
+		rec := post(`{"audio":{"voice":"af_bella"},"messages":[{"role":"user","content":"hi"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring(`"param":"modalities"`))
+	})
+})
+
+var _ = Describe("Chat WebSocket", func() {
+	var (
+		chat *realtimeChatBackend
+		conn *websocket.Conn
+		ctx  context.Context
+	)
+
+	BeforeEach(func() {
+		chat = &realtimeChatBackend{mockBackend: &mockBackend{}, chunks: []string{"It is", " noon."}}
+		srv := httptest.NewServer(ChatCompletionsWebSocket(ChatCompletions(newTestRegistry(chat), nil, discardLogger()), discardLogger()))
+		DeferCleanup(srv.Close)
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		DeferCleanup(cancel)
+		var err error
+		conn, _, err = websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() { _ = conn.CloseNow() })
+	})
+
+	send := func(msg string) {
+		Expect(conn.Write(ctx, websocket.MessageText, []byte(msg))).To(Succeed())
+	}
+	read := func() string {
+		_, data, err := conn.Read(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+	// readAnswer returns the text of the chunks up to the end of a request,
+	// and the message that ended it.
+	readAnswer := func() (string, string) {
+		var text string
+		for {
+			msg := read()
+			if msg == "[DONE]" || strings.HasPrefix(msg, `{"error"`) {
+				return text, msg
+			}
+			text += chunkContent([]byte(msg))
+		}
+	}
+	const request = `{"model":"llama","messages":[{"role":"user","content":"What time is it?"}]}`
+
+	It("streams the answers to sequential requests as messages", func() {
+		for range 2 {
+			send(request)
+			text, end := readAnswer()
+			Expect(text).To(Equal("It is noon."))
+			Expect(end).To(Equal("[DONE]"))
+		}
+		chat.mu.Lock()
+		defer chat.mu.Unlock()
+		Expect(chat.reqs).To(HaveLen(2))
+		Expect(chat.reqs[0].Stream).To(BeTrue())
+	})
+
+	It("cancels the request in progress", func() {
+		chat.hold = true
+		send(request)
+		Expect(chunkContent([]byte(read()))).To(Equal("It is"))
+
+		send(request)
+		Expect(read()).To(ContainSubstring("already in progress"))
+
+		send(`{"type":"cancel"}`)
+		Expect(read()).To(ContainSubstring(`"code":"request_cancelled"`))
+
+		chat.mu.Lock()
+		chat.hold = false
+		chat.mu.Unlock()
+		send(request)
+		text, end := readAnswer()
+		Expect(text).To(Equal("It is noon."))
+		Expect(end).To(Equal("[DONE]"))
+	})
+
+	It("answers invalid requests and messages with errors", func() {
+		send(`{"model":"llama","messages":[]}`)
+		Expect(read()).To(ContainSubstring(`"param":"messages"`))
+
+		send(`{"type":"response.create"}`)
+		Expect(read()).To(ContainSubstring(`"param":"type"`))
+
+		send(`not json`)
+		Expect(read()).To(ContainSubstring("Invalid JSON message"))
+
+		send(request)
+		_, end := readAnswer()
+		Expect(end).To(Equal("[DONE]"))
+	})
+})
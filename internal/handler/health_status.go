@@ -13,9 +13,11 @@ import (
 
 // ServiceStatus represents the health of a single backend service.
 type ServiceStatus struct {
-	Status   string       `json:"status"`             // "healthy" or "unhealthy"
-	Error    string       `json:"error,omitempty"`    // error message if unhealthy
-	Models   []ModelBrief `json:"models,omitempty"`   // available models/voices
+	Status        string                   `json:"status"`                   // "healthy" or "unhealthy"
+	Error         string                   `json:"error,omitempty"`          // error message if unhealthy
+	Models        []ModelBrief             `json:"models,omitempty"`         // available models/voices
+	LoadBalancing map[string]backend.Score `json:"load_balancing,omitempty"` // per-capability balancer inputs
+	Drain         backend.DrainState       `json:"drain,omitempty"`          // "draining" or "drained"; omitted when active
 }
 
 // ModelBrief is a lightweight summary of a model or voice offered by a backend.
@@ -43,8 +45,9 @@ type HealthStatusResponse struct {
 }
 
 // HealthStatus returns a consolidated health check handler that probes all
-// registered backends (chat, embed, TTS) and reports their health, available
-// models, and aggregate summary.
+// registered backends (chat, embed, TTS, rerank) and reports their health, available
+// models, current load-balancing scores, drain state, and aggregate summary.
+// Drained or draining backends do not degrade the overall status.
 //
 //	GET /health/status
 //
@@ -61,12 +64,18 @@ func HealthStatus(reg *backend.Registry, ttsReg *router.Registry) http.HandlerFu
 
 		// Check chat/embed backends (Ollama, MLX).
 		for _, b := range reg.All() {
-			s := ServiceStatus{Status: "healthy"}
+			s := ServiceStatus{Status: "healthy", LoadBalancing: reg.Scores(b.Name())}
+			if st := reg.DrainState(b.Name()); st != backend.DrainActive {
+				s.Drain = st
+			}
 
 			if err := b.Health(r.Context()); err != nil {
 				s.Status = "unhealthy"
 				s.Error = err.Error()
-				overall = "degraded"
+				// A backend taken out of rotation may be down for maintenance.
+				if s.Drain == "" {
+					overall = "degraded"
+				}
 			} else {
 				// Fetch model inventory on healthy backends.
 				models, listErr := b.ListModels(r.Context())
@@ -93,20 +102,34 @@ func HealthStatus(reg *backend.Registry, ttsReg *router.Registry) http.HandlerFu
 			summary.ByType["chat"]++
 		}
 
-		// Check TTS backends (Kokoro, Chatterbox).
+		// Check TTS (Kokoro, Chatterbox) and rerank backends.
 		if ttsReg != nil {
 			for _, info := range ttsReg.All() {
-				if info.TTSBackend == nil {
+				probe := info.Probe()
+				if probe == nil {
 					continue
 				}
 
-				s := ServiceStatus{Status: "healthy"}
+				s := ServiceStatus{Status: "healthy", LoadBalancing: ttsReg.Scores(info.Name)}
+				if st := ttsReg.DrainState(info.Name); st != backend.DrainActive {
+					s.Drain = st
+				}
+
+				kind := "tts"
+				if info.TTSBackend == nil {
+					kind = "rerank"
+					for _, m := range info.Models {
+						s.Models = append(s.Models, ModelBrief{ID: m.ID, Object: "model"})
+					}
+				}
 
-				if err := info.TTSBackend.Health(r.Context()); err != nil {
+				if err := probe.Health(r.Context()); err != nil {
 					s.Status = "unhealthy"
 					s.Error = err.Error()
-					overall = "degraded"
-				} else {
+					if s.Drain == "" {
+						overall = "degraded"
+					}
+				} else if info.TTSBackend != nil {
 					// Fetch voice inventory on healthy TTS backends.
 					voices, voicesErr := info.TTSBackend.Voices(r.Context())
 					if voicesErr == nil && len(voices) > 0 {
@@ -128,7 +151,7 @@ func HealthStatus(reg *backend.Registry, ttsReg *router.Registry) http.HandlerFu
 				} else {
 					summary.Unhealthy++
 				}
-				summary.ByType["tts"]++
+				summary.ByType[kind]++
 			}
 		}
 
@@ -22,6 +22,7 @@ func Models(reg *backend.Registry, hc backend.HealthChecker, logger *slog.Logger
 		defer reg.ReleaseBackend(b.Name())
 
 		resp, err := b.ListModels(r.Context())
+		backend.ReportOutcome(hc, b.Name(), err)
 		if err != nil {
 			logger.Error("list models failed", "backend", b.Name(), "err", err)
 			apierror.Write(w, apierror.FromBackendError(b.Name(), err))
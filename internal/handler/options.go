@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/menezmethod/inferencia/internal/async"
+	"github.com/menezmethod/inferencia/internal/auth"
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/cache"
+	"github.com/menezmethod/inferencia/internal/embedbatch"
+	"github.com/menezmethod/inferencia/internal/middleware"
+	"github.com/menezmethod/inferencia/internal/router"
+	"github.com/menezmethod/inferencia/internal/semcache"
+	"github.com/menezmethod/inferencia/internal/speech"
+	"github.com/menezmethod/inferencia/internal/streams"
+	"github.com/menezmethod/inferencia/internal/voices"
+)
+
+// Option configures optional behaviour of the inference handlers.
+type Option func(*options)
+
+type options struct {
+	cache *cache.Cache
+
+	semantic        *semcache.Cache
+	semanticModel   string
+	semanticTimeout time.Duration
+
+	async *async.Manager
+
+	streams *streams.Store
+
+	limits streamLimits
+
+	longSpeech  longSpeechConfig
+	voices      *voices.Catalog
+	speechCache *cache.Cache
+
+	lexicon  *speech.Lexicon
+	lexicons map[string]*speech.Lexicon
+	keys     *auth.KeyStore
+
+	realtime realtimeConfig
+
+	chatAudio *router.Registry
+
+	embedBatching *embedBatching
+}
+
+// WithCache serves deterministic requests from c and stores their responses.
+func WithCache(c *cache.Cache) Option {
+	return func(o *options) { o.cache = c }
+}
+
+// WithSemanticCache answers chat requests whose last user message is close
+// enough to an earlier one from the same key and model. Questions are embedded
+// with embedModel on the embedding backends; a lookup that cannot be embedded
+// within timeout goes to the chat backend as usual.
+func WithSemanticCache(c *semcache.Cache, embedModel string, timeout time.Duration) Option {
+	return func(o *options) {
+		o.semantic = c
+		o.semanticModel = embedModel
+		o.semanticTimeout = timeout
+	}
+}
+
+// WithAsync runs chat requests sent with "Prefer: respond-async" as jobs on
+// m, answering 202 with the job instead of waiting for the completion.
+func WithAsync(m *async.Manager) Option {
+	return func(o *options) { o.async = m }
+}
+
+// WithResumableStreams generates streamed chat completions into s, so a
+// client that loses its connection can reconnect with Last-Event-ID and pick
+// up where it left off.
+func WithResumableStreams(s *streams.Store) Option {
+	return func(o *options) { o.streams = s }
+}
+
+// WithStreamLimits bounds streamed chat responses. While the backend is
+// silent, an SSE comment is sent every heartbeat so proxies keep the
+// connection open. A backend that sends nothing for firstChunk, or goes
+// silent for idle after its first chunk, has its generation aborted, and no
+// stream runs longer than maxDuration, which replaces the server's write
+// timeout for streams. Zero disables each limit.
+func WithStreamLimits(heartbeat, firstChunk, idle, maxDuration time.Duration) Option {
+	return func(o *options) {
+		o.limits = streamLimits{heartbeat: heartbeat, firstChunk: firstChunk, idle: idle, maxDuration: maxDuration}
+	}
+}
+
+// WithLongSpeech accepts speech input of up to maxInput characters. Input
+// beyond the usual 4096 is split into segments of at most segmentLen
+// characters on sentence and paragraph boundaries, synthesized up to
+// parallelism at a time across the healthy backends for the model, and
+// joined in order, crossfading WAV and PCM segments over crossfade.
+func WithLongSpeech(maxInput, segmentLen, parallelism int, crossfade time.Duration) Option {
+	return func(o *options) {
+		o.longSpeech = longSpeechConfig{
+			maxInput:    maxInput,
+			segmentLen:  segmentLen,
+			parallelism: parallelism,
+			crossfade:   crossfade,
+		}
+	}
+}
+
+// WithVoices routes speech requests to a backend that offers the requested
+// voice, rejecting voices no backend has, and fills in a backend's first
+// voice when it has no configured default.
+func WithVoices(c *voices.Catalog) Option {
+	return func(o *options) { o.voices = c }
+}
+
+// WithSpeechCache serves synthesized audio from c and stores it there, keyed
+// by model, voice, speed, format and input, so repeated phrases do not reach a
+// TTS backend.
+func WithSpeechCache(c *cache.Cache) Option {
+	return func(o *options) { o.speechCache = c }
+}
+
+// WithLexicons rewrites speech input with a pronunciation lexicon: the one in
+// lexicons named by the API key's lexicon attribute in ks, else global.
+func WithLexicons(global *speech.Lexicon, lexicons map[string]*speech.Lexicon, ks *auth.KeyStore) Option {
+	return func(o *options) {
+		o.lexicon = global
+		o.lexicons = lexicons
+		o.keys = ks
+	}
+}
+
+// WithChatAudio lets chat requests ask for the answer to be spoken as well,
+// with "audio": {"voice", "format"}. The answer is synthesized on the TTS
+// backends in rtr, using the voice catalog and lexicons of WithVoices and
+// WithLexicons.
+func WithChatAudio(rtr *router.Registry) Option {
+	return func(o *options) { o.chatAudio = rtr }
+}
+
+// WithRealtime bounds realtime voice sessions: a turn's audio is capped at
+// maxBuffer and a session at maxDuration (0 = unlimited). Server VAD treats
+// audio louder than vadThreshold (RMS, 0-1) as speech and ends the turn after
+// vadSilence of quieter audio, unless the session sets its own.
+func WithRealtime(maxBuffer, maxDuration time.Duration, vadThreshold float64, vadSilence time.Duration) Option {
+	return func(o *options) {
+		o.realtime.maxBuffer = maxBuffer
+		o.realtime.maxDuration = maxDuration
+		o.realtime.vadThreshold = vadThreshold
+		o.realtime.vadSilence = vadSilence
+	}
+}
+
+// WithRealtimeRateLimit charges each realtime turn against the API key's
+// bucket in rl. Only the upgrade goes through the route's middleware, so
+// without it a session could run any number of turns.
+func WithRealtimeRateLimit(rl *middleware.RateLimiter) Option {
+	return func(o *options) { o.realtime.limiter = rl }
+}
+
+// WithEmbedBatching coalesces concurrent text embedding requests for the same
+// model that arrive within cfg.Window into upstream calls of at most
+// cfg.MaxBatchSize inputs. Requests are combined before a backend is chosen,
+// so each upstream call takes one of its backend's max_concurrency slots.
+// Every handler built with the same Option shares one batcher.
+func WithEmbedBatching(cfg embedbatch.Config) Option {
+	eb := &embedBatching{cfg: cfg}
+	return func(o *options) { o.embedBatching = eb }
+}
+
+// embedBatching is the batcher shared by the handlers built with one
+// WithEmbedBatching option. It is created with the first handler.
+type embedBatching struct {
+	cfg  embedbatch.Config
+	once sync.Once
+	e    *embedder
+}
+
+// embedder returns the embedder for a handler on reg: the shared, batching
+// one when eb is set, else a new one.
+func (eb *embedBatching) embedder(reg *backend.Registry, hc backend.HealthChecker, logger *slog.Logger) *embedder {
+	if eb == nil {
+		return &embedder{reg: reg, hc: hc, logger: logger}
+	}
+	eb.once.Do(func() {
+		eb.e = &embedder{reg: reg, hc: hc, logger: logger}
+		eb.e.batcher = embedbatch.NewBatcher(eb.e.send, eb.cfg)
+	})
+	return eb.e
+}
+
+// lexiconFor returns the pronunciation lexicon for the request's API key.
+func (o options) lexiconFor(ctx context.Context) *speech.Lexicon {
+	if o.keys != nil {
+		if l, ok := o.lexicons[middleware.KeyPolicyFromContext(ctx, o.keys).Lexicon]; ok {
+			return l
+		}
+	}
+	return o.lexicon
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,720 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/audio"
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/middleware"
+	"github.com/menezmethod/inferencia/internal/router"
+	"github.com/menezmethod/inferencia/internal/speech"
+)
+
+const (
+	// realtimeFrame is the audio the server VAD judges at a time.
+	realtimeFrame = 20 * time.Millisecond
+	// realtimeAudioChunk bounds the audio in one response.audio.delta.
+	realtimeAudioChunk = 500 * time.Millisecond
+	// realtimeReadLimit bounds one client message, about 2 minutes of audio
+	// in base64.
+	realtimeReadLimit = 8 << 20
+)
+
+// realtimeConfig bounds realtime sessions; see WithRealtime.
+type realtimeConfig struct {
+	maxBuffer    time.Duration
+	maxDuration  time.Duration
+	vadThreshold float64
+	vadSilence   time.Duration
+	limiter      *middleware.RateLimiter // charged once per turn (nil = unlimited)
+}
+
+// Realtime serves a voice conversation over a WebSocket, speaking a subset
+// of the OpenAI Realtime API's events as JSON text messages.
+//
+//	GET /v1/realtime?model=<chat model>
+//
+// The client streams 24 kHz mono 16-bit PCM with input_audio_buffer.append
+// (or as binary messages). With server VAD, the default, the server finds
+// where the caller starts and stops speaking; otherwise the client commits
+// the buffer and asks for a response. Each turn is transcribed on an STT
+// backend, answered on a chat backend and spoken sentence by sentence on a
+// TTS backend, with the text and audio streamed back as they are produced.
+// Speech that starts while an answer is playing cancels it (barge-in), as
+// does response.cancel.
+func Realtime(reg *backend.Registry, rtr *router.Registry, hc backend.HealthChecker, logger *slog.Logger, opts ...Option) http.HandlerFunc {
+	o := newOptions(opts)
+	return func(w http.ResponseWriter, r *http.Request) {
+		model := strings.TrimSpace(r.URL.Query().Get("model"))
+		if model == "" {
+			model = defaultChatModel
+		}
+
+		conn, err := acceptWebSocket(w, r)
+		if err != nil {
+			logger.Warn("websocket upgrade failed", "path", r.URL.Path, "err", err)
+			return
+		}
+		conn.SetReadLimit(realtimeReadLimit)
+		if d := o.realtime.maxDuration; d > 0 {
+			t := time.AfterFunc(d, func() {
+				_ = conn.Close(websocket.StatusPolicyViolation, "session reached its maximum duration")
+			})
+			defer t.Stop()
+		}
+
+		middleware.RealtimeSessionsActive.Inc()
+		defer middleware.RealtimeSessionsActive.Dec()
+
+		ctx, cancel := context.WithCancel(r.Context())
+		s := &realtimeSession{
+			ctx:     ctx,
+			conn:    conn,
+			reg:     reg,
+			rtr:     rtr,
+			hc:      hc,
+			o:       o,
+			lexicon: o.lexiconFor(r.Context()),
+			key:     middleware.APIKeyFromContext(r.Context()),
+			logger:  logger,
+			cfg:     newRealtimeSessionConfig(model, o.realtime),
+		}
+		s.run()
+
+		// Stop the turn in progress before closing, so nothing is written
+		// to a closed connection.
+		cancel()
+		if s.turn != nil {
+			<-s.turn.done
+		}
+		_ = conn.Close(websocket.StatusNormalClosure, "")
+	}
+}
+
+// realtimeSessionConfig is the session object of the Realtime API, changed
+// with session.update.
+type realtimeSessionConfig struct {
+	Model                   string                 `json:"model"`
+	Instructions            string                 `json:"instructions,omitempty"`
+	Voice                   string                 `json:"voice,omitempty"`
+	TTSModel                string                 `json:"tts_model,omitempty"`
+	Speed                   float64                `json:"speed"`
+	InputAudioFormat        string                 `json:"input_audio_format"`
+	OutputAudioFormat       string                 `json:"output_audio_format"`
+	InputAudioTranscription *realtimeTranscription `json:"input_audio_transcription"`
+	TurnDetection           *realtimeTurnDetection `json:"turn_detection"`
+	Temperature             *float64               `json:"temperature,omitempty"`
+	MaxResponseOutputTokens *int                   `json:"max_response_output_tokens,omitempty"`
+}
+
+type realtimeTranscription struct {
+	Model    string `json:"model,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+// realtimeTurnDetection configures server VAD; a null turn_detection turns
+// it off.
+type realtimeTurnDetection struct {
+	Type              string  `json:"type"`
+	Threshold         float64 `json:"threshold"`
+	PrefixPaddingMS   int     `json:"prefix_padding_ms"`
+	SilenceDurationMS int     `json:"silence_duration_ms"`
+}
+
+func newRealtimeSessionConfig(model string, rc realtimeConfig) realtimeSessionConfig {
+	return realtimeSessionConfig{
+		Model:                   model,
+		Speed:                   1,
+		InputAudioFormat:        "pcm16",
+		OutputAudioFormat:       "pcm16",
+		InputAudioTranscription: &realtimeTranscription{},
+		TurnDetection: &realtimeTurnDetection{
+			Type:              "server_vad",
+			Threshold:         rc.vadThreshold,
+			PrefixPaddingMS:   300,
+			SilenceDurationMS: int(rc.vadSilence / time.Millisecond),
+		},
+	}
+}
+
+// clone returns a copy of c that shares no pointers with it, so a
+// session.update can be decoded into the copy.
+func (c realtimeSessionConfig) clone() realtimeSessionConfig {
+	c.InputAudioTranscription = clonePtr(c.InputAudioTranscription)
+	c.TurnDetection = clonePtr(c.TurnDetection)
+	c.Temperature = clonePtr(c.Temperature)
+	c.MaxResponseOutputTokens = clonePtr(c.MaxResponseOutputTokens)
+	return c
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// validate checks c after an update, clamping speed as /v1/audio/speech
+// does.
+func (c *realtimeSessionConfig) validate() *apierror.Error {
+	if strings.TrimSpace(c.Model) == "" {
+		return apierror.InvalidParam("session.model", "model must not be empty")
+	}
+	if c.InputAudioFormat != "pcm16" {
+		return apierror.InvalidParam("session.input_audio_format", "input_audio_format must be pcm16")
+	}
+	if c.OutputAudioFormat != "pcm16" {
+		return apierror.InvalidParam("session.output_audio_format", "output_audio_format must be pcm16")
+	}
+	if c.InputAudioTranscription == nil {
+		c.InputAudioTranscription = &realtimeTranscription{}
+	}
+	if td := c.TurnDetection; td != nil {
+		switch {
+		case td.Type != "server_vad":
+			return apierror.InvalidParam("session.turn_detection.type", "turn_detection.type must be server_vad")
+		case td.Threshold <= 0 || td.Threshold >= 1:
+			return apierror.InvalidParam("session.turn_detection.threshold", "turn_detection.threshold must be between 0 and 1")
+		case td.SilenceDurationMS <= 0:
+			return apierror.InvalidParam("session.turn_detection.silence_duration_ms", "turn_detection.silence_duration_ms must be positive")
+		case td.PrefixPaddingMS < 0:
+			return apierror.InvalidParam("session.turn_detection.prefix_padding_ms", "turn_detection.prefix_padding_ms must not be negative")
+		}
+	}
+	if c.Speed <= 0 {
+		c.Speed = 1
+	}
+	c.Speed = min(max(c.Speed, 0.25), 4)
+	return nil
+}
+
+// realtimeClientEvent is any event sent by the client.
+type realtimeClientEvent struct {
+	Type    string          `json:"type"`
+	Session json.RawMessage `json:"session"`
+	Audio   string          `json:"audio"`
+	Item    *realtimeItem   `json:"item"`
+}
+
+// realtimeItem is a conversation item added by the client.
+type realtimeItem struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Role    string `json:"role"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// realtimeSession is one connection. Client events are handled in order by
+// run; each turn (transcribe, answer, speak) runs in its own goroutine after
+// the one before it, so the client can interrupt it.
+type realtimeSession struct {
+	ctx     context.Context
+	conn    *websocket.Conn
+	reg     *backend.Registry
+	rtr     *router.Registry
+	hc      backend.HealthChecker
+	o       options
+	lexicon *speech.Lexicon
+	key     string
+	logger  *slog.Logger
+
+	// Owned by run.
+	cfg    realtimeSessionConfig
+	buffer []byte // uncommitted input audio
+	vad    realtimeVAD
+	turn   *realtimeTurn // latest turn
+
+	mu      sync.Mutex
+	history []backend.Message
+}
+
+// realtimeVAD is the server VAD's state.
+type realtimeVAD struct {
+	pending  []byte        // audio short of a frame
+	pos      time.Duration // audio received so far
+	speaking bool
+	quiet    time.Duration // silence since the caller last spoke
+}
+
+// realtimeTurn is a turn in progress. cancel stops its response.
+type realtimeTurn struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// run reads client events until the connection closes.
+func (s *realtimeSession) run() {
+	s.send("session.created", map[string]any{"session": s.cfg})
+	for {
+		typ, data, err := s.conn.Read(s.ctx)
+		if err != nil {
+			if websocket.CloseStatus(err) == -1 && s.ctx.Err() == nil {
+				s.logger.Debug("realtime session ended", "err", err)
+			}
+			return
+		}
+		if typ == websocket.MessageBinary {
+			s.appendAudio(data)
+			continue
+		}
+		var ev realtimeClientEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			s.sendError(apierror.InvalidRequest("Invalid JSON in event: " + err.Error()))
+			continue
+		}
+		s.handle(ev)
+	}
+}
+
+// handle applies one client event.
+func (s *realtimeSession) handle(ev realtimeClientEvent) {
+	switch ev.Type {
+	case "session.update":
+		cfg := s.cfg.clone()
+		if err := json.Unmarshal(ev.Session, &cfg); err != nil {
+			s.sendError(apierror.InvalidParam("session", "Invalid session: "+err.Error()))
+			return
+		}
+		if apiErr := cfg.validate(); apiErr != nil {
+			s.sendError(apiErr)
+			return
+		}
+		if cfg.TurnDetection == nil {
+			s.vad = realtimeVAD{}
+		}
+		s.cfg = cfg
+		s.send("session.updated", map[string]any{"session": s.cfg})
+	case "input_audio_buffer.append":
+		pcm, err := base64.StdEncoding.DecodeString(ev.Audio)
+		if err != nil {
+			s.sendError(apierror.InvalidParam("audio", "audio must be base64-encoded pcm16"))
+			return
+		}
+		s.appendAudio(pcm)
+	case "input_audio_buffer.commit":
+		if len(s.buffer) == 0 {
+			s.sendError(apierror.InvalidRequest("The input audio buffer is empty."))
+			return
+		}
+		s.vad = realtimeVAD{pos: s.vad.pos}
+		s.commit(s.cfg.TurnDetection != nil)
+	case "input_audio_buffer.clear":
+		s.buffer = nil
+		s.vad = realtimeVAD{pos: s.vad.pos}
+		s.send("input_audio_buffer.cleared", nil)
+	case "conversation.item.create":
+		s.createItem(ev.Item)
+	case "response.create":
+		s.startTurn("", nil, true)
+	case "response.cancel":
+		s.cancelResponse()
+	default:
+		s.sendError(apierror.InvalidParam("type", "Unknown event type "+ev.Type+"."))
+	}
+}
+
+// appendAudio adds caller audio to the buffer, running server VAD over it
+// when enabled.
+func (s *realtimeSession) appendAudio(pcm []byte) {
+	if len(pcm)%2 != 0 {
+		s.sendError(apierror.InvalidParam("audio", "audio must be whole 16-bit samples"))
+		return
+	}
+	if s.cfg.TurnDetection == nil && len(s.buffer)+len(pcm) > pcmLen(s.o.realtime.maxBuffer) {
+		s.sendError(apierror.InvalidRequest("The input audio buffer is full; commit or clear it."))
+		return
+	}
+	s.buffer = append(s.buffer, pcm...)
+	if td := s.cfg.TurnDetection; td != nil {
+		s.detect(pcm, td)
+	}
+}
+
+// detect runs server VAD over newly appended audio: a frame louder than the
+// threshold is speech, and enough quiet frames after speech end the turn.
+// Outside speech the buffer keeps only the prefix padding.
+func (s *realtimeSession) detect(pcm []byte, td *realtimeTurnDetection) {
+	frame := pcmLen(realtimeFrame)
+	silence := time.Duration(td.SilenceDurationMS) * time.Millisecond
+	s.vad.pending = append(s.vad.pending, pcm...)
+	for len(s.vad.pending) >= frame {
+		loud := audio.Level(s.vad.pending[:frame]) >= td.Threshold
+		s.vad.pending = s.vad.pending[frame:]
+		s.vad.pos += realtimeFrame
+
+		switch {
+		case loud && !s.vad.speaking:
+			s.vad.speaking, s.vad.quiet = true, 0
+			s.cancelResponse()
+			s.send("input_audio_buffer.speech_started", map[string]any{"audio_start_ms": s.vad.pos.Milliseconds()})
+		case loud:
+			s.vad.quiet = 0
+		case s.vad.speaking:
+			s.vad.quiet += realtimeFrame
+			if s.vad.quiet >= silence {
+				s.vad.speaking = false
+				s.send("input_audio_buffer.speech_stopped", map[string]any{"audio_end_ms": s.vad.pos.Milliseconds()})
+				s.commit(true)
+			}
+		}
+	}
+
+	switch {
+	case !s.vad.speaking:
+		if keep := pcmLen(time.Duration(td.PrefixPaddingMS) * time.Millisecond); len(s.buffer) > keep {
+			s.buffer = append([]byte(nil), s.buffer[len(s.buffer)-keep:]...)
+		}
+	case len(s.buffer) >= pcmLen(s.o.realtime.maxBuffer):
+		// The caller has spoken for as long as a turn may last.
+		s.vad.speaking = false
+		s.send("input_audio_buffer.speech_stopped", map[string]any{"audio_end_ms": s.vad.pos.Milliseconds()})
+		s.commit(true)
+	}
+}
+
+// commit turns the buffered audio into a user turn, answered if respond.
+func (s *realtimeSession) commit(respond bool) {
+	itemID := newID("item_")
+	pcm := s.buffer
+	s.buffer = nil
+	s.send("input_audio_buffer.committed", map[string]any{"item_id": itemID})
+	s.startTurn(itemID, pcm, respond)
+}
+
+// createItem adds a text message to the conversation.
+func (s *realtimeSession) createItem(item *realtimeItem) {
+	if item == nil || item.Type != "message" {
+		s.sendError(apierror.InvalidParam("item", "item must be a message"))
+		return
+	}
+	switch item.Role {
+	case "user", "assistant", "system":
+	default:
+		s.sendError(apierror.InvalidParam("item.role", "item.role must be user, assistant or system"))
+		return
+	}
+	var text []string
+	for _, c := range item.Content {
+		text = append(text, c.Text)
+	}
+	if item.ID == "" {
+		item.ID = newID("item_")
+	}
+	s.addMessage(item.Role, strings.Join(text, "\n"))
+	s.send("conversation.item.created", map[string]any{"item": item})
+}
+
+// startTurn runs a turn once the previous one is done: pcm, if any, is
+// transcribed and added to the conversation, and the conversation is
+// answered if respond. A new turn supersedes the previous one's answer.
+// Each turn counts against the API key's rate limit, as each request on a
+// chat WebSocket does; a turn over the limit is dropped.
+func (s *realtimeSession) startTurn(itemID string, pcm []byte, respond bool) {
+	if rl := s.o.realtime.limiter; rl != nil && s.key != "" {
+		if _, ok := rl.Allow(s.key); !ok {
+			middleware.RateLimitRejections.Inc()
+			s.sendError(apierror.RateLimited())
+			return
+		}
+	}
+	prev := s.turn
+	if prev != nil {
+		prev.cancel()
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	t := &realtimeTurn{cancel: cancel, done: make(chan struct{})}
+	s.turn = t
+	cfg := s.cfg.clone()
+
+	go func() {
+		defer close(t.done)
+		defer cancel()
+		if prev != nil {
+			<-prev.done
+		}
+		if pcm != nil && !s.transcribe(itemID, pcm, cfg) {
+			return
+		}
+		if respond && ctx.Err() == nil {
+			s.respond(ctx, cfg)
+		}
+	}()
+}
+
+// cancelResponse stops the answer being generated or spoken, if any.
+func (s *realtimeSession) cancelResponse() {
+	if s.turn != nil {
+		s.turn.cancel()
+	}
+}
+
+// transcribe adds the caller's words in pcm to the conversation. It reports
+// false when there are none or they could not be transcribed. Barge-in does
+// not cancel a transcription, only the session's end does.
+func (s *realtimeSession) transcribe(itemID string, pcm []byte, cfg realtimeSessionConfig) bool {
+	tr := cfg.InputAudioTranscription
+	model := tr.Model
+	if !s.rtr.Advertises(router.CapSTT, model) {
+		model = ""
+	}
+	failed := func(apiErr *apierror.Error) bool {
+		s.send("conversation.item.input_audio_transcription.failed", map[string]any{"item_id": itemID, "error": apiErr})
+		return false
+	}
+
+	info, err := s.rtr.AdmitHealthyBackend(s.ctx, router.CapSTT, model, s.hc)
+	if err != nil {
+		s.logger.Error("no STT backend available", "err", err)
+		return failed(apierror.BackendUnavailable(tr.Model))
+	}
+	defer s.rtr.ReleaseBackend(info.Name)
+	middleware.RoutingDecisionsTotal.WithLabelValues("stt", info.Name).Inc()
+
+	if info.STTBackend == nil {
+		s.logger.Error("selected backend has no STT backend", "name", info.Name)
+		backend.ReportOutcome(s.hc, info.Name, backend.ErrBackendNotFound)
+		return failed(apierror.BackendUnavailable(info.Name))
+	}
+
+	start := time.Now()
+	resp, err := info.STTBackend.Transcribe(s.ctx, backend.TranscriptionRequest{
+		Audio:    audio.EncodeWAV(audio.PCMFormat, pcm),
+		Model:    tr.Model,
+		Language: tr.Language,
+	})
+	elapsed := time.Since(start)
+	backend.ReportOutcome(s.hc, info.Name, err)
+	if err != nil {
+		s.logger.Error("transcription failed", "backend", info.Name, "err", err)
+		return failed(apierror.FromBackendError(info.Name, err))
+	}
+	s.rtr.ObserveLatency(router.CapSTT, info.Name, elapsed)
+	middleware.BackendRequestDuration.WithLabelValues(info.Name, "stt").Observe(elapsed.Seconds())
+
+	text := strings.TrimSpace(resp.Text)
+	s.send("conversation.item.input_audio_transcription.completed", map[string]any{"item_id": itemID, "transcript": text})
+	if text == "" {
+		return false
+	}
+	s.addMessage("user", text)
+	return true
+}
+
+// respond answers the conversation, streaming the answer's text and speech,
+// and adds what was said to the conversation, even if cut short.
+func (s *realtimeSession) respond(ctx context.Context, cfg realtimeSessionConfig) {
+	id := newID("resp_")
+	s.send("response.created", map[string]any{"response": map[string]any{"id": id, "status": "in_progress"}})
+
+	text, err := s.answer(ctx, id, cfg)
+	status := "completed"
+	switch {
+	case ctx.Err() != nil:
+		status = "cancelled"
+	case err != nil:
+		status = "failed"
+		s.logger.Error("realtime response failed", "err", err)
+		var apiErr *apierror.Error
+		if !errors.As(err, &apiErr) {
+			apiErr = apierror.Internal("The response could not be generated.")
+		}
+		s.sendError(apiErr)
+	}
+	if text != "" {
+		s.addMessage("assistant", text)
+	}
+	middleware.RealtimeTurnsTotal.WithLabelValues(status).Inc()
+	s.send("response.done", map[string]any{"response": map[string]any{
+		"id":     id,
+		"status": status,
+		"output": []map[string]any{{
+			"type":    "message",
+			"role":    "assistant",
+			"content": []map[string]any{{"type": "audio", "transcript": text}},
+		}},
+	}})
+}
+
+// answer streams a chat completion for the conversation as transcript
+// deltas and hands each finished sentence to a worker that speaks it, and
+// returns the text generated.
+func (s *realtimeSession) answer(ctx context.Context, id string, cfg realtimeSessionConfig) (string, error) {
+	b, err := s.reg.AdmitHealthy(ctx, backend.KindChat, s.hc)
+	if err != nil {
+		return "", backendSelectError(s.reg, err)
+	}
+	defer s.reg.ReleaseBackend(b.Name())
+
+	sentences := make(chan string, 16)
+	spoken := make(chan error, 1)
+	go func() { spoken <- s.speakAll(ctx, id, cfg, sentences) }()
+
+	req := backend.ChatRequest{
+		Model:       cfg.Model,
+		Messages:    s.messages(cfg.Instructions),
+		Stream:      true,
+		Temperature: cfg.Temperature,
+		MaxTokens:   cfg.MaxResponseOutputTokens,
+	}
+	var text strings.Builder
+	split := speech.NewSentences(streamSegmentChars)
+	start := time.Now()
+	first := true
+	err = b.ChatCompletionStream(ctx, req, func(data []byte) error {
+		if first {
+			first = false
+			s.reg.ObserveLatency(backend.KindChat, b.Name(), time.Since(start))
+		}
+		delta := chunkContent(data)
+		if delta == "" {
+			return nil
+		}
+		text.WriteString(delta)
+		s.send("response.audio_transcript.delta", map[string]any{"response_id": id, "delta": delta})
+		for _, sentence := range split.Write(delta) {
+			sentences <- sentence
+		}
+		return nil
+	})
+	if err == nil {
+		middleware.BackendRequestDuration.WithLabelValues(b.Name(), "chat_stream").Observe(time.Since(start).Seconds())
+		if rest := split.Flush(); rest != "" {
+			sentences <- rest
+		}
+	}
+	close(sentences)
+	// A barge-in's cancellation counts as neither success nor failure, but
+	// it must still be reported so a half-open breaker's trial is returned.
+	backend.ReportOutcome(s.hc, b.Name(), err)
+	if err != nil {
+		err = apierror.FromBackendError(b.Name(), err)
+	}
+	if serr := <-spoken; err == nil {
+		err = serr
+	}
+
+	s.send("response.audio_transcript.done", map[string]any{"response_id": id, "transcript": text.String()})
+	s.send("response.audio.done", map[string]any{"response_id": id})
+	return text.String(), err
+}
+
+// speakAll synthesizes each sentence in turn and streams its audio. After a
+// failure or cancellation the remaining sentences are discarded.
+func (s *realtimeSession) speakAll(ctx context.Context, id string, cfg realtimeSessionConfig, sentences <-chan string) error {
+	var err error
+	chunk := pcmLen(realtimeAudioChunk)
+	for text := range sentences {
+		if err != nil || ctx.Err() != nil {
+			continue
+		}
+		var pcm []byte
+		if pcm, err = s.speak(ctx, cfg, text); err != nil {
+			continue
+		}
+		for len(pcm) > 0 && ctx.Err() == nil {
+			n := min(len(pcm), chunk)
+			s.send("response.audio.delta", map[string]any{"response_id": id, "delta": base64.StdEncoding.EncodeToString(pcm[:n])})
+			pcm = pcm[n:]
+		}
+	}
+	return err
+}
+
+// speak synthesizes text on a TTS backend, returning session PCM.
+func (s *realtimeSession) speak(ctx context.Context, cfg realtimeSessionConfig, text string) ([]byte, error) {
+	req := backend.TTSRequest{
+		Model:          cfg.TTSModel,
+		Voice:          cfg.Voice,
+		Input:          s.lexicon.Apply(text),
+		ResponseFormat: "wav",
+		Speed:          cfg.Speed,
+	}
+	resp, err := synthesize(ctx, s.rtr, s.hc, s.o.voices, req, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	f, pcm, err := audio.ParseWAV(resp.Audio)
+	if err != nil {
+		return nil, err
+	}
+	return audio.ToPCM(f, pcm)
+}
+
+// addMessage appends a message to the conversation.
+func (s *realtimeSession) addMessage(role, text string) {
+	content, _ := json.Marshal(text)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = append(s.history, backend.Message{Role: role, Content: content})
+}
+
+// messages returns the conversation so far, after the instructions.
+func (s *realtimeSession) messages(instructions string) []backend.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msgs := make([]backend.Message, 0, len(s.history)+1)
+	if instructions != "" {
+		content, _ := json.Marshal(instructions)
+		msgs = append(msgs, backend.Message{Role: "system", Content: content})
+	}
+	return append(msgs, s.history...)
+}
+
+// send writes a server event of type typ with fields.
+func (s *realtimeSession) send(typ string, fields map[string]any) {
+	ev := map[string]any{"type": typ, "event_id": newID("event_")}
+	for k, v := range fields {
+		ev[k] = v
+	}
+	if err := wsjson.Write(s.ctx, s.conn, ev); err != nil && s.ctx.Err() == nil {
+		s.logger.Debug("realtime event not sent", "type", typ, "err", err)
+	}
+}
+
+// sendError writes an error event.
+func (s *realtimeSession) sendError(apiErr *apierror.Error) {
+	s.send("error", map[string]any{"error": apiErr})
+}
+
+// chunkContent returns the text of the first choice of a streamed chat
+// chunk, or "" for anything else.
+func chunkContent(data []byte) string {
+	var chunk backend.ChatResponse
+	if json.Unmarshal(data, &chunk) != nil || len(chunk.Choices) == 0 || chunk.Choices[0].Delta == nil {
+		return ""
+	}
+	var text string
+	_ = json.Unmarshal(chunk.Choices[0].Delta.Content, &text)
+	return text
+}
+
+// pcmLen returns the length of d of session audio in bytes.
+func pcmLen(d time.Duration) int {
+	f := audio.PCMFormat
+	return int(d.Seconds()*float64(f.SampleRate)) * f.BlockAlign()
+}
+
+// newID returns prefix followed by 24 random hex digits.
+func newID(prefix string) string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return prefix + hex.EncodeToString(b[:])
+}
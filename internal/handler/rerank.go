@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/middleware"
+	"github.com/menezmethod/inferencia/internal/router"
+)
+
+// maxRerankDocuments caps the number of documents in one rerank request.
+const maxRerankDocuments = 1000
+
+// Rerank handles document reranking requests in the Cohere/Jina shape.
+//
+//	POST /v1/rerank
+//
+// Requests naming a model go to a rerank backend that serves it, and get 404
+// when none does; requests without one go to any rerank backend. Results are
+// returned most relevant first, cut to top_n, with each document's text when
+// return_documents is set, whatever the backend itself does.
+func Rerank(rtr *router.Registry, hc backend.HealthChecker, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req backend.RerankRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, apierror.InvalidRequest("Invalid JSON in request body: "+err.Error()))
+			return
+		}
+
+		if strings.TrimSpace(req.Query) == "" {
+			apierror.Write(w, apierror.InvalidParam("query", "query is required and must not be empty"))
+			return
+		}
+		if len(req.Documents) == 0 {
+			apierror.Write(w, apierror.InvalidParam("documents", "documents is required and must not be empty"))
+			return
+		}
+		if len(req.Documents) > maxRerankDocuments {
+			apierror.Write(w, apierror.InvalidParam("documents", fmt.Sprintf("documents must have at most %d entries", maxRerankDocuments)))
+			return
+		}
+		if req.TopN != nil && *req.TopN < 1 {
+			apierror.Write(w, apierror.InvalidParam("top_n", "top_n must be at least 1"))
+			return
+		}
+
+		if req.Model != "" && !rtr.Advertises(router.CapRerank, req.Model) {
+			apierror.Write(w, apierror.ModelNotFound(req.Model))
+			return
+		}
+		info, err := rtr.AdmitHealthyBackend(r.Context(), router.CapRerank, req.Model, hc)
+		if err != nil {
+			logger.Error("no rerank backend available", "err", err)
+			switch {
+			case errors.Is(err, backend.ErrQueueFull):
+				setRetryAfter(w, rtr.Balancer().RetryAfter())
+				apierror.Write(w, apierror.QueueFull(req.Model))
+			case errors.Is(err, backend.ErrQueueTimeout):
+				setRetryAfter(w, rtr.Balancer().RetryAfter())
+				apierror.Write(w, apierror.QueueTimeout(req.Model))
+			default:
+				apierror.Write(w, apierror.BackendUnavailable(req.Model))
+			}
+			return
+		}
+		defer rtr.ReleaseBackend(info.Name)
+
+		middleware.RoutingDecisionsTotal.WithLabelValues("rerank", info.Name).Inc()
+
+		if info.RerankBackend == nil {
+			logger.Error("selected backend has no rerank backend", "name", info.Name)
+			backend.ReportOutcome(hc, info.Name, backend.ErrBackendNotFound)
+			apierror.Write(w, apierror.BackendUnavailable(info.Name))
+			return
+		}
+
+		start := time.Now()
+		resp, err := info.RerankBackend.Rerank(r.Context(), req)
+		elapsed := time.Since(start)
+		backend.ReportOutcome(hc, info.Name, err)
+		if err != nil {
+			logger.Error("rerank failed", "backend", info.Name, "err", err)
+			apierror.Write(w, apierror.FromBackendError(info.Name, err))
+			return
+		}
+		rtr.ObserveLatency(router.CapRerank, info.Name, elapsed)
+		middleware.BackendRequestDuration.WithLabelValues(info.Name, "rerank").Observe(elapsed.Seconds())
+
+		if err := normalizeRerank(resp, req); err != nil {
+			logger.Error("invalid rerank response", "backend", info.Name, "err", err)
+			apierror.Write(w, apierror.Internal("Backend returned an invalid rerank response."))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.Error("failed to encode rerank response", "err", err)
+		}
+	}
+}
+
+// normalizeRerank sorts resp's results by relevance, applies top_n and
+// return_documents, and rejects results that do not point at a document.
+func normalizeRerank(resp *backend.RerankResponse, req backend.RerankRequest) error {
+	for i := range resp.Results {
+		res := &resp.Results[i]
+		if res.Index < 0 || res.Index >= len(req.Documents) {
+			return errors.New("result index out of range")
+		}
+		res.Document = nil
+		if req.ReturnDocuments {
+			res.Document = &req.Documents[res.Index]
+		}
+	}
+	sort.SliceStable(resp.Results, func(i, j int) bool {
+		return resp.Results[i].RelevanceScore > resp.Results[j].RelevanceScore
+	})
+	if req.TopN != nil && len(resp.Results) > *req.TopN {
+		resp.Results = resp.Results[:*req.TopN]
+	}
+	if resp.Model == "" {
+		resp.Model = req.Model
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/middleware"
+	"github.com/menezmethod/inferencia/internal/semcache"
+)
+
+// SimilarityHeader carries the cosine similarity of a semantic cache hit.
+const SimilarityHeader = "X-Inferencia-Cache-Similarity"
+
+// semanticRequest is a chat request's handle on the semantic cache: the
+// embedded question and where its answer belongs. A nil *semanticRequest
+// stores nothing.
+type semanticRequest struct {
+	c      *semcache.Cache
+	scope  semcache.Scope
+	vec    []float64
+	store  bool
+	logger *slog.Logger
+}
+
+// lookupSemantic embeds the request's question and searches the semantic
+// cache. Cache-Control applies as for the exact cache. A question that cannot
+// be embedded is not cached at all.
+func lookupSemantic(o options, reg *backend.Registry, hc backend.HealthChecker, w http.ResponseWriter, r *http.Request, req backend.ChatRequest, logger *slog.Logger) (*semanticRequest, []byte) {
+	question, ok := semanticQuestion(req)
+	if !ok {
+		return nil, nil
+	}
+	noCache, noStore := cacheControl(r)
+	if noStore {
+		middleware.CacheLookupsTotal.WithLabelValues("semantic", "bypass").Inc()
+		return nil, nil
+	}
+
+	ctx := r.Context()
+	if o.semanticTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.semanticTimeout)
+		defer cancel()
+	}
+	vec, err := embedQuestion(ctx, reg, hc, o.semanticModel, question)
+	if err != nil {
+		logger.Warn("semantic cache embedding failed", "err", err)
+		return nil, nil
+	}
+
+	sr := &semanticRequest{
+		c:      o.semantic,
+		scope:  semcache.Scope{Key: middleware.KeyIDFromContext(r.Context()), Model: req.Model},
+		vec:    vec,
+		store:  true,
+		logger: logger,
+	}
+	if noCache {
+		middleware.CacheLookupsTotal.WithLabelValues("semantic", "bypass").Inc()
+		w.Header().Set(CacheHeader, "miss")
+		return sr, nil
+	}
+
+	value, similarity, hit := o.semantic.Lookup(sr.scope, vec)
+	if !hit {
+		middleware.CacheLookupsTotal.WithLabelValues("semantic", "miss").Inc()
+		w.Header().Set(CacheHeader, "miss")
+		return sr, nil
+	}
+	middleware.CacheLookupsTotal.WithLabelValues("semantic", "hit").Inc()
+	w.Header().Set(CacheHeader, "semantic")
+	w.Header().Set(SimilarityHeader, strconv.FormatFloat(similarity, 'f', 4, 64))
+	return sr, value
+}
+
+// save adds resp to the semantic cache unless it asks for tool calls, which
+// only make sense in the conversation that produced them.
+func (sr *semanticRequest) save(resp *backend.ChatResponse) {
+	if sr == nil || !sr.store || len(resp.Choices) == 0 {
+		return
+	}
+	for _, c := range resp.Choices {
+		if c.Message == nil || len(c.Message.ToolCalls) > 0 {
+			return
+		}
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		sr.logger.Warn("failed to encode response for semantic cache", "err", err)
+		return
+	}
+	sr.c.Add(sr.scope, sr.vec, data)
+	middleware.SemanticCacheEntries.Set(float64(sr.c.Len()))
+}
+
+// semanticQuestion returns the text of the last message when it is a plain
+// user message in a request without tools.
+func semanticQuestion(req backend.ChatRequest) (string, bool) {
+	if len(req.Tools) > 0 || len(req.Messages) == 0 {
+		return "", false
+	}
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role != "user" {
+		return "", false
+	}
+	var text string
+	if err := json.Unmarshal(last.Content, &text); err != nil || strings.TrimSpace(text) == "" {
+		return "", false
+	}
+	return text, true
+}
+
+// embedQuestion embeds text on a healthy embedding backend.
+func embedQuestion(ctx context.Context, reg *backend.Registry, hc backend.HealthChecker, model, text string) ([]float64, error) {
+	b, err := reg.AdmitHealthy(ctx, backend.KindEmbed, hc)
+	if err != nil {
+		return nil, err
+	}
+	defer reg.ReleaseBackend(b.Name())
+
+	input, _ := json.Marshal(text)
+	resp, err := b.CreateEmbedding(ctx, backend.EmbedRequest{Model: model, Input: input})
+	backend.ReportOutcome(hc, b.Name(), err)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, errors.New("embedding backend returned no data")
+	}
+	return []float64(resp.Data[0].Embedding), nil
+}
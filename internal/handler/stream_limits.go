@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/middleware"
+)
+
+// streamLimits bounds streamed responses; see WithStreamLimits.
+type streamLimits struct {
+	heartbeat   time.Duration
+	firstChunk  time.Duration
+	idle        time.Duration
+	maxDuration time.Duration
+}
+
+// Causes of a stream aborted by the gateway.
+var (
+	errStreamNoFirstChunk = errors.New("backend sent nothing within the first chunk timeout")
+	errStreamIdle         = errors.New("backend sent nothing within the stream idle timeout")
+	errStreamTooLong      = errors.New("stream exceeded its maximum duration")
+)
+
+// deadlineMargin leaves time to tell the client why a stream was cut at its
+// maximum duration before the write deadline closes the connection.
+const deadlineMargin = 5 * time.Second
+
+// context derives the context for one stream's generation. It ends after
+// maxDuration, or when the returned cancel is called with a cause.
+func (l streamLimits) context(parent context.Context) (context.Context, context.CancelCauseFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	if l.maxDuration <= 0 {
+		return ctx, cancel
+	}
+	ctx, stop := context.WithTimeoutCause(ctx, l.maxDuration, errStreamTooLong)
+	return ctx, func(cause error) {
+		stop()
+		cancel(cause)
+	}
+}
+
+// watchIdle cancels with errStreamNoFirstChunk unless touch is called within
+// the first chunk timeout, which allows for prompt processing, and then with
+// errStreamIdle unless it is called at least every idle timeout. stop ends
+// the watch.
+func (l streamLimits) watchIdle(cancel context.CancelCauseFunc) (touch, stop func()) {
+	if l.firstChunk <= 0 && l.idle <= 0 {
+		return func() {}, func() {}
+	}
+	var (
+		mu      sync.Mutex
+		t       *time.Timer
+		started bool
+		stopped bool
+	)
+	if l.firstChunk > 0 {
+		t = time.AfterFunc(l.firstChunk, func() { cancel(errStreamNoFirstChunk) })
+	}
+	touch = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case stopped:
+		case started:
+			if t != nil {
+				t.Reset(l.idle)
+			}
+		default:
+			started = true
+			if t != nil {
+				t.Stop()
+				t = nil
+			}
+			if l.idle > 0 {
+				t = time.AfterFunc(l.idle, func() { cancel(errStreamIdle) })
+			}
+		}
+	}
+	stop = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		stopped = true
+		if t != nil {
+			t.Stop()
+		}
+	}
+	return touch, stop
+}
+
+// extendWriteDeadline replaces the server's write timeout for a stream with
+// maxDuration, so long streams are bounded by it rather than by
+// server.write_timeout.
+func (l streamLimits) extendWriteDeadline(w http.ResponseWriter) {
+	if l.maxDuration <= 0 {
+		return
+	}
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(l.maxDuration + deadlineMargin))
+}
+
+// streamAbort reports why ctx's stream was aborted by the gateway, or nil if
+// it was not.
+func streamAbort(ctx context.Context) error {
+	cause := context.Cause(ctx)
+	if errors.Is(cause, errStreamNoFirstChunk) || errors.Is(cause, errStreamIdle) || errors.Is(cause, errStreamTooLong) {
+		middleware.StreamAbortsTotal.WithLabelValues(abortReason(cause)).Inc()
+		return cause
+	}
+	return nil
+}
+
+func abortReason(cause error) string {
+	switch {
+	case errors.Is(cause, errStreamNoFirstChunk):
+		return "first_chunk_timeout"
+	case errors.Is(cause, errStreamIdle):
+		return "idle_timeout"
+	default:
+		return "max_duration"
+	}
+}
+
+// abortEvent is the SSE data of the error event sent when the gateway aborts a
+// stream.
+func abortEvent(cause error) []byte {
+	data, _ := json.Marshal(map[string]*apierror.Error{"error": {
+		Message: "Stream aborted: " + cause.Error() + ".",
+		Type:    apierror.TypeServer,
+		Code:    abortReason(cause),
+	}})
+	return data
+}
+
+// sseWriter serializes writes to a streamed response and, with keepAlive,
+// sends a comment whenever nothing was written for the heartbeat interval so
+// proxies do not close a connection that is waiting on the backend.
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+
+	mu       sync.Mutex
+	beat     *time.Timer
+	interval time.Duration
+	stopped  bool
+}
+
+func newSSEWriter(w http.ResponseWriter, flusher http.Flusher) *sseWriter {
+	return &sseWriter{w: w, flusher: flusher}
+}
+
+// event writes one SSE event, with an id line when id is set.
+func (s *sseWriter) event(id string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	if id != "" {
+		_, err = fmt.Fprintf(s.w, "id: %s\ndata: %s\n\n", id, data)
+	} else {
+		_, err = fmt.Fprintf(s.w, "data: %s\n\n", data)
+	}
+	if err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	if s.beat != nil {
+		s.beat.Reset(s.interval)
+	}
+	return nil
+}
+
+// keepAlive starts heartbeats every interval of silence. The returned stop
+// must be called before the handler returns; no write happens after it.
+func (s *sseWriter) keepAlive(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+	s.mu.Lock()
+	s.interval = interval
+	s.beat = time.AfterFunc(interval, s.heartbeat)
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.stopped = true
+		s.beat.Stop()
+	}
+}
+
+func (s *sseWriter) heartbeat() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return
+	}
+	if _, err := io.WriteString(s.w, ": keep-alive\n\n"); err != nil {
+		return
+	}
+	s.flusher.Flush()
+	middleware.StreamHeartbeatsTotal.Inc()
+	s.beat.Reset(s.interval)
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/middleware"
+	"github.com/menezmethod/inferencia/internal/streams"
+)
+
+// streamIDHeader names the buffered stream in a resumable response.
+const streamIDHeader = "X-Inferencia-Stream-ID"
+
+// handleResumableStream streams a chat completion that is generated into
+// store rather than straight to the client, so the generation survives a
+// dropped connection and can be resumed with Last-Event-ID. The generation
+// owns the backend slot and releases it when it ends. It reports false,
+// having written nothing, when the stream cannot be buffered.
+func handleResumableStream(w http.ResponseWriter, r *http.Request, reg *backend.Registry, hc backend.HealthChecker, b backend.Backend, req backend.ChatRequest, caches chatCaches, store *streams.Store, limits streamLimits, logger *slog.Logger) bool {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return false
+	}
+	ctx, cancel := limits.context(context.WithoutCancel(r.Context()))
+	st, ok := store.Start(middleware.KeyIDFromContext(r.Context()), func() { cancel(context.Canceled) })
+	if !ok {
+		cancel(nil)
+		return false
+	}
+
+	go generateStream(ctx, cancel, reg, hc, b, req, caches, st, limits, logger)
+
+	w.Header().Set(streamIDHeader, st.ID)
+	followStream(w, r, flusher, st, 0, limits, logger)
+	return true
+}
+
+// generateStream runs the upstream generation into st, within limits' idle
+// timeout and maximum duration.
+func generateStream(ctx context.Context, cancel context.CancelCauseFunc, reg *backend.Registry, hc backend.HealthChecker, b backend.Backend, req backend.ChatRequest, caches chatCaches, st *streams.Stream, limits streamLimits, logger *slog.Logger) {
+	defer cancel(nil)
+	defer reg.ReleaseBackend(b.Name())
+	defer st.Close()
+	touch, stopIdle := limits.watchIdle(cancel)
+	defer stopIdle()
+
+	var acc *streamAccumulator
+	if caches.enabled() {
+		acc = newStreamAccumulator()
+	}
+
+	var mu sync.Mutex
+	start := time.Now()
+	first := true
+	err := b.ChatCompletionStream(ctx, req, func(data []byte) error {
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		touch()
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if first {
+			first = false
+			reg.ObserveLatency(backend.KindChat, b.Name(), time.Since(start))
+		}
+		if acc != nil {
+			acc.add(data)
+		}
+		st.Append(data)
+		return nil
+	})
+	if err != nil {
+		if cause := streamAbort(ctx); cause != nil {
+			logger.Warn("stream aborted", "backend", b.Name(), "stream", st.ID, "reason", cause)
+			st.Append(abortEvent(cause))
+			if errors.Is(cause, errStreamIdle) {
+				backend.ReportOutcome(hc, b.Name(), err)
+			} else {
+				backend.ReportOutcome(hc, b.Name(), nil)
+			}
+			return
+		}
+		if ctx.Err() != nil {
+			logger.Info("abandoned stream cancelled", "stream", st.ID, "backend", b.Name())
+			backend.ReportOutcome(hc, b.Name(), nil)
+			return
+		}
+		logger.Error("stream error", "backend", b.Name(), "stream", st.ID, "err", err)
+		backend.ReportOutcome(hc, b.Name(), err)
+		return
+	}
+	backend.ReportOutcome(hc, b.Name(), nil)
+	middleware.BackendRequestDuration.WithLabelValues(b.Name(), "chat_stream").Observe(time.Since(start).Seconds())
+	if acc != nil {
+		mu.Lock()
+		resp, ok := acc.response()
+		mu.Unlock()
+		if ok {
+			caches.save(resp)
+		}
+	}
+}
+
+// resumeStream continues the stream named in lastEventID after the event it
+// names.
+func resumeStream(w http.ResponseWriter, r *http.Request, store *streams.Store, lastEventID string, limits streamLimits, logger *slog.Logger) {
+	id, n, ok := streams.ParseEventID(lastEventID)
+	var st *streams.Stream
+	if ok {
+		st, ok = store.Get(middleware.KeyIDFromContext(r.Context()), id)
+	}
+	if !ok {
+		middleware.StreamResumesTotal.WithLabelValues("not_found").Inc()
+		apierror.Write(w, apierror.NotFound("Stream for event "+lastEventID+" is no longer available."))
+		return
+	}
+	if !st.Buffered(n) {
+		middleware.StreamResumesTotal.WithLabelValues("dropped").Inc()
+		apierror.Write(w, apierror.NotFound("Events after "+lastEventID+" are no longer buffered."))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apierror.Write(w, apierror.Internal("Streaming not supported by this server."))
+		return
+	}
+
+	middleware.StreamResumesTotal.WithLabelValues("resumed").Inc()
+	logger.Info("stream resumed", "stream", st.ID, "after", n)
+	w.Header().Set(streamIDHeader, st.ID)
+	followStream(w, r, flusher, st, n, limits, logger)
+}
+
+// followStream writes st's events after the first n as SSE, each with its
+// event ID, until the generation ends or the client leaves. Heartbeats fill
+// the silences.
+func followStream(w http.ResponseWriter, r *http.Request, flusher http.Flusher, st *streams.Stream, n int, limits streamLimits, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	limits.extendWriteDeadline(w)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	sse := newSSEWriter(w, flusher)
+	defer sse.keepAlive(limits.heartbeat)()
+	err := st.Follow(r.Context(), n, func(id string, data []byte) error {
+		if err := sse.event(id, data); err != nil {
+			return fmt.Errorf("client disconnected: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Info("client left resumable stream", "stream", st.ID, "err", err)
+	}
+}
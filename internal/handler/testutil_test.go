@@ -2,8 +2,10 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"log/slog"
+	"sync"
 
 	"github.com/menezmethod/inferencia/internal/backend"
 	"github.com/menezmethod/inferencia/internal/router"
@@ -18,11 +20,14 @@ func discardLogger() *slog.Logger {
 type mockBackend struct {
 	chatResp    *backend.ChatResponse
 	chatErr     error
+	chatCalls   int
 	lastChatReq backend.ChatRequest
 	modelsResp  *backend.ModelsResponse
 	modelsErr   error
 	embedResp   *backend.EmbedResponse
 	embedErr    error
+	embedCalls  int
+	embedFn     func(backend.EmbedRequest) (*backend.EmbedResponse, error)
 	healthErr   error
 }
 
@@ -31,11 +36,13 @@ func (m *mockBackend) Name() string { return "mock" }
 func (m *mockBackend) Health(context.Context) error { return m.healthErr }
 
 func (m *mockBackend) ChatCompletion(_ context.Context, req backend.ChatRequest) (*backend.ChatResponse, error) {
+	m.chatCalls++
 	m.lastChatReq = req
 	return m.chatResp, m.chatErr
 }
 
 func (m *mockBackend) ChatCompletionStream(_ context.Context, _ backend.ChatRequest, send backend.StreamFunc) error {
+	m.chatCalls++
 	chunk := `{"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"hi"}}]}`
 	if err := send([]byte(chunk)); err != nil {
 		return err
@@ -47,10 +54,20 @@ func (m *mockBackend) ListModels(context.Context) (*backend.ModelsResponse, erro
 	return m.modelsResp, m.modelsErr
 }
 
-func (m *mockBackend) CreateEmbedding(_ context.Context, _ backend.EmbedRequest) (*backend.EmbedResponse, error) {
+func (m *mockBackend) CreateEmbedding(_ context.Context, req backend.EmbedRequest) (*backend.EmbedResponse, error) {
+	m.embedCalls++
+	if m.embedFn != nil {
+		return m.embedFn(req)
+	}
 	return m.embedResp, m.embedErr
 }
 
+// jsonString returns s as a JSON string literal.
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
 type stubHealthChecker struct {
 	healthy map[string]bool
 }
@@ -62,6 +79,41 @@ func (s stubHealthChecker) IsHealthy(name string) bool {
 	return s.healthy[name]
 }
 
+// outcomeRecorder is a backend.PassiveHealth that records the outcomes
+// reported to it.
+type outcomeRecorder struct {
+	mu       sync.Mutex
+	outcomes map[string][]error
+}
+
+func (o *outcomeRecorder) IsHealthy(string) bool { return true }
+
+func (o *outcomeRecorder) OnDispatch(string) bool { return true }
+
+func (o *outcomeRecorder) RecordOutcome(name string, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.outcomes == nil {
+		o.outcomes = make(map[string][]error)
+	}
+	o.outcomes[name] = append(o.outcomes[name], err)
+}
+
+// reported returns the outcomes reported for name so far.
+func (o *outcomeRecorder) reported(name string) []error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]error(nil), o.outcomes[name]...)
+}
+
+// renamedBackend is a mockBackend under another name.
+type renamedBackend struct {
+	*mockBackend
+	name string
+}
+
+func (m *renamedBackend) Name() string { return m.name }
+
 func newTestRegistry(b backend.Backend) *backend.Registry {
 	reg := backend.NewRegistry()
 	reg.Register(b)
@@ -101,3 +153,38 @@ func newTestTTSRegistry(mock *mockTTSBackend) *router.Registry {
 	})
 	return r
 }
+
+// mockRerankBackend implements backend.RerankBackend for testing.
+type mockRerankBackend struct {
+	name    string
+	resp    *backend.RerankResponse
+	err     error
+	lastReq backend.RerankRequest
+}
+
+func (m *mockRerankBackend) Name() string { return m.name }
+
+func (m *mockRerankBackend) Health(context.Context) error { return nil }
+
+func (m *mockRerankBackend) Rerank(_ context.Context, req backend.RerankRequest) (*backend.RerankResponse, error) {
+	m.lastReq = req
+	return m.resp, m.err
+}
+
+// newTestRerankRegistry creates a router.Registry with rerank backends that
+// serve the given models.
+func newTestRerankRegistry(models []string, mocks ...*mockRerankBackend) *router.Registry {
+	r := router.NewRegistry()
+	for i, m := range mocks {
+		info := router.BackendInfo{
+			Name:          m.name,
+			RerankBackend: m,
+			Capabilities:  []router.Capability{router.CapRerank},
+		}
+		if i < len(models) {
+			info.Models = []router.ModelInfo{{ID: models[i], Kind: router.CapRerank}}
+		}
+		r.Register(info)
+	}
+	return r
+}
@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// acceptWebSocket upgrades the request to a WebSocket connection. The
+// server's read and write timeouts are lifted first: the connection lasts as
+// long as the client keeps it open, and each handler bounds it itself. On
+// error the client has already been answered.
+func acceptWebSocket(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+	return websocket.Accept(w, r, nil)
+}
@@ -12,14 +12,17 @@ import (
 // contextKey is an unexported type for context keys in this package.
 type contextKey string
 
-const apiKeyContextKey contextKey = "api_key"
+const (
+	apiKeyContextKey contextKey = "api_key"
+	keyIDContextKey  contextKey = "key_id"
+)
 
 // Auth returns middleware that validates Bearer tokens against the KeyStore.
 // Requests without a valid token receive a 401 response in OpenAI error format.
 func Auth(ks *auth.KeyStore) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			key, ok := extractBearerToken(r)
+			key, ok := BearerToken(r.Header.Get("Authorization"))
 			if !ok {
 				apierror.Write(w, apierror.Unauthorized("Missing or malformed Authorization header. Expected: Bearer <api_key>"))
 				return
@@ -43,9 +46,42 @@ func APIKeyFromContext(ctx context.Context) string {
 	return key
 }
 
-// extractBearerToken parses the Authorization header for a Bearer token.
-func extractBearerToken(r *http.Request) (string, bool) {
-	h := r.Header.Get("Authorization")
+// WithAPIKey returns a copy of ctx carrying key as the authenticated API key,
+// for requests served in-process on a client's behalf.
+func WithAPIKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey, key)
+}
+
+// WithKeyID returns a copy of ctx carrying the auth.KeyID of the API key a
+// request is served for, for requests served in-process when only the ID was
+// kept, such as batch lines.
+func WithKeyID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, keyIDContextKey, id)
+}
+
+// KeyIDFromContext returns the ID set by WithKeyID, or else the auth.KeyID of
+// the authenticated API key. It is empty for unauthenticated requests.
+func KeyIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(keyIDContextKey).(string); ok {
+		return id
+	}
+	if key := APIKeyFromContext(ctx); key != "" {
+		return auth.KeyID(key)
+	}
+	return ""
+}
+
+// KeyPolicyFromContext returns the policy of the API key a request is served
+// for, resolving it from the key ID when the key itself is not in ctx.
+func KeyPolicyFromContext(ctx context.Context, ks *auth.KeyStore) auth.KeyPolicy {
+	if key := APIKeyFromContext(ctx); key != "" {
+		return ks.Policy(key)
+	}
+	return ks.PolicyByID(KeyIDFromContext(ctx))
+}
+
+// BearerToken parses an Authorization header value for a Bearer token.
+func BearerToken(h string) (string, bool) {
 	if h == "" {
 		return "", false
 	}
@@ -45,7 +45,7 @@ func Logging(logger *slog.Logger) Middleware {
 			}
 
 			if key := APIKeyFromContext(r.Context()); key != "" {
-				attrs = append(attrs, slog.String("api_key", maskKey(key)))
+				attrs = append(attrs, slog.String("api_key", MaskKey(key)))
 			}
 
 			level := slog.LevelInfo
@@ -60,8 +60,8 @@ func Logging(logger *slog.Logger) Middleware {
 	}
 }
 
-// maskKey returns the last 8 characters of an API key prefixed with "...".
-func maskKey(key string) string {
+// MaskKey returns the last 8 characters of an API key prefixed with "...".
+func MaskKey(key string) string {
 	if len(key) <= 8 {
 		return "***"
 	}
@@ -92,6 +92,12 @@ func (sw *statusWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Unwrap returns the underlying writer, so http.ResponseController can reach
+// it (e.g. to extend the write deadline of a long stream).
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
 // Flush implements http.Flusher for streaming support.
 func (sw *statusWriter) Flush() {
 	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
@@ -3,6 +3,7 @@ package middleware
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -32,6 +33,21 @@ var (
 		Help:      "Number of HTTP requests currently being processed.",
 	})
 
+	GRPCRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inferencia",
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Total gRPC calls by method and status code.",
+	}, []string{"method", "code"})
+
+	GRPCRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "inferencia",
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "gRPC call latency in seconds.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"method"})
+
 	TokensTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: "inferencia",
 		Name:      "tokens_total",
@@ -44,6 +60,12 @@ var (
 		Help:      "Whether each backend is healthy (1) or down (0).",
 	}, []string{"backend"})
 
+	BackendDrainState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "inferencia",
+		Name:      "backend_drain_state",
+		Help:      "Drain state per backend: 0 active, 1 draining, 2 drained.",
+	}, []string{"backend"})
+
 	BackendRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Namespace: "inferencia",
 		Subsystem: "backend",
@@ -80,6 +102,193 @@ var (
 		Help:      "Total characters sent for TTS synthesis.",
 	}, []string{"backend"})
 
+	TTSTimeToFirstAudio = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "inferencia",
+		Subsystem: "tts",
+		Name:      "time_to_first_audio_seconds",
+		Help:      "Time from admission to the first audio bytes of a streamed TTS response.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"backend"})
+
+	TTSCacheRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inferencia",
+		Subsystem: "tts",
+		Name:      "cache_requests_total",
+		Help:      "TTS audio cache lookups by result (hit, miss, bypass).",
+	}, []string{"result"})
+
+	TTSCacheSizeBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "inferencia",
+		Subsystem: "tts",
+		Name:      "cache_size_bytes",
+		Help:      "Audio held in the TTS cache, in bytes.",
+	})
+
+	RealtimeSessionsActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "inferencia",
+		Subsystem: "realtime",
+		Name:      "sessions_active",
+		Help:      "Open realtime voice WebSocket sessions.",
+	})
+
+	RealtimeTurnsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inferencia",
+		Subsystem: "realtime",
+		Name:      "turns_total",
+		Help:      "Realtime voice responses by status (completed, cancelled, failed).",
+	}, []string{"status"})
+
+	ChatWebSocketsActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "inferencia",
+		Subsystem: "chat_websocket",
+		Name:      "connections_active",
+		Help:      "Open chat completion WebSocket connections.",
+	})
+
+	ChatWebSocketRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inferencia",
+		Subsystem: "chat_websocket",
+		Name:      "requests_total",
+		Help:      "Chat completion requests over WebSockets by status (completed, cancelled, failed).",
+	}, []string{"status"})
+
+	CircuitState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "inferencia",
+		Subsystem: "circuit",
+		Name:      "state",
+		Help:      "Circuit breaker state per backend: 0 closed, 1 half-open, 2 open.",
+	}, []string{"backend"})
+
+	CircuitTransitionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inferencia",
+		Subsystem: "circuit",
+		Name:      "transitions_total",
+		Help:      "Total circuit breaker state transitions by backend and new state.",
+	}, []string{"backend", "state"})
+
+	PriorityRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "inferencia",
+		Subsystem: "priority",
+		Name:      "request_duration_seconds",
+		Help:      "Request latency by priority class and path, including time spent queued.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"class", "path"})
+
+	QueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "inferencia",
+		Subsystem: "queue",
+		Name:      "depth",
+		Help:      "Requests waiting for a backend with free concurrency, by capability and priority class.",
+	}, []string{"kind", "class"})
+
+	QueueWaitDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "inferencia",
+		Subsystem: "queue",
+		Name:      "wait_seconds",
+		Help:      "Time requests spent waiting for backend capacity, by capability, priority class, and outcome (admitted, timeout, canceled).",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"kind", "class", "outcome"})
+
+	QueueRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inferencia",
+		Subsystem: "queue",
+		Name:      "rejections_total",
+		Help:      "Requests turned away because the wait queue was full or timed out, by capability and reason.",
+	}, []string{"kind", "reason"})
+
+	CacheLookupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inferencia",
+		Subsystem: "cache",
+		Name:      "lookups_total",
+		Help:      "Response cache lookups by capability (chat, embed, semantic) and result (hit, miss, bypass).",
+	}, []string{"kind", "result"})
+
+	SemanticCacheEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "inferencia",
+		Subsystem: "semantic_cache",
+		Name:      "entries",
+		Help:      "Answers held in the semantic cache.",
+	})
+
+	EmbedBatchInputs = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "inferencia",
+		Subsystem: "embed_batch",
+		Name:      "inputs",
+		Help:      "Inputs per upstream embedding call made by the batcher, by backend.",
+		Buckets:   []float64{1, 2, 4, 8, 16, 32, 64, 128, 256, 512},
+	}, []string{"backend"})
+
+	EmbedBatchRequests = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "inferencia",
+		Subsystem: "embed_batch",
+		Name:      "requests",
+		Help:      "Client requests coalesced into one upstream embedding call, by model.",
+		Buckets:   []float64{1, 2, 4, 8, 16, 32, 64, 128},
+	}, []string{"model"})
+
+	BatchRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inferencia",
+		Subsystem: "batch",
+		Name:      "requests_total",
+		Help:      "Batch API lines processed, by endpoint and result (success, error).",
+	}, []string{"endpoint", "result"})
+
+	BatchesRunning = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "inferencia",
+		Subsystem: "batch",
+		Name:      "running",
+		Help:      "Batches being validated or processed.",
+	})
+
+	AsyncJobsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inferencia",
+		Subsystem: "async",
+		Name:      "jobs_total",
+		Help:      "Async jobs finished, by endpoint and status (completed, failed).",
+	}, []string{"endpoint", "status"})
+
+	AsyncJobsPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "inferencia",
+		Subsystem: "async",
+		Name:      "jobs_pending",
+		Help:      "Async jobs still running.",
+	})
+
+	AsyncWebhookDeliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inferencia",
+		Subsystem: "async",
+		Name:      "webhook_deliveries_total",
+		Help:      "Async job webhook deliveries by result (success, failure), after retries.",
+	}, []string{"result"})
+
+	StreamsBuffered = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "inferencia",
+		Subsystem: "stream",
+		Name:      "buffered",
+		Help:      "Chat streams buffered for resumption.",
+	})
+
+	StreamResumesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inferencia",
+		Subsystem: "stream",
+		Name:      "resumes_total",
+		Help:      "Stream resumption attempts by result (resumed, not_found, dropped).",
+	}, []string{"result"})
+
+	StreamHeartbeatsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "inferencia",
+		Subsystem: "stream",
+		Name:      "heartbeats_total",
+		Help:      "SSE keep-alive comments sent during upstream silence.",
+	})
+
+	StreamAbortsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "inferencia",
+		Subsystem: "stream",
+		Name:      "aborts_total",
+		Help:      "Streams aborted by the gateway, by reason (first_chunk_timeout, idle_timeout, max_duration).",
+	}, []string{"reason"})
+
 	RoutingDecisionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: "inferencia",
 		Subsystem: "router",
@@ -93,12 +302,18 @@ func normalizePath(path string) string {
 	switch path {
 	case "/v1/chat/completions":
 		return "/v1/chat/completions"
+	case "/v1/chat/completions/ws":
+		return "/v1/chat/completions/ws"
 	case "/v1/models":
 		return "/v1/models"
 	case "/v1/embeddings":
 		return "/v1/embeddings"
 	case "/v1/audio/speech":
 		return "/v1/audio/speech"
+	case "/v1/rerank":
+		return "/v1/rerank"
+	case "/v1/realtime":
+		return "/v1/realtime"
 	case "/health":
 		return "/health"
 	case "/health/ready":
@@ -111,9 +326,27 @@ func normalizePath(path string) string {
 		return "/openapi.yaml"
 	case "/docs":
 		return "/docs"
-	default:
-		return "/other"
+	case "/v1/files":
+		return "/v1/files"
+	case "/v1/batches":
+		return "/v1/batches"
+	case "/v1/async/chat/completions":
+		return "/v1/async/chat/completions"
+	}
+	// Collapse resource IDs so each route is one label value.
+	switch {
+	case strings.HasPrefix(path, "/v1/files/") && strings.HasSuffix(path, "/content"):
+		return "/v1/files/{id}/content"
+	case strings.HasPrefix(path, "/v1/files/"):
+		return "/v1/files/{id}"
+	case strings.HasPrefix(path, "/v1/batches/") && strings.HasSuffix(path, "/cancel"):
+		return "/v1/batches/{id}/cancel"
+	case strings.HasPrefix(path, "/v1/batches/"):
+		return "/v1/batches/{id}"
+	case strings.HasPrefix(path, "/v1/async/"):
+		return "/v1/async/{id}"
 	}
+	return "/other"
 }
 
 // Metrics returns middleware that records Prometheus metrics for every request.
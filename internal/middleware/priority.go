@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/menezmethod/inferencia/internal/apierror"
+	"github.com/menezmethod/inferencia/internal/auth"
+	"github.com/menezmethod/inferencia/internal/priority"
+)
+
+// PriorityHeader lets a client pick a priority class for its request, within
+// what its key's policy allows.
+const PriorityHeader = "X-Inferencia-Priority"
+
+// Priority returns middleware that assigns a priority class to each request:
+// the X-Inferencia-Priority header if present and permitted for the key,
+// otherwise the key's default class. It must run after Auth. The class is
+// stored in the request context for the backend wait queue, echoed in the
+// response header, and used to label per-class latency metrics.
+func Priority(ks *auth.KeyStore) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy := ks.Policy(APIKeyFromContext(r.Context()))
+			class := policy.Priority
+
+			if h := r.Header.Get(PriorityHeader); h != "" {
+				requested, err := priority.Parse(h)
+				if err != nil {
+					apierror.Write(w, apierror.InvalidRequest("Invalid "+PriorityHeader+" header: "+err.Error()))
+					return
+				}
+				if !policy.Allows(requested) {
+					apierror.Write(w, apierror.Forbidden("This API key may not use priority class "+requested.String()+"."))
+					return
+				}
+				class = requested
+			}
+
+			w.Header().Set(PriorityHeader, class.String())
+			start := time.Now()
+			next.ServeHTTP(w, r.WithContext(priority.WithClass(r.Context(), class)))
+			PriorityRequestDuration.WithLabelValues(class.String(), normalizePath(r.URL.Path)).Observe(time.Since(start).Seconds())
+		})
+	}
+}
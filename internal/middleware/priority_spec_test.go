@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/priority"
+)
+
+var _ = Describe("Priority middleware", func() {
+	var (
+		seen    priority.Class
+		handler http.Handler
+	)
+
+	BeforeEach(func() {
+		ks := newTestKeyStore(
+			"sk-plain",
+			"sk-ide priority=interactive",
+			"sk-ops allow_priority=batch,interactive",
+		)
+		handler = Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = priority.FromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		}), Auth(ks), Priority(ks))
+	})
+
+	serve := func(key, header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		if header != "" {
+			req.Header.Set(PriorityHeader, header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("uses the key's default class", func() {
+		rec := serve("sk-ide", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(seen).To(Equal(priority.Interactive))
+		Expect(rec.Header().Get(PriorityHeader)).To(Equal("interactive"))
+	})
+
+	It("lets any key lower its priority", func() {
+		Expect(serve("sk-plain", "batch").Code).To(Equal(http.StatusOK))
+		Expect(seen).To(Equal(priority.Batch))
+	})
+
+	It("rejects a class above the key's default with 403", func() {
+		Expect(serve("sk-plain", "interactive").Code).To(Equal(http.StatusForbidden))
+	})
+
+	It("honours allow_priority", func() {
+		Expect(serve("sk-ops", "interactive").Code).To(Equal(http.StatusOK))
+		Expect(seen).To(Equal(priority.Interactive))
+		Expect(serve("sk-ops", "normal").Code).To(Equal(http.StatusOK), "the key's own class is always allowed")
+	})
+
+	It("rejects an unknown class with 400", func() {
+		Expect(serve("sk-plain", "urgent").Code).To(Equal(http.StatusBadRequest))
+	})
+})
@@ -0,0 +1,68 @@
+// Package priority defines request priority classes. A class is attached to
+// the request context by the priority middleware and read by the backend wait
+// queue, which serves higher classes first.
+package priority
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Class is a request priority. Higher values are served first.
+type Class int
+
+const (
+	// Batch is for bulk, latency-insensitive work such as offline summarization.
+	Batch Class = iota
+	// Normal is the default class.
+	Normal
+	// Interactive is for a user waiting on the response, such as IDE completions.
+	Interactive
+)
+
+// Classes lists every class from lowest to highest.
+var Classes = []Class{Batch, Normal, Interactive}
+
+// String returns the class name used in configuration, headers, and metrics.
+func (c Class) String() string {
+	switch c {
+	case Batch:
+		return "batch"
+	case Normal:
+		return "normal"
+	case Interactive:
+		return "interactive"
+	default:
+		return fmt.Sprintf("class(%d)", int(c))
+	}
+}
+
+// Parse returns the class with the given name (case-insensitive).
+func Parse(s string) (Class, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "batch":
+		return Batch, nil
+	case "normal":
+		return Normal, nil
+	case "interactive":
+		return Interactive, nil
+	default:
+		return Normal, fmt.Errorf("unknown priority class %q (want batch, normal, or interactive)", s)
+	}
+}
+
+type contextKey struct{}
+
+// WithClass returns a copy of ctx carrying class c.
+func WithClass(ctx context.Context, c Class) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the class attached to ctx, or Normal if none is.
+func FromContext(ctx context.Context) Class {
+	if c, ok := ctx.Value(contextKey{}).(Class); ok {
+		return c
+	}
+	return Normal
+}
@@ -0,0 +1,40 @@
+package priority_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/priority"
+)
+
+var _ = Describe("Parse", func() {
+	It("round-trips every class name", func() {
+		for _, c := range priority.Classes {
+			got, err := priority.Parse(c.String())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(got).To(Equal(c))
+		}
+	})
+
+	It("is case-insensitive", func() {
+		Expect(priority.Parse(" Interactive ")).To(Equal(priority.Interactive))
+	})
+
+	It("rejects unknown names", func() {
+		_, err := priority.Parse("urgent")
+		Expect(err).To(MatchError(ContainSubstring("urgent")))
+	})
+})
+
+var _ = Describe("Context", func() {
+	It("defaults to Normal", func() {
+		Expect(priority.FromContext(context.Background())).To(Equal(priority.Normal))
+	})
+
+	It("returns the attached class", func() {
+		ctx := priority.WithClass(context.Background(), priority.Batch)
+		Expect(priority.FromContext(ctx)).To(Equal(priority.Batch))
+	})
+})
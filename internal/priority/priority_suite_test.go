@@ -0,0 +1,13 @@
+package priority_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestPriority(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Priority Suite")
+}
@@ -1,7 +1,11 @@
 // Package router provides capability-aware backend routing and selection.
 package router
 
-import "github.com/menezmethod/inferencia/internal/backend"
+import (
+	"strings"
+
+	"github.com/menezmethod/inferencia/internal/backend"
+)
 
 // Capability represents what kind of inference a backend supports.
 type Capability int
@@ -13,6 +17,10 @@ const (
 	CapEmbed
 	// CapTTS indicates the backend supports text-to-speech.
 	CapTTS
+	// CapRerank indicates the backend supports reranking.
+	CapRerank
+	// CapSTT indicates the backend supports speech-to-text.
+	CapSTT
 )
 
 // String returns the human-readable name of the capability.
@@ -24,6 +32,10 @@ func (c Capability) String() string {
 		return "embed"
 	case CapTTS:
 		return "tts"
+	case CapRerank:
+		return "rerank"
+	case CapSTT:
+		return "stt"
 	default:
 		return "unknown"
 	}
@@ -31,11 +43,40 @@ func (c Capability) String() string {
 
 // BackendInfo describes a registered backend and its capabilities.
 type BackendInfo struct {
-	Name         string
-	Backend      backend.Backend   // chat/embed capable (may be nil)
-	TTSBackend   backend.TTSBackend // TTS capable (may be nil)
-	Capabilities []Capability
-	Models       []ModelInfo
+	Name          string
+	Backend       backend.Backend       // chat/embed capable (may be nil)
+	TTSBackend    backend.TTSBackend    // TTS capable (may be nil)
+	RerankBackend backend.RerankBackend // rerank capable (may be nil)
+	STTBackend    backend.STTBackend    // speech-to-text capable (may be nil)
+	Capabilities  []Capability
+	Models        []ModelInfo
+	DefaultVoice  string // TTS voice used when a request names none (may be empty)
+}
+
+// Serves reports whether b offers a model of the given kind whose ID is model
+// or starts with it, the match the router requires when a model is named.
+func (b BackendInfo) Serves(kind Capability, model string) bool {
+	for _, m := range b.Models {
+		if m.Kind == kind && strings.HasPrefix(m.ID, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// Probe returns the backend used for health checks: the TTS, rerank or STT
+// backend, or nil when the entry has none.
+func (b BackendInfo) Probe() backend.Probe {
+	switch {
+	case b.TTSBackend != nil:
+		return b.TTSBackend
+	case b.RerankBackend != nil:
+		return b.RerankBackend
+	case b.STTBackend != nil:
+		return b.STTBackend
+	default:
+		return nil
+	}
 }
 
 // ModelInfo describes a single model exposed by a backend.
@@ -2,6 +2,7 @@ package router
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/menezmethod/inferencia/internal/backend"
@@ -71,7 +72,8 @@ func (r *Registry) Len() int {
 	return len(r.backends)
 }
 
-// BackendsByCapability returns all backends that support the given capability.
+// BackendsByCapability returns all backends that support the given capability,
+// sorted by name.
 func (r *Registry) BackendsByCapability(kind Capability) []BackendInfo {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -85,9 +87,41 @@ func (r *Registry) BackendsByCapability(kind Capability) []BackendInfo {
 			}
 		}
 	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
 	return result
 }
 
+// Advertises reports whether any backend with the given capability serves
+// model, matching IDs by prefix as routing does.
+func (r *Registry) Advertises(kind Capability, model string) bool {
+	for _, info := range r.BackendsByCapability(kind) {
+		if info.Serves(kind, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// Balancer returns the registry's load balancer so callers can configure
+// strategies and weights.
+func (r *Registry) Balancer() *backend.LoadBalancer {
+	return r.lb
+}
+
+// Scores returns the current load-balancing inputs for a backend, keyed by
+// each capability it serves.
+func (r *Registry) Scores(name string) map[string]backend.Score {
+	info, ok := r.Get(name)
+	if !ok {
+		return nil
+	}
+	scores := make(map[string]backend.Score, len(info.Capabilities))
+	for _, c := range info.Capabilities {
+		scores[c.String()] = r.lb.Score(c.String(), name)
+	}
+	return scores
+}
+
 // ErrBackendNotFound is returned when no matching backend is found.
 var ErrBackendNotFound = fmt.Errorf("no backend found")
 
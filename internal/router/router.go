@@ -1,7 +1,10 @@
 package router
 
 import (
+	"context"
+	"slices"
 	"sort"
+	"time"
 
 	"github.com/menezmethod/inferencia/internal/backend"
 )
@@ -10,12 +13,29 @@ import (
 // If a model is specified, it prefers backends that advertise that model.
 // If no model is specified, it returns the first backend that supports the capability.
 func (r *Registry) SelectBackend(kind Capability, model string) (BackendInfo, error) {
-	return r.selectBackend(kind, model, nil)
+	return r.selectBackend(context.Background(), kind, model, nil, nil, false)
 }
 
 // SelectHealthyBackend skips backends the health checker marks degraded.
 func (r *Registry) SelectHealthyBackend(kind Capability, model string, hc backend.HealthChecker) (BackendInfo, error) {
-	return r.selectBackend(kind, model, hc)
+	return r.selectBackend(context.Background(), kind, model, nil, hc, false)
+}
+
+// AdmitHealthyBackend is like SelectHealthyBackend but honours per-backend
+// concurrency limits, waiting for a free slot when every matching backend is
+// full. It returns backend.ErrQueueFull or backend.ErrQueueTimeout when the
+// request cannot be admitted, or ctx's error if the caller gives up first.
+func (r *Registry) AdmitHealthyBackend(ctx context.Context, kind Capability, model string, hc backend.HealthChecker) (BackendInfo, error) {
+	return r.selectBackend(ctx, kind, model, nil, hc, true)
+}
+
+// AdmitHealthyBackendAmong is like AdmitHealthyBackend but only considers the
+// named backends. A nil names considers all of them.
+func (r *Registry) AdmitHealthyBackendAmong(ctx context.Context, kind Capability, model string, names []string, hc backend.HealthChecker) (BackendInfo, error) {
+	if names == nil {
+		return r.AdmitHealthyBackend(ctx, kind, model, hc)
+	}
+	return r.selectBackend(ctx, kind, model, names, hc, true)
 }
 
 // ReleaseBackend decrements the in-flight counter after a routed request completes.
@@ -23,7 +43,39 @@ func (r *Registry) ReleaseBackend(name string) {
 	r.lb.Release(name)
 }
 
-func (r *Registry) selectBackend(kind Capability, model string, hc backend.HealthChecker) (BackendInfo, error) {
+// Drain stops name from receiving new requests while its in-flight requests
+// finish. It returns ErrBackendNotFound for unknown backends.
+func (r *Registry) Drain(name string) error {
+	if _, ok := r.Get(name); !ok {
+		return ErrBackendNotFound
+	}
+	r.lb.Drain(name)
+	return nil
+}
+
+// Resume puts a drained backend back into rotation. It returns
+// ErrBackendNotFound for unknown backends.
+func (r *Registry) Resume(name string) error {
+	if _, ok := r.Get(name); !ok {
+		return ErrBackendNotFound
+	}
+	r.lb.Resume(name)
+	return nil
+}
+
+// DrainState reports whether name is active, draining, or drained.
+func (r *Registry) DrainState(name string) backend.DrainState {
+	return r.lb.DrainState(name)
+}
+
+// ObserveLatency feeds a latency sample for a backend into the load balancer.
+func (r *Registry) ObserveLatency(kind Capability, name string, d time.Duration) {
+	r.lb.Observe(kind.String(), name, d)
+}
+
+// selectBackend picks among the best-matching healthy backends, restricted to
+// names unless it is nil. Unless wait is set it never queues for capacity.
+func (r *Registry) selectBackend(ctx context.Context, kind Capability, model string, names []string, hc backend.HealthChecker, wait bool) (BackendInfo, error) {
 	candidates := r.BackendsByCapability(kind)
 	if len(candidates) == 0 {
 		return BackendInfo{}, ErrCapabilityNotSupported
@@ -31,6 +83,9 @@ func (r *Registry) selectBackend(kind Capability, model string, hc backend.Healt
 
 	var healthy []BackendInfo
 	for _, c := range candidates {
+		if r.lb.Draining(c.Name) || (names != nil && !slices.Contains(names, c.Name)) {
+			continue
+		}
 		if hc == nil || hc.IsHealthy(c.Name) {
 			healthy = append(healthy, c)
 		}
@@ -40,7 +95,7 @@ func (r *Registry) selectBackend(kind Capability, model string, hc backend.Healt
 	}
 
 	if model == "" {
-		return r.pickBalanced(healthy), nil
+		return r.pickBalanced(ctx, kind, healthy, hc, wait)
 	}
 
 	type scored struct {
@@ -71,19 +126,39 @@ func (r *Registry) selectBackend(kind Capability, model string, hc backend.Healt
 			topTier = append(topTier, sc.info)
 		}
 	}
-	return r.pickBalanced(topTier), nil
+	return r.pickBalanced(ctx, kind, topTier, hc, wait)
 }
 
-func (r *Registry) pickBalanced(candidates []BackendInfo) BackendInfo {
+func (r *Registry) pickBalanced(ctx context.Context, kind Capability, candidates []BackendInfo, hc backend.HealthChecker, wait bool) (BackendInfo, error) {
 	names := make([]string, len(candidates))
 	byName := make(map[string]BackendInfo, len(candidates))
 	for i, c := range candidates {
 		names[i] = c.Name
 		byName[c.Name] = c
 	}
-	picked := r.lb.Select(names)
-	r.lb.Acquire(picked)
-	return byName[picked]
+
+	for len(names) > 0 {
+		var picked string
+		if !wait {
+			if picked = r.lb.SelectFor(kind.String(), names); picked == "" {
+				break
+			}
+			r.lb.Acquire(picked)
+		} else {
+			var err error
+			if picked, err = r.lb.Admit(ctx, kind.String(), names); err != nil {
+				return BackendInfo{}, err
+			}
+		}
+		if backend.NotifyDispatch(hc, picked) {
+			return byName[picked], nil
+		}
+		// The health checker refused it since it was found healthy, as a
+		// breaker does once its trial slots are taken; try the others.
+		r.lb.Release(picked)
+		names = slices.DeleteFunc(names, func(n string) bool { return n == picked })
+	}
+	return BackendInfo{}, backend.ErrNoHealthyBackend
 }
 
 // scoreBackend computes a match score for a backend against a capability + model.
@@ -2,6 +2,7 @@ package router
 
 import (
 	"context"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -96,6 +97,22 @@ var _ = Describe("Registry", func() {
 			Expect(embedBackends).To(BeEmpty())
 		})
 	})
+
+	Describe("Advertises", func() {
+		It("reports whether a backend serves the model for the capability", func() {
+			reg := NewRegistry()
+			reg.Register(BackendInfo{
+				Name:         "tei",
+				Capabilities: []Capability{CapRerank},
+				Models:       []ModelInfo{{ID: "bge-reranker-v2-m3", Kind: CapRerank}},
+			})
+
+			Expect(reg.Advertises(CapRerank, "bge-reranker-v2-m3")).To(BeTrue())
+			Expect(reg.Advertises(CapRerank, "bge-reranker")).To(BeTrue(), "prefix, as routing matches")
+			Expect(reg.Advertises(CapRerank, "other")).To(BeFalse())
+			Expect(reg.Advertises(CapTTS, "bge-reranker-v2-m3")).To(BeFalse())
+		})
+	})
 })
 
 var _ = Describe("SelectBackend", func() {
@@ -210,6 +227,55 @@ var _ = Describe("SelectBackend", func() {
 		})
 	})
 
+	Describe("latency-aware strategy", func() {
+		It("routes to the backend with the lower observed latency for the capability", func() {
+			reg := NewRegistry()
+			for _, name := range []string{"fast", "slow"} {
+				reg.Register(BackendInfo{
+					Name:         name,
+					TTSBackend:   &mockTTSBackend{name: name},
+					Capabilities: []Capability{CapTTS},
+				})
+			}
+			reg.Balancer().SetStrategy(CapTTS.String(), backend.StrategyEWMA)
+			reg.ObserveLatency(CapTTS, "fast", 100*time.Millisecond)
+			reg.ObserveLatency(CapTTS, "slow", 900*time.Millisecond)
+
+			info, err := reg.SelectBackend(CapTTS, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Name).To(Equal("fast"))
+			reg.ReleaseBackend(info.Name)
+
+			Expect(reg.Scores("slow")).To(HaveKey("tts"))
+		})
+	})
+
+	Describe("drain", func() {
+		It("routes around a draining backend until it resumes", func() {
+			reg := NewRegistry()
+			for _, name := range []string{"kokoro-1", "kokoro-2"} {
+				reg.Register(BackendInfo{
+					Name:         name,
+					TTSBackend:   &mockTTSBackend{name: name},
+					Capabilities: []Capability{CapTTS},
+				})
+			}
+			Expect(reg.Drain("kokoro-1")).To(Succeed())
+			Expect(reg.DrainState("kokoro-1")).To(Equal(backend.DrainDrained))
+
+			for range 3 {
+				info, err := reg.SelectBackend(CapTTS, "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Name).To(Equal("kokoro-2"))
+				reg.ReleaseBackend(info.Name)
+			}
+
+			Expect(reg.Resume("kokoro-1")).To(Succeed())
+			Expect(reg.DrainState("kokoro-1")).To(Equal(backend.DrainActive))
+			Expect(reg.Drain("missing")).To(MatchError(ErrBackendNotFound))
+		})
+	})
+
 	Describe("SelectHealthyBackend", func() {
 		It("returns error when the requested model backend is degraded", func() {
 			reg := NewRegistry()
@@ -252,6 +318,30 @@ var _ = Describe("SelectBackend", func() {
 			Expect(info.Name).To(Equal("kokoro"))
 		})
 	})
+
+	Describe("AdmitHealthyBackendAmong", func() {
+		It("only admits the named backends", func() {
+			reg := NewRegistry()
+			for _, name := range []string{"kokoro-1", "kokoro-2"} {
+				reg.Register(BackendInfo{
+					Name:         name,
+					TTSBackend:   &mockTTSBackend{name: name},
+					Capabilities: []Capability{CapTTS},
+					Models:       []ModelInfo{{ID: name, Kind: CapTTS}},
+				})
+			}
+
+			for range 4 {
+				info, err := reg.AdmitHealthyBackendAmong(context.Background(), CapTTS, "kokoro", []string{"kokoro-2"}, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Name).To(Equal("kokoro-2"))
+				reg.ReleaseBackend(info.Name)
+			}
+
+			_, err := reg.AdmitHealthyBackendAmong(context.Background(), CapTTS, "kokoro", []string{}, nil)
+			Expect(err).To(MatchError(backend.ErrNoHealthyBackend))
+		})
+	})
 })
 
 type healthStub struct {
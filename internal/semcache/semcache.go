@@ -0,0 +1,211 @@
+// Package semcache implements the semantic cache: an in-process vector index
+// of past chat answers, keyed by the embedding of the question. A new question
+// whose embedding is close enough to a cached one reuses its answer.
+//
+// Entries are scoped per API key and model so answers never leak between
+// callers or between models. The index is searched by brute force, which is
+// fast enough for the tens of thousands of entries an FAQ bot accumulates.
+package semcache
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+)
+
+// Config controls matching and retention.
+type Config struct {
+	Threshold  float64       // minimum cosine similarity for a hit (default 0.95)
+	MaxEntries int           // across all scopes; the oldest entries are evicted (default 10000)
+	TTL        time.Duration // 0 keeps entries until evicted
+}
+
+// DefaultConfig returns production-grade defaults.
+func DefaultConfig() Config {
+	return Config{
+		Threshold:  0.95,
+		MaxEntries: 10000,
+		TTL:        24 * time.Hour,
+	}
+}
+
+// Scope partitions the index. Key is an API key fingerprint from auth.KeyID,
+// so raw keys are not held by the index.
+type Scope struct {
+	Key   string
+	Model string
+}
+
+type entry struct {
+	scope   Scope
+	vec     []float64 // unit length
+	value   []byte
+	created time.Time
+}
+
+// Stats summarises the cache for the admin API.
+type Stats struct {
+	Entries int   `json:"entries"`
+	Scopes  int   `json:"scopes"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+}
+
+// Cache is safe for concurrent use.
+type Cache struct {
+	cfg Config
+	now func() time.Time
+
+	mu     sync.RWMutex
+	order  *list.List // *entry, oldest first
+	scopes map[Scope][]*list.Element
+	hits   int64
+	misses int64
+}
+
+// New creates a Cache. Zero fields in cfg fall back to DefaultConfig values,
+// except TTL.
+func New(cfg Config) *Cache {
+	def := DefaultConfig()
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = def.Threshold
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = def.MaxEntries
+	}
+	return &Cache{
+		cfg:    cfg,
+		now:    time.Now,
+		order:  list.New(),
+		scopes: make(map[Scope][]*list.Element),
+	}
+}
+
+// Lookup returns the value of the entry in scope most similar to vec, if its
+// cosine similarity reaches the threshold.
+func (c *Cache) Lookup(s Scope, vec []float64) (value []byte, similarity float64, ok bool) {
+	q := normalize(vec)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	best := -1.0
+	var match *entry
+	if q != nil {
+		for _, el := range c.scopes[s] {
+			e := el.Value.(*entry)
+			if c.expired(e) || len(e.vec) != len(q) {
+				continue
+			}
+			if sim := dot(q, e.vec); sim > best {
+				best, match = sim, e
+			}
+		}
+	}
+	if match == nil || best < c.cfg.Threshold {
+		c.misses++
+		return nil, max(best, 0), false
+	}
+	c.hits++
+	return match.value, best, true
+}
+
+// Add stores value under the question embedding vec.
+func (c *Cache) Add(s Scope, vec []float64, value []byte) {
+	v := normalize(vec)
+	if v == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el := c.order.PushBack(&entry{scope: s, vec: v, value: value, created: c.now()})
+	c.scopes[s] = append(c.scopes[s], el)
+
+	for c.order.Len() > c.cfg.MaxEntries {
+		c.removeLocked(c.order.Front())
+	}
+	// Expired entries are oldest first, so they are cheap to drop here.
+	for front := c.order.Front(); front != nil && c.expired(front.Value.(*entry)); front = c.order.Front() {
+		c.removeLocked(front)
+	}
+}
+
+// Purge removes the entries matching key and model and returns how many were
+// removed. An empty key or model matches any.
+func (c *Cache) Purge(key, model string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		s := el.Value.(*entry).scope
+		if (key == "" || s.Key == key) && (model == "" || s.Model == model) {
+			c.removeLocked(el)
+			n++
+		}
+		el = next
+	}
+	return n
+}
+
+// Len returns the number of entries.
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.order.Len()
+}
+
+// Stats returns entry counts and lookup totals.
+func (c *Cache) Stats() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return Stats{Entries: c.order.Len(), Scopes: len(c.scopes), Hits: c.hits, Misses: c.misses}
+}
+
+func (c *Cache) expired(e *entry) bool {
+	return c.cfg.TTL > 0 && c.now().Sub(e.created) >= c.cfg.TTL
+}
+
+func (c *Cache) removeLocked(el *list.Element) {
+	e := el.Value.(*entry)
+	c.order.Remove(el)
+	els := c.scopes[e.scope]
+	for i, other := range els {
+		if other == el {
+			els = append(els[:i], els[i+1:]...)
+			break
+		}
+	}
+	if len(els) == 0 {
+		delete(c.scopes, e.scope)
+	} else {
+		c.scopes[e.scope] = els
+	}
+}
+
+// normalize returns vec scaled to unit length, or nil for a zero vector.
+func normalize(vec []float64) []float64 {
+	var sum float64
+	for _, x := range vec {
+		sum += x * x
+	}
+	if sum == 0 {
+		return nil
+	}
+	norm := math.Sqrt(sum)
+	out := make([]float64, len(vec))
+	for i, x := range vec {
+		out[i] = x / norm
+	}
+	return out
+}
+
+func dot(a, b []float64) float64 {
+	var s float64
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return s
+}
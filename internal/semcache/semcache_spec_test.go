@@ -0,0 +1,80 @@
+package semcache_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/auth"
+	"github.com/menezmethod/inferencia/internal/semcache"
+)
+
+var _ = Describe("Cache", func() {
+	var (
+		c     *semcache.Cache
+		scope semcache.Scope
+	)
+
+	BeforeEach(func() {
+		c = semcache.New(semcache.Config{Threshold: 0.9, MaxEntries: 3})
+		scope = semcache.Scope{Key: auth.KeyID("sk-test"), Model: "m"}
+	})
+
+	It("returns the closest answer above the threshold", func() {
+		c.Add(scope, []float64{1, 0}, []byte("east"))
+		c.Add(scope, []float64{0, 1}, []byte("north"))
+
+		v, sim, ok := c.Lookup(scope, []float64{10, 1})
+		Expect(ok).To(BeTrue())
+		Expect(string(v)).To(Equal("east"))
+		Expect(sim).To(BeNumerically(">", 0.99))
+	})
+
+	It("misses below the threshold", func() {
+		c.Add(scope, []float64{1, 0}, []byte("east"))
+		_, sim, ok := c.Lookup(scope, []float64{1, 1})
+		Expect(ok).To(BeFalse())
+		Expect(sim).To(BeNumerically("~", 0.707, 0.001))
+		Expect(c.Stats().Misses).To(Equal(int64(1)))
+	})
+
+	It("keeps scopes apart", func() {
+		c.Add(scope, []float64{1, 0}, []byte("east"))
+		_, _, ok := c.Lookup(semcache.Scope{Key: auth.KeyID("sk-other"), Model: "m"}, []float64{1, 0})
+		Expect(ok).To(BeFalse())
+		_, _, ok = c.Lookup(semcache.Scope{Key: scope.Key, Model: "n"}, []float64{1, 0})
+		Expect(ok).To(BeFalse())
+	})
+
+	It("evicts the oldest entries beyond MaxEntries", func() {
+		for i := range 4 {
+			c.Add(scope, []float64{1, float64(i) * 10}, []byte{byte('a' + i)})
+		}
+		Expect(c.Len()).To(Equal(3))
+		_, _, ok := c.Lookup(scope, []float64{1, 0})
+		Expect(ok).To(BeFalse())
+	})
+
+	It("ignores expired entries", func() {
+		c = semcache.New(semcache.Config{Threshold: 0.9, TTL: 20 * time.Millisecond})
+		c.Add(scope, []float64{1, 0}, []byte("east"))
+		Eventually(func() bool {
+			_, _, ok := c.Lookup(scope, []float64{1, 0})
+			return ok
+		}).WithTimeout(time.Second).Should(BeFalse())
+	})
+
+	It("purges by key and model", func() {
+		other := semcache.Scope{Key: auth.KeyID("sk-other"), Model: "m"}
+		c.Add(scope, []float64{1, 0}, []byte("a"))
+		c.Add(other, []float64{1, 0}, []byte("b"))
+		c.Add(semcache.Scope{Key: scope.Key, Model: "n"}, []float64{1, 0}, []byte("c"))
+
+		Expect(c.Purge(scope.Key, "m")).To(Equal(1))
+		Expect(c.Purge("", "m")).To(Equal(1))
+		Expect(c.Purge("", "")).To(Equal(1))
+		Expect(c.Len()).To(BeZero())
+		Expect(c.Stats().Scopes).To(BeZero())
+	})
+})
@@ -0,0 +1,13 @@
+package semcache_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSemcache(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Semcache Suite")
+}
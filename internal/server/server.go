@@ -9,22 +9,28 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"github.com/menezmethod/inferencia/internal/async"
 	"github.com/menezmethod/inferencia/internal/auth"
 	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/batch"
 	"github.com/menezmethod/inferencia/internal/config"
 	"github.com/menezmethod/inferencia/internal/handler"
 	"github.com/menezmethod/inferencia/internal/middleware"
 	"github.com/menezmethod/inferencia/internal/router"
+	"github.com/menezmethod/inferencia/internal/semcache"
+	"github.com/menezmethod/inferencia/internal/voices"
 )
 
 // New creates a configured *http.Server with all routes and middleware wired.
-// hc may be nil (treats all backends as healthy) or a watchdog for degraded-backend skipping.
-func New(cfg config.Config, reg *backend.Registry, ks *auth.KeyStore, hc backend.HealthChecker, logger *slog.Logger) *http.Server {
+// hc may be nil (treats all backends as healthy) or a watchdog, optionally combined
+// with circuit breakers, for degraded-backend skipping. rl is the per-key rate
+// limiter, shared with the caller's other routes and the gRPC API so a key has
+// one budget across them. opts are passed to the chat and embeddings handlers.
+func New(cfg config.Config, reg *backend.Registry, ks *auth.KeyStore, hc backend.HealthChecker, rl *middleware.RateLimiter, logger *slog.Logger, opts ...handler.Option) *http.Server {
 	mux := http.NewServeMux()
-	rl := middleware.NewRateLimiter(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
 
 	// Middleware stack applied to authenticated API routes.
-	// Order (outermost → innermost): RequestID → Recover → Metrics → Logging → Auth → RateLimit
+	// Order (outermost → innermost): RequestID → Recover → Metrics → Logging → Auth → Priority → RateLimit
 	// Logging runs after Auth so the canonical log line includes the masked API key.
 	protected := func(h http.Handler) http.Handler {
 		return middleware.Chain(h,
@@ -33,6 +39,7 @@ func New(cfg config.Config, reg *backend.Registry, ks *auth.KeyStore, hc backend
 			middleware.Metrics(),
 			middleware.Logging(logger),
 			middleware.Auth(ks),
+			middleware.Priority(ks),
 			middleware.RateLimit(rl),
 		)
 	}
@@ -45,9 +52,12 @@ func New(cfg config.Config, reg *backend.Registry, ks *auth.KeyStore, hc backend
 	mux.Handle("GET /metrics", promhttp.Handler())
 
 	// OpenAI-compatible API endpoints — auth + rate limiting required.
-	mux.Handle("POST /v1/chat/completions", protected(handler.ChatCompletions(reg, hc, logger)))
+	chat := handler.ChatCompletions(reg, hc, logger, opts...)
+	mux.Handle("POST /v1/chat/completions", protected(chat))
+	// Every request on a chat WebSocket counts against the key's rate limit.
+	mux.Handle("GET /v1/chat/completions/ws", protected(handler.ChatCompletionsWebSocket(middleware.RateLimit(rl)(chat), logger)))
 	mux.Handle("GET /v1/models", protected(handler.Models(reg, hc, logger)))
-	mux.Handle("POST /v1/embeddings", protected(handler.Embeddings(reg, hc, logger)))
+	mux.Handle("POST /v1/embeddings", protected(handler.Embeddings(reg, hc, logger, opts...)))
 
 	return &http.Server{
 		Addr:              cfg.Server.Addr(),
@@ -66,14 +76,94 @@ func New(cfg config.Config, reg *backend.Registry, ks *auth.KeyStore, hc backend
 //
 // Usage:
 //
-//	srv := server.New(cfg, reg, ks, wd, logger)
+//	srv := server.New(cfg, reg, ks, wd, rl, logger)
 //	server.RegisterTTSRoutes(srv, rtr, logger)
-func RegisterTTSRoutes(srv *http.Server, rtr *router.Registry, hc backend.HealthChecker, logger *slog.Logger, protected func(http.Handler) http.Handler) {
+func RegisterTTSRoutes(srv *http.Server, rtr *router.Registry, hc backend.HealthChecker, logger *slog.Logger, protected func(http.Handler) http.Handler, opts ...handler.Option) {
+	if srv.Handler == nil || rtr == nil {
+		return
+	}
+	if mux, ok := srv.Handler.(*http.ServeMux); ok {
+		mux.Handle("POST /v1/audio/speech", protected(handler.Audio(rtr, hc, logger, opts...)))
+	}
+}
+
+// RegisterRerankRoutes adds the rerank endpoint to an existing server's mux.
+// It requires a router registry with rerank backends registered.
+func RegisterRerankRoutes(srv *http.Server, rtr *router.Registry, hc backend.HealthChecker, logger *slog.Logger, protected func(http.Handler) http.Handler) {
 	if srv.Handler == nil || rtr == nil {
 		return
 	}
 	if mux, ok := srv.Handler.(*http.ServeMux); ok {
-		mux.Handle("POST /v1/audio/speech", protected(handler.Audio(rtr, hc, logger)))
+		mux.Handle("POST /v1/rerank", protected(handler.Rerank(rtr, hc, logger)))
+	}
+}
+
+// RegisterRealtimeRoutes adds the realtime voice WebSocket endpoint,
+// GET /v1/realtime. It requires a router registry with STT and TTS backends
+// registered; answers come from the chat backends in reg.
+func RegisterRealtimeRoutes(srv *http.Server, reg *backend.Registry, rtr *router.Registry, hc backend.HealthChecker, logger *slog.Logger, protected func(http.Handler) http.Handler, opts ...handler.Option) {
+	if srv.Handler == nil || rtr == nil {
+		return
+	}
+	if mux, ok := srv.Handler.(*http.ServeMux); ok {
+		mux.Handle("GET /v1/realtime", protected(handler.Realtime(reg, rtr, hc, logger, opts...)))
+	}
+}
+
+// BatchHandlers returns the handlers that serve batch lines, keyed by the
+// endpoint a batch targets. They are built like the routes in New but without
+// middleware: the batch manager supplies the API key and priority itself.
+func BatchHandlers(reg *backend.Registry, hc backend.HealthChecker, logger *slog.Logger, opts ...handler.Option) map[string]http.Handler {
+	return map[string]http.Handler{
+		"/v1/chat/completions": handler.ChatCompletions(reg, hc, logger, opts...),
+		"/v1/embeddings":       handler.Embeddings(reg, hc, logger, opts...),
+	}
+}
+
+// RegisterBatchRoutes adds the Files and Batches endpoints to an existing
+// server's mux. Uploads larger than maxBytes (when positive) are rejected.
+func RegisterBatchRoutes(srv *http.Server, m *batch.Manager, store *batch.Store, maxBytes int64, logger *slog.Logger, protected func(http.Handler) http.Handler) {
+	if srv.Handler == nil || m == nil || store == nil {
+		return
+	}
+	mux, ok := srv.Handler.(*http.ServeMux)
+	if !ok {
+		return
+	}
+
+	mux.Handle("POST /v1/files", protected(handler.UploadFile(store, maxBytes, logger)))
+	mux.Handle("GET /v1/files/{id}", protected(handler.GetFile(store)))
+	mux.Handle("GET /v1/files/{id}/content", protected(handler.FileContent(store, logger)))
+	mux.Handle("POST /v1/batches", protected(handler.CreateBatch(m, logger)))
+	mux.Handle("GET /v1/batches", protected(handler.ListBatches(m)))
+	mux.Handle("GET /v1/batches/{id}", protected(handler.GetBatch(m)))
+	mux.Handle("POST /v1/batches/{id}/cancel", protected(handler.CancelBatch(m, logger)))
+}
+
+// RegisterAsyncRoutes adds the async chat endpoint and job polling to an
+// existing server's mux. opts should include handler.WithAsync(m) so that
+// /v1/chat/completions also honors "Prefer: respond-async".
+func RegisterAsyncRoutes(srv *http.Server, m *async.Manager, reg *backend.Registry, hc backend.HealthChecker, logger *slog.Logger, protected func(http.Handler) http.Handler, opts ...handler.Option) {
+	if srv.Handler == nil || m == nil {
+		return
+	}
+	mux, ok := srv.Handler.(*http.ServeMux)
+	if !ok {
+		return
+	}
+
+	chat := handler.ChatCompletions(reg, hc, logger, opts...)
+	mux.Handle("POST /v1/async/chat/completions", protected(handler.SubmitAsync(m, "/v1/chat/completions", chat, logger)))
+	mux.Handle("GET /v1/async/{id}", protected(handler.GetAsyncJob(m)))
+}
+
+// RegisterVoiceRoutes adds the voice catalog endpoint, GET /v1/audio/voices.
+func RegisterVoiceRoutes(srv *http.Server, catalog *voices.Catalog, protected func(http.Handler) http.Handler) {
+	if srv.Handler == nil || catalog == nil {
+		return
+	}
+	if mux, ok := srv.Handler.(*http.ServeMux); ok {
+		mux.Handle("GET /v1/audio/voices", protected(handler.Voices(catalog)))
 	}
 }
 
@@ -90,6 +180,7 @@ func RegisterTTSRoute(mux *http.ServeMux, rtr *router.Registry, ks *auth.KeyStor
 			middleware.Metrics(),
 			middleware.Logging(logger),
 			middleware.Auth(ks),
+			middleware.Priority(ks),
 			middleware.RateLimit(rl),
 		)
 	}
@@ -109,6 +200,54 @@ func RegisterHealthStatusRoute(srv *http.Server, reg *backend.Registry, ttsReg *
 	}
 }
 
+// RegisterAdminRoutes adds the operator API under /admin/. Requests must carry
+// one of the admin keys in adminKS as a Bearer token; regular API keys are not
+// accepted. Admin routes are not rate limited.
+func RegisterAdminRoutes(srv *http.Server, reg *backend.Registry, ttsReg *router.Registry, adminKS *auth.KeyStore, logger *slog.Logger) {
+	if srv.Handler == nil || adminKS == nil {
+		return
+	}
+	mux, ok := srv.Handler.(*http.ServeMux)
+	if !ok {
+		return
+	}
+
+	admin := adminChain(adminKS, logger)
+	mux.Handle("GET /admin/backends", admin(handler.AdminBackends(reg, ttsReg)))
+	mux.Handle("POST /admin/backends/{name}/drain", admin(handler.DrainBackend(reg, ttsReg, logger)))
+	mux.Handle("POST /admin/backends/{name}/resume", admin(handler.ResumeBackend(reg, ttsReg, logger)))
+}
+
+// RegisterSemanticCacheRoutes adds the semantic cache's stats and purge
+// endpoints to the operator API, with the same authentication as
+// RegisterAdminRoutes.
+func RegisterSemanticCacheRoutes(srv *http.Server, sc *semcache.Cache, adminKS *auth.KeyStore, logger *slog.Logger) {
+	if srv.Handler == nil || sc == nil || adminKS == nil {
+		return
+	}
+	mux, ok := srv.Handler.(*http.ServeMux)
+	if !ok {
+		return
+	}
+
+	admin := adminChain(adminKS, logger)
+	mux.Handle("GET /admin/cache/semantic", admin(handler.SemanticCacheStats(sc)))
+	mux.Handle("POST /admin/cache/semantic/purge", admin(handler.PurgeSemanticCache(sc, logger)))
+}
+
+// adminChain returns the middleware stack for operator routes.
+func adminChain(adminKS *auth.KeyStore, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return middleware.Chain(h,
+			middleware.RequestID(),
+			middleware.Recover(logger),
+			middleware.Metrics(),
+			middleware.Logging(logger),
+			middleware.Auth(adminKS),
+		)
+	}
+}
+
 // Shutdown gracefully shuts down the server with the given context.
 func Shutdown(ctx context.Context, srv *http.Server, logger *slog.Logger) {
 	logger.Info("shutting down server")
@@ -0,0 +1,91 @@
+package speech
+
+import (
+	"maps"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Lexicon replaces terms a TTS model mispronounces with spellings it reads
+// correctly, e.g. "nginx" with "engine x". Terms match case-sensitively and
+// only as whole words; a longer term wins over a shorter one at the same
+// position. A nil *Lexicon replaces nothing.
+type Lexicon struct {
+	terms []string // longest first
+	subs  map[string]string
+}
+
+// NewLexicon returns a lexicon of the given term → replacement maps. Later
+// maps override earlier ones, so a key's lexicon can be layered over the
+// global one. Empty terms are ignored.
+func NewLexicon(entries ...map[string]string) *Lexicon {
+	l := &Lexicon{subs: make(map[string]string)}
+	for _, m := range entries {
+		maps.Copy(l.subs, m)
+	}
+	delete(l.subs, "")
+	for t := range l.subs {
+		l.terms = append(l.terms, t)
+	}
+	sort.Slice(l.terms, func(i, j int) bool {
+		if len(l.terms[i]) != len(l.terms[j]) {
+			return len(l.terms[i]) > len(l.terms[j])
+		}
+		return l.terms[i] < l.terms[j]
+	})
+	return l
+}
+
+// Len returns the number of terms.
+func (l *Lexicon) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.terms)
+}
+
+// Apply returns text with every term replaced.
+func (l *Lexicon) Apply(text string) string {
+	if l.Len() == 0 {
+		return text
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		if t := l.match(text, i); t != "" {
+			b.WriteString(l.subs[t])
+			i += len(t)
+			continue
+		}
+		_, n := utf8.DecodeRuneInString(text[i:])
+		b.WriteString(text[i : i+n])
+		i += n
+	}
+	return b.String()
+}
+
+// match returns the term found at text[i:], or "".
+func (l *Lexicon) match(text string, i int) string {
+	before, _ := utf8.DecodeLastRuneInString(text[:i])
+	for _, t := range l.terms {
+		if !strings.HasPrefix(text[i:], t) {
+			continue
+		}
+		first, _ := utf8.DecodeRuneInString(t)
+		last, _ := utf8.DecodeLastRuneInString(t)
+		after, _ := utf8.DecodeRuneInString(text[i+len(t):])
+		if i > 0 && isWord(first) && isWord(before) {
+			continue
+		}
+		if i+len(t) < len(text) && isWord(last) && isWord(after) {
+			continue
+		}
+		return t
+	}
+	return ""
+}
+
+func isWord(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,150 @@
+// Package speech prepares text for synthesis: it breaks input into segments a
+// TTS backend can render independently, and translates SSML and pronunciation
+// lexicons into plain text, pauses and speed changes.
+package speech
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// abbreviations end in a period that does not end a sentence.
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "sr": true,
+	"jr": true, "st": true, "vs": true, "etc": true, "e.g": true, "i.e": true,
+	"fig": true, "approx": true,
+}
+
+// Split breaks text into sentences, trimmed and in order. Line breaks end a
+// sentence too, so headings and list items are spoken on their own. A
+// sentence longer than maxLen bytes is broken at the last clause or word
+// boundary that fits; maxLen <= 0 means no limit.
+func Split(text string, maxLen int) []string {
+	var out []string
+	for _, s := range sentences(text) {
+		for maxLen > 0 && len(s) > maxLen {
+			cut := breakPoint(s, maxLen)
+			out = append(out, strings.TrimSpace(s[:cut]))
+			s = strings.TrimSpace(s[cut:])
+		}
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// paragraphBreak separates paragraphs: a line break followed by a blank line.
+var paragraphBreak = regexp.MustCompile(`\n[ \t\r]*\n`)
+
+// Pack groups the sentences of text into segments of at most maxLen bytes,
+// for synthesizing long text in parts. Sentences are joined with a space and
+// a paragraph always starts a new segment, so segment boundaries fall where
+// a reader would pause anyway.
+func Pack(text string, maxLen int) []string {
+	var out []string
+	for _, para := range paragraphBreak.Split(text, -1) {
+		var cur strings.Builder
+		for _, s := range Split(para, maxLen) {
+			if cur.Len() > 0 && cur.Len()+1+len(s) > maxLen {
+				out = append(out, cur.String())
+				cur.Reset()
+			}
+			if cur.Len() > 0 {
+				cur.WriteByte(' ')
+			}
+			cur.WriteString(s)
+		}
+		if cur.Len() > 0 {
+			out = append(out, cur.String())
+		}
+	}
+	return out
+}
+
+// sentences splits text after terminal punctuation followed by whitespace,
+// and at line breaks.
+func sentences(text string) []string {
+	var out []string
+	emit := func(s string) {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		next := i + size
+		switch {
+		case r == '\n':
+			emit(text[start:i])
+			start = next
+		case isTerminal(r):
+			// Closing quotes and brackets belong to the sentence they end.
+			for next < len(text) {
+				c, n := utf8.DecodeRuneInString(text[next:])
+				if !isTerminal(c) && !strings.ContainsRune(`"')]”’»`, c) {
+					break
+				}
+				next += n
+			}
+			c, _ := utf8.DecodeRuneInString(text[next:])
+			wide := r == '。' || r == '！' || r == '？'
+			if next == len(text) || unicode.IsSpace(c) || wide {
+				if r != '.' || !isAbbreviation(text[start:i]) {
+					emit(text[start:next])
+					start = next
+				}
+			}
+		}
+		i = next
+	}
+	emit(text[start:])
+	return out
+}
+
+func isTerminal(r rune) bool {
+	switch r {
+	case '.', '!', '?', '…', '。', '！', '？':
+		return true
+	}
+	return false
+}
+
+// isAbbreviation reports whether s ends in a word that takes a period
+// without ending the sentence, such as "Dr" or a single initial.
+func isAbbreviation(s string) bool {
+	word := s
+	if i := strings.LastIndexFunc(s, unicode.IsSpace); i >= 0 {
+		word = s[i+1:]
+	}
+	word = strings.TrimLeft(word, `"'(`)
+	if utf8.RuneCountInString(word) == 1 {
+		r, _ := utf8.DecodeRuneInString(word)
+		return unicode.IsUpper(r)
+	}
+	return abbreviations[strings.ToLower(word)]
+}
+
+// breakPoint returns where to cut s so the first part is at most maxLen
+// bytes: after the last clause punctuation, else before the last space,
+// else at the last rune boundary.
+func breakPoint(s string, maxLen int) int {
+	head := s[:maxLen]
+	if i := strings.LastIndexAny(head, ",;:"); i > 0 {
+		return i + 1
+	}
+	if i := strings.LastIndexFunc(head, unicode.IsSpace); i > 0 {
+		return i
+	}
+	for maxLen > 0 && !utf8.RuneStart(s[maxLen]) {
+		maxLen--
+	}
+	if maxLen == 0 {
+		_, n := utf8.DecodeRuneInString(s)
+		return n
+	}
+	return maxLen
+}
@@ -0,0 +1,97 @@
+package speech_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/speech"
+)
+
+var _ = Describe("Split", func() {
+	It("splits on terminal punctuation followed by whitespace", func() {
+		Expect(speech.Split("Hello there. How are you? Fine!", 0)).To(Equal([]string{
+			"Hello there.", "How are you?", "Fine!",
+		}))
+	})
+
+	It("keeps decimals, abbreviations and initials inside the sentence", func() {
+		Expect(speech.Split("Dr. Smith paid 3.50 dollars to J. Doe. Then left.", 0)).To(Equal([]string{
+			"Dr. Smith paid 3.50 dollars to J. Doe.", "Then left.",
+		}))
+	})
+
+	It("keeps closing quotes with their sentence", func() {
+		Expect(speech.Split(`She said "stop." He did.`, 0)).To(Equal([]string{
+			`She said "stop."`, "He did.",
+		}))
+	})
+
+	It("breaks at line breaks and drops blank lines", func() {
+		Expect(speech.Split("Title\n\n- first item\n- second item", 0)).To(Equal([]string{
+			"Title", "- first item", "- second item",
+		}))
+	})
+
+	It("splits sentences that run without spaces after full-width punctuation", func() {
+		Expect(speech.Split("你好。再见！", 0)).To(Equal([]string{"你好。", "再见！"}))
+	})
+
+	It("breaks a long sentence at clause and word boundaries", func() {
+		got := speech.Split("one two three, four five six seven eight", 16)
+		Expect(got).To(Equal([]string{"one two three,", "four five six", "seven eight"}))
+		for _, s := range got {
+			Expect(len(s)).To(BeNumerically("<=", 16))
+		}
+	})
+
+	It("cuts unbroken text on rune boundaries", func() {
+		got := speech.Split(strings.Repeat("é", 5), 3)
+		Expect(got).To(Equal([]string{"é", "é", "é", "é", "é"}))
+	})
+
+	It("returns nothing for blank input", func() {
+		Expect(speech.Split("  \n ", 10)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Pack", func() {
+	It("packs sentences up to the limit", func() {
+		Expect(speech.Pack("One. Two. Three. Four.", 10)).To(Equal([]string{
+			"One. Two.", "Three.", "Four.",
+		}))
+	})
+
+	It("starts a new segment at each paragraph", func() {
+		Expect(speech.Pack("One. Two.\n\nThree.\n \nFour.", 100)).To(Equal([]string{
+			"One. Two.", "Three.", "Four.",
+		}))
+	})
+
+	It("never exceeds the limit", func() {
+		text := strings.Repeat("A fairly long sentence with several words in it. ", 40)
+		for _, seg := range speech.Pack(text, 120) {
+			Expect(len(seg)).To(BeNumerically("<=", 120))
+		}
+	})
+})
+
+var _ = Describe("Sentences", func() {
+	It("returns each sentence once the next one has started", func() {
+		s := speech.NewSentences(0)
+		var got []string
+		for _, piece := range []string{"Hel", "lo there.", " How", " are you? I'm", " fine"} {
+			got = append(got, s.Write(piece)...)
+		}
+		Expect(got).To(Equal([]string{"Hello there.", "How are you?"}))
+		Expect(s.Flush()).To(Equal("I'm fine"))
+		Expect(s.Flush()).To(BeEmpty())
+	})
+
+	It("breaks sentences longer than maxLen", func() {
+		s := speech.NewSentences(10)
+		Expect(s.Write("one two three four five six")).To(Equal([]string{"one two", "three", "four five"}))
+		Expect(s.Flush()).To(Equal("six"))
+	})
+})
@@ -0,0 +1,320 @@
+package speech
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxBreak caps a single <break>, so one tag cannot hold a backend slot for
+// minutes of silence.
+const maxBreak = 10 * time.Second
+
+// breakStrengths are the pauses for <break strength="...">.
+var breakStrengths = map[string]time.Duration{
+	"none":     0,
+	"x-weak":   100 * time.Millisecond,
+	"weak":     250 * time.Millisecond,
+	"medium":   400 * time.Millisecond,
+	"strong":   750 * time.Millisecond,
+	"x-strong": 1200 * time.Millisecond,
+}
+
+// prosodyRates are the speed multipliers for <prosody rate="...">.
+var prosodyRates = map[string]float64{
+	"x-slow": 0.5,
+	"slow":   0.75,
+	"medium": 1,
+	"fast":   1.25,
+	"x-fast": 1.5,
+}
+
+// Part is one piece of a speech script: a pause, then Text spoken at Rate
+// times the requested speed. The last part of a script may have no text,
+// when the input ends in a pause.
+type Part struct {
+	Pause time.Duration
+	Text  string
+	Rate  float64
+}
+
+// IsSSML reports whether input is an SSML document, i.e. has a <speak> root.
+func IsSSML(input string) bool {
+	return strings.HasPrefix(strings.TrimSpace(input), "<speak")
+}
+
+// Script translates input into parts any TTS backend can render: plain text,
+// pauses and speed changes. Input that is not SSML is one part with the
+// lexicon applied. SSML supports <break> (time or strength), <say-as>
+// (characters, spell-out, verbatim, digits, telephone, ordinal), <sub alias>
+// and <prosody rate>; <p> and <s> end a sentence, and other elements are
+// read as their text. The lexicon applies to text outside <say-as> and <sub>.
+func Script(input string, lex *Lexicon) ([]Part, error) {
+	if !IsSSML(input) {
+		return []Part{{Text: lex.Apply(input), Rate: 1}}, nil
+	}
+	s := &scripter{lex: lex, rate: 1}
+	dec := xml.NewDecoder(strings.NewReader(input))
+	if err := s.element(dec, xml.StartElement{}, 1); err != nil {
+		return nil, err
+	}
+	s.flush()
+	if s.pause > 0 {
+		s.parts = append(s.parts, Part{Pause: s.pause, Rate: 1})
+	}
+	return s.parts, nil
+}
+
+// scripter accumulates the parts of an SSML document.
+type scripter struct {
+	lex   *Lexicon
+	parts []Part
+	pause time.Duration   // pause before the text being accumulated
+	text  strings.Builder // text spoken at rate
+	rate  float64
+}
+
+// element reads the content of start up to its end tag, speaking it at rate.
+// The document itself is read as an element with an empty name.
+func (s *scripter) element(dec *xml.Decoder, start xml.StartElement, rate float64) error {
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) && start.Name.Local == "" {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid SSML: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			s.say(s.lex.Apply(string(t)), rate)
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			if err := s.child(dec, t, rate); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// child handles one element within the document.
+func (s *scripter) child(dec *xml.Decoder, t xml.StartElement, rate float64) error {
+	switch t.Name.Local {
+	case "break":
+		d, err := breakDuration(t)
+		if err != nil {
+			return err
+		}
+		s.flush()
+		s.pause = min(s.pause+d, maxBreak)
+		return dec.Skip()
+	case "sub":
+		alias, ok := attr(t, "alias")
+		if !ok {
+			return errors.New("invalid SSML: <sub> needs an alias")
+		}
+		s.say(alias, rate)
+		return dec.Skip()
+	case "say-as":
+		text, err := innerText(dec)
+		if err != nil {
+			return err
+		}
+		interpret, _ := attr(t, "interpret-as")
+		s.say(sayAs(interpret, text), rate)
+		return nil
+	case "prosody":
+		if v, ok := attr(t, "rate"); ok {
+			r, err := prosodyRate(v)
+			if err != nil {
+				return err
+			}
+			rate *= r
+		}
+		return s.element(dec, t, rate)
+	case "p", "s":
+		if err := s.element(dec, t, rate); err != nil {
+			return err
+		}
+		s.say("\n", rate)
+		return nil
+	default:
+		return s.element(dec, t, rate)
+	}
+}
+
+// say adds text spoken at rate, starting a new part when the rate changes.
+func (s *scripter) say(text string, rate float64) {
+	if rate != s.rate && strings.TrimSpace(s.text.String()) != "" {
+		s.flush()
+	}
+	if strings.TrimSpace(s.text.String()) == "" {
+		s.rate = rate
+	}
+	s.text.WriteString(text)
+}
+
+// flush ends the current part, if it has any text.
+func (s *scripter) flush() {
+	text := collapseSpace(s.text.String())
+	s.text.Reset()
+	if text == "" {
+		return
+	}
+	s.parts = append(s.parts, Part{Pause: s.pause, Text: text, Rate: s.rate})
+	s.pause = 0
+}
+
+// collapseSpace trims text and collapses each run of whitespace to a line
+// break if it has one, else a space, keeping the sentence breaks Split uses.
+func collapseSpace(text string) string {
+	var b strings.Builder
+	space, newline := false, false
+	for _, r := range strings.TrimSpace(text) {
+		if unicode.IsSpace(r) {
+			space = true
+			newline = newline || r == '\n'
+			continue
+		}
+		if space {
+			if newline {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(' ')
+			}
+			space, newline = false, false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// innerText returns the text content of the element just started.
+func innerText(dec *xml.Decoder) (string, error) {
+	var b strings.Builder
+	depth := 1
+	for depth > 0 {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", fmt.Errorf("invalid SSML: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return b.String(), nil
+}
+
+func attr(t xml.StartElement, name string) (string, bool) {
+	for _, a := range t.Attr {
+		if a.Name.Local == name {
+			return strings.TrimSpace(a.Value), true
+		}
+	}
+	return "", false
+}
+
+// breakDuration returns the pause for a <break>: its time, else its
+// strength, else a medium pause.
+func breakDuration(t xml.StartElement) (time.Duration, error) {
+	if v, ok := attr(t, "time"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("invalid SSML: <break> time %q (want e.g. 500ms or 2s)", v)
+		}
+		return d, nil
+	}
+	if v, ok := attr(t, "strength"); ok {
+		d, ok := breakStrengths[v]
+		if !ok {
+			return 0, fmt.Errorf("invalid SSML: unknown <break> strength %q", v)
+		}
+		return d, nil
+	}
+	return breakStrengths["medium"], nil
+}
+
+// prosodyRate parses a rate keyword, a percentage ("150%") or a multiplier
+// ("1.5").
+func prosodyRate(v string) (float64, error) {
+	if r, ok := prosodyRates[v]; ok {
+		return r, nil
+	}
+	pct := strings.HasSuffix(v, "%")
+	r, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+	if pct {
+		r /= 100
+	}
+	if err != nil || r <= 0 {
+		return 0, fmt.Errorf("invalid SSML: <prosody> rate %q", v)
+	}
+	return r, nil
+}
+
+// sayAs renders text as interpret says it should be read. Interpretations
+// the backends already read correctly (cardinal, date, ...) are left as is.
+func sayAs(interpret, text string) string {
+	text = strings.TrimSpace(text)
+	switch interpret {
+	case "characters", "spell-out", "verbatim", "digits":
+		return spell(text, false)
+	case "telephone":
+		return spell(text, true)
+	case "ordinal":
+		return ordinal(text)
+	default:
+		return text
+	}
+}
+
+// spell separates the letters and digits of text so they are read one by
+// one. Other characters are dropped; with groups set, a comma marks each
+// place they were, as the pauses between the groups of a phone number.
+func spell(text string, groups bool) string {
+	var out []string
+	gap := false
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if gap && groups && len(out) > 0 {
+				out[len(out)-1] += ","
+			}
+			out = append(out, string(r))
+			gap = false
+			continue
+		}
+		gap = true
+	}
+	return strings.Join(out, " ")
+}
+
+// ordinal appends the English ordinal suffix to a number: 1st, 22nd, 113th.
+// Anything else is returned unchanged.
+func ordinal(text string) string {
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 0 {
+		return text
+	}
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return text + suffix
+}
@@ -0,0 +1,89 @@
+package speech_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/speech"
+)
+
+var _ = Describe("Script", func() {
+	lex := speech.NewLexicon(map[string]string{"nginx": "engine x", "SQL": "sequel"})
+
+	It("returns plain text as one part with the lexicon applied", func() {
+		Expect(speech.Script("Put nginx in front.", lex)).To(Equal([]speech.Part{
+			{Text: "Put engine x in front.", Rate: 1},
+		}))
+	})
+
+	It("turns breaks into pauses before the following text", func() {
+		parts, err := speech.Script(`<speak>Hello. <break time="500ms"/> World. <break strength="strong"/></speak>`, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parts).To(Equal([]speech.Part{
+			{Text: "Hello.", Rate: 1},
+			{Pause: 500 * time.Millisecond, Text: "World.", Rate: 1},
+			{Pause: 750 * time.Millisecond, Rate: 1},
+		}))
+	})
+
+	It("replaces sub with its alias and spells out say-as", func() {
+		parts, err := speech.Script(`<speak>Call <sub alias="the W three C">W3C</sub> at <say-as interpret-as="telephone">555-0100</say-as> about the <say-as interpret-as="characters">API</say-as>, <say-as interpret-as="ordinal">22</say-as> time.</speak>`, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parts).To(Equal([]speech.Part{
+			{Text: "Call the W three C at 5 5 5, 0 1 0 0 about the A P I, 22nd time.", Rate: 1},
+		}))
+	})
+
+	It("splits parts where the prosody rate changes, multiplying nested rates", func() {
+		parts, err := speech.Script(`<speak>Normal <prosody rate="slow">slower <prosody rate="200%">faster</prosody></prosody> normal</speak>`, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parts).To(Equal([]speech.Part{
+			{Text: "Normal", Rate: 1},
+			{Text: "slower", Rate: 0.75},
+			{Text: "faster", Rate: 1.5},
+			{Text: "normal", Rate: 1},
+		}))
+	})
+
+	It("applies the lexicon outside sub and say-as only", func() {
+		parts, err := speech.Script(`<speak><p>Run SQL on nginx.</p><sub alias="SQL">x</sub></speak>`, lex)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parts).To(Equal([]speech.Part{{Text: "Run sequel on engine x.\nSQL", Rate: 1}}))
+	})
+
+	It("rejects malformed documents and attributes", func() {
+		for _, input := range []string{
+			`<speak>Hello`,
+			`<speak><break time="soon"/></speak>`,
+			`<speak><prosody rate="warp">x</prosody></speak>`,
+			`<speak><sub>x</sub></speak>`,
+		} {
+			_, err := speech.Script(input, nil)
+			Expect(err).To(MatchError(ContainSubstring("invalid SSML")), input)
+		}
+	})
+})
+
+var _ = Describe("Lexicon", func() {
+	It("replaces whole words only, case-sensitively", func() {
+		lex := speech.NewLexicon(map[string]string{"GPU": "G P U", "Go": "Go lang"})
+		Expect(lex.Apply("GPUs and a GPU, Go or go, Gopher")).To(Equal("GPUs and a G P U, Go lang or go, Gopher"))
+	})
+
+	It("prefers the longest term and matches terms with punctuation", func() {
+		lex := speech.NewLexicon(map[string]string{"C": "see", "C++": "see plus plus"})
+		Expect(lex.Apply("C++ and C.")).To(Equal("see plus plus and see."))
+	})
+
+	It("lets later maps override earlier ones", func() {
+		lex := speech.NewLexicon(map[string]string{"k8s": "kates"}, map[string]string{"k8s": "kubernetes"})
+		Expect(lex.Apply("k8s")).To(Equal("kubernetes"))
+	})
+
+	It("does nothing when nil", func() {
+		var lex *speech.Lexicon
+		Expect(lex.Apply("as is")).To(Equal("as is"))
+	})
+})
@@ -0,0 +1,38 @@
+package speech
+
+import "strings"
+
+// Sentences splits text that arrives in pieces, such as a streamed chat
+// answer, into sentences as soon as each is complete, so they can be spoken
+// before the rest of the text exists. A sentence is complete once the text
+// after it has started; the last one is returned by Flush.
+type Sentences struct {
+	maxLen int
+	buf    string
+}
+
+// NewSentences returns a Sentences that breaks sentences longer than maxLen
+// bytes as Split does.
+func NewSentences(maxLen int) *Sentences {
+	return &Sentences{maxLen: maxLen}
+}
+
+// Write adds text and returns the sentences it completed, in order.
+func (s *Sentences) Write(text string) []string {
+	s.buf += text
+	parts := Split(s.buf, s.maxLen)
+	if len(parts) < 2 {
+		return nil
+	}
+	last := parts[len(parts)-1]
+	s.buf = s.buf[strings.LastIndex(s.buf, last):]
+	return parts[:len(parts)-1]
+}
+
+// Flush returns the text not yet returned as a sentence, or "" if there is
+// none, and resets s.
+func (s *Sentences) Flush() string {
+	rest := strings.TrimSpace(s.buf)
+	s.buf = ""
+	return rest
+}
@@ -0,0 +1,238 @@
+// Package streams buffers streamed chat completions so that a client whose
+// connection drops can reconnect and resume from the last event it received
+// instead of regenerating. Generation keeps running while a stream has no
+// reader; if nobody reconnects within the grace period it is cancelled and the
+// buffer dropped. A stream's buffer may be capped, in which case its oldest
+// events are dropped and can no longer be resumed from.
+package streams
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/menezmethod/inferencia/internal/middleware"
+)
+
+// Config controls stream buffering.
+type Config struct {
+	Grace      time.Duration // how long a stream without readers is kept
+	MaxStreams int           // buffered streams at once; 0 = unlimited
+	MaxBytes   int           // event bytes buffered per stream; 0 = unlimited
+}
+
+// ErrEventsDropped is returned by Follow when events after the requested one
+// were already dropped to keep the stream within Config.MaxBytes.
+var ErrEventsDropped = errors.New("stream events no longer buffered")
+
+// Store holds the buffered streams. It is safe for concurrent use.
+type Store struct {
+	cfg Config
+
+	mu      sync.Mutex
+	streams map[string]*Stream
+}
+
+// New creates a Store.
+func New(cfg Config) *Store {
+	return &Store{cfg: cfg, streams: make(map[string]*Stream)}
+}
+
+// Start registers a new stream for owner. cancel stops its generation and is
+// called when the stream is abandoned. It reports false when MaxStreams are
+// already buffered.
+func (s *Store) Start(owner string, cancel context.CancelFunc) (*Stream, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cfg.MaxStreams > 0 && len(s.streams) >= s.cfg.MaxStreams {
+		return nil, false
+	}
+	st := &Stream{
+		ID:     newID(),
+		owner:  owner,
+		store:  s,
+		cancel: cancel,
+		wake:   make(chan struct{}),
+	}
+	s.streams[st.ID] = st
+	middleware.StreamsBuffered.Set(float64(len(s.streams)))
+	return st, true
+}
+
+// Get returns stream id if it is still buffered and belongs to owner.
+func (s *Store) Get(owner, id string) (*Stream, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.streams[id]
+	if !ok || st.owner != owner {
+		return nil, false
+	}
+	return st, true
+}
+
+func (s *Store) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.streams, id)
+	middleware.StreamsBuffered.Set(float64(len(s.streams)))
+}
+
+// Stream is one buffered generation. Events are numbered from 1.
+type Stream struct {
+	ID string
+
+	owner  string
+	store  *Store
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	events    [][]byte // events after the first dropped ones
+	dropped   int
+	size      int // bytes in events
+	done      bool
+	wake      chan struct{} // closed and replaced when events are added or the stream ends
+	followers int
+	timer     *time.Timer
+}
+
+// Append adds an event, dropping the oldest ones beyond MaxBytes. The newest
+// event is always kept.
+func (st *Stream) Append(data []byte) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.events = append(st.events, append([]byte(nil), data...))
+	st.size += len(data)
+	for limit := st.store.cfg.MaxBytes; limit > 0 && st.size > limit && len(st.events) > 1; {
+		st.size -= len(st.events[0])
+		st.events[0] = nil
+		st.events = st.events[1:]
+		st.dropped++
+	}
+	st.notify()
+}
+
+// Buffered reports whether the events after the first n are all still
+// buffered, so that Follow can resume after event n.
+func (st *Stream) Buffered(n int) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return n >= st.dropped
+}
+
+// Close marks the end of the generation. A stream nobody is reading is kept
+// for the grace period so a client that dropped near the end can still fetch
+// the rest.
+func (st *Stream) Close() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.done = true
+	st.notify()
+	if st.followers == 0 && st.timer == nil {
+		st.abandonLater()
+	}
+}
+
+// Follow calls fn with each event after the first n, in order, waiting for
+// new ones until the generation ends or ctx is done. It returns
+// ErrEventsDropped if the next event was dropped, including when a slow
+// reader falls behind. A reader that sees the end releases the stream; one
+// that leaves early starts the grace period.
+func (st *Stream) Follow(ctx context.Context, n int, fn func(id string, data []byte) error) error {
+	st.mu.Lock()
+	st.followers++
+	if st.timer != nil {
+		st.timer.Stop()
+		st.timer = nil
+	}
+	st.mu.Unlock()
+
+	finished := false
+	defer func() {
+		if finished {
+			st.store.remove(st.ID)
+			return
+		}
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		st.followers--
+		if st.followers == 0 {
+			st.abandonLater()
+		}
+	}()
+
+	for {
+		st.mu.Lock()
+		if n < st.dropped {
+			st.mu.Unlock()
+			return ErrEventsDropped
+		}
+		pending := st.events[min(n-st.dropped, len(st.events)):]
+		done := st.done
+		wake := st.wake
+		st.mu.Unlock()
+
+		for _, data := range pending {
+			n++
+			if err := fn(st.ID+":"+strconv.Itoa(n), data); err != nil {
+				return err
+			}
+		}
+		if len(pending) > 0 {
+			continue
+		}
+		if done {
+			finished = true
+			return nil
+		}
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// notify wakes the readers. The caller must hold st.mu.
+func (st *Stream) notify() {
+	close(st.wake)
+	st.wake = make(chan struct{})
+}
+
+// abandonLater drops the stream, cancelling its generation, unless a reader
+// comes back within the grace period. The caller must hold st.mu.
+func (st *Stream) abandonLater() {
+	st.timer = time.AfterFunc(st.store.cfg.Grace, func() {
+		st.mu.Lock()
+		if st.followers > 0 {
+			st.mu.Unlock()
+			return
+		}
+		st.mu.Unlock()
+		st.cancel()
+		st.store.remove(st.ID)
+	})
+}
+
+// ParseEventID splits a Last-Event-ID of the form "<stream>:<n>".
+func ParseEventID(v string) (id string, n int, ok bool) {
+	i := strings.LastIndexByte(v, ':')
+	if i <= 0 {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(v[i+1:])
+	if err != nil || n < 0 {
+		return "", 0, false
+	}
+	return v[:i], n, true
+}
+
+func newID() string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return "stream_" + hex.EncodeToString(b[:])
+}
@@ -0,0 +1,142 @@
+package streams_test
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/streams"
+)
+
+type event struct {
+	id   string
+	data string
+}
+
+// collect follows st after n events and returns what it saw.
+func collect(ctx context.Context, st *streams.Stream, n int) ([]event, error) {
+	var got []event
+	err := st.Follow(ctx, n, func(id string, data []byte) error {
+		got = append(got, event{id, string(data)})
+		return nil
+	})
+	return got, err
+}
+
+var _ = Describe("Store", func() {
+	var store *streams.Store
+
+	BeforeEach(func() {
+		store = streams.New(streams.Config{Grace: time.Minute})
+	})
+
+	It("replays events after the given one and waits for the rest", func() {
+		st, ok := store.Start("sk-a", func() {})
+		Expect(ok).To(BeTrue())
+		st.Append([]byte("a"))
+		st.Append([]byte("b"))
+
+		done := make(chan []event)
+		go func() {
+			got, _ := collect(context.Background(), st, 1)
+			done <- got
+		}()
+		st.Append([]byte("c"))
+		st.Close()
+
+		Eventually(done).Should(Receive(Equal([]event{
+			{st.ID + ":2", "b"},
+			{st.ID + ":3", "c"},
+		})))
+	})
+
+	It("releases a stream once a reader has seen it end", func() {
+		st, _ := store.Start("sk-a", func() {})
+		st.Append([]byte("a"))
+		st.Close()
+		_, err := collect(context.Background(), st, 0)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, ok := store.Get("sk-a", st.ID)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("keeps a stream whose reader left for another reader", func() {
+		st, _ := store.Start("sk-a", func() {})
+		st.Append([]byte("a"))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := collect(ctx, st, 1)
+		Expect(err).To(MatchError(context.Canceled))
+
+		again, ok := store.Get("sk-a", st.ID)
+		Expect(ok).To(BeTrue())
+		Expect(again).To(BeIdenticalTo(st))
+		_, ok = store.Get("sk-b", st.ID)
+		Expect(ok).To(BeFalse())
+	})
+
+	When("nobody comes back within the grace period", func() {
+		BeforeEach(func() {
+			store = streams.New(streams.Config{Grace: 20 * time.Millisecond})
+		})
+
+		It("cancels the generation and drops the stream", func() {
+			var cancelled atomic.Bool
+			st, _ := store.Start("sk-a", func() { cancelled.Store(true) })
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, _ = collect(ctx, st, 0)
+
+			Eventually(cancelled.Load).Should(BeTrue())
+			_, ok := store.Get("sk-a", st.ID)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	It("drops the oldest events beyond MaxBytes and refuses to resume before them", func() {
+		store = streams.New(streams.Config{Grace: time.Minute, MaxBytes: 4})
+		st, _ := store.Start("sk-a", func() {})
+		st.Append([]byte("aa"))
+		st.Append([]byte("bb"))
+		st.Append([]byte("cc"))
+		st.Close()
+
+		Expect(st.Buffered(0)).To(BeFalse())
+		_, err := collect(context.Background(), st, 0)
+		Expect(err).To(MatchError(streams.ErrEventsDropped))
+
+		Expect(st.Buffered(1)).To(BeTrue())
+		got, err := collect(context.Background(), st, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got).To(Equal([]event{
+			{st.ID + ":2", "bb"},
+			{st.ID + ":3", "cc"},
+		}))
+	})
+
+	It("refuses streams beyond MaxStreams", func() {
+		store = streams.New(streams.Config{Grace: time.Minute, MaxStreams: 1})
+		_, ok := store.Start("sk-a", func() {})
+		Expect(ok).To(BeTrue())
+		_, ok = store.Start("sk-a", func() {})
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("ParseEventID", func() {
+	It("splits the stream ID and event number", func() {
+		id, n, ok := streams.ParseEventID("stream_abc:12")
+		Expect(ok).To(BeTrue())
+		Expect(id).To(Equal("stream_abc"))
+		Expect(n).To(Equal(12))
+
+		_, _, ok = streams.ParseEventID("12")
+		Expect(ok).To(BeFalse())
+		_, _, ok = streams.ParseEventID("stream_abc:x")
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1,268 @@
+// Package voices keeps a catalog of the voices offered by the TTS backends,
+// so speech requests can be routed to a backend that has the requested voice
+// and unknown voices rejected before they reach a backend.
+package voices
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/router"
+)
+
+// fetchTimeout bounds how long one backend may take to list its voices.
+const fetchTimeout = 5 * time.Second
+
+// maxSuggestions caps the voices suggested for an unknown voice.
+const maxSuggestions = 5
+
+// Entry is a voice in the catalog with the backends that offer it.
+type Entry struct {
+	backend.Voice
+	Object   string   `json:"object"`
+	Backends []string `json:"backends"`
+}
+
+// UnknownVoiceError is returned by Route when no backend offers a voice.
+type UnknownVoiceError struct {
+	Voice       string
+	Model       string
+	Suggestions []string
+}
+
+func (e *UnknownVoiceError) Error() string {
+	msg := fmt.Sprintf("unknown voice %q", e.Voice)
+	if e.Model != "" {
+		msg = fmt.Sprintf("voice %q is not offered by model %q", e.Voice, e.Model)
+	}
+	if len(e.Suggestions) > 0 {
+		msg += "; did you mean " + strings.Join(e.Suggestions, ", ") + "?"
+	}
+	return msg
+}
+
+// Catalog lists the voices of every TTS backend in a router registry. Lists
+// are fetched on first use and again once they are older than the TTL. A
+// backend whose list cannot be fetched keeps its previous one; a backend
+// that has never listed its voices is assumed to accept any voice.
+type Catalog struct {
+	rtr    *router.Registry
+	hc     backend.HealthChecker
+	ttl    time.Duration
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	lists   map[string][]backend.Voice
+	fetched time.Time
+}
+
+// New returns a catalog over the TTS backends in rtr. Backends hc marks
+// unhealthy are left out of listings and routing.
+func New(rtr *router.Registry, hc backend.HealthChecker, ttl time.Duration, logger *slog.Logger) *Catalog {
+	return &Catalog{
+		rtr:    rtr,
+		hc:     hc,
+		ttl:    ttl,
+		logger: logger,
+		lists:  make(map[string][]backend.Voice),
+	}
+}
+
+// List returns the voices of the healthy TTS backends, sorted by ID. A voice
+// offered by several backends is listed once.
+func (c *Catalog) List(ctx context.Context) []Entry {
+	lists := c.snapshot(ctx)
+	byID := make(map[string]*Entry)
+	for _, info := range c.backends("") {
+		for _, v := range lists[info.Name] {
+			e, ok := byID[v.ID]
+			if !ok {
+				e = &Entry{Voice: v, Object: "voice"}
+				byID[v.ID] = e
+			}
+			e.Backends = append(e.Backends, info.Name)
+		}
+	}
+
+	out := make([]Entry, 0, len(byID))
+	for _, e := range byID {
+		sort.Strings(e.Backends)
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Route returns the healthy backends serving model (any, if empty) that
+// offer voice. Backends that have never listed their voices are returned
+// when none is known to offer it. The result is nil when there is nothing
+// to restrict: no voice was named or no backend serves the model. When every
+// candidate lists its voices and none has this one, Route returns an
+// *UnknownVoiceError with the closest voices they do offer.
+func (c *Catalog) Route(ctx context.Context, model, voice string) ([]string, error) {
+	if voice == "" {
+		return nil, nil
+	}
+	candidates := c.backends(model)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	lists := c.snapshot(ctx)
+	var offering, unlisted []string
+	var known []backend.Voice
+	for _, info := range candidates {
+		list, ok := lists[info.Name]
+		switch {
+		case !ok:
+			unlisted = append(unlisted, info.Name)
+		case slices.ContainsFunc(list, func(v backend.Voice) bool { return v.ID == voice }):
+			offering = append(offering, info.Name)
+		default:
+			known = append(known, list...)
+		}
+	}
+	switch {
+	case len(offering) > 0:
+		return offering, nil
+	case len(unlisted) > 0:
+		return unlisted, nil
+	}
+	return nil, &UnknownVoiceError{Voice: voice, Model: model, Suggestions: suggest(voice, known)}
+}
+
+// First returns the first voice name lists, or "" if its voices are
+// unknown.
+func (c *Catalog) First(ctx context.Context, name string) string {
+	if list := c.snapshot(ctx)[name]; len(list) > 0 {
+		return list[0].ID
+	}
+	return ""
+}
+
+// backends returns the healthy TTS backends serving model, or all healthy
+// TTS backends when model is empty.
+func (c *Catalog) backends(model string) []router.BackendInfo {
+	var out []router.BackendInfo
+	for _, info := range c.rtr.BackendsByCapability(router.CapTTS) {
+		if info.TTSBackend == nil || (c.hc != nil && !c.hc.IsHealthy(info.Name)) {
+			continue
+		}
+		if model != "" && !info.Serves(router.CapTTS, model) {
+			continue
+		}
+		out = append(out, info)
+	}
+	return out
+}
+
+// snapshot returns the voice lists, refreshing them first when they are
+// stale. Concurrent callers wait for one refresh.
+func (c *Catalog) snapshot(ctx context.Context) map[string][]backend.Voice {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fetched.IsZero() || time.Since(c.fetched) >= c.ttl {
+		c.refresh(ctx)
+	}
+	return c.lists
+}
+
+// refresh fetches every TTS backend's voices concurrently. It is called with
+// c.mu held and replaces c.lists rather than modifying it, so snapshots
+// already handed out stay valid.
+func (c *Catalog) refresh(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+	defer cancel()
+
+	type result struct {
+		name   string
+		voices []backend.Voice
+		err    error
+	}
+	infos := c.rtr.BackendsByCapability(router.CapTTS)
+	results := make(chan result, len(infos))
+	for _, info := range infos {
+		go func() {
+			if info.TTSBackend == nil {
+				results <- result{name: info.Name}
+				return
+			}
+			voices, err := info.TTSBackend.Voices(ctx)
+			results <- result{info.Name, voices, err}
+		}()
+	}
+
+	lists := make(map[string][]backend.Voice, len(infos))
+	for range infos {
+		res := <-results
+		switch {
+		case res.err != nil:
+			c.logger.Warn("listing tts voices failed", "backend", res.name, "err", res.err)
+			if prev, ok := c.lists[res.name]; ok {
+				lists[res.name] = prev
+			}
+		case len(res.voices) > 0:
+			lists[res.name] = res.voices
+		}
+	}
+	c.lists = lists
+	c.fetched = time.Now()
+}
+
+// suggest returns up to maxSuggestions voice IDs closest to voice by edit
+// distance, ignoring case.
+func suggest(voice string, voices []backend.Voice) []string {
+	type scored struct {
+		id   string
+		dist int
+	}
+	seen := make(map[string]bool)
+	var all []scored
+	for _, v := range voices {
+		if seen[v.ID] {
+			continue
+		}
+		seen[v.ID] = true
+		all = append(all, scored{v.ID, distance(strings.ToLower(voice), strings.ToLower(v.ID))})
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].dist != all[j].dist {
+			return all[i].dist < all[j].dist
+		}
+		return all[i].id < all[j].id
+	})
+
+	out := make([]string, 0, maxSuggestions)
+	for _, s := range all[:min(len(all), maxSuggestions)] {
+		out = append(out, s.id)
+	}
+	return out
+}
+
+// distance is the Levenshtein distance between a and b in bytes.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,130 @@
+package voices_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/menezmethod/inferencia/internal/backend"
+	"github.com/menezmethod/inferencia/internal/router"
+	"github.com/menezmethod/inferencia/internal/voices"
+)
+
+type ttsStub struct {
+	name   string
+	voices []backend.Voice
+	err    error
+	calls  atomic.Int32
+}
+
+func (t *ttsStub) Name() string                 { return t.name }
+func (t *ttsStub) Health(context.Context) error { return nil }
+func (t *ttsStub) Synthesize(context.Context, backend.TTSRequest) (*backend.TTSResponse, error) {
+	return nil, errors.New("not used")
+}
+func (t *ttsStub) Voices(context.Context) ([]backend.Voice, error) {
+	t.calls.Add(1)
+	return t.voices, t.err
+}
+
+// healthStub marks the backends mapped to false unhealthy.
+type healthStub map[string]bool
+
+func (h healthStub) IsHealthy(name string) bool {
+	healthy, ok := h[name]
+	return !ok || healthy
+}
+
+var _ = Describe("Catalog", func() {
+	var (
+		kokoro, other *ttsStub
+		rtr           *router.Registry
+		health        healthStub
+		catalog       *voices.Catalog
+	)
+
+	register := func(stubs ...*ttsStub) {
+		for _, s := range stubs {
+			rtr.Register(router.BackendInfo{
+				Name:         s.name,
+				TTSBackend:   s,
+				Capabilities: []router.Capability{router.CapTTS},
+				Models:       []router.ModelInfo{{ID: s.name, Kind: router.CapTTS}},
+			})
+		}
+	}
+
+	BeforeEach(func() {
+		kokoro = &ttsStub{name: "kokoro", voices: []backend.Voice{
+			{ID: "af_bella", Language: "en-US", Gender: "female"},
+			{ID: "bf_emma", Language: "en-GB", Gender: "female"},
+		}}
+		other = &ttsStub{name: "piper", voices: []backend.Voice{{ID: "af_bella"}, {ID: "amy"}}}
+		rtr = router.NewRegistry()
+		register(kokoro, other)
+		health = healthStub{}
+		catalog = voices.New(rtr, health, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	})
+
+	It("lists each voice once with the backends that offer it", func() {
+		list := catalog.List(context.Background())
+		Expect(list).To(HaveLen(3))
+		Expect(list[0].ID).To(Equal("af_bella"))
+		Expect(list[0].Language).To(Equal("en-US"))
+		Expect(list[0].Backends).To(Equal([]string{"kokoro", "piper"}))
+		Expect(list[1].ID).To(Equal("amy"))
+		Expect(list[2].Backends).To(Equal([]string{"kokoro"}))
+	})
+
+	It("leaves out unhealthy backends", func() {
+		health["piper"] = false
+		for _, e := range catalog.List(context.Background()) {
+			Expect(e.Backends).To(Equal([]string{"kokoro"}))
+		}
+	})
+
+	It("caches voice lists for the TTL", func() {
+		catalog.List(context.Background())
+		catalog.List(context.Background())
+		Expect(kokoro.calls.Load()).To(Equal(int32(1)))
+	})
+
+	It("routes a voice to the backends that offer it", func() {
+		Expect(catalog.Route(context.Background(), "", "af_bella")).To(Equal([]string{"kokoro", "piper"}))
+		Expect(catalog.Route(context.Background(), "", "amy")).To(Equal([]string{"piper"}))
+		Expect(catalog.Route(context.Background(), "kokoro", "af_bella")).To(Equal([]string{"kokoro"}))
+	})
+
+	It("does not restrict requests without a voice", func() {
+		Expect(catalog.Route(context.Background(), "kokoro", "")).To(BeNil())
+	})
+
+	It("rejects unknown voices with the closest suggestions", func() {
+		_, err := catalog.Route(context.Background(), "", "af_bela")
+		var unknown *voices.UnknownVoiceError
+		Expect(errors.As(err, &unknown)).To(BeTrue())
+		Expect(unknown.Suggestions[0]).To(Equal("af_bella"))
+
+		_, err = catalog.Route(context.Background(), "kokoro", "amy")
+		Expect(errors.As(err, &unknown)).To(BeTrue())
+		Expect(unknown.Model).To(Equal("kokoro"))
+		Expect(unknown.Suggestions).NotTo(ContainElement("amy"))
+	})
+
+	It("sends any voice to backends whose voices are unknown", func() {
+		silent := &ttsStub{name: "silent", err: errors.New("down")}
+		register(silent)
+		Expect(catalog.Route(context.Background(), "", "zz_nobody")).To(Equal([]string{"silent"}))
+	})
+
+	It("names a backend's first voice", func() {
+		Expect(catalog.First(context.Background(), "piper")).To(Equal("af_bella"))
+		Expect(catalog.First(context.Background(), "missing")).To(BeEmpty())
+	})
+})
@@ -35,7 +35,7 @@ type backendState struct {
 	healthy  bool
 }
 
-// Watchdog periodically probes chat/embed and TTS backends,
+// Watchdog periodically probes chat/embed, TTS and rerank backends,
 // updates Prometheus gauges, and marks backends degraded after
 // consecutive failures.
 type Watchdog struct {
@@ -114,13 +114,11 @@ func (w *Watchdog) probe(parent context.Context) {
 		})
 	}
 
-	// TTS backends.
+	// TTS and rerank backends.
 	if w.ttsReg != nil {
 		for _, info := range w.ttsReg.All() {
-			if info.TTSBackend != nil {
-				w.checkBackend(parent, info.Name, func(ctx context.Context) error {
-					return info.TTSBackend.Health(ctx)
-				})
+			if probe := info.Probe(); probe != nil {
+				w.checkBackend(parent, info.Name, probe.Health)
 			}
 		}
 	}